@@ -7,6 +7,8 @@ import (
 	"compress/zlib"
 	"errors"
 	"io"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // NewStreamContentDecoder returns a reader that will decode the stream
@@ -75,6 +77,8 @@ func NewContentEncoder(encoding string) (ContentEncoder, error) {
 		return NewGzipEncoder()
 	case "zlib":
 		return NewZlibEncoder()
+	case "zstd":
+		return NewZstdEncoder()
 	case "identity", "":
 		return NewIdentityEncoder(), nil
 	default:
@@ -89,6 +93,8 @@ func NewContentDecoder(encoding string) (ContentDecoder, error) {
 		return NewGzipDecoder()
 	case "zlib":
 		return NewZlibDecoder()
+	case "zstd":
+		return NewZstdDecoder()
 	case "identity", "":
 		return NewIdentityDecoder(), nil
 	default:
@@ -158,6 +164,25 @@ func (e *ZlibEncoder) Encode(data []byte) ([]byte, error) {
 	return e.buf.Bytes(), nil
 }
 
+// ZstdEncoder compresses the buffer using zstd at the default level.
+type ZstdEncoder struct {
+	encoder *zstd.Encoder
+}
+
+func NewZstdEncoder() (*ZstdEncoder, error) {
+	e, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdEncoder{
+		encoder: e,
+	}, nil
+}
+
+func (e *ZstdEncoder) Encode(data []byte) ([]byte, error) {
+	return e.encoder.EncodeAll(data, nil), nil
+}
+
 // IdentityEncoder is a null encoder that applies no transformation.
 type IdentityEncoder struct{}
 
@@ -231,6 +256,25 @@ func (d *ZlibDecoder) Decode(data []byte) ([]byte, error) {
 	return d.buf.Bytes(), nil
 }
 
+// ZstdDecoder decompresses buffers with zstd compression.
+type ZstdDecoder struct {
+	decoder *zstd.Decoder
+}
+
+func NewZstdDecoder() (*ZstdDecoder, error) {
+	d, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdDecoder{
+		decoder: d,
+	}, nil
+}
+
+func (d *ZstdDecoder) Decode(data []byte) ([]byte, error) {
+	return d.decoder.DecodeAll(data, nil)
+}
+
 // IdentityDecoder is a null decoder that returns the input.
 type IdentityDecoder struct{}
 
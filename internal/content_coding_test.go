@@ -61,6 +61,29 @@ func TestZlibEncodeDecode(t *testing.T) {
 	require.Equal(t, "howdy", string(actual))
 }
 
+func TestZstdEncodeDecode(t *testing.T) {
+	enc, err := NewContentEncoder("zstd")
+	require.NoError(t, err)
+	dec, err := NewContentDecoder("zstd")
+	require.NoError(t, err)
+
+	payload, err := enc.Encode([]byte("howdy"))
+	require.NoError(t, err)
+
+	actual, err := dec.Decode(payload)
+	require.NoError(t, err)
+
+	require.Equal(t, "howdy", string(actual))
+
+	payload, err = enc.Encode([]byte("doody"))
+	require.NoError(t, err)
+
+	actual, err = dec.Decode(payload)
+	require.NoError(t, err)
+
+	require.Equal(t, "doody", string(actual))
+}
+
 func TestIdentityEncodeDecode(t *testing.T) {
 	enc := NewIdentityEncoder()
 	dec := NewIdentityDecoder()
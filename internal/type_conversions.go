@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 )
 
@@ -198,3 +199,45 @@ func ToBool(value interface{}) (bool, error) {
 	}
 	return false, fmt.Errorf("type \"%T\" unsupported", value)
 }
+
+// FieldToFloat64 converts a float64, int64 or uint64 field value to float64.
+// Other types are not converted, to not silently change their meaning.
+func FieldToFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// FieldToInt64 converts an int64 or uint64 field value to int64 if it is in
+// range of the type
+func FieldToInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+// FieldToUint64 converts an int64 or uint64 field value to uint64 if it is
+// not negative
+func FieldToUint64(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case uint64:
+		return v, true
+	case int64:
+		if v >= 0 {
+			return uint64(v), true
+		}
+	}
+	return 0, false
+}
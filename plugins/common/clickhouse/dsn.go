@@ -0,0 +1,55 @@
+// Package clickhouse contains helpers for plugins using the ClickHouse SQL
+// driver.
+package clickhouse
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Query parameters of the former v1 driver replaced by the userinfo, host
+// and path of the DSN in the current driver
+var legacyParams = []string{"username", "password", "database", "alt_hosts"}
+
+// ConvertLegacyDSN moves the credentials, database and alternative hosts
+// given as query parameters, as accepted by the v1 driver, to the userinfo,
+// path and host of the DSN. The current driver silently ignores those
+// parameters. Values already given in the new form take precedence. The
+// returned flag is true if the DSN contained legacy parameters.
+func ConvertLegacyDSN(dsn string) (string, bool, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", false, err
+	}
+
+	params := u.Query()
+	var found bool
+	for _, p := range legacyParams {
+		if _, ok := params[p]; ok {
+			found = true
+		}
+	}
+	if !found {
+		return dsn, false, nil
+	}
+
+	if u.User == nil && params.Get("username") != "" {
+		if password, ok := params["password"]; ok {
+			u.User = url.UserPassword(params.Get("username"), password[0])
+		} else {
+			u.User = url.User(params.Get("username"))
+		}
+	}
+	if strings.TrimPrefix(u.Path, "/") == "" && params.Get("database") != "" {
+		u.Path = "/" + params.Get("database")
+	}
+	if hosts := params.Get("alt_hosts"); hosts != "" {
+		u.Host += "," + hosts
+	}
+
+	for _, p := range legacyParams {
+		params.Del(p)
+	}
+	u.RawQuery = params.Encode()
+	return u.String(), true, nil
+}
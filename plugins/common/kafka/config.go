@@ -1,6 +1,8 @@
 package kafka
 
 import (
+	"errors"
+
 	"github.com/Shopify/sarama"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 )
@@ -21,10 +23,11 @@ func (k *ReadConfig) SetConfig(config *sarama.Config) error {
 type WriteConfig struct {
 	Config
 
-	RequiredAcks     int  `toml:"required_acks"`
-	MaxRetry         int  `toml:"max_retry"`
-	MaxMessageBytes  int  `toml:"max_message_bytes"`
-	IdempotentWrites bool `toml:"idempotent_writes"`
+	RequiredAcks     int    `toml:"required_acks"`
+	MaxRetry         int    `toml:"max_retry"`
+	MaxMessageBytes  int    `toml:"max_message_bytes"`
+	IdempotentWrites bool   `toml:"idempotent_writes"`
+	TransactionalID  string `toml:"transactional_id"`
 }
 
 // SetConfig on the sarama.Config object from the WriteConfig struct.
@@ -39,6 +42,13 @@ func (k *WriteConfig) SetConfig(config *sarama.Config) error {
 	if config.Producer.Idempotent {
 		config.Net.MaxOpenRequests = 1
 	}
+	if k.TransactionalID != "" {
+		// Transactions are built on top of the idempotent producer
+		if !k.IdempotentWrites {
+			return errors.New("transactional_id requires idempotent_writes to be enabled")
+		}
+		config.Producer.Transaction.ID = k.TransactionalID
+	}
 	return k.Config.SetConfig(config)
 }
 
@@ -0,0 +1,72 @@
+package pulsar
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// Config common to all Pulsar clients.
+type Config struct {
+	ServiceURL        string          `toml:"service_url"`
+	AuthToken         string          `toml:"auth_token"`
+	AuthTokenFile     string          `toml:"auth_token_file"`
+	ConnectionTimeout config.Duration `toml:"connection_timeout"`
+	OperationTimeout  config.Duration `toml:"operation_timeout"`
+	tls.ClientConfig
+}
+
+// ClientOptions returns the options of the Pulsar client from the Config
+// struct.
+func (c *Config) ClientOptions(log telegraf.Logger) (pulsar.ClientOptions, error) {
+	if c.ServiceURL == "" {
+		return pulsar.ClientOptions{}, errors.New("service_url required")
+	}
+	u, err := url.Parse(c.ServiceURL)
+	if err != nil {
+		return pulsar.ClientOptions{}, err
+	}
+
+	opts := pulsar.ClientOptions{
+		URL:               c.ServiceURL,
+		ConnectionTimeout: time.Duration(c.ConnectionTimeout),
+		OperationTimeout:  time.Duration(c.OperationTimeout),
+		Logger:            &Logger{Log: log},
+		// Keep the client metrics out of the default registry of the
+		// prometheus_client output
+		MetricsRegisterer: prometheus.NewRegistry(),
+	}
+
+	if u.Scheme == "pulsar+ssl" || u.Scheme == "https" {
+		opts.TLSTrustCertsFilePath = c.TLSCA
+		opts.TLSAllowInsecureConnection = c.InsecureSkipVerify
+		opts.TLSValidateHostname = !c.InsecureSkipVerify
+	}
+
+	// Pulsar supports a single authentication provider
+	var providers int
+	if c.AuthToken != "" {
+		opts.Authentication = pulsar.NewAuthenticationToken(c.AuthToken)
+		providers++
+	}
+	if c.AuthTokenFile != "" {
+		opts.Authentication = pulsar.NewAuthenticationTokenFromFile(c.AuthTokenFile)
+		providers++
+	}
+	if c.TLSCert != "" && c.TLSKey != "" {
+		opts.Authentication = pulsar.NewAuthenticationTLS(c.TLSCert, c.TLSKey)
+		providers++
+	}
+	if providers > 1 {
+		return pulsar.ClientOptions{}, errors.New("auth_token, auth_token_file and tls_cert are mutually exclusive")
+	}
+
+	return opts, nil
+}
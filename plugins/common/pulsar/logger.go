@@ -0,0 +1,94 @@
+package pulsar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Logger passes the messages of the Pulsar client to the plugin logger.
+// Informational messages of the client are logged at the debug level.
+type Logger struct {
+	Log    telegraf.Logger
+	fields log.Fields
+}
+
+func (l *Logger) SubLogger(fields log.Fields) log.Logger {
+	return &Logger{Log: l.Log, fields: mergeFields(l.fields, fields)}
+}
+
+func (l *Logger) WithFields(fields log.Fields) log.Entry {
+	return &Logger{Log: l.Log, fields: mergeFields(l.fields, fields)}
+}
+
+func (l *Logger) WithField(name string, value interface{}) log.Entry {
+	return l.WithFields(log.Fields{name: value})
+}
+
+func (l *Logger) WithError(err error) log.Entry {
+	return l.WithField("error", err)
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	l.Log.Debug(l.message(fmt.Sprint(args...)))
+}
+
+func (l *Logger) Info(args ...interface{}) {
+	l.Log.Debug(l.message(fmt.Sprint(args...)))
+}
+
+func (l *Logger) Warn(args ...interface{}) {
+	l.Log.Warn(l.message(fmt.Sprint(args...)))
+}
+
+func (l *Logger) Error(args ...interface{}) {
+	l.Log.Error(l.message(fmt.Sprint(args...)))
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.Log.Debug(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.Log.Debug(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.Log.Warn(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.Log.Error(l.message(fmt.Sprintf(format, args...)))
+}
+
+// message prefixes the message and appends the fields sorted by name
+func (l *Logger) message(msg string) string {
+	var b strings.Builder
+	b.WriteString("[pulsar] ")
+	b.WriteString(msg)
+
+	names := make([]string, 0, len(l.fields))
+	for name := range l.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, " %s=%v", name, l.fields[name])
+	}
+	return b.String()
+}
+
+func mergeFields(a, b log.Fields) log.Fields {
+	fields := make(log.Fields, len(a)+len(b))
+	for k, v := range a {
+		fields[k] = v
+	}
+	for k, v := range b {
+		fields[k] = v
+	}
+	return fields
+}
@@ -0,0 +1,50 @@
+// Package templating provides the data Go templates of output plugins, e.g.
+// for topics or keys, are executed on.
+package templating
+
+import (
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Metric exposes the name, tags and time of a metric to templates, so
+// {{.Name}}, {{.Tag "key"}}, {{.Tags}} and {{.Time}} can be used
+type Metric struct {
+	metric telegraf.Metric
+}
+
+// NewMetric wraps the metric for executing templates
+func NewMetric(metric telegraf.Metric) *Metric {
+	return &Metric{metric: metric}
+}
+
+func (m *Metric) Name() string {
+	return m.metric.Name()
+}
+
+// Tag returns the value of the tag or an empty string if the tag is missing
+func (m *Metric) Tag(key string) string {
+	value, _ := m.metric.GetTag(key)
+	return value
+}
+
+func (m *Metric) Tags() map[string]string {
+	return m.metric.Tags()
+}
+
+// Time returns the time of the metric in UTC
+func (m *Metric) Time() time.Time {
+	return m.metric.Time().UTC()
+}
+
+// Execute executes the template on the metric and returns the result
+func Execute(tmpl *template.Template, metric telegraf.Metric) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, NewMetric(metric)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
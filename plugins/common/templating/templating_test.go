@@ -0,0 +1,56 @@
+package templating
+
+import (
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestExecute(t *testing.T) {
+	metric := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a", "region": "eu"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "name and tag",
+			template: `{{.Name}}-{{.Tag "host"}}`,
+			expected: "cpu-a",
+		},
+		{
+			name:     "missing tag",
+			template: `{{.Name}}-{{.Tag "missing"}}`,
+			expected: "cpu-",
+		},
+		{
+			name:     "tags",
+			template: `{{range $k, $v := .Tags}}{{$k}}={{$v}};{{end}}`,
+			expected: "host=a;region=eu;",
+		},
+		{
+			name:     "time in utc",
+			template: `{{.Time.Format "2006-01-02T15"}}`,
+			expected: "1970-01-01T00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Parse(tt.template)
+			require.NoError(t, err)
+			actual, err := Execute(tmpl, metric)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,378 @@
+// Package watermark keeps track of the highest value of a column returned by
+// incremental queries, the high-water mark, and persists it across restarts.
+package watermark
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store holds the high-water marks of all queries of a plugin instance. If a
+// path is given the marks are persisted to that file.
+type Store struct {
+	path   string
+	prefix string
+
+	sync.Mutex
+	marks map[string]interface{}
+}
+
+// fileLocks serializes the updates of stores sharing a state file
+var (
+	fileLocks   = make(map[string]*sync.Mutex)
+	fileLocksMu sync.Mutex
+)
+
+// Tracker keeps the high-water mark of a single query
+type Tracker struct {
+	Column string
+
+	key     string
+	store   *Store
+	value   interface{}
+	pending interface{}
+}
+
+// persistedMark is the on-disk representation of a high-water mark keeping the
+// type information required for comparing values
+type persistedMark struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// NewStore creates a new store and loads the marks persisted to the given
+// path, if any. An empty path disables persistence. The namespace identifies
+// the plugin instance, e.g. by its connection string, so instances sharing a
+// state file keep separate marks for the same query. Only a hash of the
+// namespace is persisted.
+func NewStore(path, namespace string) (*Store, error) {
+	sum := sha256.Sum256([]byte(namespace))
+	s := &Store{
+		prefix: hex.EncodeToString(sum[:8]) + "/",
+		marks:  make(map[string]interface{}),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var err error
+	if s.path, err = filepath.Abs(path); err != nil {
+		return nil, fmt.Errorf("resolving state file %q failed: %w", path, err)
+	}
+
+	persisted, err := load(s.path)
+	if err != nil {
+		return nil, err
+	}
+	for k, m := range persisted {
+		v, err := m.decode()
+		if err != nil {
+			return nil, fmt.Errorf("decoding mark %q of state file %q failed: %w", k, path, err)
+		}
+		if key := strings.TrimPrefix(k, s.prefix); key != k {
+			s.marks[key] = v
+		}
+	}
+
+	return s, nil
+}
+
+// load reads the persisted marks of all stores sharing the state file
+func load(path string) (map[string]persistedMark, error) {
+	persisted := make(map[string]persistedMark)
+
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return persisted, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state file failed: %w", err)
+	}
+
+	if err := json.Unmarshal(buf, &persisted); err != nil {
+		return nil, fmt.Errorf("decoding state file %q failed: %w", path, err)
+	}
+	return persisted, nil
+}
+
+func fileLock(path string) *sync.Mutex {
+	fileLocksMu.Lock()
+	defer fileLocksMu.Unlock()
+
+	lock, found := fileLocks[path]
+	if !found {
+		lock = &sync.Mutex{}
+		fileLocks[path] = lock
+	}
+	return lock
+}
+
+// Tracker returns the tracker for the query identified by the given key.
+// Previously persisted marks take precedence over the initial value.
+func (s *Store) Tracker(key, column string, initial interface{}) (*Tracker, error) {
+	if column == "" {
+		return nil, errors.New("column required")
+	}
+
+	value, err := normalize(initial)
+	if err != nil {
+		return nil, fmt.Errorf("invalid initial value: %w", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if v, found := s.marks[key]; found {
+		value = v
+	}
+
+	return &Tracker{
+		Column: column,
+		key:    key,
+		store:  s,
+		value:  value,
+	}, nil
+}
+
+func (s *Store) update(key string, value interface{}) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.marks[key] = value
+	if s.path == "" {
+		return nil
+	}
+
+	// Other stores may share the file, so only replace the updated mark in
+	// the current state instead of writing the marks of this store
+	lock := fileLock(s.path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	persisted, err := load(s.path)
+	if err != nil {
+		return err
+	}
+	m, err := encode(value)
+	if err != nil {
+		return err
+	}
+	persisted[s.prefix+key] = m
+
+	buf, err := json.MarshalIndent(persisted, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first to not corrupt the state on failures
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("creating state file failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing state file failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing state file failed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replacing state file failed: %w", err)
+	}
+	return nil
+}
+
+// Value returns the current high-water mark to be bound as query parameter.
+// The returned value is nil if no mark and no initial value exist.
+func (t *Tracker) Value() interface{} {
+	return t.value
+}
+
+// Observe records a value of the tracked column. NULL values are ignored.
+func (t *Tracker) Observe(v interface{}) error {
+	value, err := normalize(v)
+	if err != nil {
+		return fmt.Errorf("column %q: %w", t.Column, err)
+	}
+	if value == nil {
+		return nil
+	}
+
+	current := t.pending
+	if current == nil {
+		current = t.value
+	}
+	if current == nil {
+		t.pending = value
+		return nil
+	}
+
+	c, err := compare(value, current)
+	if err != nil {
+		return fmt.Errorf("column %q: %w", t.Column, err)
+	}
+	if c > 0 {
+		t.pending = value
+	}
+	return nil
+}
+
+// Commit makes the highest value observed since the last commit the new
+// high-water mark and persists it. Call Commit only after all rows of a query
+// were processed successfully.
+func (t *Tracker) Commit() error {
+	if t.pending == nil {
+		return nil
+	}
+	t.value, t.pending = t.pending, nil
+	return t.store.update(t.key, t.value)
+}
+
+// Discard drops the values observed since the last commit, e.g. if the query
+// failed
+func (t *Tracker) Discard() {
+	t.pending = nil
+}
+
+// normalize converts the given value to one of the types int64, uint64,
+// float64, string or time.Time
+func normalize(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return normalizeUint(uint64(v)), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return normalizeUint(v), nil
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case string:
+		return v, nil
+	case time.Time:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
+
+func normalizeUint(v uint64) interface{} {
+	if v <= math.MaxInt64 {
+		return int64(v)
+	}
+	return v
+}
+
+// compare returns a positive number if a is greater than b, a negative number
+// if a is less than b and zero if both are equal
+func compare(a, b interface{}) (int, error) {
+	switch av := a.(type) {
+	case int64:
+		switch bv := b.(type) {
+		case int64:
+			return sign(av < bv, av > bv), nil
+		case uint64:
+			// Normalized unsigned values always exceed the int64 range
+			return -1, nil
+		case float64:
+			return sign(float64(av) < bv, float64(av) > bv), nil
+		}
+	case uint64:
+		switch bv := b.(type) {
+		case int64:
+			return 1, nil
+		case uint64:
+			return sign(av < bv, av > bv), nil
+		case float64:
+			return sign(float64(av) < bv, float64(av) > bv), nil
+		}
+	case float64:
+		switch bv := b.(type) {
+		case int64:
+			return sign(av < float64(bv), av > float64(bv)), nil
+		case uint64:
+			return sign(av < float64(bv), av > float64(bv)), nil
+		case float64:
+			return sign(av < bv, av > bv), nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return sign(av < bv, av > bv), nil
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return sign(av.Before(bv), av.After(bv)), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %T with %T", a, b)
+}
+
+func sign(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func encode(v interface{}) (persistedMark, error) {
+	switch v := v.(type) {
+	case int64:
+		return persistedMark{Type: "int", Value: strconv.FormatInt(v, 10)}, nil
+	case uint64:
+		return persistedMark{Type: "uint", Value: strconv.FormatUint(v, 10)}, nil
+	case float64:
+		return persistedMark{Type: "float", Value: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case string:
+		return persistedMark{Type: "string", Value: v}, nil
+	case time.Time:
+		return persistedMark{Type: "time", Value: v.Format(time.RFC3339Nano)}, nil
+	}
+	return persistedMark{}, fmt.Errorf("unsupported type %T", v)
+}
+
+func (m persistedMark) decode() (interface{}, error) {
+	switch m.Type {
+	case "int":
+		return strconv.ParseInt(m.Value, 10, 64)
+	case "uint":
+		return strconv.ParseUint(m.Value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(m.Value, 64)
+	case "string":
+		return m.Value, nil
+	case "time":
+		return time.Parse(time.RFC3339Nano, m.Value)
+	}
+	return nil, fmt.Errorf("unknown type %q", m.Type)
+}
@@ -0,0 +1,173 @@
+package watermark
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	store, err := NewStore("", "")
+	require.NoError(t, err)
+
+	tracker, err := store.Tracker("query", "id", 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), tracker.Value())
+
+	// Values below the mark do not change it
+	require.NoError(t, tracker.Observe(int32(5)))
+	require.NoError(t, tracker.Commit())
+	require.Equal(t, int64(10), tracker.Value())
+
+	// The highest observed value becomes the new mark on commit only
+	for _, v := range []interface{}{int64(12), uint32(15), nil, int16(11)} {
+		require.NoError(t, tracker.Observe(v))
+	}
+	require.Equal(t, int64(10), tracker.Value())
+	require.NoError(t, tracker.Commit())
+	require.Equal(t, int64(15), tracker.Value())
+
+	// Discarded values are dropped
+	require.NoError(t, tracker.Observe(int64(20)))
+	tracker.Discard()
+	require.NoError(t, tracker.Commit())
+	require.Equal(t, int64(15), tracker.Value())
+
+	// Incompatible types cannot be compared
+	require.ErrorContains(t, tracker.Observe("abc"), "cannot compare string with int64")
+	require.ErrorContains(t, tracker.Observe(struct{}{}), "unsupported type")
+}
+
+func TestTrackerWithoutInitialValue(t *testing.T) {
+	store, err := NewStore("", "")
+	require.NoError(t, err)
+
+	tracker, err := store.Tracker("query", "ts", nil)
+	require.NoError(t, err)
+	require.Nil(t, tracker.Value())
+
+	ts := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, tracker.Observe(ts))
+	require.NoError(t, tracker.Observe(ts.Add(-time.Hour)))
+	require.NoError(t, tracker.Commit())
+	require.Equal(t, ts, tracker.Value())
+
+	_, err = store.Tracker("query", "", nil)
+	require.ErrorContains(t, err, "column required")
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        interface{}
+		b        interface{}
+		expected int
+	}{
+		{name: "int less", a: int64(1), b: int64(2), expected: -1},
+		{name: "int equal", a: int64(2), b: int64(2), expected: 0},
+		{name: "int float", a: int64(3), b: 2.5, expected: 1},
+		{name: "uint int", a: uint64(1 << 63), b: int64(2), expected: 1},
+		{name: "int uint", a: int64(2), b: uint64(1 << 63), expected: -1},
+		{name: "float uint", a: 1.5, b: uint64(1 << 63), expected: -1},
+		{name: "string", a: "2022-10-02", b: "2022-10-01", expected: 1},
+		{
+			name:     "time",
+			a:        time.Unix(1666000000, 0),
+			b:        time.Unix(1666000000, 1),
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := compare(tt.a, tt.b)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	ts := time.Date(2022, 10, 1, 12, 0, 0, 123456789, time.UTC)
+
+	store, err := NewStore(path, "instance")
+	require.NoError(t, err)
+
+	values := map[string]interface{}{
+		"int":    int64(-42),
+		"uint":   uint64(1<<63 + 1),
+		"float":  1.25,
+		"string": "abc",
+		"time":   ts,
+	}
+	for key, v := range values {
+		tracker, err := store.Tracker(key, "column", nil)
+		require.NoError(t, err)
+		require.NoError(t, tracker.Observe(v))
+		require.NoError(t, tracker.Commit())
+	}
+
+	// Persisted marks take precedence over the initial value
+	store, err = NewStore(path, "instance")
+	require.NoError(t, err)
+	for key, v := range values {
+		tracker, err := store.Tracker(key, "column", 0)
+		require.NoError(t, err)
+		require.Equal(t, v, tracker.Value(), key)
+	}
+
+	// Unknown keys use the initial value
+	tracker, err := store.Tracker("other", "column", "start")
+	require.NoError(t, err)
+	require.Equal(t, "start", tracker.Value())
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestInvalidStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	_, err := NewStore(path, "instance")
+	require.ErrorContains(t, err, "decoding state file")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"q": {"type": "complex", "value": "1"}}`), 0600))
+	_, err = NewStore(path, "instance")
+	require.ErrorContains(t, err, `unknown type "complex"`)
+}
+
+func TestSharedStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	first, err := NewStore(path, "db1")
+	require.NoError(t, err)
+	second, err := NewStore(path, "db2")
+	require.NoError(t, err)
+
+	// Both instances run the same query against different databases
+	firstTracker, err := first.Tracker("query", "id", 0)
+	require.NoError(t, err)
+	secondTracker, err := second.Tracker("query", "id", 0)
+	require.NoError(t, err)
+
+	require.NoError(t, firstTracker.Observe(10))
+	require.NoError(t, firstTracker.Commit())
+	require.NoError(t, secondTracker.Observe(20))
+	require.NoError(t, secondTracker.Commit())
+
+	// The update of one store does not overwrite the marks of the other
+	for namespace, expected := range map[string]int64{"db1": 10, "db2": 20, "db3": 0} {
+		store, err := NewStore(path, namespace)
+		require.NoError(t, err)
+		tracker, err := store.Tracker("query", "id", 0)
+		require.NoError(t, err)
+		require.Equal(t, expected, tracker.Value(), namespace)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !custom || inputs || inputs.cgroup_v2
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/inputs/cgroup_v2" // register plugin
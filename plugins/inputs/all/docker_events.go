@@ -0,0 +1,5 @@
+//go:build !custom || inputs || inputs.docker_events
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/inputs/docker_events" // register plugin
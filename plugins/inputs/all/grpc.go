@@ -0,0 +1,5 @@
+//go:build !custom || inputs || inputs.grpc
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/inputs/grpc" // register plugin
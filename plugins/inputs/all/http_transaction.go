@@ -0,0 +1,5 @@
+//go:build !custom || inputs || inputs.http_transaction
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/inputs/http_transaction" // register plugin
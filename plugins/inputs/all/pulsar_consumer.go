@@ -0,0 +1,5 @@
+//go:build !custom || inputs || inputs.pulsar_consumer
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/inputs/pulsar_consumer" // register plugin
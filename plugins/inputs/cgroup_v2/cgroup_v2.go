@@ -0,0 +1,306 @@
+//go:generate ../../../tools/readme_config_includer/generator
+//go:build linux
+
+package cgroup_v2
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gobwas/glob"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/choice"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	defaultHostProc   = "/proc"
+	defaultCgroupRoot = "/sys/fs/cgroup"
+)
+
+// Resources reporting pressure stall information
+var pressureResources = []string{"cpu", "memory", "io"}
+
+type CgroupV2 struct {
+	HostProc      string   `toml:"host_proc"`
+	CgroupRoot    string   `toml:"cgroup_root"`
+	Metrics       []string `toml:"metrics"`
+	CgroupInclude []string `toml:"cgroup_include"`
+	CgroupExclude []string `toml:"cgroup_exclude"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	include []glob.Glob
+	exclude []glob.Glob
+}
+
+func (*CgroupV2) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CgroupV2) Init() error {
+	if c.HostProc == "" {
+		c.HostProc = defaultHostProc
+	}
+	if c.CgroupRoot == "" {
+		c.CgroupRoot = defaultCgroupRoot
+	}
+
+	if len(c.Metrics) == 0 {
+		c.Metrics = []string{"pressure", "cgroup"}
+	}
+	if err := choice.CheckSlice(c.Metrics, []string{"pressure", "cgroup"}); err != nil {
+		return fmt.Errorf("invalid metrics: %w", err)
+	}
+
+	if len(c.CgroupInclude) == 0 {
+		c.CgroupInclude = []string{"/**"}
+	}
+	// Use the path separator so '*' only matches within a hierarchy level
+	for _, pattern := range c.CgroupInclude {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		c.include = append(c.include, g)
+	}
+	for _, pattern := range c.CgroupExclude {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		c.exclude = append(c.exclude, g)
+	}
+
+	return nil
+}
+
+func (c *CgroupV2) Gather(acc telegraf.Accumulator) error {
+	if choice.Contains("pressure", c.Metrics) {
+		for _, resource := range pressureResources {
+			fn := filepath.Join(c.HostProc, "pressure", resource)
+			if err := gatherPressure(acc, fn, resource, nil); err != nil {
+				acc.AddError(err)
+			}
+		}
+	}
+
+	if choice.Contains("cgroup", c.Metrics) {
+		if err := c.gatherCgroups(acc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gatherCgroups walks the unified hierarchy and collects the statistics of
+// all matching cgroups
+func (c *CgroupV2) gatherCgroups(acc telegraf.Accumulator) error {
+	if _, err := os.Stat(filepath.Join(c.CgroupRoot, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("%q is not a cgroup v2 hierarchy: %w", c.CgroupRoot, err)
+	}
+
+	return filepath.WalkDir(c.CgroupRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Cgroups might vanish while walking the hierarchy
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(c.CgroupRoot, path)
+		if err != nil {
+			return err
+		}
+		name := "/" + filepath.ToSlash(rel)
+		if rel == "." {
+			name = "/"
+		}
+
+		// Excluded cgroups are still descended into as children might match
+		if c.match(name) {
+			c.gatherCgroup(acc, path, name)
+		}
+		return nil
+	})
+}
+
+func (c *CgroupV2) match(name string) bool {
+	for _, g := range c.exclude {
+		if g.Match(name) {
+			return false
+		}
+	}
+	for _, g := range c.include {
+		if g.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CgroupV2) gatherCgroup(acc telegraf.Accumulator, path, name string) {
+	tags := map[string]string{"cgroup": name}
+
+	// The availability of the files depends on the enabled controllers, so
+	// missing files are not an error
+	for _, controller := range []string{"cpu", "memory"} {
+		fields, err := readFlatKeyed(filepath.Join(path, controller+".stat"))
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				acc.AddError(err)
+			}
+			continue
+		}
+		if len(fields) > 0 {
+			acc.AddFields("cgroup_v2_"+controller, fields, tags)
+		}
+	}
+
+	if err := gatherIO(acc, filepath.Join(path, "io.stat"), name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		acc.AddError(err)
+	}
+
+	for _, resource := range pressureResources {
+		err := gatherPressure(acc, filepath.Join(path, resource+".pressure"), resource, tags)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			acc.AddError(err)
+		}
+	}
+}
+
+// readFlatKeyed parses files with one "<key> <value>" pair per line such as
+// cpu.stat or memory.stat
+func readFlatKeyed(fn string) (map[string]interface{}, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q in %q failed: %w", parts[0], fn, err)
+		}
+		fields[parts[0]] = v
+	}
+	return fields, scanner.Err()
+}
+
+// gatherIO parses io.stat with one line per device in the format
+// "<major>:<minor> <key>=<value> ..."
+func gatherIO(acc telegraf.Accumulator, fn, name string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 {
+			continue
+		}
+
+		fields, err := parseNestedKeyed(parts[1:])
+		if err != nil {
+			return fmt.Errorf("parsing device %q in %q failed: %w", parts[0], fn, err)
+		}
+		tags := map[string]string{
+			"cgroup": name,
+			"device": parts[0],
+		}
+		acc.AddFields("cgroup_v2_io", fields, tags)
+	}
+	return scanner.Err()
+}
+
+// gatherPressure parses the pressure stall information with one line per
+// type in the format "<some|full> avg10=<v> avg60=<v> avg300=<v> total=<v>"
+func gatherPressure(acc telegraf.Accumulator, fn, resource string, tags map[string]string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 {
+			continue
+		}
+
+		fields, err := parseNestedKeyed(parts[1:])
+		if err != nil {
+			return fmt.Errorf("parsing %q pressure in %q failed: %w", parts[0], fn, err)
+		}
+
+		t := map[string]string{
+			"resource": resource,
+			"type":     parts[0],
+		}
+		for k, v := range tags {
+			t[k] = v
+		}
+		acc.AddFields("pressure", fields, t)
+	}
+	return scanner.Err()
+}
+
+// parseNestedKeyed parses "<key>=<value>" pairs. Values containing a decimal
+// point are returned as float, all others as unsigned integers.
+func parseNestedKeyed(pairs []string) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		k, v, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid key-value pair %q", pair)
+		}
+		if strings.Contains(v, ".") {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %q failed: %w", k, err)
+			}
+			fields[k] = f
+			continue
+		}
+		u, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q failed: %w", k, err)
+		}
+		fields[k] = u
+	}
+	return fields, nil
+}
+
+func init() {
+	inputs.Add("cgroup_v2", func() telegraf.Input {
+		return &CgroupV2{}
+	})
+}
@@ -0,0 +1,260 @@
+//go:build linux
+
+package cgroup_v2
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInit(t *testing.T) {
+	plugin := &CgroupV2{}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "/proc", plugin.HostProc)
+	require.Equal(t, "/sys/fs/cgroup", plugin.CgroupRoot)
+	require.Equal(t, []string{"pressure", "cgroup"}, plugin.Metrics)
+
+	plugin = &CgroupV2{Metrics: []string{"foo"}}
+	require.ErrorContains(t, plugin.Init(), "invalid metrics")
+
+	plugin = &CgroupV2{CgroupInclude: []string{"/system.slice/[a-"}}
+	require.ErrorContains(t, plugin.Init(), "invalid include pattern")
+}
+
+func TestGatherPressure(t *testing.T) {
+	plugin := &CgroupV2{
+		HostProc: filepath.Join("testdata", "proc"),
+		Metrics:  []string{"pressure"},
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("pressure",
+			map[string]string{"resource": "cpu", "type": "some"},
+			map[string]interface{}{"avg10": 1.25, "avg60": 0.5, "avg300": 0.1, "total": uint64(123456)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"resource": "cpu", "type": "full"},
+			map[string]interface{}{"avg10": 0.0, "avg60": 0.0, "avg300": 0.0, "total": uint64(0)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"resource": "memory", "type": "some"},
+			map[string]interface{}{"avg10": 0.0, "avg60": 0.12, "avg300": 0.03, "total": uint64(4567)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"resource": "memory", "type": "full"},
+			map[string]interface{}{"avg10": 0.0, "avg60": 0.05, "avg300": 0.01, "total": uint64(1234)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"resource": "io", "type": "some"},
+			map[string]interface{}{"avg10": 2.5, "avg60": 1.0, "avg300": 0.75, "total": uint64(987654)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"resource": "io", "type": "full"},
+			map[string]interface{}{"avg10": 1.5, "avg60": 0.8, "avg300": 0.5, "total": uint64(654321)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherPressureMissing(t *testing.T) {
+	plugin := &CgroupV2{
+		HostProc: t.TempDir(),
+		Metrics:  []string{"pressure"},
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 3)
+}
+
+func TestGatherCgroups(t *testing.T) {
+	plugin := &CgroupV2{
+		CgroupRoot:    filepath.Join("testdata", "cgroup"),
+		Metrics:       []string{"cgroup"},
+		CgroupInclude: []string{"/system.slice", "/system.slice/**"},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cgroup_v2_cpu",
+			map[string]string{"cgroup": "/system.slice"},
+			map[string]interface{}{
+				"usage_usec":     uint64(500000),
+				"user_usec":      uint64(300000),
+				"system_usec":    uint64(200000),
+				"nr_periods":     uint64(0),
+				"nr_throttled":   uint64(0),
+				"throttled_usec": uint64(0),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cgroup_v2_memory",
+			map[string]string{"cgroup": "/system.slice"},
+			map[string]interface{}{
+				"anon":         uint64(4096),
+				"file":         uint64(8192),
+				"kernel_stack": uint64(16384),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cgroup_v2_io",
+			map[string]string{"cgroup": "/system.slice", "device": "8:0"},
+			map[string]interface{}{
+				"rbytes": uint64(512),
+				"wbytes": uint64(1024),
+				"rios":   uint64(5),
+				"wios":   uint64(10),
+				"dbytes": uint64(0),
+				"dios":   uint64(0),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cgroup_v2_io",
+			map[string]string{"cgroup": "/system.slice", "device": "253:0"},
+			map[string]interface{}{
+				"rbytes": uint64(256),
+				"wbytes": uint64(0),
+				"rios":   uint64(2),
+				"wios":   uint64(0),
+				"dbytes": uint64(0),
+				"dios":   uint64(0),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"cgroup": "/system.slice", "resource": "cpu", "type": "some"},
+			map[string]interface{}{"avg10": 0.5, "avg60": 0.25, "avg300": 0.05, "total": uint64(2000)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"cgroup": "/system.slice", "resource": "cpu", "type": "full"},
+			map[string]interface{}{"avg10": 0.0, "avg60": 0.0, "avg300": 0.0, "total": uint64(0)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cgroup_v2_cpu",
+			map[string]string{"cgroup": "/system.slice/docker.service"},
+			map[string]interface{}{
+				"usage_usec":     uint64(250000),
+				"user_usec":      uint64(150000),
+				"system_usec":    uint64(100000),
+				"nr_periods":     uint64(10),
+				"nr_throttled":   uint64(2),
+				"throttled_usec": uint64(3000),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cgroup_v2_memory",
+			map[string]string{"cgroup": "/system.slice/docker.service"},
+			map[string]interface{}{
+				"anon":         uint64(2048),
+				"file":         uint64(1024),
+				"kernel_stack": uint64(8192),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"cgroup": "/system.slice/docker.service", "resource": "memory", "type": "some"},
+			map[string]interface{}{"avg10": 0.0, "avg60": 0.0, "avg300": 0.0, "total": uint64(100)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("pressure",
+			map[string]string{"cgroup": "/system.slice/docker.service", "resource": "memory", "type": "full"},
+			map[string]interface{}{"avg10": 0.0, "avg60": 0.0, "avg300": 0.0, "total": uint64(50)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestCgroupFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+	}{
+		{
+			name:     "all",
+			expected: []string{"/", "/system.slice", "/system.slice/docker.service", "/user.slice"},
+		},
+		{
+			name:     "single level",
+			include:  []string{"/*.slice"},
+			expected: []string{"/system.slice", "/user.slice"},
+		},
+		{
+			name:     "exclude parent",
+			include:  []string{"/**"},
+			exclude:  []string{"/", "/system.slice"},
+			expected: []string{"/system.slice/docker.service", "/user.slice"},
+		},
+		{
+			name:     "exact",
+			include:  []string{"/user.slice"},
+			expected: []string{"/user.slice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &CgroupV2{
+				CgroupRoot:    filepath.Join("testdata", "cgroup"),
+				Metrics:       []string{"cgroup"},
+				CgroupInclude: tt.include,
+				CgroupExclude: tt.exclude,
+				Log:           testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Gather(&acc))
+
+			var actual []string
+			for _, m := range acc.GetTelegrafMetrics() {
+				if m.Name() != "cgroup_v2_cpu" {
+					continue
+				}
+				name, _ := m.GetTag("cgroup")
+				actual = append(actual, name)
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestGatherNoCgroupV2(t *testing.T) {
+	plugin := &CgroupV2{
+		CgroupRoot: t.TempDir(),
+		Metrics:    []string{"cgroup"},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.ErrorContains(t, plugin.Gather(&acc), "is not a cgroup v2 hierarchy")
+}
@@ -2,16 +2,23 @@
 package dns_query
 
 import (
+	"bytes"
+	"crypto/tls"
 	_ "embed"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/miekg/dns"
 
 	"github.com/influxdata/telegraf"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -45,12 +52,64 @@ type DNSQuery struct {
 
 	// Dns query timeout in seconds. 0 means no timeout
 	Timeout int
+
+	// Request DNSSEC records and report the authenticated-data flag
+	DNSSEC bool `toml:"dnssec"`
+
+	// DS or DNSKEY records used to validate the chain of trust
+	TrustAnchors []string `toml:"dnssec_trust_anchors"`
+
+	// Report the records of the answer section
+	IncludeAnswers bool `toml:"include_answers"`
+
+	tlsint.ClientConfig
+	Log telegraf.Logger `toml:"-"`
+
+	tlsConfig *tls.Config
+	client    *http.Client
+	anchors   []*dns.DS
 }
 
 func (*DNSQuery) SampleConfig() string {
 	return sampleConfig
 }
 
+func (d *DNSQuery) Init() error {
+	d.setDefaultValues()
+
+	switch d.Network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "tcp-tls", "https":
+	default:
+		return fmt.Errorf("invalid network %q", d.Network)
+	}
+
+	tlsCfg, err := d.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	d.tlsConfig = tlsCfg
+	if d.Network == "https" {
+		d.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: time.Duration(d.Timeout) * time.Second,
+		}
+	}
+
+	if len(d.TrustAnchors) > 0 {
+		anchors, err := parseTrustAnchors(d.TrustAnchors)
+		if err != nil {
+			return err
+		}
+		d.anchors = anchors
+		d.DNSSEC = true
+	}
+
+	return nil
+}
+
 func (d *DNSQuery) Gather(acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
 	d.setDefaultValues()
@@ -66,15 +125,17 @@ func (d *DNSQuery) Gather(acc telegraf.Accumulator) error {
 					"record_type": d.RecordType,
 				}
 
-				dnsQueryTime, rcode, err := d.getDNSQueryTime(domain, server)
+				r, dnsQueryTime, rcode, err := d.getDNSQueryTime(domain, server)
 				if rcode >= 0 {
 					tags["rcode"] = dns.RcodeToString[rcode]
 					fields["rcode_value"] = rcode
 				}
+				var netErr net.Error
 				if err == nil {
 					setResult(Success, fields, tags)
 					fields["query_time_ms"] = dnsQueryTime
-				} else if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
+					d.addDNSSEC(r, server, fields, tags)
+				} else if errors.As(err, &netErr) && netErr.Timeout() {
 					setResult(Timeout, fields, tags)
 				} else if err != nil {
 					setResult(Error, fields, tags)
@@ -83,6 +144,10 @@ func (d *DNSQuery) Gather(acc telegraf.Accumulator) error {
 
 				acc.AddFields("dns_query", fields, tags)
 
+				if err == nil && d.IncludeAnswers {
+					addAnswers(acc, r, server, domain)
+				}
+
 				wg.Done()
 			}(domain, server)
 		}
@@ -107,7 +172,11 @@ func (d *DNSQuery) setDefaultValues() {
 	}
 
 	if d.Port == 0 {
-		d.Port = 53
+		if d.Network == "tcp-tls" {
+			d.Port = 853
+		} else {
+			d.Port = 53
+		}
 	}
 
 	if d.Timeout == 0 {
@@ -115,30 +184,139 @@ func (d *DNSQuery) setDefaultValues() {
 	}
 }
 
-func (d *DNSQuery) getDNSQueryTime(domain string, server string) (float64, int, error) {
+func (d *DNSQuery) getDNSQueryTime(domain string, server string) (*dns.Msg, float64, int, error) {
 	dnsQueryTime := float64(0)
 
+	recordType, err := d.parseRecordType()
+	if err != nil {
+		return nil, dnsQueryTime, -1, err
+	}
+
+	r, rtt, err := d.exchange(domain, recordType, server)
+	if err != nil {
+		return nil, dnsQueryTime, -1, err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return r, dnsQueryTime, r.Rcode, fmt.Errorf("Invalid answer (%s) from %s after %s query for %s", dns.RcodeToString[r.Rcode], server, d.RecordType, domain)
+	}
+	dnsQueryTime = float64(rtt.Nanoseconds()) / 1e6
+	return r, dnsQueryTime, r.Rcode, nil
+}
+
+// exchange sends the query to the server using the configured transport
+func (d *DNSQuery) exchange(domain string, recordType uint16, server string) (*dns.Msg, time.Duration, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(domain), recordType)
+	m.RecursionDesired = true
+	if d.DNSSEC {
+		m.SetEdns0(dns.DefaultMsgSize, true)
+		m.AuthenticatedData = true
+	}
+
+	if d.Network == "https" {
+		return d.exchangeHTTPS(m, server)
+	}
+
 	c := new(dns.Client)
 	c.ReadTimeout = time.Duration(d.Timeout) * time.Second
 	c.Net = d.Network
+	c.TLSConfig = d.tlsConfig
 
-	m := new(dns.Msg)
-	recordType, err := d.parseRecordType()
+	return c.Exchange(m, net.JoinHostPort(server, strconv.Itoa(d.Port)))
+}
+
+// exchangeHTTPS sends the query as DNS wireformat via HTTP POST as described
+// in RFC 8484. The server is the URL of the DNS-over-HTTPS endpoint.
+func (d *DNSQuery) exchangeHTTPS(m *dns.Msg, server string) (*dns.Msg, time.Duration, error) {
+	// Use a zero ID to improve HTTP caching as recommended by the RFC
+	m.Id = 0
+	buf, err := m.Pack()
 	if err != nil {
-		return dnsQueryTime, -1, err
+		return nil, 0, err
 	}
-	m.SetQuestion(dns.Fqdn(domain), recordType)
-	m.RecursionDesired = true
 
-	r, rtt, err := c.Exchange(m, net.JoinHostPort(server, strconv.Itoa(d.Port)))
+	request, err := http.NewRequest("POST", server, bytes.NewReader(buf))
 	if err != nil {
-		return dnsQueryTime, -1, err
+		return nil, 0, err
 	}
-	if r.Rcode != dns.RcodeSuccess {
-		return dnsQueryTime, r.Rcode, fmt.Errorf("Invalid answer (%s) from %s after %s query for %s", dns.RcodeToString[r.Rcode], server, d.RecordType, domain)
+	request.Header.Set("Content-Type", "application/dns-message")
+	request.Header.Set("Accept", "application/dns-message")
+
+	start := time.Now()
+	resp, err := d.client.Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	rtt := time.Since(start)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("received status code %d (%s) from %s", resp.StatusCode, http.StatusText(resp.StatusCode), server)
+	}
+
+	r := new(dns.Msg)
+	if err := r.Unpack(body); err != nil {
+		return nil, 0, fmt.Errorf("decoding answer from %s failed: %w", server, err)
+	}
+	return r, rtt, nil
+}
+
+// addDNSSEC adds the authenticated-data flag and, if trust anchors are
+// configured, the validation status of the answer
+func (d *DNSQuery) addDNSSEC(r *dns.Msg, server string, fields map[string]interface{}, tags map[string]string) {
+	if !d.DNSSEC {
+		return
+	}
+	fields["authenticated_data"] = r.AuthenticatedData
+
+	if len(d.anchors) == 0 {
+		return
+	}
+	v := &validator{
+		anchors: d.anchors,
+		query: func(name string, qtype uint16) (*dns.Msg, error) {
+			r, _, err := d.exchange(name, qtype, server)
+			if err == nil && r.Rcode != dns.RcodeSuccess {
+				err = fmt.Errorf("received %s", dns.RcodeToString[r.Rcode])
+			}
+			return r, err
+		},
+		now: time.Now(),
+	}
+	switch err := v.validate(r); {
+	case err == nil:
+		tags["dnssec_status"] = "secure"
+	case errors.Is(err, errInsecure):
+		tags["dnssec_status"] = "insecure"
+	default:
+		d.Log.Debugf("Validating answer from %s failed: %v", server, err)
+		tags["dnssec_status"] = "bogus"
+	}
+}
+
+// addAnswers adds a metric for each record of the answer section
+func addAnswers(acc telegraf.Accumulator, r *dns.Msg, server, domain string) {
+	for _, rr := range r.Answer {
+		hdr := rr.Header()
+		if hdr.Rrtype == dns.TypeRRSIG {
+			continue
+		}
+		tags := map[string]string{
+			"server":      server,
+			"domain":      domain,
+			"name":        hdr.Name,
+			"record_type": dns.TypeToString[hdr.Rrtype],
+			"value":       strings.TrimPrefix(rr.String(), hdr.String()),
+		}
+		fields := map[string]interface{}{
+			"ttl": int64(hdr.Ttl),
+		}
+		acc.AddFields("dns_query_answer", fields, tags)
 	}
-	dnsQueryTime = float64(rtt.Nanoseconds()) / 1e6
-	return dnsQueryTime, r.Rcode, nil
 }
 
 func (d *DNSQuery) parseRecordType() (uint16, error) {
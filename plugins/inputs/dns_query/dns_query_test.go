@@ -1,12 +1,18 @@
 package dns_query
 
 import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/miekg/dns"
 	"github.com/stretchr/testify/require"
 
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -213,3 +219,156 @@ func TestRecordTypeParserError(t *testing.T) {
 	_, err = dnsConfig.parseRecordType()
 	require.Error(t, err)
 }
+
+func TestInitInvalidNetwork(t *testing.T) {
+	plugin := &DNSQuery{Network: "quic"}
+	require.ErrorContains(t, plugin.Init(), `invalid network "quic"`)
+
+	plugin = &DNSQuery{TrustAnchors: []string{"foo"}}
+	require.ErrorContains(t, plugin.Init(), "parsing trust anchor")
+}
+
+func TestTransports(t *testing.T) {
+	records, anchor := testRecords(t)
+	handler := handleTestQuery(records)
+
+	pki := testutil.NewPKI("../../../testutil/pki")
+	tlsServerConfig, err := pki.TLSServerConfig().TLSConfig()
+	require.NoError(t, err)
+	// Use the default cipher suites supported by the client
+	tlsServerConfig.CipherSuites = nil
+
+	// Plain DNS via UDP
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	udpServer := &dns.Server{PacketConn: pc, Handler: handler}
+	go udpServer.ActivateAndServe() //nolint:errcheck // test server
+	defer udpServer.Shutdown()      //nolint:errcheck // test server
+
+	// DNS-over-TLS
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsServerConfig)
+	require.NoError(t, err)
+	tlsServer := &dns.Server{Listener: listener, Net: "tcp-tls", Handler: handler}
+	go tlsServer.ActivateAndServe() //nolint:errcheck // test server
+	defer tlsServer.Shutdown()      //nolint:errcheck // test server
+
+	// DNS-over-HTTPS
+	httpsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req := new(dns.Msg)
+		require.NoError(t, req.Unpack(body))
+		buf, err := answerTestQuery(records, req).Pack()
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, err = w.Write(buf)
+		require.NoError(t, err)
+	}))
+	defer httpsServer.Close()
+
+	tests := []struct {
+		name    string
+		network string
+		server  string
+		port    int
+		tls     tlsint.ClientConfig
+	}{
+		{
+			name:    "udp",
+			network: "udp",
+			server:  "127.0.0.1",
+			port:    pc.LocalAddr().(*net.UDPAddr).Port,
+		},
+		{
+			name:    "tls",
+			network: "tcp-tls",
+			server:  "127.0.0.1",
+			port:    listener.Addr().(*net.TCPAddr).Port,
+			tls:     *pki.TLSClientConfig(),
+		},
+		{
+			name:    "https",
+			network: "https",
+			server:  httpsServer.URL + "/dns-query",
+			tls:     tlsint.ClientConfig{InsecureSkipVerify: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &DNSQuery{
+				Domains:        []string{"www.example", "bogus.example", "plain.example"},
+				Network:        tt.network,
+				Servers:        []string{tt.server},
+				RecordType:     "A",
+				Port:           tt.port,
+				TrustAnchors:   []string{anchor},
+				IncludeAnswers: true,
+				ClientConfig:   tt.tls,
+				Log:            testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Gather(&acc))
+			require.Empty(t, acc.Errors)
+
+			status := make(map[string]string)
+			for _, m := range acc.GetTelegrafMetrics() {
+				if m.Name() != "dns_query" {
+					continue
+				}
+				require.Equal(t, "success", m.Tags()["result"])
+				require.Equal(t, true, m.Fields()["authenticated_data"])
+				require.Greater(t, m.Fields()["query_time_ms"], float64(0))
+				status[m.Tags()["domain"]] = m.Tags()["dnssec_status"]
+			}
+			expected := map[string]string{
+				"www.example":   "secure",
+				"bogus.example": "bogus",
+				"plain.example": "insecure",
+			}
+			require.Equal(t, expected, status)
+
+			var answers []string
+			for _, m := range acc.GetTelegrafMetrics() {
+				if m.Name() != "dns_query_answer" {
+					continue
+				}
+				require.Equal(t, tt.server, m.Tags()["server"])
+				require.Equal(t, "A", m.Tags()["record_type"])
+				require.Equal(t, int64(300), m.Fields()["ttl"])
+				answers = append(answers, m.Tags()["name"]+" "+m.Tags()["value"])
+			}
+			require.ElementsMatch(t, []string{
+				"www.example. 192.0.2.1",
+				"bogus.example. 192.0.2.3",
+				"plain.example. 192.0.2.4",
+			}, answers)
+		})
+	}
+}
+
+func handleTestQuery(records map[rrsetKey][]dns.RR) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, req *dns.Msg) {
+		w.WriteMsg(answerTestQuery(records, req)) //nolint:errcheck // test server
+	}
+}
+
+func answerTestQuery(records map[rrsetKey][]dns.RR, req *dns.Msg) *dns.Msg {
+	r := new(dns.Msg)
+	r.SetReply(req)
+	q := req.Question[0]
+	rrs, found := records[rrsetKey{q.Name, q.Qtype}]
+	if !found {
+		r.Rcode = dns.RcodeNameError
+		return r
+	}
+	r.Answer = rrs
+	r.AuthenticatedData = req.IsEdns0() != nil && req.IsEdns0().Do()
+	return r
+}
@@ -0,0 +1,257 @@
+package dns_query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// errInsecure is returned if the answer or a zone in the chain of trust is
+// not signed
+var errInsecure = errors.New("insecure")
+
+// validator checks the chain of trust of signed answers from the signing zone
+// up to one of the configured trust anchors
+type validator struct {
+	anchors []*dns.DS
+	query   func(name string, qtype uint16) (*dns.Msg, error)
+	now     time.Time
+
+	// keys caches the validated keys of the zones for a single answer
+	keys map[string][]*dns.DNSKEY
+}
+
+// parseTrustAnchors parses DS or DNSKEY records in zone file format. DNSKEY
+// records are converted to DS records using SHA-256 digests.
+func parseTrustAnchors(anchors []string) ([]*dns.DS, error) {
+	result := make([]*dns.DS, 0, len(anchors))
+	for _, a := range anchors {
+		rr, err := dns.NewRR(a)
+		if err != nil {
+			return nil, fmt.Errorf("parsing trust anchor %q failed: %w", a, err)
+		}
+		switch rr := rr.(type) {
+		case *dns.DS:
+			result = append(result, rr)
+		case *dns.DNSKEY:
+			ds := rr.ToDS(dns.SHA256)
+			if ds == nil {
+				return nil, fmt.Errorf("converting trust anchor %q failed", a)
+			}
+			result = append(result, ds)
+		default:
+			return nil, fmt.Errorf("trust anchor %q is neither a DS nor a DNSKEY record", a)
+		}
+	}
+	return result, nil
+}
+
+// validate checks the signatures of all RRsets in the answer section and
+// returns nil if the answer is secure, errInsecure if any RRset is not signed
+// or an error describing why the answer is bogus.
+func (v *validator) validate(r *dns.Msg) error {
+	v.keys = make(map[string][]*dns.DNSKEY)
+
+	rrsets, sigs := splitRRsets(r.Answer)
+	if len(rrsets) == 0 {
+		// Proving the non-existence of records requires validating
+		// NSEC/NSEC3 records which is not supported
+		return errInsecure
+	}
+	for key, rrset := range rrsets {
+		if len(sigs[key]) == 0 {
+			return errInsecure
+		}
+		if err := v.verify(rrset, sigs[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verify checks if any of the given signatures is valid for the RRset and
+// made by a validated key of the signing zone
+func (v *validator) verify(rrset []dns.RR, sigs []*dns.RRSIG) error {
+	err := errors.New("no signature by a parent zone")
+	for _, sig := range sigs {
+		if !dns.IsSubDomain(sig.SignerName, sig.Hdr.Name) {
+			continue
+		}
+		var keys []*dns.DNSKEY
+		keys, err = v.zoneKeys(sig.SignerName)
+		if err != nil {
+			return err
+		}
+		if err = verifySignature(sig, keys, rrset, v.now); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// zoneKeys returns the DNSKEYs of the given zone after validating them against
+// a trust anchor or the DS records of the parent zone
+func (v *validator) zoneKeys(zone string) ([]*dns.DNSKEY, error) {
+	zone = dns.CanonicalName(zone)
+	if keys, found := v.keys[zone]; found {
+		return keys, nil
+	}
+
+	r, err := v.query(zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, fmt.Errorf("querying DNSKEY of %q failed: %w", zone, err)
+	}
+	rrsets, sigs := splitRRsets(r.Answer)
+	key := rrsetKey{name: zone, rrtype: dns.TypeDNSKEY}
+	if len(rrsets[key]) == 0 {
+		return nil, fmt.Errorf("no DNSKEY for %q", zone)
+	}
+	keys := make([]*dns.DNSKEY, 0, len(rrsets[key]))
+	for _, rr := range rrsets[key] {
+		keys = append(keys, rr.(*dns.DNSKEY))
+	}
+
+	// Determine the key-signing keys trusted by either an anchor or the parent
+	trusted := v.anchorsFor(zone)
+	if len(trusted) == 0 {
+		if trusted, err = v.delegation(zone); err != nil {
+			return nil, err
+		}
+	}
+	var ksks []*dns.DNSKEY
+	for _, k := range keys {
+		for _, ds := range trusted {
+			if matchesDS(k, ds) {
+				ksks = append(ksks, k)
+				break
+			}
+		}
+	}
+	if len(ksks) == 0 {
+		return nil, fmt.Errorf("no DNSKEY of %q matches a trusted DS record", zone)
+	}
+
+	// The key set must be signed by one of the trusted keys
+	if len(sigs[key]) == 0 {
+		return nil, fmt.Errorf("DNSKEY of %q not signed", zone)
+	}
+	var verr error
+	for _, sig := range sigs[key] {
+		if verr = verifySignature(sig, ksks, rrsets[key], v.now); verr == nil {
+			break
+		}
+	}
+	if verr != nil {
+		return nil, fmt.Errorf("DNSKEY of %q: %w", zone, verr)
+	}
+
+	v.keys[zone] = keys
+	return keys, nil
+}
+
+// delegation returns the validated DS records of the given zone from its
+// parent zone
+func (v *validator) delegation(zone string) ([]*dns.DS, error) {
+	if zone == "." {
+		return nil, errors.New("no trust anchor for the root zone")
+	}
+
+	r, err := v.query(zone, dns.TypeDS)
+	if err != nil {
+		return nil, fmt.Errorf("querying DS of %q failed: %w", zone, err)
+	}
+	rrsets, sigs := splitRRsets(r.Answer)
+	key := rrsetKey{name: zone, rrtype: dns.TypeDS}
+	if len(rrsets[key]) == 0 {
+		// An unsigned delegation makes the zone insecure
+		return nil, errInsecure
+	}
+
+	var verr error
+	for _, sig := range sigs[key] {
+		// The DS records are signed by the parent zone, prevent loops
+		signer := dns.CanonicalName(sig.SignerName)
+		if signer == zone || !dns.IsSubDomain(signer, zone) {
+			verr = fmt.Errorf("DS of %q signed by invalid zone %q", zone, signer)
+			continue
+		}
+		keys, err := v.zoneKeys(signer)
+		if err != nil {
+			return nil, err
+		}
+		if verr = verifySignature(sig, keys, rrsets[key], v.now); verr == nil {
+			break
+		}
+	}
+	if len(sigs[key]) == 0 {
+		verr = fmt.Errorf("DS of %q not signed", zone)
+	}
+	if verr != nil {
+		return nil, verr
+	}
+
+	result := make([]*dns.DS, 0, len(rrsets[key]))
+	for _, rr := range rrsets[key] {
+		result = append(result, rr.(*dns.DS))
+	}
+	return result, nil
+}
+
+func (v *validator) anchorsFor(zone string) []*dns.DS {
+	var result []*dns.DS
+	for _, a := range v.anchors {
+		if dns.CanonicalName(a.Hdr.Name) == zone {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// rrsetKey identifies an RRset by owner name and type
+type rrsetKey struct {
+	name   string
+	rrtype uint16
+}
+
+// splitRRsets groups the records into RRsets and their signatures
+func splitRRsets(rrs []dns.RR) (map[rrsetKey][]dns.RR, map[rrsetKey][]*dns.RRSIG) {
+	rrsets := make(map[rrsetKey][]dns.RR)
+	sigs := make(map[rrsetKey][]*dns.RRSIG)
+	for _, rr := range rrs {
+		name := dns.CanonicalName(rr.Header().Name)
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			key := rrsetKey{name: name, rrtype: sig.TypeCovered}
+			sigs[key] = append(sigs[key], sig)
+			continue
+		}
+		key := rrsetKey{name: name, rrtype: rr.Header().Rrtype}
+		rrsets[key] = append(rrsets[key], rr)
+	}
+	return rrsets, sigs
+}
+
+func verifySignature(sig *dns.RRSIG, keys []*dns.DNSKEY, rrset []dns.RR, now time.Time) error {
+	if !sig.ValidityPeriod(now) {
+		return fmt.Errorf("signature of %s/%s expired or not yet valid", sig.Hdr.Name, dns.TypeToString[sig.TypeCovered])
+	}
+	for _, k := range keys {
+		if k.KeyTag() != sig.KeyTag || k.Algorithm != sig.Algorithm {
+			continue
+		}
+		if err := sig.Verify(k, rrset); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no valid signature for %s/%s", sig.Hdr.Name, dns.TypeToString[sig.TypeCovered])
+}
+
+func matchesDS(key *dns.DNSKEY, ds *dns.DS) bool {
+	if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+		return false
+	}
+	computed := key.ToDS(ds.DigestType)
+	return computed != nil && strings.EqualFold(computed.Digest, ds.Digest)
+}
@@ -0,0 +1,191 @@
+package dns_query
+
+import (
+	"crypto"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// testZone is a signed zone with a key-signing and a zone-signing key
+type testZone struct {
+	name    string
+	ksk     *dns.DNSKEY
+	kskPriv crypto.Signer
+	zsk     *dns.DNSKEY
+	zskPriv crypto.Signer
+}
+
+func newTestZone(t *testing.T, name string) *testZone {
+	z := &testZone{name: name}
+	z.ksk, z.kskPriv = newTestKey(t, name, dns.ZONE|dns.SEP)
+	z.zsk, z.zskPriv = newTestKey(t, name, dns.ZONE)
+	return z
+}
+
+func newTestKey(t *testing.T, name string, flags uint16) (*dns.DNSKEY, crypto.Signer) {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	require.NoError(t, err)
+	return key, priv.(crypto.Signer)
+}
+
+func (z *testZone) ds() *dns.DS {
+	return z.ksk.ToDS(dns.SHA256)
+}
+
+// keys returns the signed DNSKEY RRset of the zone
+func (z *testZone) keys(t *testing.T) []dns.RR {
+	rrset := []dns.RR{z.ksk, z.zsk}
+	return append(rrset, sign(t, z.name, z.ksk, z.kskPriv, rrset))
+}
+
+// signed returns the given RRset with a signature of the zone-signing key
+func (z *testZone) signed(t *testing.T, rrset ...dns.RR) []dns.RR {
+	return append(rrset, sign(t, z.name, z.zsk, z.zskPriv, rrset))
+}
+
+func sign(t *testing.T, signer string, key *dns.DNSKEY, priv crypto.Signer, rrset []dns.RR) *dns.RRSIG {
+	now := time.Now()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrset[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 3600},
+		Algorithm:  key.Algorithm,
+		SignerName: signer,
+		KeyTag:     key.KeyTag(),
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(time.Hour).Unix()),
+	}
+	require.NoError(t, sig.Sign(priv, rrset))
+	return sig
+}
+
+func mustRR(t *testing.T, s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	require.NoError(t, err)
+	return rr
+}
+
+// testRecords returns the records of a signed root zone with the signed
+// "example." and the insecurely delegated "other." zones. The second return
+// value is the trust anchor of the root zone.
+func testRecords(t *testing.T) (map[rrsetKey][]dns.RR, string) {
+	root := newTestZone(t, ".")
+	example := newTestZone(t, "example.")
+	other := newTestZone(t, "other.")
+
+	www := example.signed(t, mustRR(t, "www.example. 300 IN A 192.0.2.1"))
+	alias := append(example.signed(t, mustRR(t, "alias.example. 300 IN CNAME www.example.")), www...)
+	bogus := example.signed(t, mustRR(t, "bogus.example. 300 IN A 192.0.2.2"))
+	bogus[0].(*dns.A).A = net.ParseIP("192.0.2.3")
+
+	records := map[rrsetKey][]dns.RR{
+		{".", dns.TypeDNSKEY}:               root.keys(t),
+		{"example.", dns.TypeDS}:            root.signed(t, example.ds()),
+		{"example.", dns.TypeDNSKEY}:        example.keys(t),
+		{"www.example.", dns.TypeA}:         www,
+		{"bogus.example.", dns.TypeA}:       bogus,
+		{"plain.example.", dns.TypeA}:       {mustRR(t, "plain.example. 300 IN A 192.0.2.4")},
+		{"other.", dns.TypeDNSKEY}:          other.keys(t),
+		{"www.other.", dns.TypeA}:           other.signed(t, mustRR(t, "www.other. 300 IN A 192.0.2.5")),
+		{"alias.example.", dns.TypeA}:       alias,
+		{"expired.example.", dns.TypeA}:     expired(t, example, mustRR(t, "expired.example. 300 IN A 192.0.2.6")),
+		{"wrongsigner.example.", dns.TypeA}: other.signed(t, mustRR(t, "wrongsigner.example. 300 IN A 192.0.2.7")),
+	}
+	return records, root.ds().String()
+}
+
+func expired(t *testing.T, z *testZone, rr dns.RR) []dns.RR {
+	rrset := z.signed(t, rr)
+	sig := rrset[1].(*dns.RRSIG)
+	sig.Inception = uint32(time.Now().Add(-2 * time.Hour).Unix())
+	sig.Expiration = uint32(time.Now().Add(-time.Hour).Unix())
+	require.NoError(t, sig.Sign(z.zskPriv, rrset[:1]))
+	return rrset
+}
+
+func TestValidator(t *testing.T) {
+	records, anchor := testRecords(t)
+	anchors, err := parseTrustAnchors([]string{anchor})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "www.example.", expected: "secure"},
+		{name: "alias.example.", expected: "secure"},
+		{name: "bogus.example.", expected: "no valid signature"},
+		{name: "expired.example.", expected: "expired or not yet valid"},
+		{name: "plain.example.", expected: "insecure"},
+		{name: "www.other.", expected: "insecure"},
+		{name: "wrongsigner.example.", expected: "no signature by a parent zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries int
+			v := &validator{
+				anchors: anchors,
+				query: func(name string, qtype uint16) (*dns.Msg, error) {
+					queries++
+					r := new(dns.Msg)
+					r.Answer = records[rrsetKey{name, qtype}]
+					return r, nil
+				},
+				now: time.Now(),
+			}
+
+			answer := new(dns.Msg)
+			answer.Answer = records[rrsetKey{tt.name, dns.TypeA}]
+			err := v.validate(answer)
+			switch tt.expected {
+			case "secure":
+				require.NoError(t, err)
+				// DNSKEY of root and example plus DS of example
+				require.Equal(t, 3, queries)
+			case "insecure":
+				require.ErrorIs(t, err, errInsecure)
+			default:
+				require.ErrorContains(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidatorWrongAnchor(t *testing.T) {
+	records, _ := testRecords(t)
+	anchors, err := parseTrustAnchors([]string{newTestZone(t, ".").ksk.String()})
+	require.NoError(t, err)
+
+	v := &validator{
+		anchors: anchors,
+		query: func(name string, qtype uint16) (*dns.Msg, error) {
+			r := new(dns.Msg)
+			r.Answer = records[rrsetKey{name, qtype}]
+			return r, nil
+		},
+		now: time.Now(),
+	}
+	answer := new(dns.Msg)
+	answer.Answer = records[rrsetKey{"www.example.", dns.TypeA}]
+	require.ErrorContains(t, v.validate(answer), `no DNSKEY of "." matches a trusted DS record`)
+}
+
+func TestParseTrustAnchors(t *testing.T) {
+	_, err := parseTrustAnchors([]string{". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"})
+	require.NoError(t, err)
+
+	_, err = parseTrustAnchors([]string{"example. IN A 192.0.2.1"})
+	require.ErrorContains(t, err, "neither a DS nor a DNSKEY record")
+
+	_, err = parseTrustAnchors([]string{"example. IN DS foo"})
+	require.ErrorContains(t, err, "parsing trust anchor")
+}
@@ -0,0 +1,331 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package docker_events
+
+import (
+	"context"
+	"crypto/tls"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal/docker"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	defaultEndpoint = "unix:///var/run/docker.sock"
+)
+
+var (
+	// Attributes of container events that are not container labels
+	containerAttributes = map[string]bool{
+		"name":     true,
+		"image":    true,
+		"exitCode": true,
+		"signal":   true,
+		"execID":   true,
+	}
+	// ensure *DockerEvents implements telegraf.ServiceInput
+	_ telegraf.ServiceInput = (*DockerEvents)(nil)
+)
+
+type DockerEvents struct {
+	Endpoint         string          `toml:"endpoint"`
+	EventTypes       []string        `toml:"event_types"`
+	ActionInclude    []string        `toml:"action_include"`
+	ActionExclude    []string        `toml:"action_exclude"`
+	ContainerInclude []string        `toml:"container_name_include"`
+	ContainerExclude []string        `toml:"container_name_exclude"`
+	LabelInclude     []string        `toml:"docker_label_include"`
+	LabelExclude     []string        `toml:"docker_label_exclude"`
+	ReconnectDelay   config.Duration `toml:"reconnect_delay"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	tlsint.ClientConfig
+
+	newEnvClient func() (Client, error)
+	newClient    func(string, *tls.Config) (Client, error)
+
+	client          Client
+	actionFilter    filter.Filter
+	containerFilter filter.Filter
+	labelFilter     filter.Filter
+	filterArgs      filters.Args
+
+	// Timestamp of the last event received, used to resume the stream
+	lastSeen int64
+
+	mu       sync.Mutex
+	counters map[string]*containerCounter
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// containerCounter accumulates the number of lifecycle events of a container
+// since the plugin was started
+type containerCounter struct {
+	tags      map[string]string
+	oom       int64
+	die       int64
+	restart   int64
+	destroyed bool
+}
+
+func (*DockerEvents) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DockerEvents) Init() error {
+	var err error
+	if d.Endpoint == "ENV" {
+		d.client, err = d.newEnvClient()
+		if err != nil {
+			return err
+		}
+	} else {
+		tlsConfig, err := d.ClientConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		d.client, err = d.newClient(d.Endpoint, tlsConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(d.EventTypes) == 0 {
+		d.EventTypes = []string{events.ContainerEventType}
+	}
+	d.filterArgs = filters.NewArgs()
+	for _, t := range d.EventTypes {
+		d.filterArgs.Add("type", t)
+	}
+
+	d.actionFilter, err = filter.NewIncludeExcludeFilter(d.ActionInclude, d.ActionExclude)
+	if err != nil {
+		return fmt.Errorf("creating action filter failed: %w", err)
+	}
+	d.containerFilter, err = filter.NewIncludeExcludeFilter(d.ContainerInclude, d.ContainerExclude)
+	if err != nil {
+		return fmt.Errorf("creating container filter failed: %w", err)
+	}
+	d.labelFilter, err = filter.NewIncludeExcludeFilter(d.LabelInclude, d.LabelExclude)
+	if err != nil {
+		return fmt.Errorf("creating label filter failed: %w", err)
+	}
+
+	d.counters = make(map[string]*containerCounter)
+
+	return nil
+}
+
+func (d *DockerEvents) Start(acc telegraf.Accumulator) error {
+	acc.SetPrecision(time.Nanosecond)
+
+	// Only report events happening after startup
+	d.lastSeen = time.Now().UnixNano()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.receive(ctx, acc)
+	}()
+
+	return nil
+}
+
+// Gather reports the event counters of all containers seen since startup
+func (d *DockerEvents) Gather(acc telegraf.Accumulator) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, c := range d.counters {
+		fields := map[string]interface{}{
+			"oom_count":     c.oom,
+			"die_count":     c.die,
+			"restart_count": c.restart,
+		}
+		acc.AddFields("docker_container_events", fields, c.tags)
+
+		// Report destroyed containers one last time before forgetting them
+		if c.destroyed {
+			delete(d.counters, id)
+		}
+	}
+
+	return nil
+}
+
+func (d *DockerEvents) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+}
+
+// receive subscribes to the event stream and resubscribes from the last seen
+// event on errors until the context is cancelled
+func (d *DockerEvents) receive(ctx context.Context, acc telegraf.Accumulator) {
+	for {
+		err := d.subscribe(ctx, acc)
+		if ctx.Err() != nil {
+			return
+		}
+		acc.AddError(fmt.Errorf("receiving events failed: %w", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(d.ReconnectDelay)):
+		}
+		d.Log.Debugf("Resubscribing to events since %s", formatTimestamp(d.lastSeen))
+	}
+}
+
+func (d *DockerEvents) subscribe(ctx context.Context, acc telegraf.Accumulator) error {
+	opts := types.EventsOptions{
+		Since:   formatTimestamp(d.lastSeen),
+		Filters: d.filterArgs,
+	}
+	messages, errs := d.client.Events(ctx, opts)
+	for {
+		select {
+		case msg := <-messages:
+			d.handle(acc, msg)
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+func (d *DockerEvents) handle(acc telegraf.Accumulator, msg events.Message) {
+	ts := msg.TimeNano
+	if ts == 0 {
+		ts = msg.Time * int64(time.Second)
+	}
+
+	// The daemon includes events at the "since" timestamp, so skip events
+	// already seen before resubscribing
+	if ts <= d.lastSeen {
+		return
+	}
+	d.lastSeen = ts
+
+	// Actions such as "health_status: healthy" or "exec_start: sh" carry
+	// details after the colon
+	action, detail, _ := strings.Cut(msg.Action, ":")
+	detail = strings.TrimSpace(detail)
+	if !d.actionFilter.Match(action) {
+		return
+	}
+
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	if msg.Type == events.ContainerEventType {
+		name := msg.Actor.Attributes["name"]
+		if !d.containerFilter.Match(name) {
+			return
+		}
+
+		imageName, imageVersion := docker.ParseImage(msg.Actor.Attributes["image"])
+		tags["container_name"] = name
+		tags["container_image"] = imageName
+		tags["container_version"] = imageVersion
+
+		// All other attributes are the labels of the container
+		for k, v := range msg.Actor.Attributes {
+			if !containerAttributes[k] && d.labelFilter.Match(k) {
+				tags[k] = v
+			}
+		}
+		d.count(msg.Actor.ID, action, tags)
+
+		fields["container_id"] = msg.Actor.ID
+		if v, ok := msg.Actor.Attributes["exitCode"]; ok {
+			if code, err := strconv.ParseInt(v, 10, 64); err == nil {
+				fields["exit_code"] = code
+			}
+		}
+		if v, ok := msg.Actor.Attributes["signal"]; ok {
+			if signal, err := strconv.ParseInt(v, 10, 64); err == nil {
+				fields["signal"] = signal
+			}
+		}
+	} else {
+		if name, ok := msg.Actor.Attributes["name"]; ok {
+			tags["actor_name"] = name
+		}
+		fields["actor_id"] = msg.Actor.ID
+	}
+
+	tags["type"] = msg.Type
+	tags["action"] = action
+	if detail != "" {
+		fields["action_detail"] = detail
+	}
+
+	acc.AddFields("docker_event", fields, tags, time.Unix(0, ts))
+}
+
+func (d *DockerEvents) count(id, action string, tags map[string]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c, found := d.counters[id]
+	if !found {
+		ctags := make(map[string]string, len(tags))
+		for k, v := range tags {
+			ctags[k] = v
+		}
+		c = &containerCounter{tags: ctags}
+		d.counters[id] = c
+	}
+
+	switch action {
+	case "oom":
+		c.oom++
+	case "die":
+		c.die++
+	case "restart":
+		c.restart++
+	case "destroy":
+		c.destroyed = true
+	}
+}
+
+// formatTimestamp converts the given unix timestamp in nanoseconds to the
+// "<seconds>.<nanoseconds>" format accepted by the Docker API
+func formatTimestamp(ts int64) string {
+	return fmt.Sprintf("%d.%09d", ts/int64(time.Second), ts%int64(time.Second))
+}
+
+func init() {
+	inputs.Add("docker_events", func() telegraf.Input {
+		return &DockerEvents{
+			Endpoint:       defaultEndpoint,
+			ReconnectDelay: config.Duration(5 * time.Second),
+			newEnvClient:   NewEnvClient,
+			newClient:      NewClient,
+		}
+	})
+}
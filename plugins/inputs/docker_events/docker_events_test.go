@@ -0,0 +1,351 @@
+package docker_events
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type MockClient struct {
+	EventsF func(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+}
+
+func (c *MockClient) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	return c.EventsF(ctx, options)
+}
+
+// stream returns an event stream sending the given messages followed by the
+// error. Without an error the stream stays open until cancelled.
+func stream(ctx context.Context, msgs []events.Message, err error) (<-chan events.Message, <-chan error) {
+	messages := make(chan events.Message)
+	errs := make(chan error, 1)
+	go func() {
+		for _, m := range msgs {
+			select {
+			case messages <- m:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		if err != nil {
+			errs <- err
+			return
+		}
+		<-ctx.Done()
+		errs <- ctx.Err()
+	}()
+	return messages, errs
+}
+
+func newPlugin(client *MockClient) *DockerEvents {
+	return &DockerEvents{
+		Endpoint:       defaultEndpoint,
+		ReconnectDelay: config.Duration(10 * time.Millisecond),
+		Log:            testutil.Logger{},
+		newClient: func(string, *tls.Config) (Client, error) {
+			return client, nil
+		},
+	}
+}
+
+func TestInit(t *testing.T) {
+	plugin := newPlugin(&MockClient{})
+	require.NoError(t, plugin.Init())
+	require.Equal(t, []string{"container"}, plugin.EventTypes)
+	require.Equal(t, []string{"container"}, plugin.filterArgs.Get("type"))
+
+	plugin = newPlugin(&MockClient{})
+	plugin.EventTypes = []string{"container", "network"}
+	require.NoError(t, plugin.Init())
+	require.ElementsMatch(t, []string{"container", "network"}, plugin.filterArgs.Get("type"))
+}
+
+func TestEvents(t *testing.T) {
+	now := time.Now()
+	msgs := []events.Message{
+		{
+			Type:   "container",
+			Action: "oom",
+			Actor: events.Actor{
+				ID: "deadbeef",
+				Attributes: map[string]string{
+					"name":  "db",
+					"image": "postgres:15",
+					"app":   "shop",
+				},
+			},
+			TimeNano: now.Add(time.Second).UnixNano(),
+		},
+		{
+			Type:   "container",
+			Action: "die",
+			Actor: events.Actor{
+				ID: "deadbeef",
+				Attributes: map[string]string{
+					"name":     "db",
+					"image":    "postgres:15",
+					"app":      "shop",
+					"exitCode": "137",
+				},
+			},
+			TimeNano: now.Add(2 * time.Second).UnixNano(),
+		},
+		{
+			Type:   "container",
+			Action: "health_status: unhealthy",
+			Actor: events.Actor{
+				ID: "cafe",
+				Attributes: map[string]string{
+					"name":  "web",
+					"image": "quay.io/nginx",
+				},
+			},
+			TimeNano: now.Add(3 * time.Second).UnixNano(),
+		},
+		{
+			Type:   "container",
+			Action: "die",
+			Actor: events.Actor{
+				ID:         "excluded",
+				Attributes: map[string]string{"name": "sidecar", "image": "envoy"},
+			},
+			TimeNano: now.Add(4 * time.Second).UnixNano(),
+		},
+		{
+			Type:   "network",
+			Action: "connect",
+			Actor: events.Actor{
+				ID:         "net0",
+				Attributes: map[string]string{"name": "bridge", "container": "cafe"},
+			},
+			TimeNano: now.Add(5 * time.Second).UnixNano(),
+		},
+	}
+
+	var options types.EventsOptions
+	plugin := newPlugin(&MockClient{
+		EventsF: func(ctx context.Context, opts types.EventsOptions) (<-chan events.Message, <-chan error) {
+			options = opts
+			return stream(ctx, msgs, nil)
+		},
+	})
+	plugin.EventTypes = []string{"container", "network"}
+	plugin.ContainerExclude = []string{"sidecar"}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	acc.Wait(4)
+	plugin.Stop()
+
+	require.ElementsMatch(t, []string{"container", "network"}, options.Filters.Get("type"))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("docker_event",
+			map[string]string{
+				"type":              "container",
+				"action":            "oom",
+				"container_name":    "db",
+				"container_image":   "postgres",
+				"container_version": "15",
+				"app":               "shop",
+			},
+			map[string]interface{}{"container_id": "deadbeef"},
+			time.Unix(0, msgs[0].TimeNano),
+		),
+		testutil.MustMetric("docker_event",
+			map[string]string{
+				"type":              "container",
+				"action":            "die",
+				"container_name":    "db",
+				"container_image":   "postgres",
+				"container_version": "15",
+				"app":               "shop",
+			},
+			map[string]interface{}{"container_id": "deadbeef", "exit_code": int64(137)},
+			time.Unix(0, msgs[1].TimeNano),
+		),
+		testutil.MustMetric("docker_event",
+			map[string]string{
+				"type":              "container",
+				"action":            "health_status",
+				"container_name":    "web",
+				"container_image":   "quay.io/nginx",
+				"container_version": "unknown",
+			},
+			map[string]interface{}{"container_id": "cafe", "action_detail": "unhealthy"},
+			time.Unix(0, msgs[2].TimeNano),
+		),
+		testutil.MustMetric("docker_event",
+			map[string]string{
+				"type":       "network",
+				"action":     "connect",
+				"actor_name": "bridge",
+			},
+			map[string]interface{}{"actor_id": "net0"},
+			time.Unix(0, msgs[4].TimeNano),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	expected = []telegraf.Metric{
+		testutil.MustMetric("docker_container_events",
+			map[string]string{
+				"container_name":    "db",
+				"container_image":   "postgres",
+				"container_version": "15",
+				"app":               "shop",
+			},
+			map[string]interface{}{"oom_count": int64(1), "die_count": int64(1), "restart_count": int64(0)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("docker_container_events",
+			map[string]string{
+				"container_name":    "web",
+				"container_image":   "quay.io/nginx",
+				"container_version": "unknown",
+			},
+			map[string]interface{}{"oom_count": int64(0), "die_count": int64(0), "restart_count": int64(0)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestActionFilter(t *testing.T) {
+	now := time.Now()
+	msgs := []events.Message{
+		{
+			Type:     "container",
+			Action:   "exec_start: sh -c true",
+			Actor:    events.Actor{ID: "cafe", Attributes: map[string]string{"name": "web", "image": "nginx"}},
+			TimeNano: now.Add(time.Second).UnixNano(),
+		},
+		{
+			Type:     "container",
+			Action:   "restart",
+			Actor:    events.Actor{ID: "cafe", Attributes: map[string]string{"name": "web", "image": "nginx"}},
+			TimeNano: now.Add(2 * time.Second).UnixNano(),
+		},
+	}
+
+	plugin := newPlugin(&MockClient{
+		EventsF: func(ctx context.Context, _ types.EventsOptions) (<-chan events.Message, <-chan error) {
+			return stream(ctx, msgs, nil)
+		},
+	})
+	plugin.ActionExclude = []string{"exec_*"}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	acc.Wait(1)
+	plugin.Stop()
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	action, _ := metrics[0].GetTag("action")
+	require.Equal(t, "restart", action)
+}
+
+func TestDestroyedContainerCounters(t *testing.T) {
+	now := time.Now()
+	actor := events.Actor{ID: "cafe", Attributes: map[string]string{"name": "web", "image": "nginx"}}
+	msgs := []events.Message{
+		{Type: "container", Action: "restart", Actor: actor, TimeNano: now.Add(time.Second).UnixNano()},
+		{Type: "container", Action: "destroy", Actor: actor, TimeNano: now.Add(2 * time.Second).UnixNano()},
+	}
+
+	plugin := newPlugin(&MockClient{
+		EventsF: func(ctx context.Context, _ types.EventsOptions) (<-chan events.Message, <-chan error) {
+			return stream(ctx, msgs, nil)
+		},
+	})
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	acc.Wait(2)
+	plugin.Stop()
+
+	// The counters are reported once more after the container is destroyed
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, int64(1), acc.GetTelegrafMetrics()[0].Fields()["restart_count"])
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestReconnect(t *testing.T) {
+	now := time.Now()
+	first := events.Message{
+		Type:     "container",
+		Action:   "start",
+		Actor:    events.Actor{ID: "cafe", Attributes: map[string]string{"name": "web", "image": "nginx"}},
+		TimeNano: now.Add(time.Second).UnixNano(),
+	}
+	second := events.Message{
+		Type:     "container",
+		Action:   "stop",
+		Actor:    events.Actor{ID: "cafe", Attributes: map[string]string{"name": "web", "image": "nginx"}},
+		TimeNano: now.Add(2 * time.Second).UnixNano(),
+	}
+
+	var mu sync.Mutex
+	var since []string
+	plugin := newPlugin(&MockClient{
+		EventsF: func(ctx context.Context, opts types.EventsOptions) (<-chan events.Message, <-chan error) {
+			mu.Lock()
+			defer mu.Unlock()
+			since = append(since, opts.Since)
+			if len(since) == 1 {
+				// The daemon closes the connection after the first event
+				return stream(ctx, []events.Message{first}, io.ErrUnexpectedEOF)
+			}
+			// The resubscription replays the last seen event
+			return stream(ctx, []events.Message{first, second}, nil)
+		},
+	})
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	acc.Wait(2)
+	plugin.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, since, 2)
+	require.Equal(t, formatTimestamp(first.TimeNano), since[1])
+
+	require.Len(t, acc.Errors, 1)
+	require.True(t, errors.Is(acc.Errors[0], io.ErrUnexpectedEOF))
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 2)
+	action, _ := metrics[1].GetTag("action")
+	require.Equal(t, "stop", action)
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	require.Equal(t, "1666000000.000000042", formatTimestamp(1666000000000000042))
+	require.Equal(t, "1666000000.500000000", formatTimestamp(1666000000500000000))
+}
@@ -42,6 +42,12 @@ type GNMI struct {
 	TagSubscriptions []TagSubscription `toml:"tag_subscription"`
 	Aliases          map[string]string `toml:"aliases"`
 
+	// Retrieval mode of the data (one of: "stream", "poll", "get")
+	Mode string `toml:"mode"`
+
+	// Report the capabilities of the device as metric
+	ReportCapabilities bool `toml:"report_capabilities"`
+
 	// Optional subscription configuration
 	Encoding    string
 	Origin      string
@@ -65,6 +71,7 @@ type GNMI struct {
 	acc             telegraf.Accumulator
 	cancel          context.CancelFunc
 	wg              sync.WaitGroup
+	workers         []*Worker
 	legacyTags      bool
 
 	Log telegraf.Logger
@@ -73,6 +80,7 @@ type GNMI struct {
 type Worker struct {
 	address  string
 	tagStore *tagNode
+	trigger  chan struct{}
 }
 
 type tagNode struct {
@@ -122,7 +130,6 @@ func (c *GNMI) Start(acc telegraf.Accumulator) error {
 	var err error
 	var ctx context.Context
 	var tlscfg *tls.Config
-	var request *gnmiLib.SubscribeRequest
 	c.acc = acc
 	ctx, c.cancel = context.WithCancel(context.Background())
 
@@ -154,9 +161,26 @@ func (c *GNMI) Start(acc telegraf.Accumulator) error {
 	}
 
 	// Validate configuration
-	if request, err = c.newSubscribeRequest(); err != nil {
+	switch c.Mode {
+	case "":
+		c.Mode = "stream"
+	case "stream", "poll", "get":
+	default:
+		return fmt.Errorf("invalid mode %q", c.Mode)
+	}
+	switch c.Encoding {
+	case "proto", "json", "json_ietf", "bytes", "auto":
+	default:
+		return fmt.Errorf("unsupported encoding %s", c.Encoding)
+	}
+	if c.Mode == "get" {
+		if _, err = c.newGetRequest(gnmiLib.Encoding_PROTO); err != nil {
+			return err
+		}
+	} else if _, err = c.newSubscribeRequest(gnmiLib.Encoding_PROTO); err != nil {
 		return err
-	} else if time.Duration(c.Redial).Nanoseconds() <= 0 {
+	}
+	if time.Duration(c.Redial).Nanoseconds() <= 0 {
 		return fmt.Errorf("redial duration must be positive")
 	}
 
@@ -190,14 +214,19 @@ func (c *GNMI) Start(acc telegraf.Accumulator) error {
 	}
 
 	// Create a goroutine for each device, dial and subscribe
+	c.workers = make([]*Worker, 0, len(c.Addresses))
 	c.wg.Add(len(c.Addresses))
 	for _, addr := range c.Addresses {
-		worker := Worker{address: addr}
-		worker.tagStore = &tagNode{}
-		go func(worker Worker) {
+		worker := &Worker{
+			address:  addr,
+			tagStore: &tagNode{},
+			trigger:  make(chan struct{}, 1),
+		}
+		c.workers = append(c.workers, worker)
+		go func(worker *Worker) {
 			defer c.wg.Done()
 			for ctx.Err() == nil {
-				if err := c.subscribeGNMI(ctx, &worker, tlscfg, request); err != nil && ctx.Err() == nil {
+				if err := c.runWorker(ctx, worker, tlscfg); err != nil && ctx.Err() == nil {
 					acc.AddError(err)
 				}
 
@@ -230,7 +259,7 @@ func (s *Subscription) buildSubscription() (*gnmiLib.Subscription, error) {
 }
 
 // Create a new gNMI SubscribeRequest
-func (c *GNMI) newSubscribeRequest() (*gnmiLib.SubscribeRequest, error) {
+func (c *GNMI) newSubscribeRequest(encoding gnmiLib.Encoding) (*gnmiLib.SubscribeRequest, error) {
 	// Create subscription objects
 	var err error
 	subscriptions := make([]*gnmiLib.Subscription, len(c.Subscriptions)+len(c.TagSubscriptions))
@@ -251,16 +280,17 @@ func (c *GNMI) newSubscribeRequest() (*gnmiLib.SubscribeRequest, error) {
 		return nil, err
 	}
 
-	if c.Encoding != "proto" && c.Encoding != "json" && c.Encoding != "json_ietf" && c.Encoding != "bytes" {
-		return nil, fmt.Errorf("unsupported encoding %s", c.Encoding)
+	mode := gnmiLib.SubscriptionList_STREAM
+	if c.Mode == "poll" {
+		mode = gnmiLib.SubscriptionList_POLL
 	}
 
 	return &gnmiLib.SubscribeRequest{
 		Request: &gnmiLib.SubscribeRequest_Subscribe{
 			Subscribe: &gnmiLib.SubscriptionList{
 				Prefix:       gnmiPath,
-				Mode:         gnmiLib.SubscriptionList_STREAM,
-				Encoding:     gnmiLib.Encoding(gnmiLib.Encoding_value[strings.ToUpper(c.Encoding)]),
+				Mode:         mode,
+				Encoding:     encoding,
 				Subscription: subscriptions,
 				UpdatesOnly:  c.UpdatesOnly,
 			},
@@ -268,8 +298,39 @@ func (c *GNMI) newSubscribeRequest() (*gnmiLib.SubscribeRequest, error) {
 	}, nil
 }
 
-// SubscribeGNMI and extract telemetry data
-func (c *GNMI) subscribeGNMI(ctx context.Context, worker *Worker, tlscfg *tls.Config, request *gnmiLib.SubscribeRequest) error {
+// Create a new gNMI GetRequest covering all subscriptions
+func (c *GNMI) newGetRequest(encoding gnmiLib.Encoding) (*gnmiLib.GetRequest, error) {
+	paths := make([]*gnmiLib.Path, 0, len(c.Subscriptions)+len(c.TagSubscriptions))
+	for _, subscription := range c.TagSubscriptions {
+		gnmiPath, err := parsePath(subscription.Origin, subscription.Path, "")
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, gnmiPath)
+	}
+	for _, subscription := range c.Subscriptions {
+		gnmiPath, err := parsePath(subscription.Origin, subscription.Path, "")
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, gnmiPath)
+	}
+
+	prefix, err := parsePath(c.Origin, c.Prefix, c.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gnmiLib.GetRequest{
+		Prefix:   prefix,
+		Path:     paths,
+		Type:     gnmiLib.GetRequest_ALL,
+		Encoding: encoding,
+	}, nil
+}
+
+// Dial the device, negotiate the encoding and retrieve data using the configured mode
+func (c *GNMI) runWorker(ctx context.Context, worker *Worker, tlscfg *tls.Config) error {
 	var creds credentials.TransportCredentials
 	if tlscfg != nil {
 		creds = credentials.NewTLS(tlscfg)
@@ -284,7 +345,124 @@ func (c *GNMI) subscribeGNMI(ctx context.Context, worker *Worker, tlscfg *tls.Co
 	}
 	defer client.Close()
 
-	subscribeClient, err := gnmiLib.NewGNMIClient(client).Subscribe(ctx)
+	gnmiClient := gnmiLib.NewGNMIClient(client)
+	encoding, err := c.negotiateEncoding(ctx, worker, gnmiClient)
+	if err != nil {
+		return err
+	}
+
+	if c.Mode == "get" {
+		return c.getGNMI(ctx, worker, gnmiClient, encoding)
+	}
+	return c.subscribeGNMI(ctx, worker, gnmiClient, encoding)
+}
+
+// Request the capabilities of the device if required and choose the encoding
+func (c *GNMI) negotiateEncoding(ctx context.Context, worker *Worker, client gnmiLib.GNMIClient) (gnmiLib.Encoding, error) {
+	configured := gnmiLib.Encoding(gnmiLib.Encoding_value[strings.ToUpper(c.Encoding)])
+	if c.Encoding != "auto" && !c.ReportCapabilities {
+		return configured, nil
+	}
+
+	capabilities, err := client.Capabilities(ctx, &gnmiLib.CapabilityRequest{})
+	if err != nil {
+		if c.Encoding == "auto" {
+			return 0, fmt.Errorf("failed to request capabilities: %v", err)
+		}
+		c.Log.Warnf("Requesting capabilities of %s failed: %v", worker.address, err)
+		return configured, nil
+	}
+
+	encoding := configured
+	if c.Encoding == "auto" {
+		if encoding, err = chooseEncoding(capabilities.SupportedEncodings); err != nil {
+			return 0, err
+		}
+		c.Log.Debugf("Using encoding %s for gNMI device %s", encoding, worker.address)
+	}
+
+	if c.ReportCapabilities {
+		c.addCapabilities(worker, capabilities, encoding)
+	}
+
+	return encoding, nil
+}
+
+// Choose the preferred encoding from the ones supported by the device
+func chooseEncoding(supported []gnmiLib.Encoding) (gnmiLib.Encoding, error) {
+	for _, preferred := range []gnmiLib.Encoding{gnmiLib.Encoding_PROTO, gnmiLib.Encoding_JSON_IETF, gnmiLib.Encoding_JSON} {
+		for _, encoding := range supported {
+			if encoding == preferred {
+				return encoding, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no supported encoding in %v", supported)
+}
+
+// Add a metric describing the capabilities of the device
+func (c *GNMI) addCapabilities(worker *Worker, capabilities *gnmiLib.CapabilityResponse, encoding gnmiLib.Encoding) {
+	encodings := make([]string, 0, len(capabilities.SupportedEncodings))
+	for _, e := range capabilities.SupportedEncodings {
+		encodings = append(encodings, strings.ToLower(e.String()))
+	}
+
+	tags := map[string]string{}
+	tags["source"], _, _ = net.SplitHostPort(worker.address)
+	fields := map[string]interface{}{
+		"gnmi_version":        capabilities.GNMIVersion,
+		"encoding":            strings.ToLower(encoding.String()),
+		"supported_encodings": strings.Join(encodings, ","),
+		"supported_models":    len(capabilities.SupportedModels),
+	}
+	c.acc.AddFields("gnmi_capabilities", fields, tags)
+}
+
+// Issue a Get request on every trigger and extract telemetry data
+func (c *GNMI) getGNMI(ctx context.Context, worker *Worker, client gnmiLib.GNMIClient, encoding gnmiLib.Encoding) error {
+	request, err := c.newGetRequest(encoding)
+	if err != nil {
+		return err
+	}
+
+	c.Log.Debugf("Connection to gNMI device %s established", worker.address)
+	defer c.Log.Debugf("Connection to gNMI device %s closed", worker.address)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-worker.trigger:
+		}
+
+		reply, err := client.Get(ctx, request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to get data: %v", err)
+		}
+
+		for _, notification := range reply.Notification {
+			// Some devices do not timestamp the data returned by Get
+			if notification.Timestamp == 0 {
+				notification.Timestamp = time.Now().UnixNano()
+			}
+			c.handleSubscribeResponseUpdate(worker, &gnmiLib.SubscribeResponse_Update{Update: notification})
+		}
+	}
+}
+
+// SubscribeGNMI and extract telemetry data
+func (c *GNMI) subscribeGNMI(ctx context.Context, worker *Worker, client gnmiLib.GNMIClient, encoding gnmiLib.Encoding) error {
+	request, err := c.newSubscribeRequest(encoding)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	subscribeClient, err := client.Subscribe(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to setup subscription: %v", err)
 	}
@@ -297,6 +475,23 @@ func (c *GNMI) subscribeGNMI(ctx context.Context, worker *Worker, tlscfg *tls.Co
 		}
 	}
 
+	// In poll mode request new data on every trigger
+	if c.Mode == "poll" {
+		poll := &gnmiLib.SubscribeRequest{Request: &gnmiLib.SubscribeRequest_Poll{Poll: &gnmiLib.Poll{}}}
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-worker.trigger:
+				}
+				if err := subscribeClient.Send(poll); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
 	c.Log.Debugf("Connection to gNMI device %s established", worker.address)
 	defer c.Log.Debugf("Connection to gNMI device %s closed", worker.address)
 	for ctx.Err() == nil {
@@ -498,13 +693,24 @@ func (c *GNMI) Stop() {
 	c.wg.Wait()
 }
 
-// Gather plugin measurements (unused)
+// Gather triggers the data retrieval in "poll" and "get" mode
 func (c *GNMI) Gather(_ telegraf.Accumulator) error {
+	if c.Mode != "poll" && c.Mode != "get" {
+		return nil
+	}
+
+	for _, worker := range c.workers {
+		select {
+		case worker.trigger <- struct{}{}:
+		default:
+		}
+	}
 	return nil
 }
 
 func New() telegraf.Input {
 	return &GNMI{
+		Mode:     "stream",
 		Encoding: "proto",
 		Redial:   config.Duration(10 * time.Second),
 	}
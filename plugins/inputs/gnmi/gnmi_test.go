@@ -39,16 +39,24 @@ func TestParsePath(t *testing.T) {
 }
 
 type MockServer struct {
-	SubscribeF func(gnmiLib.GNMI_SubscribeServer) error
-	GRPCServer *grpc.Server
+	SubscribeF    func(gnmiLib.GNMI_SubscribeServer) error
+	CapabilitiesF func(*gnmiLib.CapabilityRequest) (*gnmiLib.CapabilityResponse, error)
+	GetF          func(*gnmiLib.GetRequest) (*gnmiLib.GetResponse, error)
+	GRPCServer    *grpc.Server
 }
 
-func (s *MockServer) Capabilities(context.Context, *gnmiLib.CapabilityRequest) (*gnmiLib.CapabilityResponse, error) {
-	return nil, nil
+func (s *MockServer) Capabilities(_ context.Context, req *gnmiLib.CapabilityRequest) (*gnmiLib.CapabilityResponse, error) {
+	if s.CapabilitiesF == nil {
+		return nil, nil
+	}
+	return s.CapabilitiesF(req)
 }
 
-func (s *MockServer) Get(context.Context, *gnmiLib.GetRequest) (*gnmiLib.GetResponse, error) {
-	return nil, nil
+func (s *MockServer) Get(_ context.Context, req *gnmiLib.GetRequest) (*gnmiLib.GetResponse, error) {
+	if s.GetF == nil {
+		return nil, nil
+	}
+	return s.GetF(req)
 }
 
 func (s *MockServer) Set(context.Context, *gnmiLib.SetRequest) (*gnmiLib.SetResponse, error) {
@@ -964,3 +972,229 @@ func TestTagNode(t *testing.T) {
 		})
 	}
 }
+
+func TestGetMode(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var requests []*gnmiLib.GetRequest
+	var mu sync.Mutex
+	grpcServer := grpc.NewServer()
+	gnmiServer := &MockServer{
+		GetF: func(req *gnmiLib.GetRequest) (*gnmiLib.GetResponse, error) {
+			mu.Lock()
+			requests = append(requests, req)
+			mu.Unlock()
+			return &gnmiLib.GetResponse{Notification: []*gnmiLib.Notification{mockGNMINotification()}}, nil
+		},
+		GRPCServer: grpcServer,
+	}
+	gnmiLib.RegisterGNMIServer(grpcServer, gnmiServer)
+
+	plugin := &GNMI{
+		Log:       testutil.Logger{},
+		Addresses: []string{listener.Addr().String()},
+		Mode:      "get",
+		Encoding:  "json_ietf",
+		Redial:    config.Duration(1 * time.Second),
+		Subscriptions: []Subscription{
+			{
+				Name:   "alias",
+				Origin: "type",
+				Path:   "/model",
+			},
+		},
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := grpcServer.Serve(listener)
+		require.NoError(t, err)
+	}()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+
+	// Nothing must be requested before the first gather
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, uint64(0), acc.NMetrics())
+
+	require.NoError(t, plugin.Gather(&acc))
+	acc.Wait(2)
+	plugin.Stop()
+	grpcServer.Stop()
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 1)
+	require.Equal(t, gnmiLib.Encoding_JSON_IETF, requests[0].Encoding)
+	require.Len(t, requests[0].Path, 1)
+	require.Equal(t, "type", requests[0].Path[0].Origin)
+
+	m, found := acc.Get("alias")
+	require.True(t, found)
+	require.Equal(t, int64(5678), m.Fields["some/path"])
+}
+
+func TestPollMode(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	gnmiServer := &MockServer{
+		SubscribeF: func(server gnmiLib.GNMI_SubscribeServer) error {
+			req, err := server.Recv()
+			if err != nil {
+				return err
+			}
+			if req.GetSubscribe().Mode != gnmiLib.SubscriptionList_POLL {
+				return errors.New("expected poll subscription")
+			}
+			for {
+				req, err := server.Recv()
+				if err != nil {
+					return err
+				}
+				if req.GetPoll() == nil {
+					return errors.New("expected poll request")
+				}
+				notification := mockGNMINotification()
+				err = server.Send(&gnmiLib.SubscribeResponse{Response: &gnmiLib.SubscribeResponse_Update{Update: notification}})
+				if err != nil {
+					return err
+				}
+				err = server.Send(&gnmiLib.SubscribeResponse{Response: &gnmiLib.SubscribeResponse_SyncResponse{SyncResponse: true}})
+				if err != nil {
+					return err
+				}
+			}
+		},
+		GRPCServer: grpcServer,
+	}
+	gnmiLib.RegisterGNMIServer(grpcServer, gnmiServer)
+
+	plugin := &GNMI{
+		Log:       testutil.Logger{},
+		Addresses: []string{listener.Addr().String()},
+		Mode:      "poll",
+		Encoding:  "proto",
+		Redial:    config.Duration(1 * time.Second),
+		Subscriptions: []Subscription{
+			{
+				Name:             "alias",
+				Origin:           "type",
+				Path:             "/model",
+				SubscriptionMode: "sample",
+			},
+		},
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := grpcServer.Serve(listener)
+		require.NoError(t, err)
+	}()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+
+	require.NoError(t, plugin.Gather(&acc))
+	acc.Wait(2)
+	require.NoError(t, plugin.Gather(&acc))
+	acc.Wait(4)
+
+	plugin.Stop()
+	grpcServer.Stop()
+	wg.Wait()
+	require.Empty(t, acc.Errors)
+}
+
+func TestCapabilities(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	encoding := make(chan gnmiLib.Encoding, 1)
+	grpcServer := grpc.NewServer()
+	gnmiServer := &MockServer{
+		CapabilitiesF: func(*gnmiLib.CapabilityRequest) (*gnmiLib.CapabilityResponse, error) {
+			return &gnmiLib.CapabilityResponse{
+				GNMIVersion:        "0.7.0",
+				SupportedEncodings: []gnmiLib.Encoding{gnmiLib.Encoding_JSON, gnmiLib.Encoding_JSON_IETF},
+				SupportedModels:    []*gnmiLib.ModelData{{Name: "openconfig-interfaces"}},
+			}, nil
+		},
+		SubscribeF: func(server gnmiLib.GNMI_SubscribeServer) error {
+			req, err := server.Recv()
+			if err != nil {
+				return err
+			}
+			encoding <- req.GetSubscribe().Encoding
+			<-server.Context().Done()
+			return nil
+		},
+		GRPCServer: grpcServer,
+	}
+	gnmiLib.RegisterGNMIServer(grpcServer, gnmiServer)
+
+	plugin := &GNMI{
+		Log:                testutil.Logger{},
+		Addresses:          []string{listener.Addr().String()},
+		Encoding:           "auto",
+		ReportCapabilities: true,
+		Redial:             config.Duration(1 * time.Second),
+		Subscriptions: []Subscription{
+			{
+				Name:             "alias",
+				Origin:           "type",
+				Path:             "/model",
+				SubscriptionMode: "sample",
+			},
+		},
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := grpcServer.Serve(listener)
+		require.NoError(t, err)
+	}()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+
+	require.Equal(t, gnmiLib.Encoding_JSON_IETF, <-encoding)
+	acc.Wait(1)
+	plugin.Stop()
+	grpcServer.Stop()
+	wg.Wait()
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"gnmi_capabilities",
+			map[string]string{"source": "127.0.0.1"},
+			map[string]interface{}{
+				"gnmi_version":        "0.7.0",
+				"encoding":            "json_ietf",
+				"supported_encodings": "json,json_ietf",
+				"supported_models":    1,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestChooseEncoding(t *testing.T) {
+	encoding, err := chooseEncoding([]gnmiLib.Encoding{gnmiLib.Encoding_JSON, gnmiLib.Encoding_PROTO})
+	require.NoError(t, err)
+	require.Equal(t, gnmiLib.Encoding_PROTO, encoding)
+
+	_, err = chooseEncoding([]gnmiLib.Encoding{gnmiLib.Encoding_ASCII})
+	require.Error(t, err)
+}
@@ -0,0 +1,261 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	internaltls "github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers/temporary/xpath"
+	xpathparser "github.com/influxdata/telegraf/plugins/parsers/xpath"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+// GRPC plugin instance
+type GRPC struct {
+	Address  string            `toml:"address"`
+	Method   string            `toml:"method"`
+	Request  string            `toml:"request"`
+	Metadata map[string]string `toml:"metadata"`
+	Timeout  config.Duration   `toml:"timeout"`
+
+	// Protocol-buffer definitions used instead of server reflection
+	ProtoFiles  []string `toml:"proto_files"`
+	ImportPaths []string `toml:"proto_import_paths"`
+
+	// Metric construction
+	MetricName          string         `toml:"metric_name"`
+	PrintDocument       bool           `toml:"print_document"`
+	AllowEmptySelection bool           `toml:"allow_empty_selection"`
+	NativeTypes         bool           `toml:"native_types"`
+	XPath               []xpath.Config `toml:"xpath"`
+
+	// GRPC TLS settings
+	EnableTLS bool `toml:"enable_tls"`
+	internaltls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	service string
+	method  string
+	conn    *grpc.ClientConn
+	request proto.Message
+	reply   protoreflect.MessageDescriptor
+	parser  *xpathparser.Parser
+}
+
+func (*GRPC) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GRPC) Init() error {
+	if g.Address == "" {
+		return errors.New("address must be set")
+	}
+
+	// Accept both "package.Service/Method" and "/package.Service/Method"
+	method := strings.TrimPrefix(g.Method, "/")
+	idx := strings.LastIndex(method, "/")
+	if idx < 1 || idx == len(method)-1 {
+		return fmt.Errorf("invalid method %q, expected format \"package.Service/Method\"", g.Method)
+	}
+	g.service, g.method = method[:idx], method[idx+1:]
+
+	if g.Request == "" {
+		g.Request = "{}"
+	}
+
+	if g.MetricName == "" {
+		g.MetricName = "grpc"
+	}
+
+	if len(g.XPath) == 0 {
+		return errors.New("at least one xpath section is required")
+	}
+
+	// Without server reflection we can resolve the method right away
+	if len(g.ProtoFiles) > 0 {
+		parser := protoparse.Parser{
+			ImportPaths:      g.ImportPaths,
+			InferImportPaths: true,
+		}
+		fds, err := parser.ParseFiles(g.ProtoFiles...)
+		if err != nil {
+			return fmt.Errorf("parsing protocol-buffer definitions failed: %w", err)
+		}
+		if err := g.setupMethod(fds...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *GRPC) Start(_ telegraf.Accumulator) error {
+	var creds credentials.TransportCredentials
+	if g.EnableTLS {
+		tlscfg, err := g.ClientConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		if tlscfg == nil {
+			tlscfg = &tls.Config{}
+		}
+		creds = credentials.NewTLS(tlscfg)
+	} else {
+		creds = insecure.NewCredentials()
+	}
+
+	// Dialing is non-blocking, so a server not being available at startup is
+	// not an error.
+	conn, err := grpc.Dial(g.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("failed to dial: %w", err)
+	}
+	g.conn = conn
+
+	return nil
+}
+
+func (g *GRPC) Stop() {
+	if g.conn != nil {
+		g.conn.Close()
+	}
+}
+
+func (g *GRPC) Gather(acc telegraf.Accumulator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(g.Timeout))
+	defer cancel()
+
+	for k, v := range g.Metadata {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+
+	// Resolve the method via server reflection on first use
+	if g.parser == nil {
+		if err := g.resolveMethod(ctx); err != nil {
+			return err
+		}
+	}
+
+	reply := dynamicpb.NewMessage(g.reply)
+	if err := g.conn.Invoke(ctx, "/"+g.service+"/"+g.method, g.request, reply); err != nil {
+		return fmt.Errorf("calling %s/%s failed: %w", g.service, g.method, err)
+	}
+
+	buf, err := proto.Marshal(reply)
+	if err != nil {
+		return fmt.Errorf("serializing reply failed: %w", err)
+	}
+
+	metrics, err := g.parser.Parse(buf)
+	if err != nil {
+		return fmt.Errorf("parsing reply failed: %w", err)
+	}
+
+	for _, m := range metrics {
+		if !m.HasTag("address") {
+			m.AddTag("address", g.Address)
+		}
+		acc.AddMetric(m)
+	}
+
+	return nil
+}
+
+// resolveMethod looks up the service definition using server reflection
+func (g *GRPC) resolveMethod(ctx context.Context) error {
+	client := grpcreflect.NewClient(ctx, rpb.NewServerReflectionClient(g.conn))
+	defer client.Reset()
+
+	fd, err := client.FileContainingSymbol(g.service)
+	if err != nil {
+		return fmt.Errorf("resolving service %q via reflection failed: %w", g.service, err)
+	}
+
+	return g.setupMethod(fd)
+}
+
+// setupMethod finds the method in the given file descriptors, prepares the
+// request message and creates the parser for the reply message
+func (g *GRPC) setupMethod(fds ...*desc.FileDescriptor) error {
+	registry, err := protodesc.NewFiles(desc.ToFileDescriptorSet(fds...))
+	if err != nil {
+		return fmt.Errorf("constructing registry failed: %w", err)
+	}
+
+	descriptor, err := registry.FindDescriptorByName(protoreflect.FullName(g.service))
+	if err != nil {
+		return fmt.Errorf("looking up service %q failed: %w", g.service, err)
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return fmt.Errorf("%q is not a service (%T)", g.service, descriptor)
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(g.method))
+	if method == nil {
+		return fmt.Errorf("service %q has no method %q", g.service, g.method)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return fmt.Errorf("method %q is not unary", g.method)
+	}
+
+	request := dynamicpb.NewMessage(method.Input())
+	if err := protojson.Unmarshal([]byte(g.Request), request); err != nil {
+		return fmt.Errorf("decoding request for %q failed: %w", method.Input().FullName(), err)
+	}
+
+	parser := &xpathparser.Parser{
+		Format:                    "xpath_protobuf",
+		ProtobufMessageDescriptor: method.Output(),
+		PrintDocument:             g.PrintDocument,
+		AllowEmptySelection:       g.AllowEmptySelection,
+		NativeTypes:               g.NativeTypes,
+		Configs:                   g.XPath,
+		DefaultMetricName:         g.MetricName,
+		Log:                       g.Log,
+	}
+	if err := parser.Init(); err != nil {
+		return fmt.Errorf("initializing parser failed: %w", err)
+	}
+
+	g.request = request
+	g.reply = method.Output()
+	g.parser = parser
+
+	return nil
+}
+
+func init() {
+	inputs.Add("grpc", func() telegraf.Input {
+		return &GRPC{
+			Timeout: config.Duration(5 * time.Second),
+		}
+	})
+}
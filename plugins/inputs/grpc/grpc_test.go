@@ -0,0 +1,187 @@
+package grpc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/parsers/temporary/xpath"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func startServer(t *testing.T, withReflection bool) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("foo", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	if withReflection {
+		reflection.Register(server)
+	}
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *GRPC
+		expected string
+	}{
+		{
+			name:     "no address",
+			plugin:   &GRPC{Method: "grpc.health.v1.Health/Check"},
+			expected: "address must be set",
+		},
+		{
+			name:     "invalid method",
+			plugin:   &GRPC{Address: "localhost:1234", Method: "Check"},
+			expected: "invalid method",
+		},
+		{
+			name:     "no xpath",
+			plugin:   &GRPC{Address: "localhost:1234", Method: "grpc.health.v1.Health/Check"},
+			expected: "at least one xpath section",
+		},
+		{
+			name: "streaming method",
+			plugin: &GRPC{
+				Address:    "localhost:1234",
+				Method:     "grpc.health.v1.Health/Watch",
+				ProtoFiles: []string{"testdata/health.proto"},
+				XPath:      []xpath.Config{{Fields: map[string]string{"status": "status"}}},
+			},
+			expected: "not unary",
+		},
+		{
+			name: "unknown method",
+			plugin: &GRPC{
+				Address:    "localhost:1234",
+				Method:     "grpc.health.v1.Health/Foo",
+				ProtoFiles: []string{"testdata/health.proto"},
+				XPath:      []xpath.Config{{Fields: map[string]string{"status": "status"}}},
+			},
+			expected: "has no method",
+		},
+		{
+			name: "invalid request",
+			plugin: &GRPC{
+				Address:    "localhost:1234",
+				Method:     "grpc.health.v1.Health/Check",
+				Request:    `{"unknown": 1}`,
+				ProtoFiles: []string{"testdata/health.proto"},
+				XPath:      []xpath.Config{{Fields: map[string]string{"status": "status"}}},
+			},
+			expected: "decoding request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestGather(t *testing.T) {
+	tests := []struct {
+		name       string
+		reflection bool
+		protoFiles []string
+		request    string
+		expected   []telegraf.Metric
+	}{
+		{
+			name:       "reflection",
+			reflection: true,
+			expected: []telegraf.Metric{
+				testutil.MustMetric(
+					"health",
+					map[string]string{},
+					map[string]interface{}{"status": int64(1)},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name:       "proto files",
+			protoFiles: []string{"testdata/health.proto"},
+			request:    `{"service": "foo"}`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric(
+					"health",
+					map[string]string{},
+					map[string]interface{}{"status": int64(2)},
+					time.Unix(0, 0),
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startServer(t, tt.reflection)
+			for _, m := range tt.expected {
+				m.AddTag("address", addr)
+			}
+
+			plugin := &GRPC{
+				Address:    addr,
+				Method:     "grpc.health.v1.Health/Check",
+				Request:    tt.request,
+				ProtoFiles: tt.protoFiles,
+				MetricName: "health",
+				Timeout:    config.Duration(5 * time.Second),
+				XPath: []xpath.Config{
+					{FieldsInt: map[string]string{"status": "status"}},
+				},
+				Log: testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Start(&acc))
+			defer plugin.Stop()
+
+			require.NoError(t, plugin.Gather(&acc))
+			testutil.RequireMetricsEqual(t, tt.expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+		})
+	}
+}
+
+func TestGatherNoReflection(t *testing.T) {
+	addr := startServer(t, false)
+
+	plugin := &GRPC{
+		Address: addr,
+		Method:  "grpc.health.v1.Health/Check",
+		Timeout: config.Duration(5 * time.Second),
+		XPath: []xpath.Config{
+			{Fields: map[string]string{"status": "status"}},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	require.ErrorContains(t, plugin.Gather(&acc), "via reflection failed")
+}
@@ -40,6 +40,9 @@ type HTTP struct {
 
 	SuccessStatusCodes []int `toml:"success_status_codes"`
 
+	Pagination *Pagination `toml:"pagination"`
+	Login      *Login      `toml:"login"`
+
 	Log telegraf.Logger `toml:"-"`
 
 	httpconfig.HTTPClientConfig
@@ -65,6 +68,17 @@ func (h *HTTP) Init() error {
 	if len(h.SuccessStatusCodes) == 0 {
 		h.SuccessStatusCodes = []int{200}
 	}
+
+	if h.Pagination != nil {
+		if err := h.Pagination.init(); err != nil {
+			return err
+		}
+	}
+	if h.Login != nil {
+		if err := h.Login.init(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -103,44 +117,69 @@ func (h *HTTP) gatherURL(
 	acc telegraf.Accumulator,
 	url string,
 ) error {
-	body, err := makeRequestBodyReader(h.ContentEncoding, h.Body)
-	if err != nil {
-		return err
+	next := url
+	if h.Pagination != nil {
+		var err error
+		if next, err = h.Pagination.first(url); err != nil {
+			return err
+		}
 	}
 
-	request, err := http.NewRequest(h.Method, url, body)
-	if err != nil {
-		return err
-	}
+	for page := 1; next != ""; page++ {
+		if h.Pagination != nil && page > h.Pagination.MaxPages {
+			h.Log.Warnf("Reached maximum of %d pages for %q, skipping remaining pages", h.Pagination.MaxPages, url)
+			break
+		}
 
-	if h.BearerToken != "" {
-		token, err := os.ReadFile(h.BearerToken)
+		header, b, err := h.request(next)
 		if err != nil {
 			return err
 		}
-		bearer := "Bearer " + strings.Trim(string(token), "\n")
-		request.Header.Set("Authorization", bearer)
-	}
 
-	if h.ContentEncoding == "gzip" {
-		request.Header.Set("Content-Encoding", "gzip")
-	}
+		// Instantiate a new parser for the new data to avoid trouble with stateful parsers
+		parser, err := h.parserFunc()
+		if err != nil {
+			return fmt.Errorf("instantiating parser failed: %v", err)
+		}
+		metrics, err := parser.Parse(b)
+		if err != nil {
+			return fmt.Errorf("parsing metrics failed: %v", err)
+		}
 
-	for k, v := range h.Headers {
-		if strings.ToLower(k) == "host" {
-			request.Host = v
-		} else {
-			request.Header.Add(k, v)
+		for _, metric := range metrics {
+			if !metric.HasTag("url") {
+				metric.AddTag("url", url)
+			}
+			acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
 		}
-	}
 
-	if h.Username != "" || h.Password != "" {
-		request.SetBasicAuth(h.Username, h.Password)
+		if h.Pagination == nil {
+			break
+		}
+		current := next
+		next, err = h.Pagination.next(url, current, page, header, b, len(metrics))
+		if err != nil {
+			return fmt.Errorf("determining next page failed: %w", err)
+		}
 	}
 
-	resp, err := h.client.Do(request)
+	return nil
+}
+
+// request sends a request to the given URL and returns the response header
+// and body. If the login token is rejected, the request is retried once with
+// a new token.
+func (h *HTTP) request(url string) (http.Header, []byte, error) {
+	resp, authorization, err := h.do(url)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	if h.Login != nil && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		h.Login.invalidate(authorization)
+		if resp, _, err = h.do(url); err != nil {
+			return nil, nil, err
+		}
 	}
 	defer resp.Body.Close()
 
@@ -153,7 +192,7 @@ func (h *HTTP) gatherURL(
 	}
 
 	if !responseHasSuccessCode {
-		return fmt.Errorf("received status code %d (%s), expected any value out of %v",
+		return nil, nil, fmt.Errorf("received status code %d (%s), expected any value out of %v",
 			resp.StatusCode,
 			http.StatusText(resp.StatusCode),
 			h.SuccessStatusCodes)
@@ -161,27 +200,61 @@ func (h *HTTP) gatherURL(
 
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("reading body failed: %v", err)
+		return nil, nil, fmt.Errorf("reading body failed: %v", err)
 	}
 
-	// Instantiate a new parser for the new data to avoid trouble with stateful parsers
-	parser, err := h.parserFunc()
+	return resp.Header, b, nil
+}
+
+// do sends a single request and additionally returns the login token header
+// value used, if any
+func (h *HTTP) do(url string) (*http.Response, string, error) {
+	body, err := makeRequestBodyReader(h.ContentEncoding, h.Body)
 	if err != nil {
-		return fmt.Errorf("instantiating parser failed: %v", err)
+		return nil, "", err
 	}
-	metrics, err := parser.Parse(b)
+
+	request, err := http.NewRequest(h.Method, url, body)
 	if err != nil {
-		return fmt.Errorf("parsing metrics failed: %v", err)
+		return nil, "", err
 	}
 
-	for _, metric := range metrics {
-		if !metric.HasTag("url") {
-			metric.AddTag("url", url)
+	if h.BearerToken != "" {
+		token, err := os.ReadFile(h.BearerToken)
+		if err != nil {
+			return nil, "", err
 		}
-		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+		bearer := "Bearer " + strings.Trim(string(token), "\n")
+		request.Header.Set("Authorization", bearer)
 	}
 
-	return nil
+	if h.ContentEncoding == "gzip" {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+
+	for k, v := range h.Headers {
+		if strings.ToLower(k) == "host" {
+			request.Host = v
+		} else {
+			request.Header.Add(k, v)
+		}
+	}
+
+	if h.Username != "" || h.Password != "" {
+		request.SetBasicAuth(h.Username, h.Password)
+	}
+
+	var authorization string
+	if h.Login != nil {
+		authorization, err = h.Login.header(h.client)
+		if err != nil {
+			return nil, "", err
+		}
+		request.Header.Set(h.Login.TokenHeader, authorization)
+	}
+
+	resp, err := h.client.Do(request)
+	return resp, authorization, err
 }
 
 func makeRequestBodyReader(contentEncoding, body string) (io.Reader, error) {
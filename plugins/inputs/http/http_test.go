@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -446,3 +448,338 @@ func TestHTTPWithCSVFormat(t *testing.T) {
 	require.NoError(t, acc.GatherError(plugin.Gather))
 	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
 }
+
+func newJSONItemsParser() (telegraf.Parser, error) {
+	p := &json.Parser{
+		MetricName: "items",
+		Query:      "items",
+	}
+	err := p.Init()
+	return p, err
+}
+
+func itemValues(t *testing.T, acc *testutil.Accumulator) []float64 {
+	var values []float64
+	for _, m := range acc.GetTelegrafMetrics() {
+		v, ok := m.GetField("value")
+		require.True(t, ok)
+		values = append(values, v.(float64))
+	}
+	return values
+}
+
+func TestPaginationLinkHeader(t *testing.T) {
+	pages := map[string]string{
+		"":  `{"items": [{"value": 1}, {"value": 2}]}`,
+		"2": `{"items": [{"value": 3}]}`,
+		"3": `{"items": [{"value": 4}]}`,
+	}
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		switch page {
+		case "":
+			w.Header().Add("Link", `</endpoint?page=2>; rel="next", </endpoint?page=3>; rel="last"`)
+		case "2":
+			w.Header().Add("Link", `</endpoint?page=1>; rel="prev first"`)
+			w.Header().Add("Link", `<`+"http://"+r.Host+`/endpoint?page=3>; rel="next last"`)
+		}
+		_, _ = w.Write([]byte(pages[page]))
+	}))
+	defer fakeServer.Close()
+
+	address := fakeServer.URL + "/endpoint"
+	plugin := &httpplugin.HTTP{
+		URLs:       []string{address},
+		Pagination: &httpplugin.Pagination{Strategy: "link_header"},
+		Log:        testutil.Logger{},
+	}
+	plugin.SetParserFunc(newJSONItemsParser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, []float64{1, 2, 3, 4}, itemValues(t, &acc))
+
+	// All metrics are tagged with the configured URL
+	for _, m := range acc.GetTelegrafMetrics() {
+		tag, _ := m.GetTag("url")
+		require.Equal(t, address, tag)
+	}
+}
+
+func TestPaginationJSONNextURL(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/endpoint":
+			_, _ = w.Write([]byte(`{"items": [{"value": 1}], "links": {"next": "/endpoint/2"}}`))
+		case "/endpoint/2":
+			_, _ = w.Write([]byte(`{"items": [{"value": 2}], "links": {"next": null}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer fakeServer.Close()
+
+	plugin := &httpplugin.HTTP{
+		URLs: []string{fakeServer.URL + "/endpoint"},
+		Pagination: &httpplugin.Pagination{
+			Strategy: "json_next_url",
+			NextPath: "links.next",
+		},
+		Log: testutil.Logger{},
+	}
+	plugin.SetParserFunc(newJSONItemsParser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, []float64{1, 2}, itemValues(t, &acc))
+}
+
+func TestPaginationJSONCursor(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "bar", r.URL.Query().Get("foo"))
+		switch r.URL.Query().Get("after") {
+		case "":
+			_, _ = w.Write([]byte(`{"items": [{"value": 1}], "meta": {"cursor": "abc"}}`))
+		case "abc":
+			_, _ = w.Write([]byte(`{"items": [{"value": 2}], "meta": {"cursor": "def"}}`))
+		case "def":
+			_, _ = w.Write([]byte(`{"items": [{"value": 3}], "meta": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer fakeServer.Close()
+
+	plugin := &httpplugin.HTTP{
+		URLs: []string{fakeServer.URL + "/endpoint?foo=bar"},
+		Pagination: &httpplugin.Pagination{
+			Strategy:        "json_cursor",
+			NextPath:        "meta.cursor",
+			CursorParameter: "after",
+		},
+		Log: testutil.Logger{},
+	}
+	plugin.SetParserFunc(newJSONItemsParser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, []float64{1, 2, 3}, itemValues(t, &acc))
+}
+
+func TestPaginationOffset(t *testing.T) {
+	items := []string{`{"value": 1}`, `{"value": 2}`, `{"value": 3}`, `{"value": 4}`, `{"value": 5}`}
+
+	tests := []struct {
+		name      string
+		countPath string
+		total     int
+		expected  []float64
+		requests  int
+	}{
+		{
+			name:     "partial last page",
+			total:    5,
+			expected: []float64{1, 2, 3, 4, 5},
+			requests: 3,
+		},
+		{
+			name:     "empty last page",
+			total:    4,
+			expected: []float64{1, 2, 3, 4},
+			requests: 3,
+		},
+		{
+			name:      "count path",
+			countPath: "items.#",
+			total:     5,
+			expected:  []float64{1, 2, 3, 4, 5},
+			requests:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				offset, err := strconv.Atoi(r.URL.Query().Get("start"))
+				require.NoError(t, err)
+				limit, err := strconv.Atoi(r.URL.Query().Get("count"))
+				require.NoError(t, err)
+
+				var page []string
+				for i := offset; i < offset+limit && i < tt.total; i++ {
+					page = append(page, items[i])
+				}
+				_, _ = w.Write([]byte(`{"items": [` + strings.Join(page, ",") + `]}`))
+			}))
+			defer fakeServer.Close()
+
+			plugin := &httpplugin.HTTP{
+				URLs: []string{fakeServer.URL + "/endpoint"},
+				Pagination: &httpplugin.Pagination{
+					Strategy:        "offset",
+					OffsetParameter: "start",
+					LimitParameter:  "count",
+					Limit:           2,
+					CountPath:       tt.countPath,
+				},
+				Log: testutil.Logger{},
+			}
+			plugin.SetParserFunc(newJSONItemsParser)
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, acc.GatherError(plugin.Gather))
+			require.Equal(t, tt.expected, itemValues(t, &acc))
+			require.Equal(t, tt.requests, requests)
+		})
+	}
+}
+
+func TestPaginationMaxPages(t *testing.T) {
+	var requests int
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		next := fmt.Sprintf("/endpoint?page=%d", requests+1)
+		_, _ = w.Write([]byte(`{"items": [{"value": 1}], "next": "` + next + `"}`))
+	}))
+	defer fakeServer.Close()
+
+	plugin := &httpplugin.HTTP{
+		URLs: []string{fakeServer.URL + "/endpoint"},
+		Pagination: &httpplugin.Pagination{
+			Strategy: "json_next_url",
+			NextPath: "next",
+			MaxPages: 3,
+		},
+		Log: testutil.Logger{},
+	}
+	plugin.SetParserFunc(newJSONItemsParser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, 3, requests)
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+}
+
+func TestPaginationInit(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination *httpplugin.Pagination
+		expected   string
+	}{
+		{
+			name:       "unknown strategy",
+			pagination: &httpplugin.Pagination{Strategy: "pages"},
+			expected:   `invalid pagination strategy "pages"`,
+		},
+		{
+			name:       "missing next path",
+			pagination: &httpplugin.Pagination{Strategy: "json_cursor"},
+			expected:   "pagination next_path required",
+		},
+		{
+			name:       "missing limit",
+			pagination: &httpplugin.Pagination{Strategy: "offset"},
+			expected:   "pagination limit must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &httpplugin.HTTP{
+				URLs:       []string{"http://localhost"},
+				Pagination: tt.pagination,
+				Log:        testutil.Logger{},
+			}
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	var logins int
+	token := "first"
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, `{"user": "telegraf"}`, string(body))
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			logins++
+			_, _ = w.Write([]byte(`{"data": {"token": "` + token + `"}}`))
+		case "/endpoint":
+			if r.Header.Get("X-Auth-Token") != "Token "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"items": [{"value": 1}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer fakeServer.Close()
+
+	plugin := &httpplugin.HTTP{
+		URLs: []string{fakeServer.URL + "/endpoint"},
+		Login: &httpplugin.Login{
+			URL:         fakeServer.URL + "/login",
+			Body:        `{"user": "telegraf"}`,
+			Headers:     map[string]string{"Content-Type": "application/json"},
+			TokenPath:   "data.token",
+			TokenHeader: "X-Auth-Token",
+			TokenPrefix: "Token ",
+		},
+		Log: testutil.Logger{},
+	}
+	plugin.SetParserFunc(newJSONItemsParser)
+	require.NoError(t, plugin.Init())
+
+	// The token is reused for subsequent gathers
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, 1, logins)
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+
+	// A rejected token causes a new login
+	token = "second"
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, 2, logins)
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+}
+
+func TestLoginFailure(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			_, _ = w.Write([]byte(`{"error": "invalid credentials"}`))
+		default:
+			_, _ = w.Write([]byte(`{"items": [{"value": 1}]}`))
+		}
+	}))
+	defer fakeServer.Close()
+
+	plugin := &httpplugin.HTTP{
+		URLs: []string{fakeServer.URL + "/endpoint"},
+		Login: &httpplugin.Login{
+			URL:       fakeServer.URL + "/login",
+			TokenPath: "token",
+		},
+		Log: testutil.Logger{},
+	}
+	plugin.SetParserFunc(newJSONItemsParser)
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "Authorization", plugin.Login.TokenHeader)
+
+	var acc testutil.Accumulator
+	require.ErrorContains(t, acc.GatherError(plugin.Gather), `token path "token" not found`)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+// Login describes a request issued before the actual requests to obtain a
+// token which is then passed as a header to all following requests
+type Login struct {
+	URL     string            `toml:"url"`
+	Method  string            `toml:"method"`
+	Body    string            `toml:"body"`
+	Headers map[string]string `toml:"headers"`
+
+	// GJSON path to the token in the response body
+	TokenPath   string          `toml:"token_path"`
+	TokenHeader string          `toml:"token_header"`
+	TokenPrefix string          `toml:"token_prefix"`
+	Renewal     config.Duration `toml:"renewal"`
+
+	sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (l *Login) init() error {
+	if l.URL == "" {
+		return errors.New("login url required")
+	}
+	if l.TokenPath == "" {
+		return errors.New("login token_path required")
+	}
+	if l.Method == "" {
+		l.Method = http.MethodPost
+	}
+	if l.TokenHeader == "" {
+		l.TokenHeader = "Authorization"
+	}
+	return nil
+}
+
+// header returns the header value containing the current token, logging in
+// if no token is available or the token needs to be renewed
+func (l *Login) header(client *http.Client) (string, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.token == "" || (l.Renewal > 0 && time.Now().After(l.expires)) {
+		if err := l.login(client); err != nil {
+			return "", err
+		}
+	}
+	return l.TokenPrefix + l.token, nil
+}
+
+// invalidate drops the current token if it still matches the rejected header
+// value so the next request logs in again
+func (l *Login) invalidate(rejected string) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.TokenPrefix+l.token == rejected {
+		l.token = ""
+	}
+}
+
+func (l *Login) login(client *http.Client) error {
+	var body io.Reader
+	if l.Body != "" {
+		body = strings.NewReader(l.Body)
+	}
+	request, err := http.NewRequest(l.Method, l.URL, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range l.Headers {
+		if strings.ToLower(k) == "host" {
+			request.Host = v
+		} else {
+			request.Header.Add(k, v)
+		}
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("login failed: received status code %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading login response failed: %w", err)
+	}
+
+	token := gjson.GetBytes(b, l.TokenPath).String()
+	if token == "" {
+		return fmt.Errorf("login failed: token path %q not found in response", l.TokenPath)
+	}
+	l.token = token
+	l.expires = time.Now().Add(time.Duration(l.Renewal))
+
+	return nil
+}
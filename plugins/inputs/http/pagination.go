@@ -0,0 +1,205 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/influxdata/telegraf/internal/choice"
+)
+
+const defaultMaxPages = 10
+
+// Pagination describes how to request the following pages of a paged response
+type Pagination struct {
+	// One of "link_header", "json_next_url", "json_cursor" or "offset"
+	Strategy string `toml:"strategy"`
+	MaxPages int    `toml:"max_pages"`
+
+	// GJSON path to the next URL or cursor in the response body
+	NextPath        string `toml:"next_path"`
+	CursorParameter string `toml:"cursor_parameter"`
+
+	OffsetParameter string `toml:"offset_parameter"`
+	LimitParameter  string `toml:"limit_parameter"`
+	Limit           int    `toml:"limit"`
+	// GJSON path to the number of items in the response body
+	CountPath string `toml:"count_path"`
+}
+
+func (p *Pagination) init() error {
+	strategies := []string{"link_header", "json_next_url", "json_cursor", "offset"}
+	if !choice.Contains(p.Strategy, strategies) {
+		return fmt.Errorf("invalid pagination strategy %q", p.Strategy)
+	}
+
+	if p.MaxPages == 0 {
+		p.MaxPages = defaultMaxPages
+	}
+	if p.MaxPages < 0 {
+		return errors.New("pagination max_pages must not be negative")
+	}
+
+	switch p.Strategy {
+	case "json_next_url":
+		if p.NextPath == "" {
+			return errors.New("pagination next_path required")
+		}
+	case "json_cursor":
+		if p.NextPath == "" {
+			return errors.New("pagination next_path required")
+		}
+		if p.CursorParameter == "" {
+			p.CursorParameter = "cursor"
+		}
+	case "offset":
+		if p.OffsetParameter == "" {
+			p.OffsetParameter = "offset"
+		}
+		if p.LimitParameter == "" {
+			p.LimitParameter = "limit"
+		}
+		if p.Limit <= 0 {
+			return errors.New("pagination limit must be positive")
+		}
+	}
+
+	return nil
+}
+
+// first returns the URL of the first page
+func (p *Pagination) first(address string) (string, error) {
+	if p.Strategy != "offset" {
+		return address, nil
+	}
+	return p.offsetURL(address, 0)
+}
+
+// next returns the URL of the page following the current one or an empty
+// string if there are no more pages. The number of metrics parsed from the
+// current page is used to detect the last page for offset pagination.
+func (p *Pagination) next(address, current string, page int, header http.Header, body []byte, count int) (string, error) {
+	var next string
+	switch p.Strategy {
+	case "link_header":
+		link := parseLinkHeader(header.Values("Link"), "next")
+		if link == "" {
+			return "", nil
+		}
+		u, err := resolveReference(current, link)
+		if err != nil {
+			return "", err
+		}
+		next = u
+	case "json_next_url":
+		link := gjson.GetBytes(body, p.NextPath).String()
+		if link == "" {
+			return "", nil
+		}
+		u, err := resolveReference(current, link)
+		if err != nil {
+			return "", err
+		}
+		next = u
+	case "json_cursor":
+		cursor := gjson.GetBytes(body, p.NextPath).String()
+		if cursor == "" {
+			return "", nil
+		}
+		u, err := setQueryParameters(address, map[string]string{p.CursorParameter: cursor})
+		if err != nil {
+			return "", err
+		}
+		next = u
+	case "offset":
+		if p.CountPath != "" {
+			result := gjson.GetBytes(body, p.CountPath)
+			if !result.Exists() {
+				return "", fmt.Errorf("count path %q not found in response", p.CountPath)
+			}
+			count = int(result.Int())
+		}
+		// A partial page is the last one
+		if count < p.Limit {
+			return "", nil
+		}
+		u, err := p.offsetURL(address, page*p.Limit)
+		if err != nil {
+			return "", err
+		}
+		next = u
+	}
+
+	// Protect against servers returning the same page over and over
+	if next == current {
+		return "", nil
+	}
+	return next, nil
+}
+
+func (p *Pagination) offsetURL(address string, offset int) (string, error) {
+	return setQueryParameters(address, map[string]string{
+		p.OffsetParameter: strconv.Itoa(offset),
+		p.LimitParameter:  strconv.Itoa(p.Limit),
+	})
+}
+
+// parseLinkHeader returns the target of the link with the given relation type
+// of RFC 5988 Link header values such as
+// `<https://example.com/items?page=2>; rel="next", <...>; rel="last"`
+func parseLinkHeader(values []string, rel string) string {
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+
+			for _, param := range parts[1:] {
+				k, v, found := strings.Cut(strings.TrimSpace(param), "=")
+				if !found || !strings.EqualFold(strings.TrimSpace(k), "rel") {
+					continue
+				}
+				// The relation might contain multiple space separated types
+				for _, r := range strings.Fields(strings.Trim(v, `"`)) {
+					if strings.EqualFold(r, rel) {
+						return target
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func resolveReference(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page URL %q: %w", ref, err)
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
+func setQueryParameters(address string, params map[string]string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,256 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package http_transaction
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+const defaultResponseBodyMaxSize = 32 * 1024 * 1024
+
+var resultCodes = map[string]int{
+	"success":                       0,
+	"assertion_failed":              1,
+	"body_read_error":               2,
+	"connection_failed":             3,
+	"timeout":                       4,
+	"dns_error":                     5,
+	"response_status_code_mismatch": 6,
+	"extraction_failed":             7,
+	"request_error":                 8,
+}
+
+type HTTPTransaction struct {
+	Name                string            `toml:"name"`
+	ResponseTimeout     config.Duration   `toml:"response_timeout"`
+	ResponseBodyMaxSize config.Size       `toml:"response_body_max_size"`
+	FollowRedirects     bool              `toml:"follow_redirects"`
+	Variables           map[string]string `toml:"variables"`
+	Steps               []*Step           `toml:"step"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	transport http.RoundTripper
+}
+
+// stepResult holds the outcome of a single step
+type stepResult struct {
+	result       string
+	statusCode   int
+	responseTime time.Duration
+	length       int
+}
+
+func (*HTTPTransaction) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTPTransaction) Init() error {
+	if h.Name == "" {
+		return errors.New("name required")
+	}
+	if len(h.Steps) == 0 {
+		return errors.New("at least one step required")
+	}
+	if h.ResponseTimeout <= 0 {
+		h.ResponseTimeout = config.Duration(5 * time.Second)
+	}
+	if h.ResponseBodyMaxSize == 0 {
+		h.ResponseBodyMaxSize = config.Size(defaultResponseBodyMaxSize)
+	}
+
+	names := make(map[string]bool, len(h.Steps))
+	for i, s := range h.Steps {
+		if err := s.init(i); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("duplicate step name %q", s.Name)
+		}
+		names[s.Name] = true
+	}
+
+	tlsCfg, err := h.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	h.transport = &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DisableKeepAlives: true,
+		TLSClientConfig:   tlsCfg,
+	}
+
+	return nil
+}
+
+// Gather runs all steps of the transaction in order and stops at the first
+// failing step
+func (h *HTTPTransaction) Gather(acc telegraf.Accumulator) error {
+	// Use a fresh client per run to not share session cookies between runs
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Transport: h.transport,
+		Jar:       jar,
+		Timeout:   time.Duration(h.ResponseTimeout),
+	}
+	if !h.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	variables := make(map[string]string, len(h.Variables))
+	for k, v := range h.Variables {
+		variables[k] = v
+	}
+
+	start := time.Now()
+	result := "success"
+	var failedStep string
+	var passed int
+	for _, step := range h.Steps {
+		r := h.runStep(client, step, variables)
+
+		tags := map[string]string{
+			"transaction": h.Name,
+			"step":        step.Name,
+			"method":      step.Method,
+			"result":      r.result,
+		}
+		fields := map[string]interface{}{
+			"result_code": resultCodes[r.result],
+		}
+		if r.statusCode > 0 {
+			tags["status_code"] = strconv.Itoa(r.statusCode)
+			fields["http_response_code"] = r.statusCode
+			fields["response_time"] = r.responseTime.Seconds()
+			fields["content_length"] = r.length
+		}
+		acc.AddFields("http_transaction_step", fields, tags)
+
+		if r.result != "success" {
+			result = r.result
+			failedStep = step.Name
+			break
+		}
+		passed++
+	}
+
+	tags := map[string]string{
+		"transaction": h.Name,
+		"result":      result,
+	}
+	fields := map[string]interface{}{
+		"response_time": time.Since(start).Seconds(),
+		"result_code":   resultCodes[result],
+		"steps_total":   len(h.Steps),
+		"steps_passed":  passed,
+	}
+	if failedStep != "" {
+		fields["failed_step"] = failedStep
+	}
+	acc.AddFields("http_transaction", fields, tags)
+
+	return nil
+}
+
+func (h *HTTPTransaction) runStep(client *http.Client, step *Step, variables map[string]string) stepResult {
+	request, err := step.request(variables)
+	if err != nil {
+		h.Log.Errorf("Creating request of step %q failed: %v", step.Name, err)
+		return stepResult{result: "request_error"}
+	}
+
+	// The response time includes reading the body, as the client returns as
+	// soon as the headers are received
+	start := time.Now()
+	resp, err := client.Do(request)
+	if err != nil {
+		h.Log.Debugf("Request of step %q failed: %v", step.Name, err)
+		return stepResult{result: classifyError(err)}
+	}
+	defer resp.Body.Close()
+
+	r := stepResult{statusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(h.ResponseBodyMaxSize)+1))
+	r.responseTime = time.Since(start)
+	r.length = len(body)
+	if err != nil || int64(len(body)) > int64(h.ResponseBodyMaxSize) {
+		h.Log.Debugf("Reading body of step %q failed: %v", step.Name, err)
+		r.result = "body_read_error"
+		return r
+	}
+
+	if !step.statusCodeMatches(resp.StatusCode) {
+		h.Log.Debugf("Step %q received unexpected status code %d", step.Name, resp.StatusCode)
+		r.result = "response_status_code_mismatch"
+		return r
+	}
+
+	for _, a := range step.Assert {
+		v, found := a.selectValue(resp.Header, body)
+		if !found || (a.Value != "" && v != a.Value) {
+			h.Log.Debugf("Assertion on %s of step %q failed, got %q (found: %v)", a, step.Name, v, found)
+			r.result = "assertion_failed"
+			return r
+		}
+	}
+
+	for _, e := range step.Extract {
+		v, found := e.selectValue(resp.Header, body)
+		if !found {
+			h.Log.Debugf("Extracting %q by %s in step %q failed", e.Variable, e, step.Name)
+			r.result = "extraction_failed"
+			return r
+		}
+		variables[e.Variable] = v
+	}
+
+	r.result = "success"
+	return r
+}
+
+// classifyError maps network errors to the corresponding result
+func classifyError(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		var dnsErr *net.DNSError
+		if errors.As(urlErr.Err, &dnsErr) {
+			return "dns_error"
+		}
+	}
+	return "connection_failed"
+}
+
+func init() {
+	inputs.Add("http_transaction", func() telegraf.Input {
+		return &HTTPTransaction{}
+	})
+}
@@ -0,0 +1,411 @@
+package http_transaction
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Header().Set("X-Request-Id", "42")
+		_, err := fmt.Fprint(w, `{"access_token": "secret"}`)
+		require.NoError(t, err)
+	})
+	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if c, err := r.Cookie("session"); err != nil || c.Value != "abc" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, err := fmt.Fprintf(w, `{"status": "ok", "request": %q}`, r.URL.Query().Get("id"))
+		require.NoError(t, err)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestTransaction(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	plugin := &HTTPTransaction{
+		Name: "shop",
+		Steps: []*Step{
+			{
+				Name:   "login",
+				Method: "POST",
+				URL:    ts.URL + "/login",
+				Extract: []*Selector{
+					{Variable: "token", JSONPath: "access_token"},
+					{Variable: "id", Header: "X-Request-Id"},
+				},
+			},
+			{
+				Name:    "orders",
+				URL:     ts.URL + "/orders?id={{.id}}",
+				Headers: map[string]string{"Authorization": "Bearer {{.token}}"},
+				Assert: []*Selector{
+					{JSONPath: "status", Value: "ok"},
+					{Regex: `"request":\s*"(\d+)"`, Value: "42"},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"http_transaction_step",
+			map[string]string{
+				"transaction": "shop",
+				"step":        "login",
+				"method":      "POST",
+				"result":      "success",
+				"status_code": "200",
+			},
+			map[string]interface{}{
+				"result_code":        0,
+				"http_response_code": 200,
+				"response_time":      float64(0),
+				"content_length":     26,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"http_transaction_step",
+			map[string]string{
+				"transaction": "shop",
+				"step":        "orders",
+				"method":      "GET",
+				"result":      "success",
+				"status_code": "200",
+			},
+			map[string]interface{}{
+				"result_code":        0,
+				"http_response_code": 200,
+				"response_time":      float64(0),
+				"content_length":     33,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"http_transaction",
+			map[string]string{
+				"transaction": "shop",
+				"result":      "success",
+			},
+			map[string]interface{}{
+				"response_time": float64(0),
+				"result_code":   0,
+				"steps_total":   2,
+				"steps_passed":  2,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	// Response times vary between runs so zero them for comparison
+	actual := acc.GetTelegrafMetrics()
+	for _, m := range actual {
+		m.AddField("response_time", float64(0))
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+}
+
+func TestResponseTimeIncludesBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Send the headers right away and the body later
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		_, err := fmt.Fprint(w, "done")
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTPTransaction{
+		Name:  "slow",
+		Steps: []*Step{{Name: "download", URL: ts.URL}},
+		Log:   testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	step, found := acc.Get("http_transaction_step")
+	require.True(t, found)
+	require.GreaterOrEqual(t, step.Fields["response_time"], 0.1)
+	require.Equal(t, 4, step.Fields["content_length"])
+}
+
+func TestTransactionFailures(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		steps    []*Step
+		result   string
+		failed   string
+		passed   int
+		executed int
+	}{
+		{
+			name: "status code mismatch",
+			steps: []*Step{
+				{Name: "orders", URL: ts.URL + "/orders"},
+			},
+			result:   "response_status_code_mismatch",
+			failed:   "orders",
+			executed: 1,
+		},
+		{
+			name: "expected status code",
+			steps: []*Step{
+				{Name: "orders", URL: ts.URL + "/orders", StatusCodes: []int{401}},
+			},
+			result:   "success",
+			passed:   1,
+			executed: 1,
+		},
+		{
+			name: "extraction failed",
+			steps: []*Step{
+				{
+					Name:    "login",
+					Method:  "POST",
+					URL:     ts.URL + "/login",
+					Extract: []*Selector{{Variable: "token", JSONPath: "token"}},
+				},
+				{Name: "orders", URL: ts.URL + "/orders"},
+			},
+			result:   "extraction_failed",
+			failed:   "login",
+			executed: 1,
+		},
+		{
+			name: "assertion failed",
+			steps: []*Step{
+				{
+					Name:   "login",
+					Method: "POST",
+					URL:    ts.URL + "/login",
+					Assert: []*Selector{{Header: "X-Request-Id", Value: "43"}},
+				},
+			},
+			result:   "assertion_failed",
+			failed:   "login",
+			executed: 1,
+		},
+		{
+			name: "missing variable",
+			steps: []*Step{
+				{Name: "login", Method: "POST", URL: ts.URL + "/login"},
+				{
+					Name:    "orders",
+					URL:     ts.URL + "/orders",
+					Headers: map[string]string{"Authorization": "Bearer {{.token}}"},
+				},
+			},
+			result:   "request_error",
+			failed:   "orders",
+			passed:   1,
+			executed: 2,
+		},
+		{
+			name: "missing cookie",
+			steps: []*Step{
+				{
+					Name:    "orders",
+					URL:     ts.URL + "/orders",
+					Headers: map[string]string{"Authorization": "Bearer {{.token}}"},
+				},
+			},
+			result:   "response_status_code_mismatch",
+			failed:   "orders",
+			executed: 1,
+		},
+		{
+			name: "connection failed",
+			steps: []*Step{
+				{Name: "login", URL: "http://127.0.0.1:1/login"},
+			},
+			result:   "connection_failed",
+			failed:   "login",
+			executed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &HTTPTransaction{
+				Name:      "shop",
+				Variables: map[string]string{"token": "secret"},
+				Steps:     tt.steps,
+				Log:       testutil.Logger{},
+			}
+			if tt.name == "missing variable" {
+				plugin.Variables = nil
+			}
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Gather(&acc))
+
+			metrics := acc.GetTelegrafMetrics()
+			require.Len(t, metrics, tt.executed+1)
+
+			summary := metrics[len(metrics)-1]
+			require.Equal(t, "http_transaction", summary.Name())
+			require.Equal(t, tt.result, summary.Tags()["result"])
+			require.Equal(t, int64(resultCodes[tt.result]), summary.Fields()["result_code"])
+			require.Equal(t, int64(tt.passed), summary.Fields()["steps_passed"])
+			if tt.failed == "" {
+				require.NotContains(t, summary.Fields(), "failed_step")
+			} else {
+				require.Equal(t, tt.failed, summary.Fields()["failed_step"])
+			}
+
+			last := metrics[len(metrics)-2]
+			require.Equal(t, tt.result, last.Tags()["result"])
+		})
+	}
+}
+
+func TestSessionNotShared(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	plugin := &HTTPTransaction{
+		Name:      "shop",
+		Variables: map[string]string{"token": "secret"},
+		Steps: []*Step{
+			{Name: "login", Method: "POST", URL: ts.URL + "/login", StatusCodes: []int{200, 405}},
+			{
+				Name:    "orders",
+				URL:     ts.URL + "/orders",
+				Headers: map[string]string{"Authorization": "Bearer {{.token}}"},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, "success", acc.GetTelegrafMetrics()[2].Tags()["result"])
+
+	// Without logging in the session cookie of the previous run must not be
+	// reused
+	plugin.Steps[0].Method = "GET"
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	metrics := acc.GetTelegrafMetrics()
+	require.Equal(t, "response_status_code_mismatch", metrics[2].Tags()["result"])
+	require.Equal(t, "orders", metrics[2].Fields()["failed_step"])
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *HTTPTransaction
+		expected string
+	}{
+		{
+			name:     "no name",
+			plugin:   &HTTPTransaction{Steps: []*Step{{URL: "http://localhost"}}},
+			expected: "name required",
+		},
+		{
+			name:     "no steps",
+			plugin:   &HTTPTransaction{Name: "test"},
+			expected: "at least one step required",
+		},
+		{
+			name:     "no url",
+			plugin:   &HTTPTransaction{Name: "test", Steps: []*Step{{Name: "a"}}},
+			expected: "step 1: url required",
+		},
+		{
+			name: "duplicate step",
+			plugin: &HTTPTransaction{
+				Name:  "test",
+				Steps: []*Step{{URL: "http://localhost"}, {Name: "step_1", URL: "http://localhost"}},
+			},
+			expected: `duplicate step name "step_1"`,
+		},
+		{
+			name: "invalid template",
+			plugin: &HTTPTransaction{
+				Name:  "test",
+				Steps: []*Step{{URL: "http://localhost/{{.id"}},
+			},
+			expected: "parsing url template failed",
+		},
+		{
+			name: "multiple selectors",
+			plugin: &HTTPTransaction{
+				Name: "test",
+				Steps: []*Step{
+					{
+						URL:    "http://localhost",
+						Assert: []*Selector{{JSONPath: "a", Header: "b"}},
+					},
+				},
+			},
+			expected: "exactly one of 'json_path', 'regex' or 'header' required",
+		},
+		{
+			name: "extract without variable",
+			plugin: &HTTPTransaction{
+				Name: "test",
+				Steps: []*Step{
+					{
+						URL:     "http://localhost",
+						Extract: []*Selector{{JSONPath: "a"}},
+					},
+				},
+			},
+			expected: "extract variable required",
+		},
+		{
+			name: "invalid regex",
+			plugin: &HTTPTransaction{
+				Name: "test",
+				Steps: []*Step{
+					{
+						URL:     "http://localhost",
+						Extract: []*Selector{{Variable: "a", Regex: "("}},
+					},
+				},
+			},
+			expected: "compiling regex failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package http_transaction
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/tidwall/gjson"
+)
+
+// Step is a single request of a transaction
+type Step struct {
+	Name        string            `toml:"name"`
+	Method      string            `toml:"method"`
+	URL         string            `toml:"url"`
+	Headers     map[string]string `toml:"headers"`
+	Body        string            `toml:"body"`
+	StatusCodes []int             `toml:"status_codes"`
+	Extract     []*Selector       `toml:"extract"`
+	Assert      []*Selector       `toml:"assert"`
+
+	url     *template.Template
+	body    *template.Template
+	headers map[string]*template.Template
+}
+
+// Selector selects a value from a response by either a GJSON path into the
+// body, a regular expression matching the body or a header name. For
+// extractions the value is stored in the given variable, for assertions the
+// value is compared to the expected value if any.
+type Selector struct {
+	JSONPath string `toml:"json_path"`
+	Regex    string `toml:"regex"`
+	Header   string `toml:"header"`
+
+	Variable string `toml:"variable"`
+	Value    string `toml:"value"`
+
+	regex *regexp.Regexp
+}
+
+func (s *Step) init(index int) error {
+	if s.Name == "" {
+		s.Name = fmt.Sprintf("step_%d", index+1)
+	}
+	if s.URL == "" {
+		return errors.New("url required")
+	}
+	if s.Method == "" {
+		s.Method = http.MethodGet
+	}
+
+	var err error
+	if s.url, err = newTemplate("url", s.URL); err != nil {
+		return err
+	}
+	if s.body, err = newTemplate("body", s.Body); err != nil {
+		return err
+	}
+	s.headers = make(map[string]*template.Template, len(s.Headers))
+	for k, v := range s.Headers {
+		if s.headers[k], err = newTemplate("header "+k, v); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range s.Extract {
+		if e.Variable == "" {
+			return errors.New("extract variable required")
+		}
+		if err := e.init(); err != nil {
+			return fmt.Errorf("extract %q: %w", e.Variable, err)
+		}
+	}
+	for i, a := range s.Assert {
+		if err := a.init(); err != nil {
+			return fmt.Errorf("assert %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// request creates the HTTP request of the step with all variables substituted
+func (s *Step) request(variables map[string]string) (*http.Request, error) {
+	u, err := execute(s.url, variables)
+	if err != nil {
+		return nil, err
+	}
+	body, err := execute(s.body, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	request, err := http.NewRequest(s.Method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, t := range s.headers {
+		v, err := execute(t, variables)
+		if err != nil {
+			return nil, err
+		}
+		if strings.ToLower(k) == "host" {
+			request.Host = v
+		} else {
+			request.Header.Add(k, v)
+		}
+	}
+
+	return request, nil
+}
+
+func (s *Step) statusCodeMatches(code int) bool {
+	if len(s.StatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, c := range s.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Selector) init() error {
+	n := 0
+	for _, v := range []string{s.JSONPath, s.Regex, s.Header} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("exactly one of 'json_path', 'regex' or 'header' required")
+	}
+
+	if s.Regex != "" {
+		re, err := regexp.Compile(s.Regex)
+		if err != nil {
+			return fmt.Errorf("compiling regex failed: %w", err)
+		}
+		s.regex = re
+	}
+	return nil
+}
+
+// selectValue returns the selected value and whether it was found. For
+// regular expressions the value is the first capture group, or the whole
+// match if the expression does not contain a group.
+func (s *Selector) selectValue(header http.Header, body []byte) (string, bool) {
+	switch {
+	case s.JSONPath != "":
+		result := gjson.GetBytes(body, s.JSONPath)
+		return result.String(), result.Exists()
+	case s.regex != nil:
+		match := s.regex.FindSubmatch(body)
+		if match == nil {
+			return "", false
+		}
+		if len(match) > 1 {
+			return string(match[1]), true
+		}
+		return string(match[0]), true
+	default:
+		values := header.Values(s.Header)
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	}
+}
+
+func (s *Selector) String() string {
+	switch {
+	case s.JSONPath != "":
+		return fmt.Sprintf("json_path %q", s.JSONPath)
+	case s.Regex != "":
+		return fmt.Sprintf("regex %q", s.Regex)
+	}
+	return fmt.Sprintf("header %q", s.Header)
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s template failed: %w", name, err)
+	}
+	return t, nil
+}
+
+func execute(t *template.Template, variables map[string]string) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, variables); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	mqttv5 "github.com/eclipse/paho.golang/paho"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 
 	"github.com/influxdata/telegraf"
@@ -63,9 +64,11 @@ type TopicParsingConfig struct {
 }
 type MQTTConsumer struct {
 	Servers                []string             `toml:"servers"`
+	Protocol               string               `toml:"protocol"`
 	Topics                 []string             `toml:"topics"`
 	TopicTag               *string              `toml:"topic_tag"`
 	TopicParsing           []TopicParsingConfig `toml:"topic_parsing"`
+	UserPropertyTags       []string             `toml:"user_property_tags"`
 	Username               string               `toml:"username"`
 	Password               string               `toml:"password"`
 	QoS                    int                  `toml:"qos"`
@@ -110,6 +113,15 @@ func (m *MQTTConsumer) Init() error {
 	if m.QoS > 2 || m.QoS < 0 {
 		return fmt.Errorf("qos value must be 0, 1, or 2: %d", m.QoS)
 	}
+	switch m.Protocol {
+	case "", "3.1.1":
+		if len(m.UserPropertyTags) > 0 {
+			return errors.New("user_property_tags requires protocol version 5")
+		}
+	case "5":
+	default:
+		return fmt.Errorf("unsupported protocol %q: must be \"3.1.1\" or \"5\"", m.Protocol)
+	}
 	if time.Duration(m.ConnectionTimeout) < 1*time.Second {
 		return fmt.Errorf("connection_timeout must be greater than 1s: %s", time.Duration(m.ConnectionTimeout))
 	}
@@ -125,6 +137,11 @@ func (m *MQTTConsumer) Init() error {
 	m.messages = map[telegraf.TrackingID]bool{}
 
 	for i, p := range m.TopicParsing {
+		// Messages of shared subscriptions are published on the topic without
+		// the share prefix, so match against the plain topic
+		p.Topic = stripSharePrefix(p.Topic)
+		m.TopicParsing[i].Topic = p.Topic
+
 		splitMeasurement := strings.Split(p.Measurement, "/")
 		for j := range splitMeasurement {
 			if splitMeasurement[j] != "_" && splitMeasurement[j] != "" {
@@ -158,7 +175,11 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 	m.acc = acc.WithTracking(m.MaxUndeliveredMessages)
 	m.sem = make(semaphore, m.MaxUndeliveredMessages)
 	m.ctx, m.cancel = context.WithCancel(context.Background())
-	m.client = m.clientFactory(m.opts)
+	if m.Protocol == "5" {
+		m.client = newMQTTv5Client(m.opts)
+	} else {
+		m.client = m.clientFactory(m.opts)
+	}
 	// AddRoute sets up the function for handling messages.  These need to be
 	// added in case we find a persistent session containing subscriptions so we
 	// know where to dispatch persisted and new messages to.  In the alternate
@@ -254,10 +275,20 @@ func (m *MQTTConsumer) onMessage(acc telegraf.TrackingAccumulator, msg mqtt.Mess
 		return err
 	}
 
+	var properties mqttv5.UserProperties
+	if pm, ok := msg.(userPropertiesMessage); ok {
+		properties = pm.UserProperties()
+	}
+
 	for _, metric := range metrics {
 		if m.topicTagParse != "" {
 			metric.AddTag(m.topicTagParse, msg.Topic())
 		}
+		for _, key := range m.UserPropertyTags {
+			if value := properties.Get(key); value != "" {
+				metric.AddTag(key, value)
+			}
+		}
 		for _, p := range m.TopicParsing {
 			values := strings.Split(msg.Topic(), "/")
 			if !compareTopics(p.SplitTopic, values) {
@@ -349,6 +380,18 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 	return opts, nil
 }
 
+// stripSharePrefix removes the `$share/<group>/` prefix of shared subscriptions
+func stripSharePrefix(topic string) string {
+	if !strings.HasPrefix(topic, "$share/") {
+		return topic
+	}
+	parts := strings.SplitN(topic, "/", 3)
+	if len(parts) < 3 {
+		return topic
+	}
+	return parts[2]
+}
+
 // parseFields gets multiple fields from the topic based on the user configuration (TopicParsing.Fields)
 func parseMetric(keys []string, values []string, types map[string]string, isTag bool, metric telegraf.Metric) error {
 	for i, k := range keys {
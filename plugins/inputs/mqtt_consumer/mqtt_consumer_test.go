@@ -2,9 +2,11 @@ package mqtt_consumer
 
 import (
 	"fmt"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/eclipse/paho.golang/packets"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers"
@@ -592,3 +594,143 @@ func TestSubscribeNotCalledIfSession(t *testing.T) {
 
 	require.Equal(t, client.subscribeCallCount, 0)
 }
+
+func TestStripSharePrefix(t *testing.T) {
+	require.Equal(t, "telegraf/+/cpu", stripSharePrefix("$share/group/telegraf/+/cpu"))
+	require.Equal(t, "telegraf/+/cpu", stripSharePrefix("telegraf/+/cpu"))
+	require.Equal(t, "$share/group", stripSharePrefix("$share/group"))
+}
+
+func TestInitProtocol(t *testing.T) {
+	plugin := New(nil)
+	plugin.Log = testutil.Logger{}
+	plugin.Protocol = "4"
+	require.ErrorContains(t, plugin.Init(), "unsupported protocol")
+
+	plugin = New(nil)
+	plugin.Log = testutil.Logger{}
+	plugin.UserPropertyTags = []string{"site"}
+	require.ErrorContains(t, plugin.Init(), "requires protocol version 5")
+
+	plugin = New(nil)
+	plugin.Log = testutil.Logger{}
+	plugin.Protocol = "5"
+	plugin.UserPropertyTags = []string{"site"}
+	require.NoError(t, plugin.Init())
+}
+
+// startBroker runs a minimal MQTT 5 broker accepting a single client. All
+// subscriptions are acknowledged and the given message is published after the
+// first subscription.
+func startBroker(t *testing.T, msg *packets.Publish) (string, chan map[string]packets.SubOptions) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	subscriptions := make(chan map[string]packets.SubOptions, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		available := byte(1)
+		for {
+			p, err := packets.ReadPacket(conn)
+			if err != nil {
+				return
+			}
+			switch content := p.Content.(type) {
+			case *packets.Connect:
+				connack := &packets.Connack{
+					Properties: &packets.Properties{
+						WildcardSubAvailable: &available,
+						SharedSubAvailable:   &available,
+					},
+				}
+				if _, err := connack.WriteTo(conn); err != nil {
+					return
+				}
+			case *packets.Subscribe:
+				suback := packets.NewControlPacket(packets.SUBACK)
+				suback.Content.(*packets.Suback).PacketID = content.PacketID
+				suback.Content.(*packets.Suback).Reasons = make([]byte, len(content.Subscriptions))
+				if _, err := suback.WriteTo(conn); err != nil {
+					return
+				}
+				subscriptions <- content.Subscriptions
+
+				publish := packets.NewControlPacket(packets.PUBLISH)
+				publish.Content = msg
+				if _, err := publish.WriteTo(conn); err != nil {
+					return
+				}
+			case *packets.Disconnect:
+				return
+			}
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String(), subscriptions
+}
+
+func TestMQTTv5SharedSubscription(t *testing.T) {
+	server, subscriptions := startBroker(t, &packets.Publish{
+		Topic:   "telegraf/host01/cpu",
+		Payload: []byte("cpu time_idle=42i"),
+		Properties: &packets.Properties{
+			User: []packets.User{
+				{Key: "site", Value: "berlin"},
+				{Key: "ignored", Value: "value"},
+			},
+		},
+	})
+
+	plugin := New(nil)
+	plugin.Log = testutil.Logger{}
+	plugin.Servers = []string{server}
+	plugin.Protocol = "5"
+	plugin.Topics = []string{"$share/telegraf/telegraf/+/cpu"}
+	plugin.UserPropertyTags = []string{"site"}
+	plugin.TopicParsing = []TopicParsingConfig{
+		{
+			Topic: "$share/telegraf/telegraf/+/cpu",
+			Tags:  "_/host/_",
+		},
+	}
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	select {
+	case subscribed := <-subscriptions:
+		require.Contains(t, subscribed, "$share/telegraf/telegraf/+/cpu")
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for subscription")
+	}
+
+	acc.Wait(1)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{
+				"topic": "telegraf/host01/cpu",
+				"host":  "host01",
+				"site":  "berlin",
+			},
+			map[string]interface{}{
+				"time_idle": 42,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
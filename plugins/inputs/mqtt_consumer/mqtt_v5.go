@@ -0,0 +1,247 @@
+package mqtt_consumer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	mqttv5 "github.com/eclipse/paho.golang/paho"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// userPropertiesMessage is implemented by messages received via MQTT 5 and
+// provides access to the user properties of the message
+type userPropertiesMessage interface {
+	UserProperties() mqttv5.UserProperties
+}
+
+// mqttv5Client adapts the MQTT 5 client to the Client interface, so the
+// consumer can handle both protocol versions in the same way
+type mqttv5Client struct {
+	opts    *mqtt.ClientOptions
+	client  *mqttv5.Client
+	handler mqtt.MessageHandler
+
+	sync.Mutex
+}
+
+func newMQTTv5Client(opts *mqtt.ClientOptions) Client {
+	return &mqttv5Client{opts: opts}
+}
+
+func (c *mqttv5Client) Connect() mqtt.Token {
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.ConnectTimeout)
+	defer cancel()
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return &token{err: err}
+	}
+
+	client := mqttv5.NewClient(mqttv5.ClientConfig{
+		ClientID: c.opts.ClientID,
+		Conn:     conn,
+		Router:   mqttv5.NewSingleHandlerRouter(c.route),
+		OnClientError: func(err error) {
+			c.connectionLost(err)
+		},
+		OnServerDisconnect: func(d *mqttv5.Disconnect) {
+			c.connectionLost(fmt.Errorf("disconnected by server with reason code %d", d.ReasonCode))
+		},
+	})
+
+	cp := &mqttv5.Connect{
+		ClientID:     c.opts.ClientID,
+		KeepAlive:    uint16(c.opts.KeepAlive),
+		CleanStart:   c.opts.CleanSession,
+		Username:     c.opts.Username,
+		UsernameFlag: c.opts.Username != "",
+		Password:     []byte(c.opts.Password),
+		PasswordFlag: c.opts.Password != "",
+	}
+	// Sessions end with the network connection unless an expiry interval is
+	// set, so keep persistent sessions around forever.
+	if !c.opts.CleanSession {
+		expiry := uint32(math.MaxUint32)
+		cp.Properties = &mqttv5.ConnectProperties{SessionExpiryInterval: &expiry}
+	}
+
+	ca, err := client.Connect(ctx, cp)
+	if err != nil {
+		return &token{err: err}
+	}
+
+	c.Lock()
+	c.client = client
+	c.Unlock()
+
+	return &token{sessionPresent: ca.SessionPresent}
+}
+
+// dial connects to the first reachable server
+func (c *mqttv5Client) dial(ctx context.Context) (net.Conn, error) {
+	if len(c.opts.Servers) == 0 {
+		return nil, errors.New("no servers defined")
+	}
+
+	var lastErr error
+	for _, server := range c.opts.Servers {
+		var conn net.Conn
+		var err error
+		switch server.Scheme {
+		case "tcp", "mqtt":
+			var dialer net.Dialer
+			conn, err = dialer.DialContext(ctx, "tcp", server.Host)
+		case "ssl", "tls", "tcps", "mqtts":
+			dialer := tls.Dialer{Config: c.opts.TLSConfig}
+			conn, err = dialer.DialContext(ctx, "tcp", server.Host)
+		default:
+			err = fmt.Errorf("scheme %q not supported with protocol version 5", server.Scheme)
+		}
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = fmt.Errorf("connecting to %q failed: %w", server.String(), err)
+	}
+	return nil, lastErr
+}
+
+func (c *mqttv5Client) SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.Lock()
+	c.handler = callback
+	client := c.client
+	c.Unlock()
+
+	if client == nil {
+		return &token{err: errors.New("not connected")}
+	}
+
+	subscriptions := make(map[string]mqttv5.SubscribeOptions, len(filters))
+	for topic, qos := range filters {
+		subscriptions[topic] = mqttv5.SubscribeOptions{QoS: qos}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.ConnectTimeout)
+	defer cancel()
+	if _, err := client.Subscribe(ctx, &mqttv5.Subscribe{Subscriptions: subscriptions}); err != nil {
+		return &token{err: err}
+	}
+	return &token{}
+}
+
+func (c *mqttv5Client) AddRoute(_ string, callback mqtt.MessageHandler) {
+	// All subscriptions share the same handler, so there is no need to
+	// distinguish between the topics here.
+	c.Lock()
+	c.handler = callback
+	c.Unlock()
+}
+
+func (c *mqttv5Client) Disconnect(_ uint) {
+	c.Lock()
+	client := c.client
+	c.client = nil
+	c.Unlock()
+
+	if client != nil {
+		_ = client.Disconnect(&mqttv5.Disconnect{ReasonCode: 0})
+	}
+}
+
+func (c *mqttv5Client) route(p *mqttv5.Publish) {
+	c.Lock()
+	handler := c.handler
+	c.Unlock()
+
+	if handler != nil {
+		handler(nil, &message{publish: p})
+	}
+}
+
+func (c *mqttv5Client) connectionLost(err error) {
+	c.Lock()
+	client := c.client
+	c.client = nil
+	c.Unlock()
+
+	// Errors after a deliberate disconnect are not a lost connection
+	if client == nil {
+		return
+	}
+	if c.opts.OnConnectionLost != nil {
+		c.opts.OnConnectionLost(nil, err)
+	}
+}
+
+// token is a completed operation of the MQTT 5 client
+type token struct {
+	err            error
+	sessionPresent bool
+}
+
+func (t *token) Wait() bool {
+	return true
+}
+
+func (t *token) WaitTimeout(time.Duration) bool {
+	return true
+}
+
+func (t *token) Done() <-chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+func (t *token) Error() error {
+	return t.err
+}
+
+func (t *token) SessionPresent() bool {
+	return t.sessionPresent
+}
+
+// message wraps a MQTT 5 publish packet to satisfy the mqtt.Message interface
+type message struct {
+	publish *mqttv5.Publish
+}
+
+func (m *message) Duplicate() bool {
+	return false
+}
+
+func (m *message) Qos() byte {
+	return m.publish.QoS
+}
+
+func (m *message) Retained() bool {
+	return m.publish.Retain
+}
+
+func (m *message) Topic() string {
+	return m.publish.Topic
+}
+
+func (m *message) MessageID() uint16 {
+	return m.publish.PacketID
+}
+
+func (m *message) Payload() []byte {
+	return m.publish.Payload
+}
+
+func (m *message) Ack() {
+	// Messages are acknowledged by the client automatically
+}
+
+func (m *message) UserProperties() mqttv5.UserProperties {
+	if m.publish.Properties == nil {
+		return nil
+	}
+	return m.publish.Properties.User
+}
@@ -0,0 +1,378 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Set identifiers of NetFlow v9 and IPFIX
+const (
+	setV9Template        = 0
+	setV9OptionsTemplate = 1
+	setTemplate          = 2
+	setOptionsTemplate   = 3
+	setMinData           = 256
+)
+
+// variableLength denotes an IPFIX information element of variable length
+const variableLength = 0xffff
+
+// penKey identifies an enterprise-specific information element
+type penKey struct {
+	pen uint32
+	id  uint16
+}
+
+type templateField struct {
+	id     uint16
+	pen    uint32
+	length uint16
+}
+
+type template struct {
+	fields []templateField
+	// Data records of options templates describe the exporter itself
+	// rather than flows and are skipped.
+	options bool
+}
+
+// templateKey identifies a template of an exporter. Template IDs are only
+// unique per exporter and observation domain (or source ID for NetFlow v9).
+type templateKey struct {
+	exporter string
+	domain   uint32
+	id       uint16
+}
+
+type decoder struct {
+	Log telegraf.Logger
+
+	penMappings map[penKey]fieldMapping
+	templates   map[templateKey]*template
+}
+
+func (d *decoder) init(penFiles []string) error {
+	d.penMappings = make(map[penKey]fieldMapping)
+	for _, fn := range penFiles {
+		if err := loadPENMappings(fn, d.penMappings); err != nil {
+			return fmt.Errorf("loading enterprise field definitions failed: %w", err)
+		}
+	}
+	d.templates = make(map[templateKey]*template)
+	return nil
+}
+
+func (d *decoder) decode(src net.IP, buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < 2 {
+		return nil, errors.New("packet too short")
+	}
+
+	version := binary.BigEndian.Uint16(buf)
+	switch version {
+	case 5:
+		return d.decodeV5(src, buf)
+	case 9:
+		return d.decodeV9(src, buf)
+	case 10:
+		return d.decodeIPFIX(src, buf)
+	}
+	return nil, fmt.Errorf("unsupported version %d", version)
+}
+
+func (d *decoder) decodeV5(src net.IP, buf []byte) ([]telegraf.Metric, error) {
+	const headerLength = 24
+	const recordLength = 48
+
+	if len(buf) < headerLength {
+		return nil, errors.New("packet too short for header")
+	}
+	count := int(binary.BigEndian.Uint16(buf[2:4]))
+	secs := binary.BigEndian.Uint32(buf[8:12])
+	nsecs := binary.BigEndian.Uint32(buf[12:16])
+	engineType := uint64(buf[20])
+	engineID := uint64(buf[21])
+	samplingInterval := uint64(binary.BigEndian.Uint16(buf[22:24]) & 0x3fff)
+
+	if len(buf) < headerLength+count*recordLength {
+		return nil, fmt.Errorf("packet too short for %d records", count)
+	}
+
+	tags := map[string]string{
+		"source":  src.String(),
+		"version": "NetFlowV5",
+	}
+	ts := time.Unix(int64(secs), int64(nsecs))
+
+	metrics := make([]telegraf.Metric, 0, count)
+	for i := 0; i < count; i++ {
+		r := buf[headerLength+i*recordLength : headerLength+(i+1)*recordLength]
+		fields := map[string]interface{}{
+			"src":               decodeIP(r[0:4]),
+			"dst":               decodeIP(r[4:8]),
+			"next_hop":          decodeIP(r[8:12]),
+			"in_snmp":           decodeUint(r[12:14]),
+			"out_snmp":          decodeUint(r[14:16]),
+			"in_packets":        decodeUint(r[16:20]),
+			"in_bytes":          decodeUint(r[20:24]),
+			"first_switched":    decodeUint(r[24:28]),
+			"last_switched":     decodeUint(r[28:32]),
+			"src_port":          decodeUint(r[32:34]),
+			"dst_port":          decodeUint(r[34:36]),
+			"tcp_flags":         decodeUint(r[37:38]),
+			"protocol":          decodeProtocol(r[38:39]),
+			"src_tos":           decodeUint(r[39:40]),
+			"bgp_src_as":        decodeUint(r[40:42]),
+			"bgp_dst_as":        decodeUint(r[42:44]),
+			"src_mask":          decodeUint(r[44:45]),
+			"dst_mask":          decodeUint(r[45:46]),
+			"engine_type":       engineType,
+			"engine_id":         engineID,
+			"sampling_interval": samplingInterval,
+		}
+		metrics = append(metrics, metric.New("netflow", tags, fields, ts))
+	}
+
+	return metrics, nil
+}
+
+func (d *decoder) decodeV9(src net.IP, buf []byte) ([]telegraf.Metric, error) {
+	const headerLength = 20
+
+	if len(buf) < headerLength {
+		return nil, errors.New("packet too short for header")
+	}
+	secs := binary.BigEndian.Uint32(buf[8:12])
+	sourceID := binary.BigEndian.Uint32(buf[16:20])
+
+	tags := map[string]string{
+		"source":  src.String(),
+		"version": "NetFlowV9",
+	}
+	return d.decodeSets(buf[headerLength:], src.String(), sourceID, tags, time.Unix(int64(secs), 0), false)
+}
+
+func (d *decoder) decodeIPFIX(src net.IP, buf []byte) ([]telegraf.Metric, error) {
+	const headerLength = 16
+
+	if len(buf) < headerLength {
+		return nil, errors.New("packet too short for header")
+	}
+	length := int(binary.BigEndian.Uint16(buf[2:4]))
+	if length < headerLength || length > len(buf) {
+		return nil, fmt.Errorf("invalid message length %d", length)
+	}
+	secs := binary.BigEndian.Uint32(buf[4:8])
+	domain := binary.BigEndian.Uint32(buf[12:16])
+
+	tags := map[string]string{
+		"source":  src.String(),
+		"version": "IPFIX",
+	}
+	return d.decodeSets(buf[headerLength:length], src.String(), domain, tags, time.Unix(int64(secs), 0), true)
+}
+
+// decodeSets walks the (flow-)sets of NetFlow v9 and IPFIX messages which
+// share the same layout of a two-byte ID followed by a two-byte length.
+func (d *decoder) decodeSets(buf []byte, exporter string, domain uint32, tags map[string]string, ts time.Time, ipfix bool) ([]telegraf.Metric, error) {
+	var metrics []telegraf.Metric
+	for len(buf) >= 4 {
+		id := binary.BigEndian.Uint16(buf[0:2])
+		length := int(binary.BigEndian.Uint16(buf[2:4]))
+		if length < 4 || length > len(buf) {
+			return metrics, fmt.Errorf("invalid length %d of set %d", length, id)
+		}
+		body := buf[4:length]
+		buf = buf[length:]
+
+		var err error
+		switch {
+		case !ipfix && id == setV9Template:
+			err = d.decodeV9Templates(body, exporter, domain)
+		case !ipfix && id == setV9OptionsTemplate:
+			err = d.decodeV9OptionsTemplates(body, exporter, domain)
+		case ipfix && (id == setTemplate || id == setOptionsTemplate):
+			err = d.decodeIPFIXTemplates(body, exporter, domain, id == setOptionsTemplate)
+		case id >= setMinData:
+			var m []telegraf.Metric
+			m, err = d.decodeData(body, templateKey{exporter: exporter, domain: domain, id: id}, tags, ts)
+			metrics = append(metrics, m...)
+		default:
+			d.Log.Debugf("Ignoring set with reserved ID %d from %s", id, exporter)
+		}
+		if err != nil {
+			return metrics, err
+		}
+	}
+	return metrics, nil
+}
+
+func (d *decoder) decodeV9Templates(buf []byte, exporter string, domain uint32) error {
+	for len(buf) >= 4 {
+		id := binary.BigEndian.Uint16(buf[0:2])
+		count := int(binary.BigEndian.Uint16(buf[2:4]))
+		buf = buf[4:]
+		if len(buf) < 4*count {
+			return fmt.Errorf("template %d too short for %d fields", id, count)
+		}
+
+		t := &template{fields: make([]templateField, 0, count)}
+		for i := 0; i < count; i++ {
+			t.fields = append(t.fields, templateField{
+				id:     binary.BigEndian.Uint16(buf[0:2]),
+				length: binary.BigEndian.Uint16(buf[2:4]),
+			})
+			buf = buf[4:]
+		}
+		d.templates[templateKey{exporter: exporter, domain: domain, id: id}] = t
+	}
+	return nil
+}
+
+func (d *decoder) decodeV9OptionsTemplates(buf []byte, exporter string, domain uint32) error {
+	for len(buf) >= 6 {
+		id := binary.BigEndian.Uint16(buf[0:2])
+		scopeLength := int(binary.BigEndian.Uint16(buf[2:4]))
+		optionLength := int(binary.BigEndian.Uint16(buf[4:6]))
+		buf = buf[6:]
+		if (scopeLength+optionLength)%4 != 0 || len(buf) < scopeLength+optionLength {
+			return fmt.Errorf("invalid options template %d", id)
+		}
+
+		t := &template{options: true}
+		for i := 0; i < scopeLength+optionLength; i += 4 {
+			t.fields = append(t.fields, templateField{
+				id:     binary.BigEndian.Uint16(buf[i : i+2]),
+				length: binary.BigEndian.Uint16(buf[i+2 : i+4]),
+			})
+		}
+		buf = buf[scopeLength+optionLength:]
+		d.templates[templateKey{exporter: exporter, domain: domain, id: id}] = t
+
+		// The remainder might be padding to a 32-bit boundary
+		if len(buf) < 6 {
+			break
+		}
+	}
+	return nil
+}
+
+func (d *decoder) decodeIPFIXTemplates(buf []byte, exporter string, domain uint32, options bool) error {
+	for len(buf) >= 4 {
+		id := binary.BigEndian.Uint16(buf[0:2])
+		count := int(binary.BigEndian.Uint16(buf[2:4]))
+		buf = buf[4:]
+
+		key := templateKey{exporter: exporter, domain: domain, id: id}
+		if count == 0 {
+			// Template withdrawal
+			delete(d.templates, key)
+			continue
+		}
+		if options {
+			// Skip the scope field count
+			if len(buf) < 2 {
+				return fmt.Errorf("options template %d too short", id)
+			}
+			buf = buf[2:]
+		}
+
+		t := &template{fields: make([]templateField, 0, count), options: options}
+		for i := 0; i < count; i++ {
+			if len(buf) < 4 {
+				return fmt.Errorf("template %d too short for %d fields", id, count)
+			}
+			f := templateField{
+				id:     binary.BigEndian.Uint16(buf[0:2]),
+				length: binary.BigEndian.Uint16(buf[2:4]),
+			}
+			buf = buf[4:]
+			// The enterprise bit denotes a following enterprise number
+			if f.id&0x8000 != 0 {
+				if len(buf) < 4 {
+					return fmt.Errorf("template %d too short for enterprise number", id)
+				}
+				f.id &= 0x7fff
+				f.pen = binary.BigEndian.Uint32(buf[0:4])
+				buf = buf[4:]
+			}
+			t.fields = append(t.fields, f)
+		}
+		d.templates[key] = t
+	}
+	return nil
+}
+
+func (d *decoder) decodeData(buf []byte, key templateKey, tags map[string]string, ts time.Time) ([]telegraf.Metric, error) {
+	t, found := d.templates[key]
+	if !found {
+		// Data might arrive before the template, so this is not an error
+		d.Log.Debugf("Skipping data set for unknown template %d of %s (domain %d)", key.id, key.exporter, key.domain)
+		return nil, nil
+	}
+	if t.options {
+		return nil, nil
+	}
+
+	// Determine the minimum record length to detect trailing padding
+	var minLength int
+	for _, f := range t.fields {
+		if f.length == variableLength {
+			minLength++
+		} else {
+			minLength += int(f.length)
+		}
+	}
+	if minLength == 0 {
+		return nil, fmt.Errorf("template %d has zero length", key.id)
+	}
+
+	var metrics []telegraf.Metric
+	for len(buf) >= minLength {
+		fields := make(map[string]interface{}, len(t.fields))
+		for _, f := range t.fields {
+			length := int(f.length)
+			if f.length == variableLength {
+				if len(buf) < 1 {
+					return metrics, errors.New("record too short for variable length")
+				}
+				length = int(buf[0])
+				buf = buf[1:]
+				if length == 255 {
+					if len(buf) < 2 {
+						return metrics, errors.New("record too short for variable length")
+					}
+					length = int(binary.BigEndian.Uint16(buf[0:2]))
+					buf = buf[2:]
+				}
+			}
+			if len(buf) < length {
+				return metrics, fmt.Errorf("record too short for field %d", f.id)
+			}
+			name, value := d.decodeField(f, buf[:length])
+			fields[name] = value
+			buf = buf[length:]
+		}
+		metrics = append(metrics, metric.New("netflow", tags, fields, ts))
+	}
+	return metrics, nil
+}
+
+func (d *decoder) decodeField(f templateField, raw []byte) (string, interface{}) {
+	if f.pen != 0 {
+		if m, found := d.penMappings[penKey{pen: f.pen, id: f.id}]; found {
+			return m.name, m.decoder(raw)
+		}
+		return "type_" + strconv.FormatUint(uint64(f.pen), 10) + "_" + strconv.FormatUint(uint64(f.id), 10), decodeHex(raw)
+	}
+	if m, found := fieldMappings[f.id]; found {
+		return m.name, m.decoder(raw)
+	}
+	return "type_" + strconv.FormatUint(uint64(f.id), 10), decodeHex(raw)
+}
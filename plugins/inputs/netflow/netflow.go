@@ -0,0 +1,123 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package netflow
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+const maxPacketSize = 64 * 1024
+
+type NetFlow struct {
+	ServiceAddress string      `toml:"service_address"`
+	ReadBufferSize config.Size `toml:"read_buffer_size"`
+	PENFiles       []string    `toml:"private_enterprise_number_files"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	conn    *net.UDPConn
+	decoder *decoder
+	wg      sync.WaitGroup
+}
+
+func (*NetFlow) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NetFlow) Init() error {
+	if n.ServiceAddress == "" {
+		return errors.New("service_address required")
+	}
+
+	n.decoder = &decoder{Log: n.Log}
+	return n.decoder.init(n.PENFiles)
+}
+
+func (n *NetFlow) Start(acc telegraf.Accumulator) error {
+	u, err := url.Parse(n.ServiceAddress)
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "udp", "udp4", "udp6":
+	default:
+		return fmt.Errorf("unsupported network type: %s", u.Scheme)
+	}
+
+	addr, err := net.ResolveUDPAddr(u.Scheme, u.Host)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP(u.Scheme, addr)
+	if err != nil {
+		return err
+	}
+	n.conn = conn
+
+	if n.ReadBufferSize > 0 {
+		if err := conn.SetReadBuffer(int(n.ReadBufferSize)); err != nil {
+			return err
+		}
+	}
+	n.Log.Infof("Listening on %s://%s", conn.LocalAddr().Network(), conn.LocalAddr().String())
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.read(acc)
+	}()
+
+	return nil
+}
+
+// Gather is a NOOP as the plugin receives flow packets asynchronously
+func (n *NetFlow) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (n *NetFlow) Stop() {
+	if n.conn != nil {
+		_ = n.conn.Close()
+	}
+	n.wg.Wait()
+}
+
+func (n *NetFlow) read(acc telegraf.Accumulator) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		count, src, err := n.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				acc.AddError(err)
+			}
+			return
+		}
+
+		metrics, err := n.decoder.decode(src.IP, buf[:count])
+		if err != nil {
+			acc.AddError(fmt.Errorf("decoding packet from %s failed: %w", src.IP, err))
+		}
+		for _, m := range metrics {
+			acc.AddMetric(m)
+		}
+	}
+}
+
+func init() {
+	inputs.Add("netflow", func() telegraf.Input {
+		return &NetFlow{}
+	})
+}
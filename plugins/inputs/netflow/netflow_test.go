@@ -0,0 +1,289 @@
+package netflow
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInit(t *testing.T) {
+	plugin := &NetFlow{}
+	require.ErrorContains(t, plugin.Init(), "service_address required")
+
+	plugin = &NetFlow{
+		ServiceAddress: "udp://:2055",
+		PENFiles:       []string{filepath.Join("testdata", "pen.csv")},
+	}
+	require.NoError(t, plugin.Init())
+	require.Contains(t, plugin.decoder.penMappings, penKey{pen: 32473, id: 1})
+
+	plugin = &NetFlow{
+		ServiceAddress: "udp://:2055",
+		PENFiles:       []string{filepath.Join("testdata", "nonexisting.csv")},
+	}
+	require.ErrorContains(t, plugin.Init(), "loading enterprise field definitions failed")
+}
+
+func TestLoadPENMappingsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "wrong number of columns",
+			content:  "32473.1,foo",
+			expected: "expected 3 columns",
+		},
+		{
+			name:     "missing element id",
+			content:  "32473,foo,uint",
+			expected: "invalid element identifier",
+		},
+		{
+			name:     "invalid enterprise number",
+			content:  "abc.1,foo,uint",
+			expected: "invalid enterprise number",
+		},
+		{
+			name:     "unknown type",
+			content:  "32473.1,foo,complex",
+			expected: "unknown type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "pen.csv")
+			require.NoError(t, os.WriteFile(fn, []byte(tt.content), 0600))
+			require.ErrorContains(t, loadPENMappings(fn, make(map[penKey]fieldMapping)), tt.expected)
+		})
+	}
+}
+
+func TestDecodeV5(t *testing.T) {
+	d := &decoder{Log: testutil.Logger{}}
+	require.NoError(t, d.init(nil))
+
+	buf, err := os.ReadFile(filepath.Join("testdata", "netflow_v5.bin"))
+	require.NoError(t, err)
+
+	metrics, err := d.decode(net.ParseIP("127.0.0.1"), buf)
+	require.NoError(t, err)
+
+	tags := map[string]string{"source": "127.0.0.1", "version": "NetFlowV5"}
+	ts := time.Unix(1666000000, 500)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("netflow", tags,
+			map[string]interface{}{
+				"src":               "10.0.0.1",
+				"dst":               "192.168.1.10",
+				"next_hop":          "10.0.0.254",
+				"in_snmp":           uint64(1),
+				"out_snmp":          uint64(2),
+				"in_packets":        uint64(10),
+				"in_bytes":          uint64(1500),
+				"first_switched":    uint64(350000),
+				"last_switched":     uint64(359000),
+				"src_port":          uint64(51234),
+				"dst_port":          uint64(443),
+				"tcp_flags":         uint64(0x1b),
+				"protocol":          "tcp",
+				"src_tos":           uint64(0),
+				"bgp_src_as":        uint64(65001),
+				"bgp_dst_as":        uint64(65002),
+				"src_mask":          uint64(24),
+				"dst_mask":          uint64(16),
+				"engine_type":       uint64(1),
+				"engine_id":         uint64(2),
+				"sampling_interval": uint64(100),
+			},
+			ts,
+		),
+		testutil.MustMetric("netflow", tags,
+			map[string]interface{}{
+				"src":               "10.0.0.2",
+				"dst":               "8.8.8.8",
+				"next_hop":          "10.0.0.254",
+				"in_snmp":           uint64(1),
+				"out_snmp":          uint64(3),
+				"in_packets":        uint64(1),
+				"in_bytes":          uint64(64),
+				"first_switched":    uint64(359500),
+				"last_switched":     uint64(359500),
+				"src_port":          uint64(53000),
+				"dst_port":          uint64(53),
+				"tcp_flags":         uint64(0),
+				"protocol":          "udp",
+				"src_tos":           uint64(0),
+				"bgp_src_as":        uint64(0),
+				"bgp_dst_as":        uint64(15169),
+				"src_mask":          uint64(24),
+				"dst_mask":          uint64(8),
+				"engine_type":       uint64(1),
+				"engine_id":         uint64(2),
+				"sampling_interval": uint64(100),
+			},
+			ts,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestDecodeV9(t *testing.T) {
+	d := &decoder{Log: testutil.Logger{}}
+	require.NoError(t, d.init(nil))
+
+	buf, err := os.ReadFile(filepath.Join("testdata", "netflow_v9.bin"))
+	require.NoError(t, err)
+
+	metrics, err := d.decode(net.ParseIP("127.0.0.1"), buf)
+	require.NoError(t, err)
+
+	tags := map[string]string{"source": "127.0.0.1", "version": "NetFlowV9"}
+	ts := time.Unix(1666000000, 0)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("netflow", tags,
+			map[string]interface{}{
+				"src":        "172.16.0.1",
+				"dst":        "172.16.0.2",
+				"src_port":   uint64(1024),
+				"dst_port":   uint64(80),
+				"protocol":   "tcp",
+				"in_bytes":   uint64(4000),
+				"in_packets": uint64(5),
+				"in_snmp":    uint64(7),
+			},
+			ts,
+		),
+		testutil.MustMetric("netflow", tags,
+			map[string]interface{}{
+				"src":        "172.16.0.3",
+				"dst":        "172.16.0.4",
+				"src_port":   uint64(1025),
+				"dst_port":   uint64(22),
+				"protocol":   "tcp",
+				"in_bytes":   uint64(120),
+				"in_packets": uint64(2),
+				"in_snmp":    uint64(7),
+			},
+			ts,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	// Templates are cached per exporter and source ID
+	require.Contains(t, d.templates, templateKey{exporter: "127.0.0.1", domain: 7, id: 256})
+	require.Contains(t, d.templates, templateKey{exporter: "127.0.0.1", domain: 7, id: 257})
+	require.True(t, d.templates[templateKey{exporter: "127.0.0.1", domain: 7, id: 257}].options)
+
+	// Data of another exporter cannot be decoded with the cached template
+	data := append([]byte{}, buf[:20]...)
+	data = append(data, buf[len(buf)-64:len(buf)-12]...)
+	metrics, err = d.decode(net.ParseIP("127.0.0.2"), data)
+	require.NoError(t, err)
+	require.Empty(t, metrics)
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	d := &decoder{Log: testutil.Logger{}}
+	require.NoError(t, d.init([]string{filepath.Join("testdata", "pen.csv")}))
+
+	buf, err := os.ReadFile(filepath.Join("testdata", "ipfix.bin"))
+	require.NoError(t, err)
+
+	metrics, err := d.decode(net.ParseIP("2001:db8::ff"), buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("netflow",
+			map[string]string{"source": "2001:db8::ff", "version": "IPFIX"},
+			map[string]interface{}{
+				"src":            "2001:db8::1",
+				"dst":            "2001:db8::2",
+				"protocol":       "udp",
+				"in_bytes":       uint64(123456),
+				"interface_name": "eth0",
+				"app_latency_us": uint64(99),
+				"type_32473_2":   "0xbeef",
+			},
+			time.Unix(1666000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestDecodeIPFIXTemplateWithdrawal(t *testing.T) {
+	d := &decoder{Log: testutil.Logger{}}
+	require.NoError(t, d.init(nil))
+
+	buf, err := os.ReadFile(filepath.Join("testdata", "ipfix.bin"))
+	require.NoError(t, err)
+	_, err = d.decode(net.ParseIP("127.0.0.1"), buf)
+	require.NoError(t, err)
+
+	key := templateKey{exporter: "127.0.0.1", domain: 42, id: 300}
+	require.Contains(t, d.templates, key)
+
+	withdrawal := []byte{
+		0x00, 0x0a, 0x00, 0x18, // version, length
+		0x63, 0x4d, 0x2c, 0x80, // export time
+		0x00, 0x00, 0x00, 0x04, // sequence
+		0x00, 0x00, 0x00, 0x2a, // observation domain
+		0x00, 0x02, 0x00, 0x08, // template set
+		0x01, 0x2c, 0x00, 0x00, // template 300 without fields
+	}
+	_, err = d.decode(net.ParseIP("127.0.0.1"), withdrawal)
+	require.NoError(t, err)
+	require.NotContains(t, d.templates, key)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	d := &decoder{Log: testutil.Logger{}}
+	require.NoError(t, d.init(nil))
+
+	_, err := d.decode(net.ParseIP("127.0.0.1"), []byte{0x00})
+	require.ErrorContains(t, err, "too short")
+
+	_, err = d.decode(net.ParseIP("127.0.0.1"), []byte{0x00, 0x07})
+	require.ErrorContains(t, err, "unsupported version 7")
+
+	buf, err := os.ReadFile(filepath.Join("testdata", "netflow_v5.bin"))
+	require.NoError(t, err)
+	_, err = d.decode(net.ParseIP("127.0.0.1"), buf[:len(buf)-1])
+	require.ErrorContains(t, err, "too short for 2 records")
+}
+
+func TestReceive(t *testing.T) {
+	plugin := &NetFlow{
+		ServiceAddress: "udp://127.0.0.1:0",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	buf, err := os.ReadFile(filepath.Join("testdata", "netflow_v9.bin"))
+	require.NoError(t, err)
+
+	client, err := net.Dial("udp", plugin.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write(buf)
+	require.NoError(t, err)
+
+	acc.Wait(2)
+	for _, m := range acc.GetTelegrafMetrics() {
+		require.Equal(t, "netflow", m.Name())
+		require.Equal(t, map[string]string{"source": "127.0.0.1", "version": "NetFlowV9"}, m.Tags())
+	}
+}
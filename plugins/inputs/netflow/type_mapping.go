@@ -0,0 +1,304 @@
+package netflow
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// decoderFunc converts the raw bytes of an information element to a field value
+type decoderFunc func([]byte) interface{}
+
+// fieldMapping describes how to name and decode an information element
+type fieldMapping struct {
+	name    string
+	decoder decoderFunc
+}
+
+var decoders = map[string]decoderFunc{
+	"uint":   decodeUint,
+	"int":    decodeInt,
+	"float":  decodeFloat,
+	"bool":   decodeBool,
+	"ip":     decodeIP,
+	"mac":    decodeMAC,
+	"hex":    decodeHex,
+	"string": decodeString,
+	"proto":  decodeProtocol,
+}
+
+// Mapping of the information elements defined by IANA for IPFIX, see
+// https://www.iana.org/assignments/ipfix/ipfix.xhtml
+// The NetFlow v9 field types are identical for the elements below, so the
+// same names are used for all protocol versions.
+var fieldMappings = map[uint16]fieldMapping{
+	1:   {"in_bytes", decodeUint},                // octetDeltaCount
+	2:   {"in_packets", decodeUint},              // packetDeltaCount
+	3:   {"flows", decodeUint},                   // deltaFlowCount
+	4:   {"protocol", decodeProtocol},            // protocolIdentifier
+	5:   {"src_tos", decodeUint},                 // ipClassOfService
+	6:   {"tcp_flags", decodeUint},               // tcpControlBits
+	7:   {"src_port", decodeUint},                // sourceTransportPort
+	8:   {"src", decodeIP},                       // sourceIPv4Address
+	9:   {"src_mask", decodeUint},                // sourceIPv4PrefixLength
+	10:  {"in_snmp", decodeUint},                 // ingressInterface
+	11:  {"dst_port", decodeUint},                // destinationTransportPort
+	12:  {"dst", decodeIP},                       // destinationIPv4Address
+	13:  {"dst_mask", decodeUint},                // destinationIPv4PrefixLength
+	14:  {"out_snmp", decodeUint},                // egressInterface
+	15:  {"next_hop", decodeIP},                  // ipNextHopIPv4Address
+	16:  {"bgp_src_as", decodeUint},              // bgpSourceAsNumber
+	17:  {"bgp_dst_as", decodeUint},              // bgpDestinationAsNumber
+	18:  {"bgp_next_hop", decodeIP},              // bgpNextHopIPv4Address
+	19:  {"out_mcast_packets", decodeUint},       // postMCastPacketDeltaCount
+	20:  {"out_mcast_bytes", decodeUint},         // postMCastOctetDeltaCount
+	21:  {"last_switched", decodeUint},           // flowEndSysUpTime
+	22:  {"first_switched", decodeUint},          // flowStartSysUpTime
+	23:  {"out_bytes", decodeUint},               // postOctetDeltaCount
+	24:  {"out_packets", decodeUint},             // postPacketDeltaCount
+	25:  {"min_length", decodeUint},              // minimumIpTotalLength
+	26:  {"max_length", decodeUint},              // maximumIpTotalLength
+	27:  {"src", decodeIP},                       // sourceIPv6Address
+	28:  {"dst", decodeIP},                       // destinationIPv6Address
+	29:  {"src_mask", decodeUint},                // sourceIPv6PrefixLength
+	30:  {"dst_mask", decodeUint},                // destinationIPv6PrefixLength
+	31:  {"flow_label", decodeUint},              // flowLabelIPv6
+	32:  {"icmp_type_code", decodeUint},          // icmpTypeCodeIPv4
+	33:  {"igmp_type", decodeUint},               // igmpType
+	34:  {"sampling_interval", decodeUint},       // samplingInterval
+	35:  {"sampling_algorithm", decodeUint},      // samplingAlgorithm
+	36:  {"flow_active_timeout", decodeUint},     // flowActiveTimeout
+	37:  {"flow_inactive_timeout", decodeUint},   // flowIdleTimeout
+	38:  {"engine_type", decodeUint},             // engineType
+	39:  {"engine_id", decodeUint},               // engineId
+	40:  {"total_bytes_exported", decodeUint},    // exportedOctetTotalCount
+	41:  {"total_messages_exported", decodeUint}, // exportedMessageTotalCount
+	42:  {"total_flows_exported", decodeUint},    // exportedFlowRecordTotalCount
+	44:  {"src_prefix", decodeIP},                // sourceIPv4Prefix
+	45:  {"dst_prefix", decodeIP},                // destinationIPv4Prefix
+	46:  {"mpls_top_label_type", decodeUint},     // mplsTopLabelType
+	47:  {"mpls_top_label_ip", decodeIP},         // mplsTopLabelIPv4Address
+	52:  {"min_ttl", decodeUint},                 // minimumTTL
+	53:  {"max_ttl", decodeUint},                 // maximumTTL
+	54:  {"fragment_id", decodeUint},             // fragmentIdentification
+	55:  {"dst_tos", decodeUint},                 // postIpClassOfService
+	56:  {"in_src_mac", decodeMAC},               // sourceMacAddress
+	57:  {"out_dst_mac", decodeMAC},              // postDestinationMacAddress
+	58:  {"vlan_src", decodeUint},                // vlanId
+	59:  {"vlan_dst", decodeUint},                // postVlanId
+	60:  {"ip_version", decodeUint},              // ipVersion
+	61:  {"direction", decodeUint},               // flowDirection
+	62:  {"next_hop", decodeIP},                  // ipNextHopIPv6Address
+	63:  {"bgp_next_hop", decodeIP},              // bgpNextHopIPv6Address
+	64:  {"ipv6_extensions", decodeUint},         // ipv6ExtensionHeaders
+	70:  {"mpls_label_1", decodeHex},             // mplsTopLabelStackSection
+	71:  {"mpls_label_2", decodeHex},             // mplsLabelStackSection2
+	72:  {"mpls_label_3", decodeHex},             // mplsLabelStackSection3
+	80:  {"in_dst_mac", decodeMAC},               // destinationMacAddress
+	81:  {"out_src_mac", decodeMAC},              // postSourceMacAddress
+	82:  {"interface_name", decodeString},        // interfaceName
+	83:  {"interface_desc", decodeString},        // interfaceDescription
+	85:  {"in_total_bytes", decodeUint},          // octetTotalCount
+	86:  {"in_total_packets", decodeUint},        // packetTotalCount
+	88:  {"fragment_offset", decodeUint},         // fragmentOffset
+	89:  {"forwarding_status", decodeUint},       // forwardingStatus
+	90:  {"mpls_vpn_rd", decodeHex},              // mplsVpnRouteDistinguisher
+	94:  {"application_desc", decodeString},      // applicationDescription
+	95:  {"application_id", decodeHex},           // applicationId
+	96:  {"application_name", decodeString},      // applicationName
+	128: {"bgp_next_as", decodeUint},             // bgpNextAdjacentAsNumber
+	129: {"bgp_prev_as", decodeUint},             // bgpPrevAdjacentAsNumber
+	130: {"exporter", decodeIP},                  // exporterIPv4Address
+	131: {"exporter", decodeIP},                  // exporterIPv6Address
+	136: {"flow_end_reason", decodeUint},         // flowEndReason
+	138: {"observation_point_id", decodeUint},    // observationPointId
+	139: {"icmp_type_code", decodeUint},          // icmpTypeCodeIPv6
+	144: {"exporting_process_id", decodeUint},    // exportingProcessId
+	148: {"flow_id", decodeUint},                 // flowId
+	149: {"observation_domain_id", decodeUint},   // observationDomainId
+	150: {"flow_start", decodeUint},              // flowStartSeconds
+	151: {"flow_end", decodeUint},                // flowEndSeconds
+	152: {"flow_start_ms", decodeUint},           // flowStartMilliseconds
+	153: {"flow_end_ms", decodeUint},             // flowEndMilliseconds
+	154: {"flow_start_us", decodeUint},           // flowStartMicroseconds
+	155: {"flow_end_us", decodeUint},             // flowEndMicroseconds
+	156: {"flow_start_ns", decodeUint},           // flowStartNanoseconds
+	157: {"flow_end_ns", decodeUint},             // flowEndNanoseconds
+	160: {"system_init_ms", decodeUint},          // systemInitTimeMilliseconds
+	161: {"flow_duration_ms", decodeUint},        // flowDurationMilliseconds
+	176: {"icmp_type", decodeUint},               // icmpTypeIPv4
+	177: {"icmp_code", decodeUint},               // icmpCodeIPv4
+	178: {"icmp_type", decodeUint},               // icmpTypeIPv6
+	179: {"icmp_code", decodeUint},               // icmpCodeIPv6
+	180: {"src_port", decodeUint},                // udpSourcePort
+	181: {"dst_port", decodeUint},                // udpDestinationPort
+	182: {"src_port", decodeUint},                // tcpSourcePort
+	183: {"dst_port", decodeUint},                // tcpDestinationPort
+	192: {"ttl", decodeUint},                     // ipTTL
+	210: {"padding", decodeHex},                  // paddingOctets
+	224: {"ip_total_length", decodeUint},         // ipTotalLength
+	225: {"post_nat_src", decodeIP},              // postNATSourceIPv4Address
+	226: {"post_nat_dst", decodeIP},              // postNATDestinationIPv4Address
+	227: {"post_napt_src_port", decodeUint},      // postNAPTSourceTransportPort
+	228: {"post_napt_dst_port", decodeUint},      // postNAPTDestinationTransportPort
+	230: {"nat_event", decodeUint},               // natEvent
+	234: {"in_vrf_id", decodeUint},               // ingressVRFID
+	235: {"out_vrf_id", decodeUint},              // egressVRFID
+	239: {"biflow_direction", decodeUint},        // biflowDirection
+	281: {"post_nat_src", decodeIP},              // postNATSourceIPv6Address
+	282: {"post_nat_dst", decodeIP},              // postNATDestinationIPv6Address
+	323: {"observation_time_ms", decodeUint},     // observationTimeMilliseconds
+	352: {"layer2_bytes", decodeUint},            // layer2OctetDeltaCount
+	361: {"port_range_start", decodeUint},        // portRangeStart
+	362: {"port_range_end", decodeUint},          // portRangeEnd
+	363: {"port_range_step_size", decodeUint},    // portRangeStepSize
+	364: {"port_range_num_ports", decodeUint},    // portRangeNumPorts
+}
+
+// Mapping of IP protocol numbers to names
+var protocolNames = map[uint64]string{
+	1:   "icmp",
+	2:   "igmp",
+	4:   "ipip",
+	6:   "tcp",
+	17:  "udp",
+	41:  "ipv6",
+	47:  "gre",
+	50:  "esp",
+	51:  "ah",
+	58:  "ipv6-icmp",
+	89:  "ospf",
+	103: "pim",
+	112: "vrrp",
+	132: "sctp",
+}
+
+func decodeUint(b []byte) interface{} {
+	if len(b) > 8 {
+		return decodeHex(b)
+	}
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v
+}
+
+func decodeInt(b []byte) interface{} {
+	if len(b) == 0 || len(b) > 8 {
+		return decodeHex(b)
+	}
+	// Sign-extend the big-endian value of arbitrary length
+	v := int64(int8(b[0]))
+	for _, x := range b[1:] {
+		v = v<<8 | int64(x)
+	}
+	return v
+}
+
+func decodeFloat(b []byte) interface{} {
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	return decodeHex(b)
+}
+
+func decodeBool(b []byte) interface{} {
+	// IPFIX encodes true as 1 and false as 2
+	if len(b) != 1 {
+		return decodeHex(b)
+	}
+	return b[0] == 1
+}
+
+func decodeIP(b []byte) interface{} {
+	switch len(b) {
+	case net.IPv4len, net.IPv6len:
+		return net.IP(b).String()
+	}
+	return decodeHex(b)
+}
+
+func decodeMAC(b []byte) interface{} {
+	return net.HardwareAddr(b).String()
+}
+
+func decodeHex(b []byte) interface{} {
+	return "0x" + hex.EncodeToString(b)
+}
+
+func decodeString(b []byte) interface{} {
+	return strings.TrimRight(string(b), "\x00")
+}
+
+func decodeProtocol(b []byte) interface{} {
+	v, ok := decodeUint(b).(uint64)
+	if !ok {
+		return decodeHex(b)
+	}
+	if name, found := protocolNames[v]; found {
+		return name
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// loadPENMappings reads the definitions of enterprise specific information
+// elements. Each line has the format "<pen>.<element id>,<name>,<type>" and
+// lines starting with '#' are ignored.
+func loadPENMappings(filename string, mappings map[penKey]fieldMapping) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	var lineno int
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ",")
+		if len(parts) != 3 {
+			return fmt.Errorf("%s:%d: expected 3 columns but got %d", filename, lineno, len(parts))
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+
+		id := strings.SplitN(parts[0], ".", 2)
+		if len(id) != 2 {
+			return fmt.Errorf("%s:%d: invalid element identifier %q", filename, lineno, parts[0])
+		}
+		pen, err := strconv.ParseUint(id[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid enterprise number %q: %w", filename, lineno, id[0], err)
+		}
+		element, err := strconv.ParseUint(id[1], 10, 15)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid element id %q: %w", filename, lineno, id[1], err)
+		}
+		if parts[1] == "" {
+			return fmt.Errorf("%s:%d: empty name", filename, lineno)
+		}
+		decoder, found := decoders[parts[2]]
+		if !found {
+			return fmt.Errorf("%s:%d: unknown type %q", filename, lineno, parts[2])
+		}
+
+		mappings[penKey{pen: uint32(pen), id: uint16(element)}] = fieldMapping{name: parts[1], decoder: decoder}
+	}
+	return scanner.Err()
+}
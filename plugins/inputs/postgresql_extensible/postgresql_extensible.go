@@ -15,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/watermark"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/postgresql"
 )
@@ -30,7 +31,8 @@ type Postgresql struct {
 	Timestamp          string
 	Query              query
 	Debug              bool
-	PreparedStatements bool `toml:"prepared_statements"`
+	PreparedStatements bool   `toml:"prepared_statements"`
+	StateFile          string `toml:"incremental_state_file"`
 
 	Log telegraf.Logger
 }
@@ -43,6 +45,11 @@ type query []struct {
 	Tagvalue    string
 	Measurement string
 	Timestamp   string
+
+	IncrementalColumn       string      `toml:"incremental_column"`
+	IncrementalInitialValue interface{} `toml:"incremental_initial_value"`
+
+	watermark *watermark.Tracker
 }
 
 var ignoredColumns = map[string]bool{"stats_reset": true}
@@ -53,6 +60,7 @@ func (*Postgresql) SampleConfig() string {
 
 func (p *Postgresql) Init() error {
 	var err error
+	var store *watermark.Store
 	for i := range p.Query {
 		if p.Query[i].Sqlquery == "" {
 			p.Query[i].Sqlquery, err = ReadQueryFromFile(p.Query[i].Script)
@@ -60,6 +68,28 @@ func (p *Postgresql) Init() error {
 				return err
 			}
 		}
+
+		// Setup the high-water mark tracking for incremental queries. The
+		// database filter of 'withdbname' is appended to the query and cannot
+		// be combined with the parameter of incremental queries.
+		if p.Query[i].IncrementalColumn != "" {
+			if p.Query[i].Withdbname {
+				return fmt.Errorf("'withdbname' cannot be used with incremental column %q", p.Query[i].IncrementalColumn)
+			}
+			if store == nil {
+				if store, err = watermark.NewStore(p.StateFile, p.Address); err != nil {
+					return err
+				}
+			}
+			tracker, err := store.Tracker(p.Query[i].Sqlquery, p.Query[i].IncrementalColumn, p.Query[i].IncrementalInitialValue)
+			if err != nil {
+				return fmt.Errorf("setting up incremental query failed: %w", err)
+			}
+			if tracker.Value() == nil {
+				return fmt.Errorf("'incremental_initial_value' required for incremental column %q", p.Query[i].IncrementalColumn)
+			}
+			p.Query[i].watermark = tracker
+		}
 	}
 	p.Service.IsPgBouncer = !p.PreparedStatements
 	return nil
@@ -122,16 +152,29 @@ func (p *Postgresql) Gather(acc telegraf.Accumulator) error {
 		sqlQuery += queryAddon
 
 		if p.Query[i].Version <= dbVersion {
-			p.gatherMetricsFromQuery(acc, sqlQuery, p.Query[i].Tagvalue, p.Query[i].Timestamp, measName)
+			p.gatherMetricsFromQuery(acc, sqlQuery, p.Query[i].Tagvalue, p.Query[i].Timestamp, measName, p.Query[i].watermark)
 		}
 	}
 	return nil
 }
 
-func (p *Postgresql) gatherMetricsFromQuery(acc telegraf.Accumulator, sqlQuery string, tagValue string, timestamp string, measName string) {
+func (p *Postgresql) gatherMetricsFromQuery(
+	acc telegraf.Accumulator,
+	sqlQuery string,
+	tagValue string,
+	timestamp string,
+	measName string,
+	tracker *watermark.Tracker,
+) {
 	var columns []string
+	var args []interface{}
+
+	// Pass the high-water mark as parameter to incremental queries
+	if tracker != nil {
+		args = append(args, tracker.Value())
+	}
 
-	rows, err := p.DB.Query(sqlQuery)
+	rows, err := p.DB.Query(sqlQuery, args...)
 	if err != nil {
 		acc.AddError(err)
 		return
@@ -156,19 +199,32 @@ func (p *Postgresql) gatherMetricsFromQuery(acc telegraf.Accumulator, sqlQuery s
 	p.Timestamp = timestamp
 
 	for rows.Next() {
-		err = p.accRow(measName, rows, acc, columns)
+		err = p.accRow(measName, rows, acc, columns, tracker)
 		if err != nil {
 			acc.AddError(err)
 			break
 		}
 	}
+
+	if tracker != nil {
+		if err == nil {
+			err = rows.Err()
+		}
+		if err != nil {
+			tracker.Discard()
+			return
+		}
+		if err := tracker.Commit(); err != nil {
+			acc.AddError(fmt.Errorf("storing high-water mark failed: %w", err))
+		}
+	}
 }
 
 type scanner interface {
 	Scan(dest ...interface{}) error
 }
 
-func (p *Postgresql) accRow(measName string, row scanner, acc telegraf.Accumulator, columns []string) error {
+func (p *Postgresql) accRow(measName string, row scanner, acc telegraf.Accumulator, columns []string, tracker *watermark.Tracker) error {
 	var (
 		err        error
 		columnVars []interface{}
@@ -194,6 +250,16 @@ func (p *Postgresql) accRow(measName string, row scanner, acc telegraf.Accumulat
 		return err
 	}
 
+	if tracker != nil {
+		c, ok := columnMap[tracker.Column]
+		if !ok {
+			return fmt.Errorf("incremental column %q not found in result", tracker.Column)
+		}
+		if err := tracker.Observe(*c); err != nil {
+			return fmt.Errorf("tracking incremental column failed: %w", err)
+		}
+	}
+
 	if c, ok := columnMap["datname"]; ok && *c != nil {
 		// extract the database name from the column map
 		switch datname := (*c).(type) {
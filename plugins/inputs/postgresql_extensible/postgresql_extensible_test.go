@@ -3,6 +3,7 @@ package postgresql_extensible
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -299,13 +300,71 @@ func TestAccRow(t *testing.T) {
 		{fields: []interface{}{"name", "gato"}},
 	}
 	for i := range testRows {
-		err := p.accRow("pgTEST", testRows[i], &acc, columns)
+		err := p.accRow("pgTEST", testRows[i], &acc, columns, nil)
 		if err != nil {
 			t.Fatalf("Scan failed: %s", err)
 		}
 	}
 }
 
+func TestAccRowIncremental(t *testing.T) {
+	p := &Postgresql{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Log:       testutil.Logger{},
+	}
+	p.Query = query{
+		{
+			Sqlquery:                "SELECT id, value FROM events WHERE id > $1",
+			IncrementalColumn:       "id",
+			IncrementalInitialValue: int64(0),
+		},
+	}
+	require.NoError(t, p.Init())
+	tracker := p.Query[0].watermark
+	require.Equal(t, int64(0), tracker.Value())
+
+	var acc testutil.Accumulator
+	columns := []string{"id", "value"}
+	testRows := []fakeRow{
+		{fields: []interface{}{int64(2), "a"}},
+		{fields: []interface{}{int64(5), "b"}},
+	}
+	for _, row := range testRows {
+		require.NoError(t, p.accRow("pgTEST", row, &acc, columns, tracker))
+	}
+	require.NoError(t, tracker.Commit())
+	require.Equal(t, int64(5), tracker.Value())
+
+	require.ErrorContains(t, p.accRow("pgTEST", fakeRow{fields: []interface{}{"a"}}, &acc, []string{"value"}, tracker),
+		`incremental column "id" not found`)
+
+	// The mark is restored on restart
+	restarted := &Postgresql{
+		StateFile: p.StateFile,
+		Query:     p.Query,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, restarted.Init())
+	require.Equal(t, int64(5), restarted.Query[0].watermark.Value())
+
+	// An initial value is required
+	p = &Postgresql{Log: testutil.Logger{}}
+	p.Query = query{{Sqlquery: "SELECT id FROM events WHERE id > $1", IncrementalColumn: "id"}}
+	require.ErrorContains(t, p.Init(), "'incremental_initial_value' required")
+
+	// The database filter would be appended to the mark condition
+	p = &Postgresql{Log: testutil.Logger{}}
+	p.Query = query{
+		{
+			Sqlquery:                "SELECT id FROM events WHERE id > $1 AND datname",
+			Withdbname:              true,
+			IncrementalColumn:       "id",
+			IncrementalInitialValue: int64(0),
+		},
+	}
+	require.ErrorContains(t, p.Init(), "'withdbname' cannot be used with incremental column")
+}
+
 type fakeRow struct {
 	fields []interface{}
 }
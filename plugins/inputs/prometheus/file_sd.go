@@ -0,0 +1,173 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/globpath"
+)
+
+// fileSDCheckInterval is the interval for checking the discovery files for
+// modifications
+var fileSDCheckInterval = 5 * time.Second
+
+type FileSDConfig struct {
+	// Glob patterns of JSON or YAML files containing target groups
+	Files []string `toml:"files"`
+
+	// Interval for re-reading all files even if they were not modified
+	RefreshInterval config.Duration `toml:"refresh_interval"`
+
+	// Rules to keep or drop targets and labels
+	Rules []*TargetRule `toml:"rule"`
+}
+
+type fileSDState struct {
+	modTime time.Time
+	size    int64
+	urls    map[string]URLAndAddress
+}
+
+type fileSD struct {
+	globs []*globpath.GlobPath
+	files map[string]*fileSDState
+}
+
+func newFileSD(patterns []string) (*fileSD, error) {
+	sd := &fileSD{files: make(map[string]*fileSDState)}
+	for _, pattern := range patterns {
+		g, err := globpath.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file_sd pattern %q: %v", pattern, err)
+		}
+		sd.globs = append(sd.globs, g)
+	}
+	return sd, nil
+}
+
+func (p *Prometheus) startFileSD(ctx context.Context) error {
+	sd, err := newFileSD(p.FileSDConfig.Files)
+	if err != nil {
+		return err
+	}
+
+	p.refreshFileSD(sd, true)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		check := time.NewTicker(fileSDCheckInterval)
+		defer check.Stop()
+		refresh := time.NewTicker(time.Duration(p.FileSDConfig.RefreshInterval))
+		defer refresh.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-check.C:
+				p.refreshFileSD(sd, false)
+			case <-refresh.C:
+				p.refreshFileSD(sd, true)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// refreshFileSD reads the discovery files that were added or modified since
+// the last refresh, or all files if forced. The targets of files that cannot
+// be read are kept until the file is removed.
+func (p *Prometheus) refreshFileSD(sd *fileSD, force bool) {
+	found := make(map[string]bool)
+	changed := false
+	for _, g := range sd.globs {
+		for _, filename := range g.Match() {
+			if found[filename] {
+				continue
+			}
+			found[filename] = true
+
+			info, err := os.Stat(filename)
+			if err != nil {
+				p.Log.Warnf("Unable to read file_sd file %q: %v", filename, err)
+				continue
+			}
+
+			state, exists := sd.files[filename]
+			if exists && !force && info.ModTime().Equal(state.modTime) && info.Size() == state.size {
+				continue
+			}
+			if !exists {
+				state = &fileSDState{}
+				sd.files[filename] = state
+			}
+			state.modTime = info.ModTime()
+			state.size = info.Size()
+
+			urls, err := p.readFileSD(filename)
+			if err != nil {
+				p.Log.Warnf("Unable to read file_sd file %q: %v", filename, err)
+				continue
+			}
+			state.urls = urls
+			changed = true
+		}
+	}
+
+	for filename := range sd.files {
+		if !found[filename] {
+			p.Log.Debugf("Removing targets of file_sd file %q", filename)
+			delete(sd.files, filename)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	fileSDServices := make(map[string]URLAndAddress)
+	for _, state := range sd.files {
+		for k, v := range state.urls {
+			fileSDServices[k] = v
+		}
+	}
+	p.Log.Debugf("Discovered %d targets from file_sd", len(fileSDServices))
+
+	p.lock.Lock()
+	p.fileSDServices = fileSDServices
+	p.lock.Unlock()
+}
+
+func (p *Prometheus) readFileSD(filename string) (map[string]URLAndAddress, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []targetGroup
+	switch filepath.Ext(filename) {
+	case ".json":
+		err = json.Unmarshal(content, &groups)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(content, &groups)
+	default:
+		return nil, fmt.Errorf("unsupported file extension %q", filepath.Ext(filename))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]string{"__meta_filepath": filename}
+	return targetURLs(groups, meta, p.FileSDConfig.Rules), nil
+}
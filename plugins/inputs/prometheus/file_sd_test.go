@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestFileSD(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "targets.json")
+	yamlFile := filepath.Join(dir, "targets.yml")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`[{"targets": ["10.0.0.1:9100"], "labels": {"job": "node"}}]`), 0600))
+	require.NoError(t, os.WriteFile(yamlFile, []byte("- targets: ['10.0.0.2:9100']\n  labels:\n    job: app\n"), 0600))
+
+	p := &Prometheus{
+		Log: testutil.Logger{},
+		FileSDConfig: FileSDConfig{
+			Files: []string{filepath.Join(dir, "*")},
+			Rules: []*TargetRule{{Action: "labeldrop", Regex: "__meta_filepath"}},
+		},
+	}
+	require.NoError(t, p.Init())
+
+	sd, err := newFileSD(p.FileSDConfig.Files)
+	require.NoError(t, err)
+	p.refreshFileSD(sd, false)
+
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	require.Equal(t, map[string]string{"job": "node"}, urls["http://10.0.0.1:9100/metrics"].Tags)
+	require.Equal(t, map[string]string{"job": "app"}, urls["http://10.0.0.2:9100/metrics"].Tags)
+
+	// Invalid files keep their previous targets
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`[{"targets": [`), 0600))
+	p.refreshFileSD(sd, true)
+	urls, err = p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+
+	// Modified files are reloaded
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`[{"targets": ["10.0.0.3:9100"]}]`), 0600))
+	p.refreshFileSD(sd, false)
+	urls, err = p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	require.Contains(t, urls, "http://10.0.0.3:9100/metrics")
+
+	// Removed files drop their targets
+	require.NoError(t, os.Remove(yamlFile))
+	p.refreshFileSD(sd, false)
+	urls, err = p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+}
+
+func TestFileSDWatch(t *testing.T) {
+	defer func(interval time.Duration) { fileSDCheckInterval = interval }(fileSDCheckInterval)
+	fileSDCheckInterval = 10 * time.Millisecond
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintln(w, sampleGaugeTextFormat)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	filename := filepath.Join(t.TempDir(), "targets.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`[]`), 0600))
+
+	p := &Prometheus{
+		Log:          testutil.Logger{},
+		URLTag:       "url",
+		FileSDConfig: FileSDConfig{Files: []string{filename}},
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Start(&acc))
+	defer p.Stop()
+
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Empty(t, urls)
+
+	target := fmt.Sprintf(`[{"targets": [%q], "labels": {"__meta_datacenter": "eu"}}]`, ts.Listener.Addr().String())
+	require.NoError(t, os.WriteFile(filename, []byte(target), 0600))
+	require.Eventually(t, func() bool {
+		urls, err := p.GetAllURLs()
+		return err == nil && len(urls) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, acc.GatherError(p.Gather))
+	require.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+	require.Equal(t, "eu", acc.TagValue("go_goroutines", "datacenter"))
+	require.Equal(t, filename, acc.TagValue("go_goroutines", "filepath"))
+	require.Equal(t, ts.URL+"/metrics", acc.TagValue("go_goroutines", "url"))
+}
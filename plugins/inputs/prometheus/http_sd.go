@@ -0,0 +1,104 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+)
+
+type HTTPSDConfig struct {
+	// URL of the endpoint returning the target groups as JSON
+	URL string `toml:"url"`
+
+	// Interval for querying the endpoint
+	RefreshInterval config.Duration `toml:"refresh_interval"`
+
+	// Rules to keep or drop targets and labels
+	Rules []*TargetRule `toml:"rule"`
+}
+
+func (p *Prometheus) startHTTPSD(ctx context.Context) error {
+	client, err := p.createHTTPClient()
+	if err != nil {
+		return err
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		// Store last error status and change log level depending on repeated occurence
+		refreshFailed := false
+		for {
+			if err := p.refreshHTTPSD(ctx, client); err != nil {
+				message := fmt.Sprintf("Unable to refresh http_sd targets: %v", err)
+				if refreshFailed {
+					p.Log.Debug(message)
+				} else {
+					p.Log.Warn(message)
+				}
+				refreshFailed = true
+			} else if refreshFailed {
+				refreshFailed = false
+				p.Log.Info("Successfully refreshed http_sd targets after previous errors")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(p.HTTPSDConfig.RefreshInterval)):
+			}
+		}
+	}()
+
+	return nil
+}
+
+// refreshHTTPSD queries the endpoint for the current targets. The previous
+// targets are kept if the query fails.
+func (p *Prometheus) refreshHTTPSD(ctx context.Context, client *http.Client) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.HTTPSDConfig.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Accept", "application/json")
+	refresh := time.Duration(p.HTTPSDConfig.RefreshInterval).Seconds()
+	req.Header.Set("X-Prometheus-Refresh-Interval-Seconds", strconv.FormatFloat(refresh, 'f', -1, 64))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", p.HTTPSDConfig.URL, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading body: %v", err)
+	}
+
+	var groups []targetGroup
+	if err := json.Unmarshal(body, &groups); err != nil {
+		return fmt.Errorf("error parsing targets: %v", err)
+	}
+
+	httpSDServices := targetURLs(groups, nil, p.HTTPSDConfig.Rules)
+	p.Log.Debugf("Discovered %d targets from http_sd", len(httpSDServices))
+
+	p.lock.Lock()
+	p.httpSDServices = httpSDServices
+	p.lock.Unlock()
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestHTTPSD(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintln(w, sampleGaugeTextFormat)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	var requests int32
+	sd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "0.05", r.Header.Get("X-Prometheus-Refresh-Interval-Seconds"))
+		// Fail after the first request to check the targets are kept
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `[
+			{"targets": [%q], "labels": {"__meta_env": "prod"}},
+			{"targets": ["10.0.0.1:9100"], "labels": {"__meta_env": "dev"}}
+		]`, ts.Listener.Addr().String())
+		require.NoError(t, err)
+	}))
+	defer sd.Close()
+
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		URLTag: "url",
+		HTTPSDConfig: HTTPSDConfig{
+			URL:             sd.URL,
+			RefreshInterval: config.Duration(50 * time.Millisecond),
+			Rules:           []*TargetRule{{Action: "keep", SourceLabels: []string{"__meta_env"}, Regex: "prod"}},
+		},
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Start(&acc))
+	defer p.Stop()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&requests) > 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+
+	require.NoError(t, acc.GatherError(p.Gather))
+	require.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+	require.Equal(t, "prod", acc.TagValue("go_goroutines", "env"))
+	require.Equal(t, ts.URL+"/metrics", acc.TagValue("go_goroutines", "url"))
+}
+
+func TestHTTPSDInvalidRule(t *testing.T) {
+	p := &Prometheus{
+		Log: testutil.Logger{},
+		HTTPSDConfig: HTTPSDConfig{
+			URL:   "http://localhost:8080/targets",
+			Rules: []*TargetRule{{Action: "keep"}},
+		},
+	}
+	require.ErrorContains(t, p.Init(), "http_sd: rule 1: source_labels required")
+}
@@ -51,6 +51,10 @@ type Prometheus struct {
 	// Consul SD configuration
 	ConsulConfig ConsulConfig `toml:"consul"`
 
+	// File and HTTP based SD configuration
+	FileSDConfig FileSDConfig `toml:"file_sd"`
+	HTTPSDConfig HTTPSDConfig `toml:"http_sd"`
+
 	// Bearer Token authorization file path
 	BearerToken       string `toml:"bearer_token"`
 	BearerTokenString string `toml:"bearer_token_string"`
@@ -96,6 +100,10 @@ type Prometheus struct {
 
 	// List of consul services to scrape
 	consulServices map[string]URLAndAddress
+
+	// List of targets discovered by file and HTTP based SD
+	fileSDServices map[string]URLAndAddress
+	httpSDServices map[string]URLAndAddress
 }
 
 func (*Prometheus) SampleConfig() string {
@@ -139,6 +147,23 @@ func (p *Prometheus) Init() error {
 		p.Log.Infof("Using the label selector: %v and field selector: %v", p.podLabelSelector, p.podFieldSelector)
 	}
 
+	if len(p.FileSDConfig.Files) > 0 {
+		if p.FileSDConfig.RefreshInterval <= 0 {
+			p.FileSDConfig.RefreshInterval = config.Duration(5 * time.Minute)
+		}
+		if err := initTargetRules(p.FileSDConfig.Rules); err != nil {
+			return fmt.Errorf("file_sd: %v", err)
+		}
+	}
+	if p.HTTPSDConfig.URL != "" {
+		if p.HTTPSDConfig.RefreshInterval <= 0 {
+			p.HTTPSDConfig.RefreshInterval = config.Duration(time.Minute)
+		}
+		if err := initTargetRules(p.HTTPSDConfig.Rules); err != nil {
+			return fmt.Errorf("http_sd: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -185,6 +210,13 @@ func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
 	for k, v := range p.consulServices {
 		allURLs[k] = v
 	}
+	// add all targets discovered by file and HTTP based SD
+	for k, v := range p.fileSDServices {
+		allURLs[k] = v
+	}
+	for k, v := range p.httpSDServices {
+		allURLs[k] = v
+	}
 	// loop through all pods scraped via the prometheus annotation on the pods
 	for k, v := range p.kubernetesPods {
 		allURLs[k] = v
@@ -415,7 +447,7 @@ func fieldSelectorIsSupported(fieldSelector fields.Selector) (bool, string) {
 	return true, ""
 }
 
-// Start will start the Kubernetes and/or Consul scraping if enabled in the configuration
+// Start will start the Kubernetes, Consul, file and/or HTTP based scraping if enabled in the configuration
 func (p *Prometheus) Start(_ telegraf.Accumulator) error {
 	var ctx context.Context
 	p.wg = sync.WaitGroup{}
@@ -431,6 +463,16 @@ func (p *Prometheus) Start(_ telegraf.Accumulator) error {
 			return err
 		}
 	}
+	if len(p.FileSDConfig.Files) > 0 {
+		if err := p.startFileSD(ctx); err != nil {
+			return err
+		}
+	}
+	if p.HTTPSDConfig.URL != "" {
+		if err := p.startHTTPSD(ctx); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
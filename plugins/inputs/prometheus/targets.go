@@ -0,0 +1,179 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Labels with a special meaning for the scrape target as used by Prometheus
+const (
+	addressLabel     = "__address__"
+	schemeLabel      = "__scheme__"
+	metricsPathLabel = "__metrics_path__"
+	paramLabelPrefix = "__param_"
+	metaLabelPrefix  = "__meta_"
+)
+
+// targetGroup is a group of scrape targets sharing the same labels as used
+// by Prometheus file and HTTP based service discovery
+type targetGroup struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
+}
+
+// TargetRule keeps or drops discovered targets or their labels similar to
+// the keep, drop, labelkeep and labeldrop actions of Prometheus relabeling
+type TargetRule struct {
+	// One of "keep", "drop", "labelkeep" or "labeldrop"
+	Action string `toml:"action"`
+
+	// Labels whose values are joined with the separator and matched against
+	// the regular expression for the "keep" and "drop" actions
+	SourceLabels []string `toml:"source_labels"`
+	Separator    string   `toml:"separator"`
+
+	// Regular expression anchored at both ends
+	Regex string `toml:"regex"`
+
+	regex *regexp.Regexp
+}
+
+func (r *TargetRule) init() error {
+	switch r.Action {
+	case "keep", "drop":
+		if len(r.SourceLabels) == 0 {
+			return fmt.Errorf("source_labels required for action %q", r.Action)
+		}
+	case "labelkeep", "labeldrop":
+	default:
+		return fmt.Errorf("invalid action %q", r.Action)
+	}
+
+	if r.Separator == "" {
+		r.Separator = ";"
+	}
+	if r.Regex == "" {
+		r.Regex = "(.*)"
+	}
+
+	var err error
+	r.regex, err = regexp.Compile("^(?:" + r.Regex + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %v", r.Regex, err)
+	}
+	return nil
+}
+
+// apply returns false if the target should be dropped. Label rules modify
+// the labels in place but never remove the labels defining the target URL.
+func (r *TargetRule) apply(labels map[string]string) bool {
+	switch r.Action {
+	case "keep", "drop":
+		values := make([]string, 0, len(r.SourceLabels))
+		for _, name := range r.SourceLabels {
+			values = append(values, labels[name])
+		}
+		matched := r.regex.MatchString(strings.Join(values, r.Separator))
+		return matched == (r.Action == "keep")
+	case "labelkeep", "labeldrop":
+		for name := range labels {
+			if isTargetLabel(name) {
+				continue
+			}
+			if r.regex.MatchString(name) != (r.Action == "labelkeep") {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
+
+func initTargetRules(rules []*TargetRule) error {
+	for i, r := range rules {
+		if err := r.init(); err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func isTargetLabel(name string) bool {
+	return name == addressLabel || name == schemeLabel || name == metricsPathLabel ||
+		strings.HasPrefix(name, paramLabelPrefix)
+}
+
+// targetURLs converts the target groups into the URLs to scrape after
+// applying the rules. The meta labels are added to every target before the
+// rules are applied.
+func targetURLs(groups []targetGroup, meta map[string]string, rules []*TargetRule) map[string]URLAndAddress {
+	urls := make(map[string]URLAndAddress)
+	for _, group := range groups {
+		for _, target := range group.Targets {
+			labels := make(map[string]string, len(group.Labels)+len(meta)+1)
+			for k, v := range meta {
+				labels[k] = v
+			}
+			for k, v := range group.Labels {
+				labels[k] = v
+			}
+			labels[addressLabel] = target
+
+			keep := true
+			for _, r := range rules {
+				if keep = r.apply(labels); !keep {
+					break
+				}
+			}
+			if !keep {
+				continue
+			}
+
+			u, tags := targetURL(labels)
+			urls[u.String()] = URLAndAddress{
+				URL:         u,
+				OriginalURL: u,
+				Tags:        tags,
+			}
+		}
+	}
+	return urls
+}
+
+// targetURL builds the scrape URL from the target labels. Meta labels are
+// converted to tags with the "__meta_" prefix removed, other labels starting
+// with "__" are dropped.
+func targetURL(labels map[string]string) (*url.URL, map[string]string) {
+	u := &url.URL{
+		Scheme: labels[schemeLabel],
+		Host:   labels[addressLabel],
+		Path:   labels[metricsPathLabel],
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+	}
+	if u.Path == "" {
+		u.Path = "/metrics"
+	}
+
+	params := url.Values{}
+	tags := make(map[string]string)
+	for k, v := range labels {
+		if strings.HasPrefix(k, paramLabelPrefix) {
+			params.Set(strings.TrimPrefix(k, paramLabelPrefix), v)
+		} else if strings.HasPrefix(k, metaLabelPrefix) && v != "" {
+			tags[strings.TrimPrefix(k, metaLabelPrefix)] = v
+		}
+	}
+	u.RawQuery = params.Encode()
+
+	// Regular labels take precedence over meta labels of the same name
+	for k, v := range labels {
+		if !strings.HasPrefix(k, "__") && v != "" {
+			tags[k] = v
+		}
+	}
+
+	return u, tags
+}
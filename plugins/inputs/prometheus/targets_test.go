@@ -0,0 +1,124 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetURLs(t *testing.T) {
+	groups := []targetGroup{
+		{
+			Targets: []string{"10.0.0.1:9100", "10.0.0.2:9100"},
+			Labels: map[string]string{
+				"job":              "node",
+				"__meta_env":       "prod",
+				"__meta_job":       "ignored",
+				"__meta_empty":     "",
+				"__scheme__":       "https",
+				"__metrics_path__": "/probe",
+				"__param_module":   "http_2xx",
+				"__internal__":     "dropped",
+			},
+		},
+		{
+			Targets: []string{"10.0.0.3:8080"},
+		},
+	}
+
+	urls := targetURLs(groups, map[string]string{"__meta_filepath": "/etc/targets.json"}, nil)
+	require.Len(t, urls, 3)
+
+	u, found := urls["https://10.0.0.1:9100/probe?module=http_2xx"]
+	require.True(t, found)
+	require.Equal(t, map[string]string{"job": "node", "env": "prod", "filepath": "/etc/targets.json"}, u.Tags)
+	require.Equal(t, u.URL, u.OriginalURL)
+
+	u, found = urls["http://10.0.0.3:8080/metrics"]
+	require.True(t, found)
+	require.Equal(t, map[string]string{"filepath": "/etc/targets.json"}, u.Tags)
+}
+
+func TestTargetRules(t *testing.T) {
+	groups := []targetGroup{
+		{
+			Targets: []string{"10.0.0.1:9100"},
+			Labels:  map[string]string{"job": "node", "__meta_env": "prod", "team": "ops"},
+		},
+		{
+			Targets: []string{"10.0.0.2:9100"},
+			Labels:  map[string]string{"job": "node", "__meta_env": "dev", "team": "dev"},
+		},
+		{
+			Targets: []string{"10.0.0.3:9200"},
+			Labels:  map[string]string{"job": "app", "__meta_env": "prod"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		rules    []*TargetRule
+		expected map[string]map[string]string
+	}{
+		{
+			name:  "keep",
+			rules: []*TargetRule{{Action: "keep", SourceLabels: []string{"__meta_env"}, Regex: "prod"}},
+			expected: map[string]map[string]string{
+				"http://10.0.0.1:9100/metrics": {"job": "node", "env": "prod", "team": "ops"},
+				"http://10.0.0.3:9200/metrics": {"job": "app", "env": "prod"},
+			},
+		},
+		{
+			name:  "drop on multiple labels",
+			rules: []*TargetRule{{Action: "drop", SourceLabels: []string{"job", "__address__"}, Regex: "node;.*:9100"}},
+			expected: map[string]map[string]string{
+				"http://10.0.0.3:9200/metrics": {"job": "app", "env": "prod"},
+			},
+		},
+		{
+			name:     "regex is anchored",
+			rules:    []*TargetRule{{Action: "keep", SourceLabels: []string{"__meta_env"}, Regex: "pro"}},
+			expected: map[string]map[string]string{},
+		},
+		{
+			name: "labeldrop",
+			rules: []*TargetRule{
+				{Action: "labeldrop", Regex: "__meta_.*|team"},
+			},
+			expected: map[string]map[string]string{
+				"http://10.0.0.1:9100/metrics": {"job": "node"},
+				"http://10.0.0.2:9100/metrics": {"job": "node"},
+				"http://10.0.0.3:9200/metrics": {"job": "app"},
+			},
+		},
+		{
+			name: "keep before labelkeep",
+			rules: []*TargetRule{
+				{Action: "keep", SourceLabels: []string{"team"}, Regex: "ops"},
+				{Action: "labelkeep", Regex: "job"},
+			},
+			expected: map[string]map[string]string{
+				"http://10.0.0.1:9100/metrics": {"job": "node"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, initTargetRules(tt.rules))
+
+			urls := targetURLs(groups, nil, tt.rules)
+			actual := make(map[string]map[string]string, len(urls))
+			for k, u := range urls {
+				actual[k] = u.Tags
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestTargetRulesInvalid(t *testing.T) {
+	require.ErrorContains(t, initTargetRules([]*TargetRule{{Action: "replace"}}), `rule 1: invalid action "replace"`)
+	require.ErrorContains(t, initTargetRules([]*TargetRule{{Action: "keep"}}), "source_labels required")
+	require.ErrorContains(t, initTargetRules([]*TargetRule{{Action: "labeldrop", Regex: "("}}), "invalid regex")
+}
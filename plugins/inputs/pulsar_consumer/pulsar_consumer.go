@@ -0,0 +1,285 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar_consumer
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	pulsarcommon "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	defaultMaxUndeliveredMessages = 1000
+	defaultSubscriptionName       = "telegraf_metrics_consumers"
+	receiveRetryDelay             = 5 * time.Second
+)
+
+var subscriptionTypes = map[string]pulsar.SubscriptionType{
+	"exclusive":  pulsar.Exclusive,
+	"shared":     pulsar.Shared,
+	"failover":   pulsar.Failover,
+	"key_shared": pulsar.KeyShared,
+}
+
+var initialPositions = map[string]pulsar.SubscriptionInitialPosition{
+	"earliest": pulsar.SubscriptionPositionEarliest,
+	"latest":   pulsar.SubscriptionPositionLatest,
+}
+
+type empty struct{}
+type semaphore chan empty
+
+type PulsarConsumer struct {
+	Topics                 []string        `toml:"topics"`
+	TopicsPattern          string          `toml:"topics_pattern"`
+	TopicTag               string          `toml:"topic_tag"`
+	SubscriptionName       string          `toml:"subscription_name"`
+	SubscriptionType       string          `toml:"subscription_type"`
+	InitialPosition        string          `toml:"initial_position"`
+	ReceiverQueueSize      int             `toml:"receiver_queue_size"`
+	NackRedeliveryDelay    config.Duration `toml:"nack_redelivery_delay"`
+	MaxMessageLen          int             `toml:"max_message_len"`
+	MaxUndeliveredMessages int             `toml:"max_undelivered_messages"`
+
+	pulsarcommon.Config
+
+	Log telegraf.Logger `toml:"-"`
+
+	clientOptions   pulsar.ClientOptions
+	consumerOptions pulsar.ConsumerOptions
+	newClient       func(pulsar.ClientOptions) (pulsar.Client, error)
+	client          pulsar.Client
+	consumer        pulsar.Consumer
+
+	parser parsers.Parser
+	acc    telegraf.TrackingAccumulator
+	sem    semaphore
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	undelivered map[telegraf.TrackingID]pulsar.Message
+}
+
+func (*PulsarConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PulsarConsumer) SetParser(parser parsers.Parser) {
+	p.parser = parser
+}
+
+func (p *PulsarConsumer) Init() error {
+	if p.MaxUndeliveredMessages == 0 {
+		p.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	if p.SubscriptionName == "" {
+		p.SubscriptionName = defaultSubscriptionName
+	}
+
+	if len(p.Topics) == 0 && p.TopicsPattern == "" {
+		return errors.New("topics or topics_pattern required")
+	}
+	if len(p.Topics) > 0 && p.TopicsPattern != "" {
+		return errors.New("topics and topics_pattern are mutually exclusive")
+	}
+
+	subscriptionType, found := subscriptionTypes[strings.ToLower(p.SubscriptionType)]
+	if !found {
+		return fmt.Errorf("invalid subscription type %q", p.SubscriptionType)
+	}
+	initialPosition, found := initialPositions[strings.ToLower(p.InitialPosition)]
+	if !found {
+		return fmt.Errorf("invalid initial position %q", p.InitialPosition)
+	}
+
+	var err error
+	p.clientOptions, err = p.Config.ClientOptions(p.Log)
+	if err != nil {
+		return err
+	}
+
+	p.consumerOptions = pulsar.ConsumerOptions{
+		Topics:                      p.Topics,
+		TopicsPattern:               p.TopicsPattern,
+		SubscriptionName:            p.SubscriptionName,
+		Type:                        subscriptionType,
+		SubscriptionInitialPosition: initialPosition,
+		ReceiverQueueSize:           p.ReceiverQueueSize,
+		NackRedeliveryDelay:         time.Duration(p.NackRedeliveryDelay),
+	}
+
+	if p.newClient == nil {
+		p.newClient = pulsar.NewClient
+	}
+
+	return nil
+}
+
+func (p *PulsarConsumer) Start(acc telegraf.Accumulator) error {
+	var err error
+	p.client, err = p.newClient(p.clientOptions)
+	if err != nil {
+		return fmt.Errorf("creating client failed: %w", err)
+	}
+	p.consumer, err = p.client.Subscribe(p.consumerOptions)
+	if err != nil {
+		p.client.Close()
+		return fmt.Errorf("subscribing failed: %w", err)
+	}
+
+	p.acc = acc.WithTracking(p.MaxUndeliveredMessages)
+	p.sem = make(semaphore, p.MaxUndeliveredMessages)
+	p.undelivered = make(map[telegraf.TrackingID]pulsar.Message, p.MaxUndeliveredMessages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.receive(ctx)
+	}()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case track := <-p.acc.Delivered():
+				p.onDelivery(track)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (p *PulsarConsumer) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *PulsarConsumer) Stop() {
+	p.cancel()
+	p.wg.Wait()
+
+	// Messages not delivered yet are redelivered after the subscription is
+	// closed
+	p.consumer.Close()
+	p.client.Close()
+}
+
+// receive reads the messages as long as there is an available slot for a
+// new message
+func (p *PulsarConsumer) receive(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p.sem <- empty{}:
+		}
+
+		msg, err := p.consumer.Receive(ctx)
+		if err != nil {
+			<-p.sem
+			if ctx.Err() != nil {
+				return
+			}
+			p.acc.AddError(fmt.Errorf("receiving message failed: %w", err))
+			// Ignore returned error as we cannot do anything about it anyway
+			//nolint:errcheck,revive
+			internal.SleepContext(ctx, receiveRetryDelay)
+			continue
+		}
+
+		if err := p.onMessage(msg); err != nil {
+			p.acc.AddError(err)
+		}
+	}
+}
+
+// onMessage parses the message and if successful saves it to be
+// acknowledged after delivery. Messages that cannot be processed are
+// acknowledged immediately, as redelivering them would fail again.
+func (p *PulsarConsumer) onMessage(msg pulsar.Message) error {
+	payload := msg.Payload()
+	if p.MaxMessageLen != 0 && len(payload) > p.MaxMessageLen {
+		p.ack(msg)
+		<-p.sem
+		return fmt.Errorf("message exceeds max_message_len (actual %d, max %d)",
+			len(payload), p.MaxMessageLen)
+	}
+
+	metrics, err := p.parser.Parse(payload)
+	if err != nil {
+		p.ack(msg)
+		<-p.sem
+		return err
+	}
+
+	if p.TopicTag != "" {
+		for _, metric := range metrics {
+			metric.AddTag(p.TopicTag, msg.Topic())
+		}
+	}
+
+	p.mu.Lock()
+	id := p.acc.AddTrackingMetricGroup(metrics)
+	p.undelivered[id] = msg
+	p.mu.Unlock()
+	return nil
+}
+
+// onDelivery acknowledges delivered messages, messages rejected by an output
+// are negatively acknowledged to be redelivered
+func (p *PulsarConsumer) onDelivery(track telegraf.DeliveryInfo) {
+	p.mu.Lock()
+	msg, ok := p.undelivered[track.ID()]
+	delete(p.undelivered, track.ID())
+	p.mu.Unlock()
+
+	if !ok {
+		p.Log.Errorf("Could not mark message delivered: %d", track.ID())
+		return
+	}
+
+	if track.Delivered() {
+		p.ack(msg)
+	} else {
+		p.consumer.Nack(msg)
+	}
+	<-p.sem
+}
+
+func (p *PulsarConsumer) ack(msg pulsar.Message) {
+	if err := p.consumer.Ack(msg); err != nil {
+		p.Log.Errorf("Acknowledging message %v failed: %v", msg.ID(), err)
+	}
+}
+
+func init() {
+	inputs.Add("pulsar_consumer", func() telegraf.Input {
+		return &PulsarConsumer{
+			SubscriptionType: "shared",
+			InitialPosition:  "latest",
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package pulsar_consumer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/models"
+	pulsarcommon "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type fakeMessage struct {
+	pulsar.Message
+	id      int
+	topic   string
+	payload []byte
+}
+
+func (m *fakeMessage) Topic() string {
+	return m.topic
+}
+
+func (m *fakeMessage) Payload() []byte {
+	return m.payload
+}
+
+func (m *fakeMessage) ID() pulsar.MessageID {
+	return nil
+}
+
+type fakeConsumer struct {
+	pulsar.Consumer
+	sync.Mutex
+	messages chan pulsar.Message
+	acked    []int
+	nacked   []int
+	closed   bool
+}
+
+func (c *fakeConsumer) Receive(ctx context.Context) (pulsar.Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg := <-c.messages:
+		return msg, nil
+	}
+}
+
+func (c *fakeConsumer) Ack(msg pulsar.Message) error {
+	c.Lock()
+	defer c.Unlock()
+	c.acked = append(c.acked, msg.(*fakeMessage).id)
+	return nil
+}
+
+func (c *fakeConsumer) Nack(msg pulsar.Message) {
+	c.Lock()
+	defer c.Unlock()
+	c.nacked = append(c.nacked, msg.(*fakeMessage).id)
+}
+
+func (c *fakeConsumer) Close() {
+	c.Lock()
+	defer c.Unlock()
+	c.closed = true
+}
+
+func (c *fakeConsumer) ackedIDs() []int {
+	c.Lock()
+	defer c.Unlock()
+	return append([]int(nil), c.acked...)
+}
+
+func (c *fakeConsumer) nackedIDs() []int {
+	c.Lock()
+	defer c.Unlock()
+	return append([]int(nil), c.nacked...)
+}
+
+type fakeClient struct {
+	pulsar.Client
+	consumer *fakeConsumer
+	options  pulsar.ConsumerOptions
+	closed   bool
+}
+
+func (c *fakeClient) Subscribe(options pulsar.ConsumerOptions) (pulsar.Consumer, error) {
+	c.options = options
+	return c.consumer, nil
+}
+
+func (c *fakeClient) Close() {
+	c.closed = true
+}
+
+type testMetricMaker struct{}
+
+func (tm *testMetricMaker) Name() string {
+	return "TestPlugin"
+}
+
+func (tm *testMetricMaker) LogName() string {
+	return tm.Name()
+}
+
+func (tm *testMetricMaker) MakeMetric(metric telegraf.Metric) telegraf.Metric {
+	return metric
+}
+
+func (tm *testMetricMaker) Log() telegraf.Logger {
+	return models.NewLogger("test", "test", "")
+}
+
+func newTestPlugin(t *testing.T, client *fakeClient) *PulsarConsumer {
+	plugin := &PulsarConsumer{
+		Topics:           []string{"persistent://public/default/telegraf"},
+		TopicTag:         "topic",
+		SubscriptionType: "key_shared",
+		InitialPosition:  "earliest",
+		MaxMessageLen:    100,
+		Config: pulsarcommon.Config{
+			ServiceURL: "pulsar://localhost:6650",
+		},
+		Log: testutil.Logger{},
+		newClient: func(pulsar.ClientOptions) (pulsar.Client, error) {
+			return client, nil
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+
+	return plugin
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *PulsarConsumer
+		errmsg string
+	}{
+		{
+			name:   "missing topics",
+			plugin: &PulsarConsumer{},
+			errmsg: "topics or topics_pattern required",
+		},
+		{
+			name: "topics and pattern",
+			plugin: &PulsarConsumer{
+				Topics:        []string{"telegraf"},
+				TopicsPattern: "telegraf-.*",
+			},
+			errmsg: "topics and topics_pattern are mutually exclusive",
+		},
+		{
+			name: "invalid subscription type",
+			plugin: &PulsarConsumer{
+				Topics:           []string{"telegraf"},
+				SubscriptionType: "round_robin",
+				InitialPosition:  "latest",
+			},
+			errmsg: `invalid subscription type "round_robin"`,
+		},
+		{
+			name: "invalid initial position",
+			plugin: &PulsarConsumer{
+				Topics:           []string{"telegraf"},
+				SubscriptionType: "shared",
+				InitialPosition:  "oldest",
+			},
+			errmsg: `invalid initial position "oldest"`,
+		},
+		{
+			name: "missing service url",
+			plugin: &PulsarConsumer{
+				Topics:           []string{"telegraf"},
+				SubscriptionType: "shared",
+				InitialPosition:  "latest",
+			},
+			errmsg: "service_url required",
+		},
+		{
+			name: "multiple authentication methods",
+			plugin: &PulsarConsumer{
+				Topics:           []string{"telegraf"},
+				SubscriptionType: "shared",
+				InitialPosition:  "latest",
+				Config: pulsarcommon.Config{
+					ServiceURL:    "pulsar://localhost:6650",
+					AuthToken:     "token",
+					AuthTokenFile: "/etc/telegraf/token",
+				},
+			},
+			errmsg: "mutually exclusive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.errmsg)
+		})
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	client := &fakeClient{consumer: &fakeConsumer{messages: make(chan pulsar.Message)}}
+	plugin := newTestPlugin(t, client)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	plugin.Stop()
+
+	require.Equal(t, []string{"persistent://public/default/telegraf"}, client.options.Topics)
+	require.Equal(t, "telegraf_metrics_consumers", client.options.SubscriptionName)
+	require.Equal(t, pulsar.KeyShared, client.options.Type)
+	require.Equal(t, pulsar.SubscriptionPositionEarliest, client.options.SubscriptionInitialPosition)
+	require.True(t, client.consumer.closed)
+	require.True(t, client.closed)
+}
+
+func TestAckAfterDelivery(t *testing.T) {
+	consumer := &fakeConsumer{messages: make(chan pulsar.Message, 10)}
+	plugin := newTestPlugin(t, &fakeClient{consumer: consumer})
+
+	dst := make(chan telegraf.Metric, 10)
+	acc := agent.NewAccumulator(&testMetricMaker{}, dst)
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	topic := "persistent://public/default/telegraf"
+	consumer.messages <- &fakeMessage{id: 1, topic: topic, payload: []byte("cpu value=42i 0")}
+	consumer.messages <- &fakeMessage{id: 2, topic: topic, payload: []byte("mem value=23i 0")}
+	consumer.messages <- &fakeMessage{id: 3, topic: topic, payload: []byte("invalid")}
+	consumer.messages <- &fakeMessage{id: 4, topic: topic, payload: make([]byte, 101)}
+
+	// Invalid and oversized messages are acknowledged right away
+	require.Eventually(t, func() bool {
+		return len(consumer.ackedIDs()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.ElementsMatch(t, []int{3, 4}, consumer.ackedIDs())
+
+	var metrics []telegraf.Metric
+	for len(metrics) < 2 {
+		select {
+		case m := <-dst:
+			metrics = append(metrics, m)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for metrics")
+		}
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"topic": topic}, map[string]interface{}{"value": int64(42)}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{"topic": topic}, map[string]interface{}{"value": int64(23)}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	// Rejected metrics are negatively acknowledged for redelivery
+	metrics[0].Accept()
+	metrics[1].Reject()
+	require.Eventually(t, func() bool {
+		return len(consumer.ackedIDs()) == 3 && len(consumer.nackedIDs()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.ElementsMatch(t, []int{1, 3, 4}, consumer.ackedIDs())
+	require.Equal(t, []int{2}, consumer.nackedIDs())
+}
+
+func TestMaxUndeliveredMessages(t *testing.T) {
+	consumer := &fakeConsumer{messages: make(chan pulsar.Message, 10)}
+	plugin := newTestPlugin(t, &fakeClient{consumer: consumer})
+	plugin.MaxUndeliveredMessages = 1
+
+	dst := make(chan telegraf.Metric, 10)
+	acc := agent.NewAccumulator(&testMetricMaker{}, dst)
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	consumer.messages <- &fakeMessage{id: 1, payload: []byte("cpu value=42i 0")}
+	consumer.messages <- &fakeMessage{id: 2, payload: []byte("mem value=23i 0")}
+
+	// The second message is only received after the first is delivered
+	var m telegraf.Metric
+	select {
+	case m = <-dst:
+		require.Equal(t, "cpu", m.Name())
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for metric")
+	}
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, dst)
+
+	m.Accept()
+	select {
+	case m = <-dst:
+		require.Equal(t, "mem", m.Name())
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for metric")
+	}
+}
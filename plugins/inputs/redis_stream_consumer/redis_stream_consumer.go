@@ -0,0 +1,499 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package redis_stream_consumer
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	defaultMaxUndeliveredMessages = 1000
+)
+
+type empty struct{}
+type semaphore chan empty
+
+// entry identifies a stream entry waiting for its metrics to be delivered
+type entry struct {
+	stream string
+	id     string
+}
+
+// streamClient contains the subset of redis commands used by the plugin
+type streamClient interface {
+	XGroupCreateMkStream(ctx context.Context, stream, group, start string) error
+	XReadGroup(ctx context.Context, args *redis.XReadGroupArgs) ([]redis.XStream, error)
+	XAck(ctx context.Context, stream, group string, ids ...string) error
+	XPendingExt(ctx context.Context, args *redis.XPendingExtArgs) ([]redis.XPendingExt, error)
+	XClaim(ctx context.Context, args *redis.XClaimArgs) ([]redis.XMessage, error)
+	Close() error
+}
+
+type RedisStreamConsumer struct {
+	Server   string `toml:"server"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	tls.ClientConfig
+
+	Streams   []string `toml:"streams"`
+	Group     string   `toml:"group"`
+	Consumer  string   `toml:"consumer"`
+	StartID   string   `toml:"start_id"`
+	DataField string   `toml:"data_field"`
+	StreamTag string   `toml:"stream_tag"`
+
+	BatchSize              int64           `toml:"batch_size"`
+	BlockTimeout           config.Duration `toml:"block_timeout"`
+	MaxUndeliveredMessages int             `toml:"max_undelivered_messages"`
+
+	ClaimMinIdle  config.Duration `toml:"claim_min_idle"`
+	ClaimInterval config.Duration `toml:"claim_interval"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	client      streamClient
+	parser      telegraf.Parser
+	acc         telegraf.TrackingAccumulator
+	sem         semaphore
+	undelivered map[telegraf.TrackingID]entry
+	mu          sync.Mutex
+	wg          sync.WaitGroup
+	cancel      context.CancelFunc
+}
+
+func (*RedisStreamConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RedisStreamConsumer) SetParser(parser telegraf.Parser) {
+	r.parser = parser
+}
+
+func (r *RedisStreamConsumer) Init() error {
+	if len(r.Streams) == 0 {
+		return errors.New("at least one stream is required")
+	}
+	if r.Group == "" {
+		return errors.New("group must be set")
+	}
+	if r.DataField == "" {
+		return errors.New("data_field must be set")
+	}
+
+	if r.Consumer == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("determining consumer name failed: %w", err)
+		}
+		r.Consumer = hostname
+	}
+
+	if r.MaxUndeliveredMessages <= 0 {
+		r.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	if r.BatchSize <= 0 {
+		r.BatchSize = 100
+	}
+	if r.BatchSize > int64(r.MaxUndeliveredMessages) {
+		r.BatchSize = int64(r.MaxUndeliveredMessages)
+	}
+	// Blocking without timeout would prevent the plugin from stopping
+	if r.BlockTimeout <= 0 {
+		r.BlockTimeout = config.Duration(time.Second)
+	}
+	if r.ClaimInterval <= 0 {
+		r.ClaimInterval = config.Duration(time.Minute)
+	}
+
+	return nil
+}
+
+func (r *RedisStreamConsumer) Start(acc telegraf.Accumulator) error {
+	if r.client == nil {
+		client, err := r.connect()
+		if err != nil {
+			return err
+		}
+		r.client = client
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	// Create the consumer group, a group existing already is fine
+	for _, stream := range r.Streams {
+		err := r.client.XGroupCreateMkStream(ctx, stream, r.Group, r.StartID)
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			cancel()
+			return fmt.Errorf("creating group %q for stream %q failed: %w", r.Group, stream, err)
+		}
+	}
+
+	r.acc = acc.WithTracking(r.MaxUndeliveredMessages)
+	r.sem = make(semaphore, r.MaxUndeliveredMessages)
+	r.undelivered = make(map[telegraf.TrackingID]entry)
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.onDelivery(ctx)
+	}()
+	go func() {
+		defer r.wg.Done()
+		r.receiver(ctx)
+	}()
+
+	return nil
+}
+
+func (r *RedisStreamConsumer) Stop() {
+	r.cancel()
+	r.wg.Wait()
+	if err := r.client.Close(); err != nil {
+		r.Log.Errorf("Closing connection failed: %v", err)
+	}
+	r.client = nil
+}
+
+// All gathering is done in the Start function
+func (r *RedisStreamConsumer) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (r *RedisStreamConsumer) connect() (streamClient, error) {
+	server := r.Server
+	if !strings.HasPrefix(server, "tcp://") && !strings.HasPrefix(server, "unix://") {
+		server = "tcp://" + server
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse address %q: %w", server, err)
+	}
+
+	username := r.Username
+	password := r.Password
+	if u.User != nil {
+		if username == "" {
+			username = u.User.Username()
+		}
+		if pw, ok := u.User.Password(); ok && password == "" {
+			password = pw
+		}
+	}
+
+	address := u.Host
+	if u.Scheme == "unix" {
+		address = u.Path
+	}
+
+	tlsConfig, err := r.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:      address,
+		Username:  username,
+		Password:  password,
+		Network:   u.Scheme,
+		TLSConfig: tlsConfig,
+	})
+	return &redisClient{client}, nil
+}
+
+// receiver reads new entries from the streams and periodically claims the
+// entries pending for too long, e.g. because their consumer died
+func (r *RedisStreamConsumer) receiver(ctx context.Context) {
+	streams := make([]string, 0, 2*len(r.Streams))
+	streams = append(streams, r.Streams...)
+	for range r.Streams {
+		streams = append(streams, ">")
+	}
+
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		if r.ClaimMinIdle > 0 && time.Since(lastClaim) >= time.Duration(r.ClaimInterval) {
+			r.claim(ctx)
+			lastClaim = time.Now()
+		}
+
+		result, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.Group,
+			Consumer: r.Consumer,
+			Streams:  streams,
+			Count:    r.BatchSize,
+			Block:    time.Duration(r.BlockTimeout),
+		})
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			r.acc.AddError(fmt.Errorf("reading from streams failed: %w", err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, stream := range result {
+			for _, msg := range stream.Messages {
+				if !r.process(ctx, stream.Stream, msg) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// claim takes over the entries pending longer than the configured idle time
+// and processes them again
+func (r *RedisStreamConsumer) claim(ctx context.Context) {
+	for _, stream := range r.Streams {
+		if !r.claimStream(ctx, stream) {
+			return
+		}
+	}
+}
+
+// claimStream pages through all pending entries of the stream in batches.
+// Returns false if the context was cancelled.
+func (r *RedisStreamConsumer) claimStream(ctx context.Context, stream string) bool {
+	start := "-"
+	for ctx.Err() == nil {
+		pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  r.Group,
+			Start:  start,
+			End:    "+",
+			Count:  r.BatchSize,
+		})
+		if err != nil {
+			r.acc.AddError(fmt.Errorf("listing pending entries of stream %q failed: %w", stream, err))
+			return true
+		}
+
+		ids := make([]string, 0, len(pending))
+		for _, p := range pending {
+			if p.Idle < time.Duration(r.ClaimMinIdle) {
+				continue
+			}
+			// Do not claim entries still waiting for delivery by ourselves
+			if p.Consumer == r.Consumer && r.isUndelivered(stream, p.ID) {
+				continue
+			}
+			ids = append(ids, p.ID)
+		}
+
+		if len(ids) > 0 {
+			msgs, err := r.client.XClaim(ctx, &redis.XClaimArgs{
+				Stream:   stream,
+				Group:    r.Group,
+				Consumer: r.Consumer,
+				MinIdle:  time.Duration(r.ClaimMinIdle),
+				Messages: ids,
+			})
+			if err != nil {
+				r.acc.AddError(fmt.Errorf("claiming entries of stream %q failed: %w", stream, err))
+				return true
+			}
+			r.Log.Debugf("Claimed %d pending entries of stream %q", len(msgs), stream)
+
+			for _, msg := range msgs {
+				if !r.process(ctx, stream, msg) {
+					return false
+				}
+			}
+		}
+
+		if int64(len(pending)) < r.BatchSize {
+			return true
+		}
+
+		// Continue after the last entry, the exclusive range syntax requires
+		// Redis 6.2 so compute the following ID instead
+		start, err = nextID(pending[len(pending)-1].ID)
+		if err != nil {
+			r.acc.AddError(fmt.Errorf("paging pending entries of stream %q failed: %w", stream, err))
+			return true
+		}
+	}
+	return false
+}
+
+// nextID returns the smallest stream entry ID following the given one
+func nextID(id string) (string, error) {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid entry ID %q", id)
+	}
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid entry ID %q: %w", id, err)
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid entry ID %q: %w", id, err)
+	}
+	if seq == math.MaxUint64 {
+		return strconv.FormatUint(ms+1, 10) + "-0", nil
+	}
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq+1, 10), nil
+}
+
+// process parses the given entry and adds the resulting metrics as tracking
+// group. Entries that cannot be parsed are acknowledged right away so they
+// are not claimed over and over again. Returns false if the context was
+// cancelled while waiting for the delivery of previous metrics.
+func (r *RedisStreamConsumer) process(ctx context.Context, stream string, msg redis.XMessage) bool {
+	value, found := msg.Values[r.DataField]
+	if !found {
+		r.Log.Errorf("Entry %s of stream %q has no field %q", msg.ID, stream, r.DataField)
+		r.ack(ctx, stream, msg.ID)
+		return true
+	}
+	data, ok := value.(string)
+	if !ok {
+		data = fmt.Sprint(value)
+	}
+
+	metrics, err := r.parser.Parse([]byte(data))
+	if err != nil {
+		r.Log.Errorf("Parsing entry %s of stream %q failed: %v", msg.ID, stream, err)
+		r.ack(ctx, stream, msg.ID)
+		return true
+	}
+	if len(metrics) == 0 {
+		r.ack(ctx, stream, msg.ID)
+		return true
+	}
+
+	if r.StreamTag != "" {
+		for _, m := range metrics {
+			m.AddTag(r.StreamTag, stream)
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case r.sem <- empty{}:
+	}
+
+	r.mu.Lock()
+	id := r.acc.AddTrackingMetricGroup(metrics)
+	r.undelivered[id] = entry{stream: stream, id: msg.ID}
+	r.mu.Unlock()
+
+	return true
+}
+
+// onDelivery acknowledges the entries whose metrics were delivered
+// successfully. Entries with failed deliveries stay pending and are
+// claimed again after the idle time.
+func (r *RedisStreamConsumer) onDelivery(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case info := <-r.acc.Delivered():
+			r.mu.Lock()
+			e, found := r.undelivered[info.ID()]
+			delete(r.undelivered, info.ID())
+			r.mu.Unlock()
+
+			if !found {
+				r.Log.Errorf("Could not find entry for delivered metrics %d", info.ID())
+				continue
+			}
+			<-r.sem
+
+			if info.Delivered() {
+				r.ack(ctx, e.stream, e.id)
+			}
+		}
+	}
+}
+
+func (r *RedisStreamConsumer) isUndelivered(stream, id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.undelivered {
+		if e.stream == stream && e.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RedisStreamConsumer) ack(ctx context.Context, stream, id string) {
+	if err := r.client.XAck(ctx, stream, r.Group, id); err != nil && ctx.Err() == nil {
+		r.acc.AddError(fmt.Errorf("acknowledging entry %s of stream %q failed: %w", id, stream, err))
+	}
+}
+
+// redisClient adapts the redis client to the streamClient interface
+type redisClient struct {
+	client *redis.Client
+}
+
+func (c *redisClient) XGroupCreateMkStream(ctx context.Context, stream, group, start string) error {
+	return c.client.XGroupCreateMkStream(ctx, stream, group, start).Err()
+}
+
+func (c *redisClient) XReadGroup(ctx context.Context, args *redis.XReadGroupArgs) ([]redis.XStream, error) {
+	return c.client.XReadGroup(ctx, args).Result()
+}
+
+func (c *redisClient) XAck(ctx context.Context, stream, group string, ids ...string) error {
+	return c.client.XAck(ctx, stream, group, ids...).Err()
+}
+
+func (c *redisClient) XPendingExt(ctx context.Context, args *redis.XPendingExtArgs) ([]redis.XPendingExt, error) {
+	return c.client.XPendingExt(ctx, args).Result()
+}
+
+func (c *redisClient) XClaim(ctx context.Context, args *redis.XClaimArgs) ([]redis.XMessage, error) {
+	return c.client.XClaim(ctx, args).Result()
+}
+
+func (c *redisClient) Close() error {
+	return c.client.Close()
+}
+
+func init() {
+	inputs.Add("redis_stream_consumer", func() telegraf.Input {
+		return &RedisStreamConsumer{
+			Server:                 "tcp://localhost:6379",
+			Group:                  "telegraf",
+			StartID:                "$",
+			DataField:              "data",
+			BatchSize:              100,
+			BlockTimeout:           config.Duration(time.Second),
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+			ClaimMinIdle:           config.Duration(5 * time.Minute),
+			ClaimInterval:          config.Duration(time.Minute),
+		}
+	})
+}
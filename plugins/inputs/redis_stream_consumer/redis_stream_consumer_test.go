@@ -0,0 +1,369 @@
+package redis_stream_consumer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type fakePending struct {
+	consumer string
+	since    time.Time
+}
+
+type fakeStream struct {
+	entries       []redis.XMessage
+	lastDelivered int
+	pending       map[string]*fakePending
+}
+
+// fakeClient is an in-memory implementation of the stream commands of a
+// single consumer group
+type fakeClient struct {
+	streams map[string]*fakeStream
+	acked   []string
+	seq     int
+	sync.Mutex
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{streams: make(map[string]*fakeStream)}
+}
+
+func (c *fakeClient) add(stream string, values map[string]interface{}) string {
+	c.Lock()
+	defer c.Unlock()
+
+	s, found := c.streams[stream]
+	if !found {
+		s = &fakeStream{pending: make(map[string]*fakePending)}
+		c.streams[stream] = s
+	}
+	c.seq++
+	id := fmt.Sprintf("%d-0", c.seq)
+	s.entries = append(s.entries, redis.XMessage{ID: id, Values: values})
+	return id
+}
+
+func (c *fakeClient) ackedIDs() []string {
+	c.Lock()
+	defer c.Unlock()
+	ids := append([]string{}, c.acked...)
+	sort.Strings(ids)
+	return ids
+}
+
+func (c *fakeClient) XGroupCreateMkStream(_ context.Context, stream, _, _ string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, found := c.streams[stream]; !found {
+		c.streams[stream] = &fakeStream{pending: make(map[string]*fakePending)}
+	}
+	return nil
+}
+
+func (c *fakeClient) XReadGroup(ctx context.Context, args *redis.XReadGroupArgs) ([]redis.XStream, error) {
+	c.Lock()
+	var result []redis.XStream
+	for _, name := range args.Streams[:len(args.Streams)/2] {
+		s := c.streams[name]
+		var msgs []redis.XMessage
+		for s.lastDelivered < len(s.entries) && int64(len(msgs)) < args.Count {
+			msg := s.entries[s.lastDelivered]
+			s.pending[msg.ID] = &fakePending{consumer: args.Consumer, since: time.Now()}
+			msgs = append(msgs, msg)
+			s.lastDelivered++
+		}
+		if len(msgs) > 0 {
+			result = append(result, redis.XStream{Stream: name, Messages: msgs})
+		}
+	}
+	c.Unlock()
+
+	if len(result) == 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(args.Block):
+		}
+		return nil, redis.Nil
+	}
+	return result, nil
+}
+
+func (c *fakeClient) XAck(_ context.Context, stream, _ string, ids ...string) error {
+	c.Lock()
+	defer c.Unlock()
+	for _, id := range ids {
+		delete(c.streams[stream].pending, id)
+		c.acked = append(c.acked, id)
+	}
+	return nil
+}
+
+func (c *fakeClient) XPendingExt(_ context.Context, args *redis.XPendingExtArgs) ([]redis.XPendingExt, error) {
+	c.Lock()
+	defer c.Unlock()
+	s := c.streams[args.Stream]
+	var result []redis.XPendingExt
+	for _, msg := range s.entries {
+		p, found := s.pending[msg.ID]
+		if !found || (args.Start != "-" && compareIDs(msg.ID, args.Start) < 0) {
+			continue
+		}
+		if int64(len(result)) == args.Count {
+			break
+		}
+		result = append(result, redis.XPendingExt{ID: msg.ID, Consumer: p.consumer, Idle: time.Since(p.since)})
+	}
+	return result, nil
+}
+
+// compareIDs compares stream entry IDs of the form ms-seq
+func compareIDs(a, b string) int {
+	var ams, aseq, bms, bseq uint64
+	//nolint:errcheck,revive // The IDs are generated by the fake
+	fmt.Sscanf(a, "%d-%d", &ams, &aseq)
+	//nolint:errcheck,revive // The IDs are generated by the fake
+	fmt.Sscanf(b, "%d-%d", &bms, &bseq)
+	if ams != bms {
+		if ams < bms {
+			return -1
+		}
+		return 1
+	}
+	if aseq != bseq {
+		if aseq < bseq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func (c *fakeClient) XClaim(_ context.Context, args *redis.XClaimArgs) ([]redis.XMessage, error) {
+	c.Lock()
+	defer c.Unlock()
+	s := c.streams[args.Stream]
+	var result []redis.XMessage
+	for _, id := range args.Messages {
+		p, found := s.pending[id]
+		if !found || time.Since(p.since) < args.MinIdle {
+			continue
+		}
+		p.consumer = args.Consumer
+		p.since = time.Now()
+		for _, msg := range s.entries {
+			if msg.ID == id {
+				result = append(result, msg)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeClient) Close() error {
+	return nil
+}
+
+type testMetricMaker struct{}
+
+func (tm *testMetricMaker) Name() string {
+	return "TestPlugin"
+}
+
+func (tm *testMetricMaker) LogName() string {
+	return tm.Name()
+}
+
+func (tm *testMetricMaker) MakeMetric(metric telegraf.Metric) telegraf.Metric {
+	return metric
+}
+
+func (tm *testMetricMaker) Log() telegraf.Logger {
+	return models.NewLogger("test", "test", "")
+}
+
+func newTestPlugin(t *testing.T, client streamClient) *RedisStreamConsumer {
+	plugin := &RedisStreamConsumer{
+		Streams:      []string{"telegraf"},
+		Group:        "telegraf",
+		Consumer:     "test",
+		StartID:      "$",
+		DataField:    "data",
+		StreamTag:    "stream",
+		BlockTimeout: config.Duration(10 * time.Millisecond),
+		Log:          testutil.Logger{},
+		client:       client,
+	}
+	require.NoError(t, plugin.Init())
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+
+	return plugin
+}
+
+func TestInit(t *testing.T) {
+	plugin := &RedisStreamConsumer{Group: "telegraf", DataField: "data"}
+	require.ErrorContains(t, plugin.Init(), "at least one stream")
+
+	plugin = &RedisStreamConsumer{Streams: []string{"telegraf"}, DataField: "data"}
+	require.ErrorContains(t, plugin.Init(), "group must be set")
+
+	plugin = &RedisStreamConsumer{Streams: []string{"telegraf"}, Group: "telegraf", DataField: "data"}
+	require.NoError(t, plugin.Init())
+	require.NotEmpty(t, plugin.Consumer)
+	require.Equal(t, int64(100), plugin.BatchSize)
+	require.Equal(t, config.Duration(time.Second), plugin.BlockTimeout)
+}
+
+func TestAckAfterDelivery(t *testing.T) {
+	client := newFakeClient()
+	plugin := newTestPlugin(t, client)
+
+	dst := make(chan telegraf.Metric, 10)
+	acc := agent.NewAccumulator(&testMetricMaker{}, dst)
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	id1 := client.add("telegraf", map[string]interface{}{"data": "cpu value=42i 0"})
+	id2 := client.add("telegraf", map[string]interface{}{"data": "mem value=23i 0"})
+	id3 := client.add("telegraf", map[string]interface{}{"other": "mem value=23i 0"})
+	id4 := client.add("telegraf", map[string]interface{}{"data": "invalid"})
+
+	// Entries without data or with invalid data are acknowledged right away
+	require.Eventually(t, func() bool {
+		return len(client.ackedIDs()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.ElementsMatch(t, []string{id3, id4}, client.ackedIDs())
+
+	var metrics []telegraf.Metric
+	for len(metrics) < 2 {
+		select {
+		case m := <-dst:
+			metrics = append(metrics, m)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for metrics")
+		}
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"stream": "telegraf"}, map[string]interface{}{"value": int64(42)}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{"stream": "telegraf"}, map[string]interface{}{"value": int64(23)}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	// Rejected metrics must not be acknowledged
+	metrics[0].Accept()
+	metrics[1].Reject()
+	require.Eventually(t, func() bool {
+		return len(client.ackedIDs()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.ElementsMatch(t, []string{id1, id3, id4}, client.ackedIDs())
+	client.Lock()
+	require.Contains(t, client.streams["telegraf"].pending, id2)
+	client.Unlock()
+}
+
+func TestClaimPending(t *testing.T) {
+	client := newFakeClient()
+	plugin := newTestPlugin(t, client)
+	plugin.ClaimMinIdle = config.Duration(time.Millisecond)
+	plugin.ClaimInterval = config.Duration(10 * time.Millisecond)
+
+	// Simulate an entry delivered to a consumer that died afterwards
+	id := client.add("telegraf", map[string]interface{}{"data": "cpu value=42i 0"})
+	stream := client.streams["telegraf"]
+	stream.lastDelivered = 1
+	stream.pending[id] = &fakePending{consumer: "dead", since: time.Now().Add(-time.Hour)}
+
+	dst := make(chan telegraf.Metric, 10)
+	acc := agent.NewAccumulator(&testMetricMaker{}, dst)
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	select {
+	case m := <-dst:
+		require.Equal(t, "cpu", m.Name())
+		m.Accept()
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for metric")
+	}
+
+	require.Eventually(t, func() bool {
+		return len(client.ackedIDs()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{id}, client.ackedIDs())
+}
+
+func TestClaimPendingPages(t *testing.T) {
+	client := newFakeClient()
+	plugin := newTestPlugin(t, client)
+	plugin.BatchSize = 2
+	plugin.ClaimMinIdle = config.Duration(time.Minute)
+	plugin.ClaimInterval = config.Duration(10 * time.Millisecond)
+
+	// The first batch of pending entries belongs to a live consumer, the
+	// entries of the dead consumer are only found on the following pages
+	stream := &fakeStream{pending: make(map[string]*fakePending)}
+	client.streams["telegraf"] = stream
+	for i := 0; i < 2; i++ {
+		id := client.add("telegraf", map[string]interface{}{"data": "mem value=23i 0"})
+		stream.pending[id] = &fakePending{consumer: "alive", since: time.Now()}
+	}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id := client.add("telegraf", map[string]interface{}{"data": "cpu value=42i 0"})
+		stream.pending[id] = &fakePending{consumer: "dead", since: time.Now().Add(-time.Hour)}
+		ids = append(ids, id)
+	}
+	stream.lastDelivered = len(stream.entries)
+
+	dst := make(chan telegraf.Metric, 10)
+	acc := agent.NewAccumulator(&testMetricMaker{}, dst)
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	for i := 0; i < len(ids); i++ {
+		select {
+		case m := <-dst:
+			require.Equal(t, "cpu", m.Name())
+			m.Accept()
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for metric")
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		return len(client.ackedIDs()) == len(ids)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.ElementsMatch(t, ids, client.ackedIDs())
+}
+
+func TestNextID(t *testing.T) {
+	id, err := nextID("1526569495631-0")
+	require.NoError(t, err)
+	require.Equal(t, "1526569495631-1", id)
+
+	id, err = nextID("1526569495631-18446744073709551615")
+	require.NoError(t, err)
+	require.Equal(t, "1526569495632-0", id)
+
+	_, err = nextID("invalid")
+	require.ErrorContains(t, err, `invalid entry ID "invalid"`)
+}
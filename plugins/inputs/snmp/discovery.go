@@ -0,0 +1,408 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/snmp"
+)
+
+// sysObjectIDOid is the OID of SNMPv2-MIB::sysObjectID.0 used to identify
+// the vendor and model of a device during discovery.
+const sysObjectIDOid = ".1.3.6.1.2.1.1.2.0"
+
+// maxDiscoveryHosts limits the number of addresses a single network may
+// expand to, so a typo in a prefix length does not start a sweep of millions
+// of hosts.
+const maxDiscoveryHosts = 65536
+
+// Discovery holds the configuration for finding SNMP agents by sweeping
+// networks with a sysObjectID probe.
+type Discovery struct {
+	// Networks to sweep in CIDR notation, e.g. "10.0.0.0/24".
+	Networks []string `toml:"networks"`
+	// Transport and port used for the probe and for gathering from the
+	// discovered agents.
+	Transport string `toml:"transport"`
+	Port      uint16 `toml:"port"`
+	// Interval at which the networks are swept again.
+	RefreshInterval config.Duration `toml:"refresh_interval"`
+	// Timeout for a single probe. Probes are not retried.
+	Timeout config.Duration `toml:"timeout"`
+	// Number of hosts probed in parallel.
+	MaxConcurrency int `toml:"max_concurrency"`
+	// Profiles select the fields and tables gathered from a device based on
+	// its sysObjectID.
+	Profiles []Profile `toml:"profile"`
+}
+
+// Profile is a template of fields and tables applied to discovered devices
+// whose sysObjectID starts with one of the given prefixes.
+type Profile struct {
+	Name string `toml:"name"`
+	// SysObjectIDs are the OID prefixes matched against the sysObjectID of a
+	// device. A profile without prefixes matches any device not matched by
+	// another profile.
+	SysObjectIDs []string `toml:"sys_object_ids"`
+
+	Tables []Table `toml:"table"`
+	Fields []Field `toml:"field"`
+}
+
+// discoveredAgent is an agent found during a network sweep along with the
+// profile that matched it.
+type discoveredAgent struct {
+	agent   string
+	profile *Profile
+	conn    snmpConnection
+}
+
+func (d *Discovery) enabled() bool {
+	return len(d.Networks) > 0
+}
+
+func (d *Discovery) init(tr Translator) error {
+	if !d.enabled() {
+		return nil
+	}
+
+	if d.Transport == "" {
+		d.Transport = "udp"
+	}
+	if d.Port == 0 {
+		d.Port = 161
+	}
+	if d.RefreshInterval == 0 {
+		d.RefreshInterval = config.Duration(time.Hour)
+	}
+	if d.Timeout == 0 {
+		d.Timeout = config.Duration(time.Second)
+	}
+	if d.MaxConcurrency <= 0 {
+		d.MaxConcurrency = 64
+	}
+
+	for _, network := range d.Networks {
+		if _, err := expandNetwork(network); err != nil {
+			return err
+		}
+	}
+
+	if len(d.Profiles) == 0 {
+		return errors.New("discovery requires at least one profile")
+	}
+
+	for i := range d.Profiles {
+		p := &d.Profiles[i]
+		if p.Name == "" {
+			return fmt.Errorf("discovery profile %d is not named", i)
+		}
+		for j, prefix := range p.SysObjectIDs {
+			oid, err := translateOidPrefix(tr, prefix)
+			if err != nil {
+				return fmt.Errorf("profile %s: translating sys_object_id %q: %w", p.Name, prefix, err)
+			}
+			p.SysObjectIDs[j] = oid
+		}
+		for j := range p.Tables {
+			if err := p.Tables[j].Init(tr); err != nil {
+				return fmt.Errorf("profile %s: initializing table %s: %w", p.Name, p.Tables[j].Name, err)
+			}
+		}
+		for j := range p.Fields {
+			if err := p.Fields[j].init(tr); err != nil {
+				return fmt.Errorf("profile %s: initializing field %s: %w", p.Name, p.Fields[j].Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// match returns the profile with the longest sysObjectID prefix matching the
+// given OID, falling back to the first profile without any prefix.
+func (d *Discovery) match(sysObjectID string) *Profile {
+	var best, fallback *Profile
+	bestLen := -1
+	for i := range d.Profiles {
+		p := &d.Profiles[i]
+		if len(p.SysObjectIDs) == 0 {
+			if fallback == nil {
+				fallback = p
+			}
+			continue
+		}
+		for _, prefix := range p.SysObjectIDs {
+			if sysObjectID != prefix && !strings.HasPrefix(sysObjectID, prefix+".") {
+				continue
+			}
+			if len(prefix) > bestLen {
+				best = p
+				bestLen = len(prefix)
+			}
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return fallback
+}
+
+// translateOidPrefix converts a textual OID into its numeric form with a
+// leading dot.
+func translateOidPrefix(tr Translator, oid string) (string, error) {
+	if strings.ContainsAny(oid, ":abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+		_, oidNum, _, _, err := tr.SnmpTranslate(oid)
+		if err != nil {
+			return "", err
+		}
+		oid = oidNum
+	}
+	if !strings.HasPrefix(oid, ".") {
+		oid = "." + oid
+	}
+	return oid, nil
+}
+
+// expandNetwork returns all host addresses of the given CIDR network. The
+// network and broadcast addresses of IPv4 networks larger than /31 are
+// omitted.
+func expandNetwork(network string) ([]net.IP, error) {
+	ip, ipnet, err := net.ParseCIDR(network)
+	if err != nil {
+		return nil, fmt.Errorf("parsing network %q: %w", network, err)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ipnet.IP = ipnet.IP.To4()
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	if bits-ones > 16 {
+		return nil, fmt.Errorf("network %q exceeds the limit of %d hosts", network, maxDiscoveryHosts)
+	}
+
+	hosts := make([]net.IP, 0, 1<<(bits-ones))
+	for cur := ipnet.IP.Mask(ipnet.Mask); ipnet.Contains(cur); cur = nextIP(cur) {
+		hosts = append(hosts, cur)
+	}
+
+	if bits == 32 && bits-ones > 1 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return hosts, nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// probeAgent queries the sysObjectID of the given agent.
+func (s *Snmp) probeAgent(agent string) (string, error) {
+	cfg := s.ClientConfig
+	cfg.Timeout = s.Discovery.Timeout
+	cfg.Retries = 0
+
+	gs, err := snmp.NewWrapper(cfg)
+	if err != nil {
+		return "", err
+	}
+	if err := gs.SetAgent(agent); err != nil {
+		return "", err
+	}
+	if err := gs.Connect(); err != nil {
+		return "", err
+	}
+	defer gs.Conn.Close()
+
+	pkt, err := gs.Get([]string{sysObjectIDOid})
+	if err != nil {
+		return "", err
+	}
+	if len(pkt.Variables) == 0 {
+		return "", errors.New("empty response")
+	}
+	v := pkt.Variables[0]
+	if v.Type != gosnmp.ObjectIdentifier {
+		return "", fmt.Errorf("unexpected type %v for sysObjectID", v.Type)
+	}
+	oid, ok := v.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected value type %T for sysObjectID", v.Value)
+	}
+	return oid, nil
+}
+
+// runDiscovery sweeps the networks right away and then every refresh
+// interval until the context is cancelled. The sweep runs independently of
+// the gather cycle, so a large network does not delay gathering from the
+// other agents.
+func (s *Snmp) runDiscovery(ctx context.Context, acc telegraf.Accumulator) {
+	ticker := time.NewTicker(time.Duration(s.Discovery.RefreshInterval))
+	defer ticker.Stop()
+
+	for {
+		if err := s.discover(); err != nil {
+			acc.AddError(fmt.Errorf("discovering agents: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// discover sweeps all configured networks and replaces the list of
+// discovered agents. Connections to agents found in a previous sweep are
+// kept, connections to agents no longer found are closed.
+func (s *Snmp) discover() error {
+	static := make(map[string]bool, len(s.Agents))
+	for _, agent := range s.Agents {
+		normalized, err := normalizeAgent(agent)
+		if err != nil {
+			continue
+		}
+		static[normalized] = true
+	}
+
+	var agents []string
+	for _, network := range s.Discovery.Networks {
+		hosts, err := expandNetwork(network)
+		if err != nil {
+			return err
+		}
+		for _, host := range hosts {
+			agent := s.Discovery.Transport + "://" + net.JoinHostPort(host.String(), strconv.Itoa(int(s.Discovery.Port)))
+			if !static[agent] {
+				agents = append(agents, agent)
+			}
+		}
+	}
+
+	probe := s.probe
+	if probe == nil {
+		probe = s.probeAgent
+	}
+
+	var mu sync.Mutex
+	found := make(map[string]*Profile)
+	sem := make(chan struct{}, s.Discovery.MaxConcurrency)
+	var wg sync.WaitGroup
+	for _, agent := range agents {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(agent string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			oid, err := probe(agent)
+			if err != nil {
+				return
+			}
+			p := s.Discovery.match(oid)
+			if p == nil {
+				s.Log.Debugf("Agent %s with sysObjectID %s does not match any profile", agent, oid)
+				return
+			}
+			mu.Lock()
+			found[agent] = p
+			mu.Unlock()
+		}(agent)
+	}
+	wg.Wait()
+
+	// Swap the agents while no gather is running, the connections are not
+	// safe for concurrent use
+	s.discoveredMu.Lock()
+	defer s.discoveredMu.Unlock()
+
+	previous := make(map[string]*discoveredAgent, len(s.discovered))
+	for _, d := range s.discovered {
+		previous[d.agent] = d
+	}
+
+	discovered := make([]*discoveredAgent, 0, len(found))
+	for agent, p := range found {
+		d := &discoveredAgent{agent: agent, profile: p}
+		if prev, ok := previous[agent]; ok {
+			d.conn = prev.conn
+			delete(previous, agent)
+		}
+		discovered = append(discovered, d)
+	}
+	sort.Slice(discovered, func(i, j int) bool { return discovered[i].agent < discovered[j].agent })
+
+	for _, d := range previous {
+		closeConnection(d.conn)
+	}
+
+	s.Log.Debugf("Discovered %d agents", len(discovered))
+	s.discovered = discovered
+
+	return nil
+}
+
+// normalizeAgent returns the agent in the form transport://host:port used
+// for discovered agents, applying the same defaults as the connection.
+func normalizeAgent(agent string) (string, error) {
+	var gs snmp.GosnmpWrapper
+	gs.GoSNMP = &gosnmp.GoSNMP{}
+	if err := gs.SetAgent(agent); err != nil {
+		return "", err
+	}
+	return gs.Transport + "://" + net.JoinHostPort(gs.Target, strconv.Itoa(int(gs.Port))), nil
+}
+
+// closeConnection closes the socket of a connection if it was established.
+func closeConnection(conn snmpConnection) {
+	gs, ok := conn.(snmp.GosnmpWrapper)
+	if !ok || gs.GoSNMP == nil || gs.Conn == nil {
+		return
+	}
+	// Ignore the error as the connection is not used anymore
+	//nolint:errcheck,revive
+	gs.Conn.Close()
+}
+
+// getDiscoveredConnection returns the cached connection of a discovered
+// agent, creating it if necessary.
+func (s *Snmp) getDiscoveredConnection(d *discoveredAgent) (snmpConnection, error) {
+	if d.conn != nil {
+		if err := d.conn.Reconnect(); err != nil {
+			return d.conn, fmt.Errorf("reconnecting: %w", err)
+		}
+		return d.conn, nil
+	}
+
+	gs, err := s.newConnection(d.agent)
+	if err != nil {
+		return nil, err
+	}
+	d.conn = gs
+
+	if err := gs.Connect(); err != nil {
+		return nil, fmt.Errorf("setting up connection: %w", err)
+	}
+	return gs, nil
+}
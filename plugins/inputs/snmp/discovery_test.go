@@ -0,0 +1,301 @@
+package snmp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// startResponder runs a minimal SNMPv2c agent answering get requests with the
+// given values.
+func startResponder(t *testing.T, values map[string]gosnmp.SnmpPDU) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c, Logger: gosnmp.NewLogger(nil)}
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			req, err := decoder.SnmpDecodePacket(buf[:n])
+			if err != nil {
+				continue
+			}
+			resp := &gosnmp.SnmpPacket{
+				Version:   req.Version,
+				Community: req.Community,
+				PDUType:   gosnmp.GetResponse,
+				RequestID: req.RequestID,
+				Logger:    decoder.Logger,
+			}
+			for _, v := range req.Variables {
+				pdu, ok := values[v.Name]
+				if !ok {
+					pdu = gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.NoSuchObject}
+				}
+				resp.Variables = append(resp.Variables, pdu)
+			}
+			out, err := resp.MarshalMsg()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteToUDP(out, addr)
+		}
+	}()
+
+	return conn
+}
+
+func TestExpandNetwork(t *testing.T) {
+	hosts, err := expandNetwork("192.168.0.0/30")
+	require.NoError(t, err)
+	require.Equal(t, []net.IP{net.ParseIP("192.168.0.1").To4(), net.ParseIP("192.168.0.2").To4()}, hosts)
+
+	hosts, err = expandNetwork("192.168.0.7/32")
+	require.NoError(t, err)
+	require.Equal(t, []net.IP{net.ParseIP("192.168.0.7").To4()}, hosts)
+
+	hosts, err = expandNetwork("2001:db8::/127")
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+
+	_, err = expandNetwork("10.0.0.0/8")
+	require.Error(t, err)
+
+	_, err = expandNetwork("10.0.0.0")
+	require.Error(t, err)
+}
+
+func TestDiscoveryMatch(t *testing.T) {
+	d := Discovery{
+		Profiles: []Profile{
+			{Name: "default"},
+			{Name: "cisco", SysObjectIDs: []string{".1.3.6.1.4.1.9"}},
+			{Name: "cisco-switch", SysObjectIDs: []string{".1.3.6.1.4.1.9.1.2"}},
+		},
+	}
+
+	require.Equal(t, "cisco", d.match(".1.3.6.1.4.1.9.1.1").Name)
+	require.Equal(t, "cisco-switch", d.match(".1.3.6.1.4.1.9.1.2.5").Name)
+	require.Equal(t, "cisco-switch", d.match(".1.3.6.1.4.1.9.1.2").Name)
+	require.Equal(t, "default", d.match(".1.3.6.1.4.1.99").Name)
+
+	d.Profiles = d.Profiles[1:]
+	require.Nil(t, d.match(".1.3.6.1.4.1.99"))
+}
+
+func TestDiscoveryInit(t *testing.T) {
+	d := Discovery{
+		Networks: []string{"10.0.0.0/24"},
+		Profiles: []Profile{
+			{Name: "foo", SysObjectIDs: []string{"1.3.6.1.4.1.9"}},
+		},
+	}
+	require.NoError(t, d.init(NewNetsnmpTranslator()))
+	require.Equal(t, "udp", d.Transport)
+	require.Equal(t, uint16(161), d.Port)
+	require.Equal(t, config.Duration(time.Hour), d.RefreshInterval)
+	require.Equal(t, ".1.3.6.1.4.1.9", d.Profiles[0].SysObjectIDs[0])
+
+	d = Discovery{Networks: []string{"10.0.0.0/24"}}
+	require.ErrorContains(t, d.init(NewNetsnmpTranslator()), "at least one profile")
+
+	d = Discovery{Networks: []string{"10.0.0.0/24"}, Profiles: []Profile{{}}}
+	require.ErrorContains(t, d.init(NewNetsnmpTranslator()), "not named")
+}
+
+func TestDiscoveryProbe(t *testing.T) {
+	conn := startResponder(t, map[string]gosnmp.SnmpPDU{
+		sysObjectIDOid: {Name: sysObjectIDOid, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.9.1.1208"},
+	})
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	s := &Snmp{
+		ClientConfig: snmp.ClientConfig{
+			Version:   2,
+			Community: "public",
+			Timeout:   config.Duration(time.Second),
+		},
+		Discovery: Discovery{
+			Networks: []string{"127.0.0.1/32"},
+			Port:     uint16(port),
+			Profiles: []Profile{
+				{Name: "cisco", SysObjectIDs: []string{".1.3.6.1.4.1.9"}},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Discovery.init(NewNetsnmpTranslator()))
+	require.NoError(t, s.discover())
+
+	require.Len(t, s.discovered, 1)
+	require.Equal(t, "udp://127.0.0.1:"+strconv.Itoa(port), s.discovered[0].agent)
+	require.Equal(t, "cisco", s.discovered[0].profile.Name)
+}
+
+func TestGatherDiscovered(t *testing.T) {
+	s := &Snmp{
+		Agents:       []string{"udp://10.0.0.1:161"},
+		AgentHostTag: "agent_host",
+		Name:         "mytable",
+		Fields: []Field{
+			{Name: "myfield1", Oid: ".1.0.0.1.1", IsTag: true},
+			{Name: "myfield3", Oid: ".1.0.0.1.3"},
+		},
+		Discovery: Discovery{
+			Networks: []string{"10.0.0.0/30"},
+			Profiles: []Profile{
+				{
+					Name: "foo",
+					Fields: []Field{
+						{Name: "myfield2", Oid: ".1.0.0.1.2"},
+					},
+				},
+			},
+		},
+		connectionCache: []snmpConnection{tsc},
+		probe: func(agent string) (string, error) {
+			return ".1.3.6.1.4.1.9.1.1", nil
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Discovery.init(NewNetsnmpTranslator()))
+
+	// Skip the static agent and run the discovery only once.
+	require.NoError(t, s.discover())
+	require.Len(t, s.discovered, 1)
+	require.Equal(t, "udp://10.0.0.2:161", s.discovered[0].agent)
+	s.discovered[0].conn = tsc
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	require.Len(t, acc.Metrics, 2)
+
+	var static, discovered *testutil.Metric
+	for _, m := range acc.Metrics {
+		if _, ok := m.Fields["myfield2"]; ok {
+			discovered = m
+		} else {
+			static = m
+		}
+	}
+	require.NotNil(t, static)
+	require.NotNil(t, discovered)
+	require.Equal(t, "baz", static.Tags["myfield1"])
+	require.Equal(t, "baz", discovered.Tags["myfield1"])
+	require.Equal(t, "byte slice", static.Fields["myfield3"])
+	require.Equal(t, 234, discovered.Fields["myfield2"])
+	require.Equal(t, "byte slice", discovered.Fields["myfield3"])
+
+	// A repeated discovery keeps the existing connection.
+	require.NoError(t, s.discover())
+	require.Equal(t, tsc, s.discovered[0].conn)
+}
+
+func TestDiscoverySkipsStaticAgents(t *testing.T) {
+	s := &Snmp{
+		Agents: []string{"10.0.0.1", "udp://10.0.0.2", "udp://10.0.0.3:161", "tcp://10.0.0.4:161", "10.0.0.5:1161"},
+		Discovery: Discovery{
+			Networks: []string{"10.0.0.0/29"},
+			Profiles: []Profile{{Name: "foo"}},
+		},
+		probe: func(agent string) (string, error) {
+			return ".1.3.6.1.4.1.9.1.1", nil
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Discovery.init(NewNetsnmpTranslator()))
+	require.NoError(t, s.discover())
+
+	agents := make([]string, 0, len(s.discovered))
+	for _, d := range s.discovered {
+		agents = append(agents, d.agent)
+	}
+	expected := []string{
+		"udp://10.0.0.4:161",
+		"udp://10.0.0.5:161",
+		"udp://10.0.0.6:161",
+	}
+	require.Equal(t, expected, agents)
+}
+
+func TestDiscoveryClosesVanishedAgents(t *testing.T) {
+	reachable := map[string]bool{"udp://10.0.0.1:161": true, "udp://10.0.0.2:161": true}
+	s := &Snmp{
+		Discovery: Discovery{
+			Networks: []string{"10.0.0.0/30"},
+			Profiles: []Profile{{Name: "foo"}},
+		},
+		probe: func(agent string) (string, error) {
+			if !reachable[agent] {
+				return "", errors.New("timeout")
+			}
+			return ".1.3.6.1.4.1.9.1.1", nil
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Discovery.init(NewNetsnmpTranslator()))
+	require.NoError(t, s.discover())
+	require.Len(t, s.discovered, 2)
+
+	conns := make([]net.Conn, 0, len(s.discovered))
+	for _, d := range s.discovered {
+		client, server := net.Pipe()
+		defer server.Close()
+		d.conn = snmp.GosnmpWrapper{GoSNMP: &gosnmp.GoSNMP{Conn: client}}
+		conns = append(conns, client)
+	}
+
+	// The connection of the vanished agent is closed, the other one is kept
+	delete(reachable, "udp://10.0.0.2:161")
+	require.NoError(t, s.discover())
+	require.Len(t, s.discovered, 1)
+	require.Equal(t, "udp://10.0.0.1:161", s.discovered[0].agent)
+
+	require.NoError(t, conns[0].SetDeadline(time.Now()))
+	require.ErrorIs(t, conns[1].SetDeadline(time.Now()), io.ErrClosedPipe)
+}
+
+func TestDiscoveryInBackground(t *testing.T) {
+	probed := make(chan struct{})
+	s := &Snmp{
+		Discovery: Discovery{
+			Networks: []string{"10.0.0.1/32"},
+			Profiles: []Profile{{Name: "foo"}},
+		},
+		probe: func(agent string) (string, error) {
+			<-probed
+			return ".1.3.6.1.4.1.9.1.1", nil
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Discovery.init(NewNetsnmpTranslator()))
+
+	// Gathering is not blocked by a running sweep
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+	require.NoError(t, s.Gather(acc))
+	require.Empty(t, acc.Metrics)
+
+	close(probed)
+	require.Eventually(t, func() bool {
+		s.discoveredMu.RLock()
+		defer s.discoveredMu.RUnlock()
+		return len(s.discovered) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
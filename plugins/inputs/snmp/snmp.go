@@ -2,6 +2,7 @@
 package snmp
 
 import (
+	"context"
 	_ "embed"
 	"encoding/binary"
 	"errors"
@@ -58,8 +59,17 @@ type Snmp struct {
 	Name   string  `toml:"name"`
 	Fields []Field `toml:"field"`
 
+	// Discovery finds additional agents by sweeping networks.
+	Discovery Discovery `toml:"discovery"`
+
 	connectionCache []snmpConnection
 
+	discovered   []*discoveredAgent
+	discoveredMu sync.RWMutex
+	probe        func(agent string) (string, error)
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+
 	Log telegraf.Logger `toml:"-"`
 
 	translator Translator
@@ -101,6 +111,10 @@ func (s *Snmp) Init() error {
 		}
 	}
 
+	if err := s.Discovery.init(s.translator); err != nil {
+		return fmt.Errorf("initializing discovery: %w", err)
+	}
+
 	if len(s.AgentHostTag) == 0 {
 		s.AgentHostTag = "agent_host"
 	}
@@ -108,6 +122,40 @@ func (s *Snmp) Init() error {
 	return nil
 }
 
+// Start runs the agent discovery in the background if enabled.
+func (s *Snmp) Start(acc telegraf.Accumulator) error {
+	if !s.Discovery.enabled() {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.runDiscovery(ctx, acc)
+	}()
+	return nil
+}
+
+// Stop ends the agent discovery and closes the connections to the agents.
+func (s *Snmp) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	s.discoveredMu.Lock()
+	defer s.discoveredMu.Unlock()
+	for _, d := range s.discovered {
+		closeConnection(d.conn)
+	}
+	for _, gs := range s.connectionCache {
+		closeConnection(gs)
+	}
+}
+
 // Table holds the configuration for a SNMP table.
 type Table struct {
 	// Name will be the name of the measurement.
@@ -305,6 +353,10 @@ func (e *walkError) Unwrap() error {
 // Any error encountered does not halt the process. The errors are accumulated
 // and returned at the end.
 func (s *Snmp) Gather(acc telegraf.Accumulator) error {
+	// Keep the discovered agents and their connections while gathering
+	s.discoveredMu.RLock()
+	defer s.discoveredMu.RUnlock()
+
 	var wg sync.WaitGroup
 	for i, agent := range s.Agents {
 		wg.Add(1)
@@ -315,30 +367,47 @@ func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 				acc.AddError(fmt.Errorf("agent %s: %w", agent, err))
 				return
 			}
-
-			// First is the top-level fields. We treat the fields as table prefixes with an empty index.
-			t := Table{
-				Name:   s.Name,
-				Fields: s.Fields,
-			}
-			topTags := map[string]string{}
-			if err := s.gatherTable(acc, gs, t, topTags, false); err != nil {
-				acc.AddError(fmt.Errorf("agent %s: %w", agent, err))
-			}
-
-			// Now is the real tables.
-			for _, t := range s.Tables {
-				if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
-					acc.AddError(fmt.Errorf("agent %s: gathering table %s: %w", agent, t.Name, err))
-				}
-			}
+			s.gatherAgent(acc, gs, agent, s.Fields, s.Tables)
 		}(i, agent)
 	}
+	for _, d := range s.discovered {
+		wg.Add(1)
+		go func(d *discoveredAgent) {
+			defer wg.Done()
+			gs, err := s.getDiscoveredConnection(d)
+			if err != nil {
+				acc.AddError(fmt.Errorf("agent %s: %w", d.agent, err))
+				return
+			}
+			fields := append(append([]Field{}, s.Fields...), d.profile.Fields...)
+			tables := append(append([]Table{}, s.Tables...), d.profile.Tables...)
+			s.gatherAgent(acc, gs, d.agent, fields, tables)
+		}(d)
+	}
 	wg.Wait()
 
 	return nil
 }
 
+func (s *Snmp) gatherAgent(acc telegraf.Accumulator, gs snmpConnection, agent string, fields []Field, tables []Table) {
+	// First is the top-level fields. We treat the fields as table prefixes with an empty index.
+	t := Table{
+		Name:   s.Name,
+		Fields: fields,
+	}
+	topTags := map[string]string{}
+	if err := s.gatherTable(acc, gs, t, topTags, false); err != nil {
+		acc.AddError(fmt.Errorf("agent %s: %w", agent, err))
+	}
+
+	// Now is the real tables.
+	for _, t := range tables {
+		if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
+			acc.AddError(fmt.Errorf("agent %s: gathering table %s: %w", agent, t.Name, err))
+		}
+	}
+}
+
 func (s *Snmp) gatherTable(acc telegraf.Accumulator, gs snmpConnection, t Table, topTags map[string]string, walk bool) error {
 	rt, err := t.Build(gs, walk, s.translator)
 	if err != nil {
@@ -576,16 +645,7 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 		return gs, nil
 	}
 
-	agent := s.Agents[idx]
-
-	var err error
-	var gs snmp.GosnmpWrapper
-	gs, err = snmp.NewWrapper(s.ClientConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	err = gs.SetAgent(agent)
+	gs, err := s.newConnection(s.Agents[idx])
 	if err != nil {
 		return nil, err
 	}
@@ -599,6 +659,21 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 	return gs, nil
 }
 
+// newConnection creates an unconnected wrapper for the given agent using the
+// client configuration of the plugin.
+func (s *Snmp) newConnection(agent string) (snmp.GosnmpWrapper, error) {
+	gs, err := snmp.NewWrapper(s.ClientConfig)
+	if err != nil {
+		return snmp.GosnmpWrapper{}, err
+	}
+
+	if err := gs.SetAgent(agent); err != nil {
+		return snmp.GosnmpWrapper{}, err
+	}
+
+	return gs, nil
+}
+
 // fieldConvert converts from any type according to the conv specification
 func fieldConvert(conv string, v interface{}) (interface{}, error) {
 	if conv == "" {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/common/watermark"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -24,18 +25,23 @@ var sampleConfig string
 
 // SQLServer struct
 type SQLServer struct {
-	Servers      []string        `toml:"servers"`
-	AuthMethod   string          `toml:"auth_method"`
-	QueryVersion int             `toml:"query_version" deprecated:"1.16.0;use 'database_type' instead"`
-	AzureDB      bool            `toml:"azuredb" deprecated:"1.16.0;use 'database_type' instead"`
-	DatabaseType string          `toml:"database_type"`
-	IncludeQuery []string        `toml:"include_query"`
-	ExcludeQuery []string        `toml:"exclude_query"`
-	HealthMetric bool            `toml:"health_metric"`
-	Log          telegraf.Logger `toml:"-"`
+	Servers      []string `toml:"servers"`
+	AuthMethod   string   `toml:"auth_method"`
+	QueryVersion int      `toml:"query_version" deprecated:"1.16.0;use 'database_type' instead"`
+	AzureDB      bool     `toml:"azuredb" deprecated:"1.16.0;use 'database_type' instead"`
+	DatabaseType string   `toml:"database_type"`
+	IncludeQuery []string `toml:"include_query"`
+	ExcludeQuery []string `toml:"exclude_query"`
+	HealthMetric bool     `toml:"health_metric"`
+
+	IncrementalQueries []IncrementalQuery `toml:"incremental_query"`
+	StateFile          string             `toml:"incremental_state_file"`
+
+	Log telegraf.Logger `toml:"-"`
 
 	pools       []*sql.DB
 	queries     MapQuery
+	incremental []Query
 	adalToken   *adal.Token
 	muCacheLock sync.RWMutex
 }
@@ -46,6 +52,18 @@ type Query struct {
 	Script         string
 	ResultByRow    bool
 	OrderedColumns []string
+
+	// High-water marks of incremental queries per connection string
+	watermarks map[string]*watermark.Tracker
+}
+
+// IncrementalQuery is a user defined query reading only the rows added since
+// its last run
+type IncrementalQuery struct {
+	Name                    string      `toml:"name"`
+	Query                   string      `toml:"query"`
+	IncrementalColumn       string      `toml:"incremental_column"`
+	IncrementalInitialValue interface{} `toml:"incremental_initial_value"`
 }
 
 // MapQuery type
@@ -165,6 +183,10 @@ func (s *SQLServer) initQueries() error {
 		}
 	}
 
+	for _, query := range s.incremental {
+		queries[query.ScriptName] = query
+	}
+
 	filterQueries, err := filter.NewIncludeExcludeFilter(s.IncludeQuery, s.ExcludeQuery)
 	if err != nil {
 		return err
@@ -290,8 +312,15 @@ func (s *SQLServer) Stop() {
 }
 
 func (s *SQLServer) gatherServer(pool *sql.DB, query Query, acc telegraf.Accumulator, connectionString string) error {
+	// Pass the high-water mark as parameter to incremental queries
+	var args []interface{}
+	tracker := query.watermarks[connectionString]
+	if tracker != nil {
+		args = append(args, tracker.Value())
+	}
+
 	// execute query
-	rows, err := pool.Query(query.Script)
+	rows, err := pool.Query(query.Script, args...)
 	if err != nil {
 		serverName, databaseName := getConnectionIdentifiers(connectionString)
 
@@ -313,15 +342,28 @@ func (s *SQLServer) gatherServer(pool *sql.DB, query Query, acc telegraf.Accumul
 	}
 
 	for rows.Next() {
-		err = s.accRow(query, acc, rows)
+		err = s.accRow(query, acc, rows, tracker)
 		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+
+	if tracker != nil {
+		if err != nil {
+			tracker.Discard()
 			return err
 		}
+		if err := tracker.Commit(); err != nil {
+			return fmt.Errorf("storing high-water mark failed: %w", err)
+		}
 	}
-	return rows.Err()
+	return err
 }
 
-func (s *SQLServer) accRow(query Query, acc telegraf.Accumulator, row scanner) error {
+func (s *SQLServer) accRow(query Query, acc telegraf.Accumulator, row scanner, tracker *watermark.Tracker) error {
 	var columnVars []interface{}
 	var fields = make(map[string]interface{})
 
@@ -340,6 +382,16 @@ func (s *SQLServer) accRow(query Query, acc telegraf.Accumulator, row scanner) e
 		return err
 	}
 
+	if tracker != nil {
+		value, found := columnMap[tracker.Column]
+		if !found {
+			return fmt.Errorf("incremental column %q not found in result", tracker.Column)
+		}
+		if err := tracker.Observe(*value); err != nil {
+			return fmt.Errorf("tracking incremental column failed: %w", err)
+		}
+	}
+
 	// measurement: identified by the header
 	// tags: all other fields of type string
 	tags := map[string]string{}
@@ -421,6 +473,40 @@ func (s *SQLServer) Init() error {
 		s.Log.Warn("Warning: Server list is empty.")
 	}
 
+	// Setup the high-water mark tracking of incremental queries for each
+	// server, using the query as key for persisting the marks
+	stores := make(map[string]*watermark.Store, len(s.Servers))
+	for _, q := range s.IncrementalQueries {
+		if q.Name == "" || q.Query == "" || q.IncrementalColumn == "" {
+			return errors.New("incremental queries require 'name', 'query' and 'incremental_column'")
+		}
+
+		query := Query{
+			ScriptName: q.Name,
+			Script:     q.Query,
+			watermarks: make(map[string]*watermark.Tracker, len(s.Servers)),
+		}
+		for _, serv := range s.Servers {
+			store, found := stores[serv]
+			if !found {
+				var err error
+				if store, err = watermark.NewStore(s.StateFile, serv); err != nil {
+					return err
+				}
+				stores[serv] = store
+			}
+			tracker, err := store.Tracker(q.Query, q.IncrementalColumn, q.IncrementalInitialValue)
+			if err != nil {
+				return fmt.Errorf("setting up incremental query %q failed: %w", q.Name, err)
+			}
+			if tracker.Value() == nil {
+				return fmt.Errorf("'incremental_initial_value' required for incremental query %q", q.Name)
+			}
+			query.watermarks[serv] = tracker
+		}
+		s.incremental = append(s.incremental, query)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,95 @@
+package alertmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/auth"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/generic"
+)
+
+// message is the payload sent by the Alertmanager webhook receiver
+type message struct {
+	Version  string  `json:"version"`
+	GroupKey string  `json:"groupKey"`
+	Status   string  `json:"status"`
+	Receiver string  `json:"receiver"`
+	Alerts   []alert `json:"alerts"`
+}
+
+type alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+type AlertmanagerWebhook struct {
+	Path        string
+	BearerToken string `toml:"bearer_token"`
+	auth.BasicAuth
+
+	acc           telegraf.Accumulator
+	log           telegraf.Logger
+	authenticator *generic.Authenticator
+}
+
+func (am *AlertmanagerWebhook) Init() error {
+	am.authenticator = &generic.Authenticator{}
+	if am.BearerToken != "" {
+		am.authenticator.TokenHeader = "Authorization"
+		am.authenticator.Secret = "Bearer " + am.BearerToken
+	}
+	return am.authenticator.Init()
+}
+
+func (am *AlertmanagerWebhook) Register(router *mux.Router, acc telegraf.Accumulator, log telegraf.Logger) {
+	am.acc = acc
+	am.log = log
+
+	router.HandleFunc(am.Path, generic.NewHandler(am.authenticator, &am.BasicAuth, log, am.process)).Methods("POST")
+	am.log.Infof("Started the webhooks_alertmanager on %s", am.Path)
+}
+
+// process adds a metric for each alert of the notification. The labels of the
+// alert become tags and the annotations become fields.
+func (am *AlertmanagerWebhook) process(_ *http.Request, body []byte) error {
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return err
+	}
+
+	for _, a := range msg.Alerts {
+		tags := make(map[string]string, len(a.Labels)+2)
+		for k, v := range a.Labels {
+			tags[k] = v
+		}
+		tags["receiver"] = msg.Receiver
+		tags["status"] = a.Status
+
+		fields := map[string]interface{}{
+			"fingerprint":   a.Fingerprint,
+			"generator_url": a.GeneratorURL,
+			"group_key":     msg.GroupKey,
+			"starts_at":     a.StartsAt.Unix(),
+		}
+		// Alerts that are still firing have a zero or future end time
+		if !a.EndsAt.IsZero() && a.Status == "resolved" {
+			fields["ends_at"] = a.EndsAt.Unix()
+			fields["duration"] = a.EndsAt.Sub(a.StartsAt).Seconds()
+		}
+		for k, v := range a.Annotations {
+			fields["annotation_"+k] = v
+		}
+
+		am.acc.AddFields("alertmanager_webhooks", fields, tags)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package alertmanager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const notification = `{
+  "version": "4",
+  "groupKey": "{}:{alertname=\"HighLoad\"}",
+  "status": "resolved",
+  "receiver": "telegraf",
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "HighLoad", "instance": "server01"},
+      "annotations": {"summary": "Load is high"},
+      "startsAt": "2022-10-17T10:00:00Z",
+      "endsAt": "0001-01-01T00:00:00Z",
+      "generatorURL": "http://prometheus/graph",
+      "fingerprint": "c2e3f1a5b0d4e6f7"
+    },
+    {
+      "status": "resolved",
+      "labels": {"alertname": "HighLoad", "instance": "server02"},
+      "annotations": {},
+      "startsAt": "2022-10-17T10:00:00Z",
+      "endsAt": "2022-10-17T10:05:30Z",
+      "generatorURL": "http://prometheus/graph",
+      "fingerprint": "a1b2c3d4e5f60718"
+    }
+  ]
+}`
+
+func postWebhook(router *mux.Router, body string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/alertmanager", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestNotification(t *testing.T) {
+	am := &AlertmanagerWebhook{Path: "/alertmanager"}
+	require.NoError(t, am.Init())
+
+	var acc testutil.Accumulator
+	router := mux.NewRouter()
+	am.Register(router, &acc, testutil.Logger{})
+
+	resp := postWebhook(router, notification, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	start := time.Date(2022, 10, 17, 10, 0, 0, 0, time.UTC)
+	expected := []telegraf.Metric{
+		metric.New(
+			"alertmanager_webhooks",
+			map[string]string{
+				"alertname": "HighLoad",
+				"instance":  "server01",
+				"receiver":  "telegraf",
+				"status":    "firing",
+			},
+			map[string]interface{}{
+				"fingerprint":        "c2e3f1a5b0d4e6f7",
+				"generator_url":      "http://prometheus/graph",
+				"group_key":          `{}:{alertname="HighLoad"}`,
+				"starts_at":          start.Unix(),
+				"annotation_summary": "Load is high",
+			},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"alertmanager_webhooks",
+			map[string]string{
+				"alertname": "HighLoad",
+				"instance":  "server02",
+				"receiver":  "telegraf",
+				"status":    "resolved",
+			},
+			map[string]interface{}{
+				"fingerprint":   "a1b2c3d4e5f60718",
+				"generator_url": "http://prometheus/graph",
+				"group_key":     `{}:{alertname="HighLoad"}`,
+				"starts_at":     start.Unix(),
+				"ends_at":       start.Add(330 * time.Second).Unix(),
+				"duration":      330.0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestBearerToken(t *testing.T) {
+	am := &AlertmanagerWebhook{Path: "/alertmanager", BearerToken: "token"}
+	require.NoError(t, am.Init())
+
+	var acc testutil.Accumulator
+	router := mux.NewRouter()
+	am.Register(router, &acc, testutil.Logger{})
+
+	resp := postWebhook(router, notification, "")
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = postWebhook(router, notification, "wrong")
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = postWebhook(router, notification, "token")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, uint64(2), acc.NMetrics())
+}
+
+func TestInvalidPayload(t *testing.T) {
+	am := &AlertmanagerWebhook{Path: "/alertmanager"}
+	require.NoError(t, am.Init())
+
+	var acc testutil.Accumulator
+	router := mux.NewRouter()
+	am.Register(router, &acc, testutil.Logger{})
+
+	resp := postWebhook(router, `{"alerts": [`, "")
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,169 @@
+package generic
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // required by some providers
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+// Authenticator verifies webhook requests by a shared token or by an HMAC
+// signature of the payload and optionally rejects outdated or replayed
+// requests based on a timestamp header.
+type Authenticator struct {
+	Secret             string          `toml:"secret"`
+	TokenHeader        string          `toml:"token_header"`
+	SignatureHeader    string          `toml:"signature_header"`
+	SignatureAlgorithm string          `toml:"signature_algorithm"`
+	SignaturePrefix    string          `toml:"signature_prefix"`
+	SignatureEncoding  string          `toml:"signature_encoding"`
+	TimestampHeader    string          `toml:"timestamp_header"`
+	TimestampTolerance config.Duration `toml:"timestamp_tolerance"`
+	TimestampSigned    bool            `toml:"timestamp_signed"`
+	TimestampSeparator string          `toml:"timestamp_separator"`
+
+	hash   func() hash.Hash
+	decode func(string) ([]byte, error)
+
+	// seen holds the signatures of accepted requests until they expire
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// Init checks the settings and applies the defaults
+func (a *Authenticator) Init() error {
+	if (a.TokenHeader != "" || a.SignatureHeader != "") && a.Secret == "" {
+		return errors.New("secret required for token or signature verification")
+	}
+
+	if a.SignatureHeader != "" {
+		switch strings.ToLower(a.SignatureAlgorithm) {
+		case "sha1":
+			a.hash = sha1.New
+		case "", "sha256":
+			a.hash = sha256.New
+		case "sha512":
+			a.hash = sha512.New
+		default:
+			return fmt.Errorf("invalid signature algorithm %q", a.SignatureAlgorithm)
+		}
+
+		switch a.SignatureEncoding {
+		case "", "hex":
+			a.decode = hex.DecodeString
+		case "base64":
+			a.decode = base64.StdEncoding.DecodeString
+		default:
+			return fmt.Errorf("invalid signature encoding %q", a.SignatureEncoding)
+		}
+	}
+
+	if a.TimestampSigned && (a.TimestampHeader == "" || a.SignatureHeader == "") {
+		return errors.New("signed timestamps require both a timestamp and a signature header")
+	}
+	if a.TimestampTolerance <= 0 {
+		a.TimestampTolerance = config.Duration(5 * time.Minute)
+	}
+	if a.TimestampSeparator == "" {
+		a.TimestampSeparator = "."
+	}
+	a.seen = make(map[string]time.Time)
+
+	return nil
+}
+
+// Verify checks the token, signature and timestamp of the request with the
+// given payload
+func (a *Authenticator) Verify(r *http.Request, body []byte, now time.Time) error {
+	if a.TokenHeader != "" {
+		token := r.Header.Get(a.TokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.Secret)) != 1 {
+			return errors.New("invalid token")
+		}
+	}
+
+	var timestamp string
+	if a.TimestampHeader != "" {
+		timestamp = r.Header.Get(a.TimestampHeader)
+		if err := a.checkTimestamp(timestamp, now); err != nil {
+			return err
+		}
+	}
+
+	if a.SignatureHeader == "" {
+		return nil
+	}
+
+	signature := r.Header.Get(a.SignatureHeader)
+	if signature == "" {
+		return fmt.Errorf("missing signature header %q", a.SignatureHeader)
+	}
+	if !strings.HasPrefix(signature, a.SignaturePrefix) {
+		return errors.New("invalid signature prefix")
+	}
+	actual, err := a.decode(strings.TrimPrefix(signature, a.SignaturePrefix))
+	if err != nil {
+		return fmt.Errorf("decoding signature failed: %w", err)
+	}
+
+	mac := hmac.New(a.hash, []byte(a.Secret))
+	if a.TimestampSigned {
+		mac.Write([]byte(timestamp + a.TimestampSeparator))
+	}
+	mac.Write(body)
+	if !hmac.Equal(actual, mac.Sum(nil)) {
+		return errors.New("invalid signature")
+	}
+
+	if a.TimestampHeader != "" {
+		return a.checkReplay(signature, now)
+	}
+	return nil
+}
+
+func (a *Authenticator) checkTimestamp(timestamp string, now time.Time) error {
+	if timestamp == "" {
+		return fmt.Errorf("missing timestamp header %q", a.TimestampHeader)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if math.Abs(float64(age)) > float64(a.TimestampTolerance) {
+		return fmt.Errorf("timestamp %q outside of tolerance", timestamp)
+	}
+	return nil
+}
+
+// checkReplay rejects signatures seen within the timestamp tolerance. Older
+// requests are already rejected by their timestamp.
+func (a *Authenticator) checkReplay(signature string, now time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for s, expiry := range a.seen {
+		if now.After(expiry) {
+			delete(a.seen, s)
+		}
+	}
+	if _, found := a.seen[signature]; found {
+		return errors.New("replayed request")
+	}
+	a.seen[signature] = now.Add(2 * time.Duration(a.TimestampTolerance))
+	return nil
+}
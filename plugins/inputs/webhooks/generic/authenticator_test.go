@@ -0,0 +1,196 @@
+package generic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+func TestAuthenticatorToken(t *testing.T) {
+	a := &Authenticator{Secret: "secret", TokenHeader: "X-Token"}
+	require.NoError(t, a.Init())
+
+	r := httptest.NewRequest("POST", "/", nil)
+	require.ErrorContains(t, a.Verify(r, nil, time.Now()), "invalid token")
+
+	r.Header.Set("X-Token", "wrong")
+	require.ErrorContains(t, a.Verify(r, nil, time.Now()), "invalid token")
+
+	r.Header.Set("X-Token", "secret")
+	require.NoError(t, a.Verify(r, nil, time.Now()))
+}
+
+func TestAuthenticatorSignature(t *testing.T) {
+	body := []byte("cpu value=42")
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	sha256Hex := hex.EncodeToString(mac.Sum(nil))
+
+	mac = hmac.New(sha512.New, []byte("secret"))
+	mac.Write(body)
+	sha512Base64 := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name          string
+		authenticator *Authenticator
+		signature     string
+		expected      string
+	}{
+		{
+			name:          "hex with prefix",
+			authenticator: &Authenticator{SignaturePrefix: "sha256="},
+			signature:     "sha256=" + sha256Hex,
+		},
+		{
+			name:          "base64",
+			authenticator: &Authenticator{SignatureAlgorithm: "sha512", SignatureEncoding: "base64"},
+			signature:     sha512Base64,
+		},
+		{
+			name:          "missing prefix",
+			authenticator: &Authenticator{SignaturePrefix: "sha256="},
+			signature:     sha256Hex,
+			expected:      "invalid signature prefix",
+		},
+		{
+			name:          "wrong algorithm",
+			authenticator: &Authenticator{SignatureAlgorithm: "sha1"},
+			signature:     sha256Hex,
+			expected:      "invalid signature",
+		},
+		{
+			name:          "invalid encoding",
+			authenticator: &Authenticator{},
+			signature:     sha512Base64,
+			expected:      "decoding signature failed",
+		},
+		{
+			name:          "missing signature",
+			authenticator: &Authenticator{},
+			expected:      `missing signature header "X-Signature"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.authenticator
+			a.Secret = "secret"
+			a.SignatureHeader = "X-Signature"
+			require.NoError(t, a.Init())
+
+			r := httptest.NewRequest("POST", "/", nil)
+			if tt.signature != "" {
+				r.Header.Set("X-Signature", tt.signature)
+			}
+			err := a.Verify(r, body, time.Now())
+			if tt.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorTimestamp(t *testing.T) {
+	a := &Authenticator{
+		Secret:             "secret",
+		SignatureHeader:    "X-Signature",
+		SignaturePrefix:    "v0=",
+		TimestampHeader:    "X-Timestamp",
+		TimestampTolerance: config.Duration(time.Minute),
+		TimestampSigned:    true,
+		TimestampSeparator: ":",
+	}
+	require.NoError(t, a.Init())
+
+	body := []byte(`{"value": 42}`)
+	now := time.Unix(1666000000, 0)
+	sign := func(ts int64) (string, string) {
+		timestamp := strconv.FormatInt(ts, 10)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(timestamp + ":"))
+		mac.Write(body)
+		return timestamp, "v0=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	// Valid request
+	timestamp, signature := sign(now.Unix() - 30)
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("X-Timestamp", timestamp)
+	r.Header.Set("X-Signature", signature)
+	require.NoError(t, a.Verify(r, body, now))
+
+	// Replaying the same request is rejected
+	require.ErrorContains(t, a.Verify(r, body, now.Add(time.Second)), "replayed request")
+
+	// Changing the timestamp invalidates the signature
+	r.Header.Set("X-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	require.ErrorContains(t, a.Verify(r, body, now), "invalid signature")
+
+	// Outdated requests are rejected
+	timestamp, signature = sign(now.Unix() - 120)
+	r.Header.Set("X-Timestamp", timestamp)
+	r.Header.Set("X-Signature", signature)
+	require.ErrorContains(t, a.Verify(r, body, now), "outside of tolerance")
+
+	// Missing and invalid timestamps are rejected
+	r.Header.Del("X-Timestamp")
+	require.ErrorContains(t, a.Verify(r, body, now), `missing timestamp header "X-Timestamp"`)
+	r.Header.Set("X-Timestamp", "yesterday")
+	require.ErrorContains(t, a.Verify(r, body, now), `invalid timestamp "yesterday"`)
+
+	// Expired signatures are forgotten
+	require.Len(t, a.seen, 1)
+	timestamp, signature = sign(now.Unix() + 300)
+	r.Header.Set("X-Timestamp", timestamp)
+	r.Header.Set("X-Signature", signature)
+	require.NoError(t, a.Verify(r, body, now.Add(5*time.Minute)))
+	require.Len(t, a.seen, 1)
+}
+
+func TestAuthenticatorInit(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticator *Authenticator
+		expected      string
+	}{
+		{
+			name:          "missing secret",
+			authenticator: &Authenticator{SignatureHeader: "X-Signature"},
+			expected:      "secret required",
+		},
+		{
+			name:          "invalid algorithm",
+			authenticator: &Authenticator{Secret: "secret", SignatureHeader: "X-Signature", SignatureAlgorithm: "md5"},
+			expected:      `invalid signature algorithm "md5"`,
+		},
+		{
+			name:          "invalid encoding",
+			authenticator: &Authenticator{Secret: "secret", SignatureHeader: "X-Signature", SignatureEncoding: "base32"},
+			expected:      `invalid signature encoding "base32"`,
+		},
+		{
+			name:          "signed timestamp without header",
+			authenticator: &Authenticator{Secret: "secret", SignatureHeader: "X-Signature", TimestampSigned: true},
+			expected:      "signed timestamps require",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.authenticator.Init(), tt.expected)
+		})
+	}
+}
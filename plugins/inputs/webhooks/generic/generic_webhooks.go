@@ -0,0 +1,122 @@
+package generic
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/common/auth"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// maxBodySize limits the size of accepted payloads
+const maxBodySize = 64 * 1024 * 1024
+
+// ProcessFunc handles the verified payload of a request
+type ProcessFunc func(r *http.Request, body []byte) error
+
+// NewHandler returns an HTTP handler checking the basic auth credentials and
+// verifying the request using the authenticator before passing the payload
+// to the process function
+func NewHandler(a *Authenticator, basic *auth.BasicAuth, log telegraf.Logger, process ProcessFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		if !basic.Verify(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxBodySize {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if err := a.Verify(r, body, time.Now()); err != nil {
+			log.Debugf("Rejected request on %s: %v", r.URL.Path, err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := process(r, body); err != nil {
+			log.Errorf("Processing request on %s failed: %v", r.URL.Path, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// GenericWebhook receives payloads in any data format on a configurable path
+type GenericWebhook struct {
+	Path       string            `toml:"path"`
+	HeaderTags map[string]string `toml:"http_header_tags"`
+	Authenticator
+	auth.BasicAuth
+	parsers.Config
+
+	acc    telegraf.Accumulator
+	log    telegraf.Logger
+	parser parsers.Parser
+}
+
+func (gw *GenericWebhook) Init() error {
+	if gw.Path == "" {
+		return fmt.Errorf("path required")
+	}
+	if gw.DataFormat == "" {
+		gw.DataFormat = "influx"
+	}
+	if gw.MetricName == "" {
+		gw.MetricName = "webhooks_generic"
+	}
+
+	if err := gw.Authenticator.Init(); err != nil {
+		return fmt.Errorf("generic webhook %q: %w", gw.Path, err)
+	}
+
+	parser, err := parsers.NewParser(&gw.Config)
+	if err != nil {
+		return fmt.Errorf("generic webhook %q: %w", gw.Path, err)
+	}
+	gw.parser = parser
+
+	return nil
+}
+
+func (gw *GenericWebhook) Register(router *mux.Router, acc telegraf.Accumulator, log telegraf.Logger) {
+	gw.acc = acc
+	gw.log = log
+	models.SetLoggerOnPlugin(gw.parser, log)
+
+	router.HandleFunc(gw.Path, NewHandler(&gw.Authenticator, &gw.BasicAuth, log, gw.process)).Methods("POST")
+	gw.log.Infof("Started the webhooks_generic on %s", gw.Path)
+}
+
+func (gw *GenericWebhook) process(r *http.Request, body []byte) error {
+	metrics, err := gw.parser.Parse(body)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range metrics {
+		for header, tag := range gw.HeaderTags {
+			if v := r.Header.Get(header); v != "" {
+				m.AddTag(tag, v)
+			}
+		}
+		gw.acc.AddMetric(m)
+	}
+	return nil
+}
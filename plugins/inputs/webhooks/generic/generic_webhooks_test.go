@@ -0,0 +1,133 @@
+package generic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	_ "github.com/influxdata/telegraf/plugins/parsers/all"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func postWebhook(router *mux.Router, path string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGenericInflux(t *testing.T) {
+	gw := &GenericWebhook{
+		Path:       "/influx",
+		HeaderTags: map[string]string{"X-Source": "source"},
+	}
+	require.NoError(t, gw.Init())
+
+	var acc testutil.Accumulator
+	router := mux.NewRouter()
+	gw.Register(router, &acc, testutil.Logger{})
+
+	resp := postWebhook(router, "/influx", "cpu value=42 1666000000000000000", map[string]string{"X-Source": "ci"})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"source": "ci"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(1666000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	resp = postWebhook(router, "/influx", "not line protocol", nil)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGenericJSONSigned(t *testing.T) {
+	gw := &GenericWebhook{
+		Path: "/json",
+		Authenticator: Authenticator{
+			Secret:          "secret",
+			SignatureHeader: "X-Hub-Signature-256",
+			SignaturePrefix: "sha256=",
+		},
+	}
+	gw.DataFormat = "json"
+	gw.JSONStringFields = []string{"state"}
+	require.NoError(t, gw.Init())
+
+	var acc testutil.Accumulator
+	router := mux.NewRouter()
+	gw.Register(router, &acc, testutil.Logger{})
+
+	body := `{"state": "ok", "value": 42}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	resp := postWebhook(router, "/json", body, nil)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = postWebhook(router, "/json", body+" ", map[string]string{"X-Hub-Signature-256": signature})
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	resp = postWebhook(router, "/json", body, map[string]string{"X-Hub-Signature-256": signature})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"webhooks_generic",
+			map[string]string{},
+			map[string]interface{}{"state": "ok", "value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGenericBasicAuth(t *testing.T) {
+	gw := &GenericWebhook{Path: "/influx"}
+	gw.Username = "user"
+	gw.Password = "pass"
+	require.NoError(t, gw.Init())
+
+	var acc testutil.Accumulator
+	router := mux.NewRouter()
+	gw.Register(router, &acc, testutil.Logger{})
+
+	resp := postWebhook(router, "/influx", "cpu value=42", nil)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	req := httptest.NewRequest("POST", "/influx", strings.NewReader("cpu value=42"))
+	req.SetBasicAuth("user", "pass")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+}
+
+func TestGenericInit(t *testing.T) {
+	require.ErrorContains(t, (&GenericWebhook{}).Init(), "path required")
+
+	gw := &GenericWebhook{Path: "/generic", Authenticator: Authenticator{SignatureHeader: "X-Signature"}}
+	require.ErrorContains(t, gw.Init(), `generic webhook "/generic": secret required`)
+
+	gw = &GenericWebhook{Path: "/generic"}
+	gw.DataFormat = "unknown"
+	require.ErrorContains(t, gw.Init(), `generic webhook "/generic"`)
+}
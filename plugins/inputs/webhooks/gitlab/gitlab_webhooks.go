@@ -0,0 +1,174 @@
+package gitlab
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tidwall/gjson"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/auth"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/generic"
+)
+
+// event describes the tags and fields extracted from an event kind as GJSON
+// paths into the payload
+type event struct {
+	tags   map[string]string
+	fields map[string]string
+}
+
+var common = map[string]string{
+	"project": "project.path_with_namespace",
+	"user":    "user.username",
+}
+
+var events = map[string]event{
+	"push": {
+		tags:   map[string]string{"ref": "ref", "user": "user_username"},
+		fields: map[string]string{"before": "before", "after": "after", "commits": "total_commits_count"},
+	},
+	"tag_push": {
+		tags:   map[string]string{"ref": "ref", "user": "user_username"},
+		fields: map[string]string{"before": "before", "after": "after"},
+	},
+	"merge_request": {
+		tags: map[string]string{
+			"action":        "object_attributes.action",
+			"state":         "object_attributes.state",
+			"source_branch": "object_attributes.source_branch",
+			"target_branch": "object_attributes.target_branch",
+		},
+		fields: map[string]string{"iid": "object_attributes.iid", "title": "object_attributes.title", "url": "object_attributes.url"},
+	},
+	"issue": {
+		tags:   map[string]string{"action": "object_attributes.action", "state": "object_attributes.state"},
+		fields: map[string]string{"iid": "object_attributes.iid", "title": "object_attributes.title", "url": "object_attributes.url"},
+	},
+	"note": {
+		tags:   map[string]string{"noteable_type": "object_attributes.noteable_type"},
+		fields: map[string]string{"id": "object_attributes.id", "url": "object_attributes.url"},
+	},
+	"pipeline": {
+		tags: map[string]string{
+			"status": "object_attributes.status",
+			"ref":    "object_attributes.ref",
+			"source": "object_attributes.source",
+		},
+		fields: map[string]string{
+			"id":              "object_attributes.id",
+			"duration":        "object_attributes.duration",
+			"queued_duration": "object_attributes.queued_duration",
+		},
+	},
+	"build": {
+		tags: map[string]string{
+			"status": "build_status",
+			"stage":  "build_stage",
+			"name":   "build_name",
+			"ref":    "ref",
+		},
+		fields: map[string]string{
+			"id":              "build_id",
+			"pipeline_id":     "pipeline_id",
+			"duration":        "build_duration",
+			"queued_duration": "build_queued_duration",
+		},
+	},
+	"deployment": {
+		tags:   map[string]string{"status": "status", "environment": "environment"},
+		fields: map[string]string{"id": "deployment_id", "url": "deployable_url"},
+	},
+	"release": {
+		tags:   map[string]string{"action": "action", "tag": "tag"},
+		fields: map[string]string{"name": "name", "url": "url"},
+	},
+	"wiki_page": {
+		tags:   map[string]string{"action": "object_attributes.action"},
+		fields: map[string]string{"title": "object_attributes.title", "url": "object_attributes.url"},
+	},
+}
+
+type GitlabWebhook struct {
+	Path   string
+	Secret string
+	auth.BasicAuth
+
+	acc           telegraf.Accumulator
+	log           telegraf.Logger
+	authenticator *generic.Authenticator
+}
+
+func (gl *GitlabWebhook) Init() error {
+	gl.authenticator = &generic.Authenticator{Secret: gl.Secret}
+	if gl.Secret != "" {
+		gl.authenticator.TokenHeader = "X-Gitlab-Token"
+	}
+	return gl.authenticator.Init()
+}
+
+func (gl *GitlabWebhook) Register(router *mux.Router, acc telegraf.Accumulator, log telegraf.Logger) {
+	gl.acc = acc
+	gl.log = log
+
+	router.HandleFunc(gl.Path, generic.NewHandler(gl.authenticator, &gl.BasicAuth, log, gl.process)).Methods("POST")
+	gl.log.Infof("Started the webhooks_gitlab on %s", gl.Path)
+}
+
+func (gl *GitlabWebhook) process(_ *http.Request, body []byte) error {
+	if !gjson.ValidBytes(body) {
+		return errors.New("invalid JSON payload")
+	}
+	payload := gjson.ParseBytes(body)
+
+	kind := payload.Get("object_kind").String()
+	e, found := events[kind]
+	if !found {
+		gl.log.Debugf("Ignoring unsupported event %q", kind)
+		return nil
+	}
+
+	tags := map[string]string{"event": kind}
+	for tag, path := range common {
+		if v := payload.Get(path); v.Exists() && v.String() != "" {
+			tags[tag] = v.String()
+		}
+	}
+	for tag, path := range e.tags {
+		if v := payload.Get(path); v.Exists() && v.String() != "" {
+			tags[tag] = v.String()
+		}
+	}
+
+	fields := make(map[string]interface{}, len(e.fields))
+	for field, path := range e.fields {
+		if v := fieldValue(field, payload.Get(path)); v != nil {
+			fields[field] = v
+		}
+	}
+	if len(fields) == 0 {
+		fields["count"] = 1
+	}
+
+	gl.acc.AddFields("gitlab_webhooks", fields, tags)
+	return nil
+}
+
+// fieldValue converts the JSON value to a field value. Durations are always
+// floats as GitLab reports them with or without fraction.
+func fieldValue(name string, v gjson.Result) interface{} {
+	switch v.Type {
+	case gjson.Number:
+		if strings.HasSuffix(name, "duration") || strings.ContainsAny(v.Raw, ".eE") {
+			return v.Float()
+		}
+		return v.Int()
+	case gjson.String:
+		return v.String()
+	case gjson.True, gjson.False:
+		return v.Bool()
+	}
+	return nil
+}
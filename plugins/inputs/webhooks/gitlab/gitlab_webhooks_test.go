@@ -0,0 +1,124 @@
+package gitlab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const pushEvent = `{
+  "object_kind": "push",
+  "before": "95790bf891e76fee5e1747ab589903a6a1f80f22",
+  "after": "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+  "ref": "refs/heads/master",
+  "user_username": "jsmith",
+  "project": {"path_with_namespace": "mike/diaspora"},
+  "total_commits_count": 4
+}`
+
+const pipelineEvent = `{
+  "object_kind": "pipeline",
+  "object_attributes": {
+    "id": 31,
+    "ref": "master",
+    "source": "merge_request_event",
+    "status": "success",
+    "duration": 63,
+    "queued_duration": 0.5
+  },
+  "user": {"username": "root"},
+  "project": {"path_with_namespace": "gitlab-org/gitlab-test"}
+}`
+
+func setup(t *testing.T, gl *GitlabWebhook) (*testutil.Accumulator, *mux.Router) {
+	require.NoError(t, gl.Init())
+
+	var acc testutil.Accumulator
+	router := mux.NewRouter()
+	gl.Register(router, &acc, testutil.Logger{})
+	return &acc, router
+}
+
+func postWebhook(router *mux.Router, body string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/gitlab", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("X-Gitlab-Token", token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPushEvent(t *testing.T) {
+	acc, router := setup(t, &GitlabWebhook{Path: "/gitlab"})
+
+	resp := postWebhook(router, pushEvent, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	fields := map[string]interface{}{
+		"before":  "95790bf891e76fee5e1747ab589903a6a1f80f22",
+		"after":   "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+		"commits": int64(4),
+	}
+	tags := map[string]string{
+		"event":   "push",
+		"project": "mike/diaspora",
+		"ref":     "refs/heads/master",
+		"user":    "jsmith",
+	}
+	acc.AssertContainsTaggedFields(t, "gitlab_webhooks", fields, tags)
+}
+
+func TestPipelineEvent(t *testing.T) {
+	acc, router := setup(t, &GitlabWebhook{Path: "/gitlab"})
+
+	resp := postWebhook(router, pipelineEvent, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	fields := map[string]interface{}{
+		"id":              int64(31),
+		"duration":        float64(63),
+		"queued_duration": 0.5,
+	}
+	tags := map[string]string{
+		"event":   "pipeline",
+		"project": "gitlab-org/gitlab-test",
+		"user":    "root",
+		"status":  "success",
+		"ref":     "master",
+		"source":  "merge_request_event",
+	}
+	acc.AssertContainsTaggedFields(t, "gitlab_webhooks", fields, tags)
+}
+
+func TestSecretToken(t *testing.T) {
+	acc, router := setup(t, &GitlabWebhook{Path: "/gitlab", Secret: "secret"})
+
+	resp := postWebhook(router, pushEvent, "")
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = postWebhook(router, pushEvent, "wrong")
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+	require.Equal(t, uint64(0), acc.NMetrics())
+
+	resp = postWebhook(router, pushEvent, "secret")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, uint64(1), acc.NMetrics())
+}
+
+func TestUnsupportedEvent(t *testing.T) {
+	acc, router := setup(t, &GitlabWebhook{Path: "/gitlab"})
+
+	resp := postWebhook(router, `{"object_kind": "feature_flag"}`, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, uint64(0), acc.NMetrics())
+
+	resp = postWebhook(router, `{"object_kind":`, "")
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
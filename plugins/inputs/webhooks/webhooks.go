@@ -12,9 +12,12 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/alertmanager"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/artifactory"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/filestack"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/generic"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/github"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/gitlab"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/mandrill"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/papertrail"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/particle"
@@ -29,6 +32,12 @@ type Webhook interface {
 	Register(router *mux.Router, acc telegraf.Accumulator, log telegraf.Logger)
 }
 
+// Initializer is implemented by webhooks that need to check their settings
+// before being registered
+type Initializer interface {
+	Init() error
+}
+
 func init() {
 	inputs.Add("webhooks", func() telegraf.Input { return NewWebhooks() })
 }
@@ -44,6 +53,10 @@ type Webhooks struct {
 	Particle    *particle.ParticleWebhook       `toml:"particle"`
 	Artifactory *artifactory.ArtifactoryWebhook `toml:"artifactory"`
 
+	Gitlab       *gitlab.GitlabWebhook             `toml:"gitlab"`
+	Alertmanager *alertmanager.AlertmanagerWebhook `toml:"alertmanager"`
+	Generic      []*generic.GenericWebhook         `toml:"generic"`
+
 	Log telegraf.Logger `toml:"-"`
 
 	srv *http.Server
@@ -57,11 +70,23 @@ func (*Webhooks) SampleConfig() string {
 	return sampleConfig
 }
 
+func (wb *Webhooks) Init() error {
+	for _, webhook := range wb.AvailableWebhooks() {
+		if w, ok := webhook.(Initializer); ok {
+			if err := w.Init(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (wb *Webhooks) Gather(_ telegraf.Accumulator) error {
 	return nil
 }
 
-// AvailableWebhooks Looks for fields which implement Webhook interface
+// AvailableWebhooks Looks for fields which implement Webhook interface or
+// are slices of such elements
 func (wb *Webhooks) AvailableWebhooks() []Webhook {
 	webhooks := make([]Webhook, 0)
 	s := reflect.ValueOf(wb).Elem()
@@ -72,6 +97,15 @@ func (wb *Webhooks) AvailableWebhooks() []Webhook {
 			continue
 		}
 
+		if f.Kind() == reflect.Slice {
+			for j := 0; j < f.Len(); j++ {
+				if wbPlugin, ok := f.Index(j).Interface().(Webhook); ok && !f.Index(j).IsNil() {
+					webhooks = append(webhooks, wbPlugin)
+				}
+			}
+			continue
+		}
+
 		if wbPlugin, ok := f.Interface().(Webhook); ok {
 			if !reflect.ValueOf(wbPlugin).IsNil() {
 				webhooks = append(webhooks, wbPlugin)
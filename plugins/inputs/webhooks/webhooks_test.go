@@ -4,11 +4,17 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/alertmanager"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/artifactory"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/generic"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/github"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/gitlab"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/papertrail"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/particle"
 	"github.com/influxdata/telegraf/plugins/inputs/webhooks/rollbar"
+	_ "github.com/influxdata/telegraf/plugins/parsers/all"
 )
 
 func TestAvailableWebhooks(t *testing.T) {
@@ -47,4 +53,32 @@ func TestAvailableWebhooks(t *testing.T) {
 	if !reflect.DeepEqual(wb.AvailableWebhooks(), expected) {
 		t.Errorf("expected to be %v.\nGot %v", expected, wb.AvailableWebhooks())
 	}
+
+	wb.Gitlab = &gitlab.GitlabWebhook{Path: "/gitlab"}
+	expected = append(expected, wb.Gitlab)
+	if !reflect.DeepEqual(wb.AvailableWebhooks(), expected) {
+		t.Errorf("expected to be %v.\nGot %v", expected, wb.AvailableWebhooks())
+	}
+
+	wb.Alertmanager = &alertmanager.AlertmanagerWebhook{Path: "/alertmanager"}
+	expected = append(expected, wb.Alertmanager)
+	if !reflect.DeepEqual(wb.AvailableWebhooks(), expected) {
+		t.Errorf("expected to be %v.\nGot %v", expected, wb.AvailableWebhooks())
+	}
+
+	wb.Generic = []*generic.GenericWebhook{{Path: "/generic1"}, {Path: "/generic2"}}
+	expected = append(expected, wb.Generic[0], wb.Generic[1])
+	if !reflect.DeepEqual(wb.AvailableWebhooks(), expected) {
+		t.Errorf("expected to be %v.\nGot %v", expected, wb.AvailableWebhooks())
+	}
+}
+
+func TestInit(t *testing.T) {
+	wb := NewWebhooks()
+	wb.Gitlab = &gitlab.GitlabWebhook{Path: "/gitlab"}
+	wb.Generic = []*generic.GenericWebhook{{Path: "/generic"}}
+	require.NoError(t, wb.Init())
+
+	wb.Generic = append(wb.Generic, &generic.GenericWebhook{})
+	require.ErrorContains(t, wb.Init(), "path required")
 }
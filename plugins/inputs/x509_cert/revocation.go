@@ -0,0 +1,229 @@
+package x509_cert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxRevocationResponseSize limits the size of OCSP responses and CRLs
+const maxRevocationResponseSize = 32 * 1024 * 1024
+
+var revocationCodes = map[string]int{
+	"good":    0,
+	"revoked": 1,
+	"unknown": 2,
+	"error":   3,
+}
+
+// revocationStatus is the result of an OCSP or CRL check
+type revocationStatus struct {
+	status     string
+	revokedAt  time.Time
+	nextUpdate time.Time
+	err        error
+}
+
+// cachedCRL is a downloaded revocation list
+type cachedCRL struct {
+	list    *x509.RevocationList
+	fetched time.Time
+}
+
+// crlCache keeps downloaded revocation lists until they are outdated or the
+// maximum age is exceeded
+type crlCache struct {
+	maxAge time.Duration
+
+	sync.Mutex
+	entries map[string]*cachedCRL
+}
+
+func (s revocationStatus) addTo(prefix string, fields map[string]interface{}, tags map[string]string) {
+	tags[prefix+"_status"] = s.status
+	fields[prefix+"_status_code"] = revocationCodes[s.status]
+	if !s.revokedAt.IsZero() {
+		fields[prefix+"_revoked_at"] = s.revokedAt.Unix()
+	}
+	if !s.nextUpdate.IsZero() {
+		fields[prefix+"_next_update"] = s.nextUpdate.Unix()
+	}
+	if s.err != nil {
+		fields[prefix+"_error"] = s.err.Error()
+	}
+}
+
+// checkOCSP determines the revocation status of the certificate using the
+// stapled OCSP response, if any, or by querying the OCSP responders of the
+// certificate. The second return value is false if no responder is available.
+func (c *X509Cert) checkOCSP(cert, issuer *x509.Certificate, stapled []byte) (revocationStatus, bool) {
+	if len(stapled) == 0 && len(cert.OCSPServer) == 0 {
+		return revocationStatus{}, false
+	}
+	if issuer == nil {
+		return revocationStatus{status: "error", err: errors.New("issuer not found")}, true
+	}
+
+	if len(stapled) > 0 {
+		resp, err := ocsp.ParseResponseForCert(stapled, cert, issuer)
+		if err != nil {
+			return revocationStatus{status: "error", err: fmt.Errorf("parsing stapled response failed: %w", err)}, true
+		}
+		return ocspStatus(resp), true
+	}
+
+	request, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return revocationStatus{status: "error", err: err}, true
+	}
+	for _, server := range cert.OCSPServer {
+		var resp *ocsp.Response
+		resp, err = c.queryOCSP(server, request, cert, issuer)
+		if err == nil {
+			return ocspStatus(resp), true
+		}
+		c.Log.Debugf("Querying OCSP responder %q failed: %v", server, err)
+	}
+	return revocationStatus{status: "error", err: err}, true
+}
+
+func (c *X509Cert) queryOCSP(server string, request []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	resp, err := c.client.Post(server, "application/ocsp-request", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(body, cert, issuer)
+}
+
+func ocspStatus(resp *ocsp.Response) revocationStatus {
+	s := revocationStatus{nextUpdate: resp.NextUpdate}
+	switch resp.Status {
+	case ocsp.Good:
+		s.status = "good"
+	case ocsp.Revoked:
+		s.status = "revoked"
+		s.revokedAt = resp.RevokedAt
+	default:
+		s.status = "unknown"
+	}
+	return s
+}
+
+// checkCRL determines the revocation status of the certificate using the
+// revocation lists of the certificate's distribution points. The second return
+// value is false if the certificate has no distribution points.
+func (c *X509Cert) checkCRL(cert, issuer *x509.Certificate, now time.Time) (revocationStatus, bool) {
+	if len(cert.CRLDistributionPoints) == 0 {
+		return revocationStatus{}, false
+	}
+	if issuer == nil {
+		return revocationStatus{status: "error", err: errors.New("issuer not found")}, true
+	}
+
+	var err error
+	for _, location := range cert.CRLDistributionPoints {
+		var list *x509.RevocationList
+		list, err = c.crls.get(c.client, location, issuer, now)
+		if err != nil {
+			c.Log.Debugf("Getting CRL %q failed: %v", location, err)
+			continue
+		}
+
+		s := revocationStatus{status: "good", nextUpdate: list.NextUpdate}
+		for _, revoked := range list.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				s.status = "revoked"
+				s.revokedAt = revoked.RevocationTime
+				break
+			}
+		}
+		return s, true
+	}
+	return revocationStatus{status: "error", err: err}, true
+}
+
+// get returns the cached revocation list of the given location or downloads
+// it if it is not cached or outdated
+func (cache *crlCache) get(client *http.Client, location string, issuer *x509.Certificate, now time.Time) (*x509.RevocationList, error) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	if entry, found := cache.entries[location]; found {
+		outdated := !entry.list.NextUpdate.IsZero() && now.After(entry.list.NextUpdate)
+		if !outdated && now.Sub(entry.fetched) < cache.maxAge {
+			if err := entry.list.CheckSignatureFrom(issuer); err != nil {
+				return nil, fmt.Errorf("invalid signature: %w", err)
+			}
+			return entry.list, nil
+		}
+		delete(cache.entries, location)
+	}
+
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing failed: %w", err)
+	}
+	if err := list.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	if !list.NextUpdate.IsZero() && now.After(list.NextUpdate) {
+		return nil, fmt.Errorf("outdated since %v", list.NextUpdate)
+	}
+
+	cache.entries[location] = &cachedCRL{list: list, fetched: now}
+	return list, nil
+}
+
+// findIssuer returns the issuer of the certificate from the verified chains
+// or the certificates presented by the source. Nil is returned if the issuer
+// cannot be found.
+func findIssuer(cert *x509.Certificate, chains [][]*x509.Certificate, certs []*x509.Certificate) *x509.Certificate {
+	for _, chain := range chains {
+		if len(chain) > 1 && chain[0].Equal(cert) {
+			return chain[1]
+		}
+	}
+	for _, candidate := range certs {
+		if candidate.Equal(cert) {
+			continue
+		}
+		if bytes.Equal(candidate.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// isSelfSigned returns true for root certificates
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
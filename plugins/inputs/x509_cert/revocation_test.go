@@ -0,0 +1,309 @@
+package x509_cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// testCA is a locally generated certificate authority with an OCSP responder
+// and a CRL distribution point
+type testCA struct {
+	cert    *x509.Certificate
+	key     crypto.Signer
+	revoked map[int64]time.Time
+	server  *httptest.Server
+
+	ocspRequests int32
+	crlRequests  int32
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+
+	ca := &testCA{
+		cert:    cert,
+		key:     key,
+		revoked: make(map[int64]time.Time),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ocsp", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ca.ocspRequests, 1)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		request, err := ocsp.ParseRequest(body)
+		require.NoError(t, err)
+		_, err = w.Write(ca.ocspResponse(t, request.SerialNumber))
+		require.NoError(t, err)
+	})
+	mux.HandleFunc("/crl", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ca.crlRequests, 1)
+		_, err := w.Write(ca.crl(t))
+		require.NoError(t, err)
+	})
+	ca.server = httptest.NewServer(mux)
+	t.Cleanup(ca.server.Close)
+
+	return ca
+}
+
+// issue creates a leaf certificate with the OCSP and CRL locations of the CA
+func (ca *testCA) issue(t *testing.T, serial int64, revoked bool) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		OCSPServer:            []string{ca.server.URL + "/ocsp"},
+		CRLDistributionPoints: []string{ca.server.URL + "/crl"},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+
+	if revoked {
+		ca.revoked[serial] = now.Add(-30 * time.Minute).Truncate(time.Second)
+	}
+	return tls.Certificate{Certificate: [][]byte{raw, ca.cert.Raw}, PrivateKey: key}
+}
+
+func (ca *testCA) ocspResponse(t *testing.T, serial *big.Int) []byte {
+	now := time.Now().Truncate(time.Minute)
+	template := ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: serial,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(time.Hour),
+	}
+	if revokedAt, found := ca.revoked[serial.Int64()]; found {
+		template.Status = ocsp.Revoked
+		template.RevokedAt = revokedAt
+		template.RevocationReason = ocsp.KeyCompromise
+	}
+	resp, err := ocsp.CreateResponse(ca.cert, ca.cert, template, ca.key)
+	require.NoError(t, err)
+	return resp
+}
+
+func (ca *testCA) crl(t *testing.T) []byte {
+	now := time.Now()
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now.Add(-time.Minute),
+		NextUpdate: now.Add(time.Hour),
+	}
+	for serial, revokedAt := range ca.revoked {
+		template.RevokedCertificates = append(template.RevokedCertificates, pkix.RevokedCertificate{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: revokedAt,
+		})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.key)
+	require.NoError(t, err)
+	return crl
+}
+
+// writeChain writes the PEM encoded certificates to a temporary file
+func writeChain(t *testing.T, name string, certs ...[]byte) string {
+	var content []byte
+	for _, raw := range certs {
+		content = append(content, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw})...)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	return path
+}
+
+func TestRevocation(t *testing.T) {
+	ca := newTestCA(t)
+	good := ca.issue(t, 10, false)
+	revoked := ca.issue(t, 11, true)
+
+	plugin := &X509Cert{
+		Sources: []string{
+			writeChain(t, "good.pem", good.Certificate...),
+			writeChain(t, "revoked.pem", revoked.Certificate...),
+		},
+		CABundles:        []string{writeChain(t, "ca.pem", ca.cert.Raw)},
+		OCSP:             true,
+		CRL:              true,
+		ExcludeRootCerts: true,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	// The CRL must only be fetched once
+	for i := 0; i < 2; i++ {
+		var acc testutil.Accumulator
+		require.NoError(t, plugin.Gather(&acc))
+		require.Empty(t, acc.Errors)
+
+		metrics := acc.GetTelegrafMetrics()
+		require.Len(t, metrics, 2)
+		for _, m := range metrics {
+			require.Equal(t, "valid", m.Tags()["verification"])
+
+			expected := "good"
+			if m.Tags()["serial_number"] == "b" {
+				expected = "revoked"
+				revokedAt := ca.revoked[11].Unix()
+				require.Equal(t, revokedAt, m.Fields()["ocsp_revoked_at"])
+				require.Equal(t, revokedAt, m.Fields()["crl_revoked_at"])
+			}
+			require.Equal(t, expected, m.Tags()["ocsp_status"])
+			require.Equal(t, int64(revocationCodes[expected]), m.Fields()["ocsp_status_code"])
+			require.Equal(t, false, m.Fields()["ocsp_stapled"])
+			require.Contains(t, m.Fields(), "ocsp_next_update")
+			require.Equal(t, expected, m.Tags()["crl_status"])
+			require.Equal(t, int64(revocationCodes[expected]), m.Fields()["crl_status_code"])
+			require.Contains(t, m.Fields(), "crl_next_update")
+		}
+	}
+	require.Equal(t, int32(4), atomic.LoadInt32(&ca.ocspRequests))
+	require.Equal(t, int32(1), atomic.LoadInt32(&ca.crlRequests))
+}
+
+func TestRevocationRootSkipped(t *testing.T) {
+	ca := newTestCA(t)
+	good := ca.issue(t, 10, false)
+
+	plugin := &X509Cert{
+		Sources:   []string{writeChain(t, "good.pem", good.Certificate...)},
+		CABundles: []string{writeChain(t, "ca.pem", ca.cert.Raw)},
+		OCSP:      true,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, "good", metrics[0].Tags()["ocsp_status"])
+	require.NotContains(t, metrics[1].Tags(), "ocsp_status")
+	require.NotContains(t, metrics[1].Tags(), "crl_status")
+}
+
+func TestRevocationIssuerNotFound(t *testing.T) {
+	ca := newTestCA(t)
+	good := ca.issue(t, 10, false)
+
+	plugin := &X509Cert{
+		Sources: []string{writeChain(t, "good.pem", good.Certificate[0])},
+		CRL:     true,
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, "invalid", metrics[0].Tags()["verification"])
+	require.Contains(t, metrics[0].Fields(), "verification_error")
+	require.Equal(t, "error", metrics[0].Tags()["crl_status"])
+	require.Equal(t, "issuer not found", metrics[0].Fields()["crl_error"])
+	require.Equal(t, int32(0), atomic.LoadInt32(&ca.crlRequests))
+}
+
+func TestOCSPStapling(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, 12, true)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	cert.OCSPStaple = ca.ocspResponse(t, leaf.SerialNumber)
+
+	ts := httptest.NewUnstartedServer(http.NotFoundHandler())
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	ts.StartTLS()
+	defer ts.Close()
+
+	plugin := &X509Cert{
+		Sources:          []string{"tcp://" + ts.Listener.Addr().String()},
+		ServerName:       "localhost",
+		CABundles:        []string{writeChain(t, "ca.pem", ca.cert.Raw)},
+		OCSP:             true,
+		ExcludeRootCerts: true,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, "valid", metrics[0].Tags()["verification"])
+	require.Equal(t, true, metrics[0].Fields()["ocsp_stapled"])
+	require.Equal(t, "revoked", metrics[0].Tags()["ocsp_status"])
+	require.Equal(t, int32(0), atomic.LoadInt32(&ca.ocspRequests))
+}
+
+func TestCABundles(t *testing.T) {
+	ca := newTestCA(t)
+	other := newTestCA(t)
+	good := ca.issue(t, 10, false)
+
+	plugin := &X509Cert{
+		Sources:   []string{writeChain(t, "good.pem", good.Certificate[0])},
+		CABundles: []string{writeChain(t, "other.pem", other.cert.Raw)},
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, "invalid", metrics[0].Tags()["verification"])
+	require.Contains(t, metrics[0].Fields()["verification_error"], "certificate signed by unknown authority")
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("foo"), 0600))
+	plugin = &X509Cert{CABundles: []string{empty}}
+	require.ErrorContains(t, plugin.Init(), "no certificates found in CA bundle")
+}
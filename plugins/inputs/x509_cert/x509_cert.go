@@ -11,6 +11,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net"
+	"net/http"
 	"net/smtp"
 	"net/url"
 	"os"
@@ -39,12 +40,23 @@ type X509Cert struct {
 	Timeout          config.Duration `toml:"timeout"`
 	ServerName       string          `toml:"server_name"`
 	ExcludeRootCerts bool            `toml:"exclude_root_certs"`
-	tlsCfg           *tls.Config
+
+	CABundles          []string        `toml:"ca_bundles"`
+	IncludeSystemRoots bool            `toml:"include_system_roots"`
+	OCSP               bool            `toml:"ocsp"`
+	CRL                bool            `toml:"crl"`
+	CRLCacheTTL        config.Duration `toml:"crl_cache_ttl"`
+
+	tlsCfg *tls.Config
 	_tls.ClientConfig
 	proxy.TCPProxy
 	locations []*url.URL
 	globpaths []*globpath.GlobPath
 	Log       telegraf.Logger
+
+	roots  *x509.CertPool
+	client *http.Client
+	crls   *crlCache
 }
 
 func (c *X509Cert) sourcesToURLs() error {
@@ -85,19 +97,21 @@ func (c *X509Cert) serverName(u *url.URL) (string, error) {
 	return u.Hostname(), nil
 }
 
-func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certificate, error) {
+// getCert returns the certificates of the source and, for TLS connections,
+// the OCSP response stapled by the server if any
+func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certificate, []byte, error) {
 	protocol := u.Scheme
 	switch u.Scheme {
 	case "udp", "udp4", "udp6":
 		ipConn, err := net.DialTimeout(u.Scheme, u.Host, timeout)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer ipConn.Close()
 
 		serverName, err := c.serverName(u)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		dtlsCfg := &dtls.Config{
@@ -108,7 +122,7 @@ func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certifica
 		}
 		conn, err := dtls.Client(ipConn, dtlsCfg)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer conn.Close()
 
@@ -117,7 +131,7 @@ func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certifica
 		for _, rawCert := range rawCerts {
 			parsed, err := x509.ParseCertificate(rawCert)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			if parsed != nil {
@@ -125,25 +139,25 @@ func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certifica
 			}
 		}
 
-		return certs, nil
+		return certs, nil, nil
 	case "https":
 		protocol = "tcp"
 		fallthrough
 	case "tcp", "tcp4", "tcp6":
 		dialer, err := c.Proxy()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		ipConn, err := dialer.DialTimeout(protocol, u.Host, timeout)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer ipConn.Close()
 
 		serverName, err := c.serverName(u)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		downloadTLSCfg := c.tlsCfg.Clone()
@@ -155,28 +169,28 @@ func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certifica
 
 		hsErr := conn.Handshake()
 		if hsErr != nil {
-			return nil, hsErr
+			return nil, nil, hsErr
 		}
 
-		certs := conn.ConnectionState().PeerCertificates
+		state := conn.ConnectionState()
 
-		return certs, nil
+		return state.PeerCertificates, state.OCSPResponse, nil
 	case "file":
 		content, err := os.ReadFile(u.Path)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		var certs []*x509.Certificate
 		for {
 			block, rest := pem.Decode(bytes.TrimSpace(content))
 			if block == nil {
-				return nil, fmt.Errorf("failed to parse certificate PEM")
+				return nil, nil, fmt.Errorf("failed to parse certificate PEM")
 			}
 
 			if block.Type == "CERTIFICATE" {
 				cert, err := x509.ParseCertificate(block.Bytes)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				certs = append(certs, cert)
 			}
@@ -185,17 +199,17 @@ func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certifica
 			}
 			content = rest
 		}
-		return certs, nil
+		return certs, nil, nil
 	case "smtp":
 		ipConn, err := net.DialTimeout("tcp", u.Host, timeout)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer ipConn.Close()
 
 		serverName, err := c.serverName(u)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		downloadTLSCfg := c.tlsCfg.Clone()
@@ -204,24 +218,24 @@ func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certifica
 
 		smtpConn, err := smtp.NewClient(ipConn, u.Host)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		err = smtpConn.Hello(downloadTLSCfg.ServerName)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		id, err := smtpConn.Text.Cmd("STARTTLS")
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		smtpConn.Text.StartResponse(id)
 		defer smtpConn.Text.EndResponse(id)
 		_, _, err = smtpConn.Text.ReadResponse(220)
 		if err != nil {
-			return nil, fmt.Errorf("did not get 220 after STARTTLS: %s", err.Error())
+			return nil, nil, fmt.Errorf("did not get 220 after STARTTLS: %s", err.Error())
 		}
 
 		tlsConn := tls.Client(ipConn, downloadTLSCfg)
@@ -229,14 +243,14 @@ func (c *X509Cert) getCert(u *url.URL, timeout time.Duration) ([]*x509.Certifica
 
 		hsErr := tlsConn.Handshake()
 		if hsErr != nil {
-			return nil, hsErr
+			return nil, nil, hsErr
 		}
 
-		certs := tlsConn.ConnectionState().PeerCertificates
+		state := tlsConn.ConnectionState()
 
-		return certs, nil
+		return state.PeerCertificates, state.OCSPResponse, nil
 	default:
-		return nil, fmt.Errorf("unsupported scheme '%s' in location %s", u.Scheme, u.String())
+		return nil, nil, fmt.Errorf("unsupported scheme '%s' in location %s", u.Scheme, u.String())
 	}
 }
 
@@ -331,7 +345,7 @@ func (c *X509Cert) Gather(acc telegraf.Accumulator) error {
 	}
 
 	for _, location := range append(c.locations, collectedUrls...) {
-		certs, err := c.getCert(location, time.Duration(c.Timeout))
+		certs, stapled, err := c.getCert(location, time.Duration(c.Timeout))
 		if err != nil {
 			acc.AddError(fmt.Errorf("cannot get SSL cert '%s': %s", location, err.Error()))
 		}
@@ -357,11 +371,13 @@ func (c *X509Cert) Gather(acc telegraf.Accumulator) error {
 					}
 				}
 			}
-			if c.tlsCfg.RootCAs != nil {
+			if c.roots != nil {
+				opts.Roots = c.roots
+			} else if c.tlsCfg.RootCAs != nil {
 				opts.Roots = c.tlsCfg.RootCAs
 			}
 
-			_, err = cert.Verify(opts)
+			chains, err := cert.Verify(opts)
 			if err == nil {
 				tags["verification"] = "valid"
 				fields["verification_code"] = 0
@@ -380,6 +396,26 @@ func (c *X509Cert) Gather(acc telegraf.Accumulator) error {
 				fields["verification_error"] = err.Error()
 			}
 
+			if (c.OCSP || c.CRL) && !isSelfSigned(cert) {
+				issuer := findIssuer(cert, chains, certs)
+				if c.OCSP {
+					// Only the leaf certificate can be stapled
+					var staple []byte
+					if i == 0 {
+						staple = stapled
+						fields["ocsp_stapled"] = len(stapled) > 0
+					}
+					if status, ok := c.checkOCSP(cert, issuer, staple); ok {
+						status.addTo("ocsp", fields, tags)
+					}
+				}
+				if c.CRL {
+					if status, ok := c.checkCRL(cert, issuer, now); ok {
+						status.addTo("crl", fields, tags)
+					}
+				}
+			}
+
 			acc.AddFields("x509_cert", fields, tags)
 			if c.ExcludeRootCerts {
 				break
@@ -414,9 +450,51 @@ func (c *X509Cert) Init() error {
 	}
 	c.tlsCfg = tlsCfg
 
+	if len(c.CABundles) > 0 {
+		if c.roots, err = loadCABundles(c.CABundles, c.IncludeSystemRoots); err != nil {
+			return err
+		}
+	}
+
+	if c.OCSP || c.CRL {
+		if c.CRLCacheTTL <= 0 {
+			c.CRLCacheTTL = config.Duration(time.Hour)
+		}
+		c.client = &http.Client{
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+			Timeout:   time.Duration(c.Timeout),
+		}
+		c.crls = &crlCache{
+			maxAge:  time.Duration(c.CRLCacheTTL),
+			entries: make(map[string]*cachedCRL),
+		}
+	}
+
 	return nil
 }
 
+// loadCABundles creates the trust store for verifying certificates from the
+// given PEM files and optionally the system roots
+func loadCABundles(files []string, includeSystem bool) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if includeSystem {
+		var err error
+		if pool, err = x509.SystemCertPool(); err != nil {
+			return nil, fmt.Errorf("loading system roots failed: %w", err)
+		}
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle failed: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %q", file)
+		}
+	}
+	return pool, nil
+}
+
 func init() {
 	inputs.Add("x509_cert", func() telegraf.Input {
 		return &X509Cert{
@@ -0,0 +1,5 @@
+//go:build !custom || outputs || outputs.pulsar
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/outputs/pulsar" // register plugin
@@ -0,0 +1,5 @@
+//go:build !custom || outputs || outputs.statsd
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/outputs/statsd" // register plugin
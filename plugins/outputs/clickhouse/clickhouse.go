@@ -0,0 +1,441 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package clickhouse
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	timestampColumnType = "DateTime64(9)"
+	tagsColumnType      = "Map(String, String)"
+)
+
+type ClickHouse struct {
+	Servers         []string        `toml:"servers"`
+	Database        string          `toml:"database"`
+	Username        string          `toml:"username"`
+	Password        string          `toml:"password"`
+	Timeout         config.Duration `toml:"timeout"`
+	Compression     string          `toml:"compression"`
+	TimestampColumn string          `toml:"timestamp_column"`
+	TagsAsMap       bool            `toml:"tags_as_map"`
+	TagsColumn      string          `toml:"tags_column"`
+	CreateTables    bool            `toml:"create_tables"`
+	AddColumns      bool            `toml:"add_columns"`
+	Engine          string          `toml:"engine"`
+	PartitionBy     string          `toml:"partition_by"`
+	OrderBy         string          `toml:"order_by"`
+	TTL             string          `toml:"ttl"`
+	TableSettings   string          `toml:"table_settings"`
+	Log             telegraf.Logger `toml:"-"`
+	tls.ClientConfig
+
+	options *clickhouse.Options
+	client  client
+	tables  map[string]*table
+}
+
+// cell is the value of a tag or field of a metric in the column it is
+// written to
+type cell struct {
+	name  string
+	value interface{}
+	tag   bool
+}
+
+func (*ClickHouse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *ClickHouse) Init() error {
+	if len(c.Servers) == 0 {
+		return errors.New("no servers configured")
+	}
+	if c.Database == "" {
+		return errors.New("database required")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if c.TimestampColumn == "" {
+		return errors.New("timestamp column required")
+	}
+	if c.TagsAsMap && (c.TagsColumn == "" || c.TagsColumn == c.TimestampColumn) {
+		return fmt.Errorf("invalid tags column %q", c.TagsColumn)
+	}
+	if c.CreateTables && c.Engine == "" {
+		return errors.New("engine required for creating tables")
+	}
+
+	c.options = &clickhouse.Options{
+		Addr: c.Servers,
+		Auth: clickhouse.Auth{
+			Database: c.Database,
+			Username: c.Username,
+			Password: c.Password,
+		},
+		DialTimeout: time.Duration(c.Timeout),
+		ReadTimeout: time.Duration(c.Timeout),
+	}
+
+	switch c.Compression {
+	case "", "none":
+	case "lz4":
+		c.options.Compression = &clickhouse.Compression{Method: clickhouse.CompressionLZ4}
+	default:
+		return fmt.Errorf("invalid compression %q", c.Compression)
+	}
+
+	tlsConfig, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	c.options.TLS = tlsConfig
+
+	c.tables = make(map[string]*table)
+
+	return nil
+}
+
+func (c *ClickHouse) Connect() error {
+	nc, err := newNativeClient(c.options)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout))
+	defer cancel()
+	if err := nc.conn.Ping(ctx); err != nil {
+		nc.close() //nolint:revive // There is another error which will be returned here
+		return fmt.Errorf("connecting to %s failed: %v", strings.Join(c.Servers, ","), err)
+	}
+
+	c.client = nc
+	return nil
+}
+
+func (c *ClickHouse) Close() error {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.close()
+}
+
+// Write inserts the metrics of each measurement into the table of the same
+// name using a single block per table
+func (c *ClickHouse) Write(metrics []telegraf.Metric) error {
+	var names []string
+	batches := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		if _, found := batches[m.Name()]; !found {
+			names = append(names, m.Name())
+		}
+		batches[m.Name()] = append(batches[m.Name()], m)
+	}
+
+	for _, name := range names {
+		if err := c.writeTable(name, batches[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ClickHouse) writeTable(name string, metrics []telegraf.Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout))
+	defer cancel()
+
+	rows := make([][]cell, 0, len(metrics))
+	for _, m := range metrics {
+		rows = append(rows, c.cells(m))
+	}
+
+	t, err := c.table(ctx, name, rows)
+	if err != nil {
+		return err
+	}
+
+	query, data := c.insert(name, t, metrics, rows)
+	if err := c.client.insert(ctx, query, data); err != nil {
+		// The table might have been modified by someone else, so the columns
+		// are queried again on the next write
+		delete(c.tables, name)
+		return fmt.Errorf("inserting into %q failed: %v", name, err)
+	}
+	return nil
+}
+
+// cells returns the tags and fields of the metric with the name of their
+// column. Fields are suffixed with "_field" if their name collides with a
+// tag or the timestamp column, tags colliding with the timestamp column with
+// "_tag".
+func (c *ClickHouse) cells(m telegraf.Metric) []cell {
+	cells := make([]cell, 0, len(m.TagList())+len(m.FieldList()))
+	if !c.TagsAsMap {
+		for _, tag := range m.TagList() {
+			name := tag.Key
+			if name == c.TimestampColumn {
+				name += "_tag"
+			}
+			cells = append(cells, cell{name: name, value: tag.Value, tag: true})
+		}
+	}
+	for _, field := range m.FieldList() {
+		name := field.Key
+		if name == c.TimestampColumn || (c.TagsAsMap && name == c.TagsColumn) || (!c.TagsAsMap && m.HasTag(name)) {
+			name += "_field"
+		}
+		cells = append(cells, cell{name: name, value: field.Value})
+	}
+	return cells
+}
+
+// table returns the columns of the table after creating the table or adding
+// the columns missing for the given rows if enabled
+func (c *ClickHouse) table(ctx context.Context, name string, rows [][]cell) (*table, error) {
+	t, found := c.tables[name]
+	if !found {
+		names, types, err := c.client.columns(ctx, c.Database, name)
+		if err != nil {
+			return nil, fmt.Errorf("querying columns of %q failed: %v", name, err)
+		}
+		if len(names) == 0 {
+			if !c.CreateTables {
+				return nil, fmt.Errorf("table %q does not exist", name)
+			}
+			if t, err = c.createTable(ctx, name, rows); err != nil {
+				return nil, err
+			}
+			c.tables[name] = t
+			return t, nil
+		}
+
+		t = newTable(names, types)
+		if t.byName[c.TimestampColumn] == nil {
+			return nil, fmt.Errorf("table %q has no timestamp column %q", name, c.TimestampColumn)
+		}
+		c.tables[name] = t
+	}
+
+	if !c.AddColumns {
+		return t, nil
+	}
+
+	tags, fields := c.missingColumns(t, rows)
+	missing := append(tags, fields...)
+	if c.TagsAsMap && t.byName[c.TagsColumn] == nil {
+		missing = append([]*column{newColumn(c.TagsColumn, tagsColumnType)}, missing...)
+	}
+	if len(missing) == 0 {
+		return t, nil
+	}
+
+	clauses := make([]string, 0, len(missing))
+	for _, col := range missing {
+		clauses = append(clauses, "ADD COLUMN IF NOT EXISTS "+quoteIdent(col.name)+" "+col.ctype)
+	}
+	query := "ALTER TABLE " + c.tableIdent(name) + " " + strings.Join(clauses, ", ")
+	if err := c.client.exec(ctx, query); err != nil {
+		return nil, fmt.Errorf("adding columns to %q failed: %v", name, err)
+	}
+	for _, col := range missing {
+		c.Log.Debugf("Added column %q of type %s to %q", col.name, col.ctype, name)
+		t.add(col)
+	}
+
+	return t, nil
+}
+
+// missingColumns returns the new columns for the tags and fields not present
+// in the table sorted by name. The type of new field columns is taken from
+// the first value.
+func (c *ClickHouse) missingColumns(t *table, rows [][]cell) (tags, fields []*column) {
+	added := make(map[string]bool)
+	for _, row := range rows {
+		for _, cl := range row {
+			if t.byName[cl.name] != nil || added[cl.name] {
+				continue
+			}
+			if cl.tag {
+				tags = append(tags, newColumn(cl.name, tagColumnType))
+			} else {
+				ctype, ok := fieldColumnType(cl.value)
+				if !ok {
+					continue
+				}
+				fields = append(fields, newColumn(cl.name, ctype))
+			}
+			added[cl.name] = true
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].name < tags[j].name })
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	return tags, fields
+}
+
+func (c *ClickHouse) createTable(ctx context.Context, name string, rows [][]cell) (*table, error) {
+	t := &table{byName: make(map[string]*column)}
+	t.add(newColumn(c.TimestampColumn, timestampColumnType))
+	if c.TagsAsMap {
+		t.add(newColumn(c.TagsColumn, tagsColumnType))
+	}
+
+	// The tag columns followed by the timestamp are the default sorting key.
+	// Maps cannot be part of the key.
+	tags, fields := c.missingColumns(t, rows)
+	keys := make([]string, 0, len(tags)+1)
+	for _, col := range tags {
+		t.add(col)
+		keys = append(keys, quoteIdent(col.name))
+	}
+	keys = append(keys, quoteIdent(c.TimestampColumn))
+	for _, col := range fields {
+		t.add(col)
+	}
+
+	definitions := make([]string, 0, len(t.columns))
+	for _, col := range t.columns {
+		definitions = append(definitions, quoteIdent(col.name)+" "+col.ctype)
+	}
+
+	replacer := strings.NewReplacer(
+		"{TABLE}", quoteIdent(name),
+		"{TIMESTAMP}", quoteIdent(c.TimestampColumn),
+		"{KEY_COLUMNS}", strings.Join(keys, ", "),
+	)
+	query := "CREATE TABLE IF NOT EXISTS " + c.tableIdent(name) +
+		" (" + strings.Join(definitions, ", ") + ")" +
+		" ENGINE = " + replacer.Replace(c.Engine)
+	if c.PartitionBy != "" {
+		query += " PARTITION BY " + replacer.Replace(c.PartitionBy)
+	}
+	if c.OrderBy != "" {
+		query += " ORDER BY " + replacer.Replace(c.OrderBy)
+	}
+	if c.TTL != "" {
+		query += " TTL " + replacer.Replace(c.TTL)
+	}
+	if c.TableSettings != "" {
+		query += " SETTINGS " + c.TableSettings
+	}
+
+	if err := c.client.exec(ctx, query); err != nil {
+		return nil, fmt.Errorf("creating table %q failed: %v", name, err)
+	}
+	c.Log.Debugf("Created table %q", name)
+
+	return t, nil
+}
+
+// insert returns the statement and the column data inserting the metrics.
+// Only columns present in the metrics are inserted so the server fills in
+// the defaults of the others.
+func (c *ClickHouse) insert(name string, t *table, metrics []telegraf.Metric, rows [][]cell) (string, []interface{}) {
+	used := make(map[string]bool)
+	for _, row := range rows {
+		for _, cl := range row {
+			used[cl.name] = true
+		}
+	}
+
+	columns := make([]*column, 0, len(t.columns))
+	index := make(map[string]int, len(t.columns))
+	for _, col := range t.columns {
+		if !used[col.name] || col.name == c.TimestampColumn || (c.TagsAsMap && col.name == c.TagsColumn) {
+			continue
+		}
+		if col.dtype == typeUnsupported {
+			if !t.warned[col.name] {
+				c.Log.Warnf("Column %q of %q has unsupported type %s, its values are dropped", col.name, name, col.ctype)
+				t.warn(col.name)
+			}
+			continue
+		}
+		index[col.name] = len(columns)
+		columns = append(columns, col)
+	}
+
+	values := make([][]interface{}, len(columns))
+	for i := range values {
+		values[i] = make([]interface{}, len(rows))
+	}
+	for i, row := range rows {
+		for _, cl := range row {
+			j, found := index[cl.name]
+			if !found {
+				continue
+			}
+			col := columns[j]
+			values[j][i] = col.convert(cl.value)
+			if values[j][i] == nil && !t.warned[col.name] {
+				written := "NULL"
+				if !col.nullable {
+					written = fmt.Sprintf("%#v", col.zero())
+				}
+				c.Log.Warnf("Value %v (%T) does not fit column %q of type %s of %q, writing %s", cl.value, cl.value, col.name, col.ctype, name, written)
+				t.warn(col.name)
+			}
+		}
+	}
+
+	timestamps := make([]time.Time, 0, len(metrics))
+	for _, m := range metrics {
+		timestamps = append(timestamps, m.Time())
+	}
+
+	names := []string{quoteIdent(c.TimestampColumn)}
+	data := []interface{}{timestamps}
+	if c.TagsAsMap {
+		tags := make([]map[string]string, 0, len(metrics))
+		for _, m := range metrics {
+			tags = append(tags, m.Tags())
+		}
+		names = append(names, quoteIdent(c.TagsColumn))
+		data = append(data, tags)
+	}
+	for i, col := range columns {
+		names = append(names, quoteIdent(col.name))
+		data = append(data, col.data(values[i]))
+	}
+
+	return "INSERT INTO " + c.tableIdent(name) + " (" + strings.Join(names, ", ") + ")", data
+}
+
+func (c *ClickHouse) tableIdent(name string) string {
+	return quoteIdent(c.Database) + "." + quoteIdent(name)
+}
+
+func init() {
+	outputs.Add("clickhouse", func() telegraf.Output {
+		return &ClickHouse{
+			Servers:         []string{"localhost:9000"},
+			Database:        "default",
+			Timeout:         config.Duration(5 * time.Second),
+			Compression:     "lz4",
+			TimestampColumn: "timestamp",
+			TagsColumn:      "tags",
+			CreateTables:    true,
+			AddColumns:      true,
+			Engine:          "MergeTree",
+			PartitionBy:     "toYYYYMM({TIMESTAMP})",
+			OrderBy:         "({KEY_COLUMNS})",
+		}
+	})
+}
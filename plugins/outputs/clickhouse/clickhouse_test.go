@@ -0,0 +1,389 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// recordingClient records the statements sent to the server and returns the
+// configured columns of existing tables
+type recordingClient struct {
+	tables     map[string][2][]string
+	statements []string
+	inserts    []recordedInsert
+	insertErr  error
+}
+
+type recordedInsert struct {
+	query string
+	data  []interface{}
+}
+
+func (c *recordingClient) exec(_ context.Context, query string) error {
+	c.statements = append(c.statements, query)
+	return nil
+}
+
+func (c *recordingClient) columns(_ context.Context, database, table string) ([]string, []string, error) {
+	columns := c.tables[database+"."+table]
+	return columns[0], columns[1], nil
+}
+
+func (c *recordingClient) insert(_ context.Context, query string, data []interface{}) error {
+	if c.insertErr != nil {
+		return c.insertErr
+	}
+	c.inserts = append(c.inserts, recordedInsert{query: query, data: data})
+	return nil
+}
+
+func (*recordingClient) close() error {
+	return nil
+}
+
+func newPlugin(t *testing.T) (*ClickHouse, *recordingClient) {
+	plugin := &ClickHouse{
+		Servers:         []string{"localhost:9000"},
+		Database:        "default",
+		Timeout:         config.Duration(5 * time.Second),
+		TimestampColumn: "timestamp",
+		TagsColumn:      "tags",
+		CreateTables:    true,
+		AddColumns:      true,
+		Engine:          "MergeTree",
+		PartitionBy:     "toYYYYMM({TIMESTAMP})",
+		OrderBy:         "({KEY_COLUMNS})",
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	client := &recordingClient{tables: make(map[string][2][]string)}
+	plugin.client = client
+	return plugin, client
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func str(v string) *string {
+	return &v
+}
+
+func TestCreateTable(t *testing.T) {
+	plugin, client := newPlugin(t)
+	plugin.TTL = "toDateTime({TIMESTAMP}) + INTERVAL 30 DAY"
+	plugin.TableSettings = "index_granularity = 8192"
+
+	ts := time.Unix(1666000000, 0).UTC()
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 98.5, "count": int64(3)},
+			ts,
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{"usage_idle": 97.0, "state": "running"},
+			ts.Add(time.Second),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS `default`.`cpu` (" +
+			"`timestamp` DateTime64(9), `cpu` LowCardinality(String), `host` LowCardinality(String), " +
+			"`count` Nullable(Int64), `state` Nullable(String), `usage_idle` Nullable(Float64)) " +
+			"ENGINE = MergeTree PARTITION BY toYYYYMM(`timestamp`) ORDER BY (`cpu`, `host`, `timestamp`) " +
+			"TTL toDateTime(`timestamp`) + INTERVAL 30 DAY SETTINGS index_granularity = 8192",
+	}, client.statements)
+
+	require.Len(t, client.inserts, 1)
+	require.Equal(t, "INSERT INTO `default`.`cpu` (`timestamp`, `cpu`, `host`, `count`, `state`, `usage_idle`)", client.inserts[0].query)
+	count := int64(3)
+	require.Equal(t, []interface{}{
+		[]time.Time{ts, ts.Add(time.Second)},
+		[]interface{}{"cpu0", ""},
+		[]interface{}{"server01", "server02"},
+		[]*int64{&count, nil},
+		[]*string{nil, str("running")},
+		[]*float64{float(98.5), float(97.0)},
+	}, client.inserts[0].data)
+
+	// The table is only created once
+	require.NoError(t, plugin.Write(metrics))
+	require.Len(t, client.statements, 1)
+	require.Len(t, client.inserts, 2)
+}
+
+// warnLogger counts the logged warnings
+type warnLogger struct {
+	testutil.Logger
+	warnings []string
+}
+
+func (l *warnLogger) Warnf(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+	l.Logger.Warnf(format, args...)
+}
+
+func TestAddColumns(t *testing.T) {
+	logger := &warnLogger{}
+	plugin, client := newPlugin(t)
+	plugin.Log = logger
+	client.tables["default.mem"] = [2][]string{
+		{"timestamp", "host", "used", "free", "comment"},
+		{"DateTime64(3)", "String", "Float64", "Nullable(UInt64)", "Array(String)"},
+	}
+
+	ts := time.Unix(1666000000, 0).UTC()
+	metrics := []telegraf.Metric{
+		metric.New(
+			"mem",
+			map[string]string{"host": "server01", "timestamp": "a"},
+			map[string]interface{}{"used": int64(10), "free": int64(-1), "comment": "x", "host": 1.5},
+			ts,
+		),
+		metric.New(
+			"mem",
+			map[string]string{"region": "eu"},
+			map[string]interface{}{"cached": true, "used": "n/a"},
+			ts.Add(time.Second),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Equal(t, []string{
+		"ALTER TABLE `default`.`mem` " +
+			"ADD COLUMN IF NOT EXISTS `region` LowCardinality(String), " +
+			"ADD COLUMN IF NOT EXISTS `timestamp_tag` LowCardinality(String), " +
+			"ADD COLUMN IF NOT EXISTS `cached` Nullable(Bool), " +
+			"ADD COLUMN IF NOT EXISTS `host_field` Nullable(Float64)",
+	}, client.statements)
+
+	// Values are converted to the type of existing columns
+	require.Len(t, client.inserts, 1)
+	require.Equal(t,
+		"INSERT INTO `default`.`mem` (`timestamp`, `host`, `used`, `free`, `region`, `timestamp_tag`, `cached`, `host_field`)",
+		client.inserts[0].query,
+	)
+	cached := true
+	require.Equal(t, []interface{}{
+		[]time.Time{ts, ts.Add(time.Second)},
+		[]string{"server01", ""},
+		[]float64{10, 0},
+		[]*uint64{nil, nil},
+		[]interface{}{"", "eu"},
+		[]interface{}{"a", ""},
+		[]*bool{nil, &cached},
+		[]*float64{float(1.5), nil},
+	}, client.inserts[0].data)
+
+	// The unsupported column and the dropped values are logged once
+	require.Len(t, logger.warnings, 3)
+	require.Contains(t, logger.warnings[1], `column "free" of type Nullable(UInt64) of "mem", writing NULL`)
+	require.Contains(t, logger.warnings[2], `column "used" of type Float64 of "mem", writing 0`)
+	require.NoError(t, plugin.Write(metrics))
+	require.Len(t, logger.warnings, 3)
+}
+
+func TestTagsAsMap(t *testing.T) {
+	plugin, client := newPlugin(t)
+	plugin.TagsAsMap = true
+
+	ts := time.Unix(1666000000, 0).UTC()
+	metrics := []telegraf.Metric{
+		metric.New(
+			"disk",
+			map[string]string{"host": "server01", "path": "/"},
+			map[string]interface{}{"used": uint64(5), "tags": "x"},
+			ts,
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS `default`.`disk` (" +
+			"`timestamp` DateTime64(9), `tags` Map(String, String), `tags_field` Nullable(String), `used` Nullable(UInt64)) " +
+			"ENGINE = MergeTree PARTITION BY toYYYYMM(`timestamp`) ORDER BY (`timestamp`)",
+	}, client.statements)
+
+	require.Len(t, client.inserts, 1)
+	require.Equal(t, "INSERT INTO `default`.`disk` (`timestamp`, `tags`, `tags_field`, `used`)", client.inserts[0].query)
+	used := uint64(5)
+	require.Equal(t, []interface{}{
+		[]time.Time{ts},
+		[]map[string]string{{"host": "server01", "path": "/"}},
+		[]*string{str("x")},
+		[]*uint64{&used},
+	}, client.inserts[0].data)
+}
+
+func TestExistingTableWithoutMap(t *testing.T) {
+	plugin, client := newPlugin(t)
+	plugin.TagsAsMap = true
+	client.tables["default.disk"] = [2][]string{{"timestamp", "used"}, {"DateTime", "Nullable(UInt64)"}}
+
+	m := metric.New("disk", map[string]string{"host": "server01"}, map[string]interface{}{"used": uint64(5)}, time.Now())
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Equal(t, []string{
+		"ALTER TABLE `default`.`disk` ADD COLUMN IF NOT EXISTS `tags` Map(String, String)",
+	}, client.statements)
+}
+
+func TestDisabledSchemaManagement(t *testing.T) {
+	plugin, client := newPlugin(t)
+	plugin.CreateTables = false
+	plugin.AddColumns = false
+
+	m := metric.New("cpu", map[string]string{"host": "server01"}, map[string]interface{}{"usage_idle": 42.0}, time.Now())
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), `table "cpu" does not exist`)
+
+	client.tables["default.cpu"] = [2][]string{{"ts", "usage_idle"}, {"DateTime", "Float64"}}
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), `table "cpu" has no timestamp column "timestamp"`)
+
+	// Unknown columns are dropped
+	client.tables["default.cpu"] = [2][]string{{"timestamp", "usage_idle"}, {"DateTime", "Float64"}}
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Empty(t, client.statements)
+	require.Len(t, client.inserts, 1)
+	require.Equal(t, "INSERT INTO `default`.`cpu` (`timestamp`, `usage_idle`)", client.inserts[0].query)
+}
+
+func TestInsertFailure(t *testing.T) {
+	plugin, client := newPlugin(t)
+	client.tables["default.cpu"] = [2][]string{{"timestamp", "usage_idle"}, {"DateTime", "Float64"}}
+	client.insertErr = errors.New("connection reset")
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"usage_idle": 42.0}, time.Now())
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), `inserting into "cpu" failed: connection reset`)
+
+	// The columns are queried again after a failure
+	require.NotContains(t, plugin.tables, "cpu")
+	client.insertErr = nil
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Contains(t, plugin.tables, "cpu")
+}
+
+func TestNewColumn(t *testing.T) {
+	tests := []struct {
+		ctype          string
+		dtype          dataType
+		nullable       bool
+		lowCardinality bool
+	}{
+		{ctype: "Float64", dtype: typeFloat},
+		{ctype: "Nullable(Int64)", dtype: typeInt, nullable: true},
+		{ctype: "LowCardinality(String)", dtype: typeString, lowCardinality: true},
+		{ctype: "LowCardinality(Nullable(String))", dtype: typeString, nullable: true, lowCardinality: true},
+		{ctype: "Nullable(Bool)", dtype: typeBool, nullable: true},
+		{ctype: "UInt64", dtype: typeUint},
+		{ctype: "Int32", dtype: typeUnsupported},
+		{ctype: "Array(String)", dtype: typeUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ctype, func(t *testing.T) {
+			c := newColumn("a", tt.ctype)
+			require.Equal(t, tt.dtype, c.dtype)
+			require.Equal(t, tt.nullable, c.nullable)
+			require.Equal(t, tt.lowCardinality, c.lowCardinality)
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	require.Equal(t, "`a\\`b\\\\c`", quoteIdent("a`b\\c"))
+	require.Equal(t, `'it\'s'`, quoteString("it's"))
+}
+
+func TestInit(t *testing.T) {
+	plugin := &ClickHouse{Database: "default", Timeout: config.Duration(time.Second), TimestampColumn: "timestamp"}
+	require.ErrorContains(t, plugin.Init(), "no servers configured")
+
+	plugin = &ClickHouse{Servers: []string{"localhost:9000"}, Database: "default", TimestampColumn: "timestamp"}
+	require.ErrorContains(t, plugin.Init(), "timeout must be positive")
+
+	plugin = &ClickHouse{Servers: []string{"localhost:9000"}, Database: "default", Timeout: config.Duration(time.Second), TimestampColumn: "timestamp", Compression: "gzip"}
+	require.ErrorContains(t, plugin.Init(), `invalid compression "gzip"`)
+
+	plugin = &ClickHouse{Servers: []string{"localhost:9000"}, Database: "default", Timeout: config.Duration(time.Second), TimestampColumn: "timestamp", TagsAsMap: true}
+	require.ErrorContains(t, plugin.Init(), `invalid tags column ""`)
+
+	plugin = &ClickHouse{Servers: []string{"localhost:9000"}, Database: "default", Timeout: config.Duration(time.Second), TimestampColumn: "timestamp", CreateTables: true}
+	require.ErrorContains(t, plugin.Init(), "engine required")
+}
+
+func TestClickHouseIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "9000"
+	container := testutil.Container{
+		Image:        "clickhouse/clickhouse-server",
+		ExposedPorts: []string{servicePort, "8123"},
+		WaitingFor: wait.ForAll(
+			wait.NewHTTPStrategy("/").WithPort(nat.Port("8123")),
+			wait.ForListeningPort(nat.Port(servicePort)),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer func() {
+		require.NoError(t, container.Terminate(), "terminating container failed")
+	}()
+
+	plugin, _ := newPlugin(t)
+	plugin.Servers = []string{fmt.Sprintf("%s:%s", container.Address, container.Ports[servicePort])}
+	plugin.Compression = "lz4"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	ts := time.Unix(1666000000, 0).UTC()
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "server01"}, map[string]interface{}{"usage_idle": 98.5}, ts),
+	}))
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "server02", "cpu": "cpu0"}, map[string]interface{}{"usage_idle": int64(97), "ok": true}, ts),
+	}))
+
+	// Read back the data after the schema changed
+	client := plugin.client.(*nativeClient)
+	rows, err := client.conn.Query(context.Background(), "SELECT host, cpu, usage_idle, ok FROM cpu ORDER BY host")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	type row struct {
+		host, cpu string
+		usage     *float64
+		ok        *bool
+	}
+	var actual []row
+	for rows.Next() {
+		var r row
+		require.NoError(t, rows.Scan(&r.host, &r.cpu, &r.usage, &r.ok))
+		actual = append(actual, r)
+	}
+	require.NoError(t, rows.Err())
+
+	ok := true
+	require.Equal(t, []row{
+		{host: "server01", usage: float(98.5)},
+		{host: "server02", cpu: "cpu0", usage: float(97), ok: &ok},
+	}, actual)
+}
@@ -0,0 +1,88 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// client contains the operations of the plugin on the server so they can be
+// replaced in tests
+type client interface {
+	// exec runs a statement without result
+	exec(ctx context.Context, query string) error
+	// columns returns the names and types of the columns of the table in
+	// their order of definition or nothing if the table does not exist
+	columns(ctx context.Context, database, table string) ([]string, []string, error)
+	// insert writes the given columns in a single block, data contains one
+	// slice of values per column
+	insert(ctx context.Context, query string, data []interface{}) error
+	close() error
+}
+
+type nativeClient struct {
+	conn clickhouse.Conn
+}
+
+func newNativeClient(options *clickhouse.Options) (*nativeClient, error) {
+	conn, err := clickhouse.Open(options)
+	if err != nil {
+		return nil, err
+	}
+	return &nativeClient{conn: conn}, nil
+}
+
+func (c *nativeClient) exec(ctx context.Context, query string) error {
+	return c.conn.Exec(ctx, query)
+}
+
+func (c *nativeClient) columns(ctx context.Context, database, table string) ([]string, []string, error) {
+	query := "SELECT name, type FROM system.columns WHERE database = " + quoteString(database) +
+		" AND table = " + quoteString(table) + " ORDER BY position"
+	rows, err := c.conn.Query(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var names, types []string
+	for rows.Next() {
+		var name, ctype string
+		if err := rows.Scan(&name, &ctype); err != nil {
+			return nil, nil, err
+		}
+		names = append(names, name)
+		types = append(types, ctype)
+	}
+	return names, types, rows.Err()
+}
+
+func (c *nativeClient) insert(ctx context.Context, query string, data []interface{}) error {
+	batch, err := c.conn.PrepareBatch(ctx, query)
+	if err != nil {
+		return err
+	}
+	for i, values := range data {
+		// A failing append releases the connection of the batch
+		if err := batch.Column(i).Append(values); err != nil {
+			return fmt.Errorf("appending column %d failed: %v", i, err)
+		}
+	}
+	return batch.Send()
+}
+
+func (c *nativeClient) close() error {
+	return c.conn.Close()
+}
+
+// quoteIdent quotes a database, table or column name
+func quoteIdent(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+// quoteString quotes a string literal
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
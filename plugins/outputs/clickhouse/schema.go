@@ -0,0 +1,234 @@
+package clickhouse
+
+import (
+	"strings"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+type dataType int
+
+const (
+	typeUnsupported dataType = iota
+	typeString
+	typeFloat
+	typeInt
+	typeUint
+	typeBool
+)
+
+// Types the values are converted to, other column types are not written
+var dataTypes = map[string]dataType{
+	"String":  typeString,
+	"Float64": typeFloat,
+	"Int64":   typeInt,
+	"UInt64":  typeUint,
+	"Bool":    typeBool,
+}
+
+type column struct {
+	name           string
+	ctype          string
+	dtype          dataType
+	nullable       bool
+	lowCardinality bool
+}
+
+// newColumn creates a column from its ClickHouse type as reported by the
+// server
+func newColumn(name, ctype string) *column {
+	c := &column{name: name, ctype: ctype}
+
+	t := ctype
+	if strings.HasPrefix(t, "LowCardinality(") && strings.HasSuffix(t, ")") {
+		c.lowCardinality = true
+		t = strings.TrimSuffix(strings.TrimPrefix(t, "LowCardinality("), ")")
+	}
+	if strings.HasPrefix(t, "Nullable(") && strings.HasSuffix(t, ")") {
+		c.nullable = true
+		t = strings.TrimSuffix(strings.TrimPrefix(t, "Nullable("), ")")
+	}
+	c.dtype = dataTypes[t]
+
+	return c
+}
+
+// tagColumnType is the type of new tag columns. Missing tags are stored as
+// empty strings, so tags can be used in the sorting key.
+const tagColumnType = "LowCardinality(String)"
+
+// fieldColumnType returns the type of a new column for the field value
+func fieldColumnType(v interface{}) (string, bool) {
+	switch v.(type) {
+	case float64:
+		return "Nullable(Float64)", true
+	case int64:
+		return "Nullable(Int64)", true
+	case uint64:
+		return "Nullable(UInt64)", true
+	case bool:
+		return "Nullable(Bool)", true
+	case string:
+		return "Nullable(String)", true
+	}
+	return "", false
+}
+
+// convert converts the value to the type of the column. Values that cannot
+// be converted without loss are returned as nil.
+func (c *column) convert(v interface{}) interface{} {
+	switch c.dtype {
+	case typeFloat:
+		if v, ok := internal.FieldToFloat64(v); ok {
+			return v
+		}
+	case typeInt:
+		if v, ok := internal.FieldToInt64(v); ok {
+			return v
+		}
+	case typeUint:
+		if v, ok := internal.FieldToUint64(v); ok {
+			return v
+		}
+	case typeBool:
+		if v, ok := v.(bool); ok {
+			return v
+		}
+	case typeString:
+		if v, ok := v.(string); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// data returns the converted values of the column as slice accepted by the
+// driver for the column type. Missing values are inserted as NULL or as
+// zero value if the column is not nullable.
+func (c *column) data(values []interface{}) interface{} {
+	switch {
+	case c.lowCardinality:
+		// Values are passed as they are with nil being NULL
+		if !c.nullable {
+			for i, v := range values {
+				if v == nil {
+					values[i] = c.zero()
+				}
+			}
+		}
+		return values
+	case c.dtype == typeString && c.nullable:
+		out := make([]*string, len(values))
+		for i, v := range values {
+			if v, ok := v.(string); ok {
+				out[i] = &v
+			}
+		}
+		return out
+	case c.dtype == typeString:
+		out := make([]string, len(values))
+		for i, v := range values {
+			out[i], _ = v.(string)
+		}
+		return out
+	case c.dtype == typeFloat && c.nullable:
+		out := make([]*float64, len(values))
+		for i, v := range values {
+			if v, ok := v.(float64); ok {
+				out[i] = &v
+			}
+		}
+		return out
+	case c.dtype == typeFloat:
+		out := make([]float64, len(values))
+		for i, v := range values {
+			out[i], _ = v.(float64)
+		}
+		return out
+	case c.dtype == typeInt && c.nullable:
+		out := make([]*int64, len(values))
+		for i, v := range values {
+			if v, ok := v.(int64); ok {
+				out[i] = &v
+			}
+		}
+		return out
+	case c.dtype == typeInt:
+		out := make([]int64, len(values))
+		for i, v := range values {
+			out[i], _ = v.(int64)
+		}
+		return out
+	case c.dtype == typeUint && c.nullable:
+		out := make([]*uint64, len(values))
+		for i, v := range values {
+			if v, ok := v.(uint64); ok {
+				out[i] = &v
+			}
+		}
+		return out
+	case c.dtype == typeUint:
+		out := make([]uint64, len(values))
+		for i, v := range values {
+			out[i], _ = v.(uint64)
+		}
+		return out
+	case c.dtype == typeBool && c.nullable:
+		out := make([]*bool, len(values))
+		for i, v := range values {
+			if v, ok := v.(bool); ok {
+				out[i] = &v
+			}
+		}
+		return out
+	case c.dtype == typeBool:
+		out := make([]bool, len(values))
+		for i, v := range values {
+			out[i], _ = v.(bool)
+		}
+		return out
+	}
+	return nil
+}
+
+func (c *column) zero() interface{} {
+	switch c.dtype {
+	case typeFloat:
+		return float64(0)
+	case typeInt:
+		return int64(0)
+	case typeUint:
+		return uint64(0)
+	case typeBool:
+		return false
+	}
+	return ""
+}
+
+// table holds the columns of a table as known to the plugin
+type table struct {
+	columns []*column
+	byName  map[string]*column
+	warned  map[string]bool
+}
+
+func newTable(names, types []string) *table {
+	t := &table{byName: make(map[string]*column, len(names))}
+	for i, name := range names {
+		t.add(newColumn(name, types[i]))
+	}
+	return t
+}
+
+func (t *table) add(c *column) {
+	t.columns = append(t.columns, c)
+	t.byName[c.name] = c
+}
+
+// warn records that a warning about the column was logged
+func (t *table) warn(name string) {
+	if t.warned == nil {
+		t.warned = make(map[string]bool)
+	}
+	t.warned[name] = true
+}
@@ -0,0 +1,55 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	awsV2 "github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// awsSigningTransport signs the requests using AWS Signature Version 4 as
+// required by Amazon OpenSearch Service
+type awsSigningTransport struct {
+	transport http.RoundTripper
+	config    awsV2.Config
+	service   string
+	region    string
+}
+
+func (t *awsSigningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The signature contains the hash of the body
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	hash := sha256.Sum256(body)
+
+	signed := req.Clone(req.Context())
+	signed.Body = nil
+	signed.ContentLength = int64(len(body))
+	if len(body) > 0 {
+		signed.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	credentials, err := t.config.Credentials.Retrieve(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	signer := v4.NewSigner()
+	err = signer.SignHTTP(req.Context(), credentials, signed, hex.EncodeToString(hash[:]), t.service, t.region, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return t.transport.RoundTrip(signed)
+}
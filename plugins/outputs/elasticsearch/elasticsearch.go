@@ -6,6 +6,7 @@ import (
 	"context"
 	"crypto/sha256"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	internalaws "github.com/influxdata/telegraf/plugins/common/aws"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
@@ -29,6 +31,9 @@ var sampleConfig string
 
 type Elasticsearch struct {
 	AuthBearerToken     string          `toml:"auth_bearer_token"`
+	AwsService          string          `toml:"aws_service"`
+	DataStream          bool            `toml:"data_stream"`
+	DeadLetterIndex     string          `toml:"dead_letter_index"`
 	DefaultPipeline     string          `toml:"default_pipeline"`
 	DefaultTagValue     string          `toml:"default_tag_value"`
 	EnableGzip          bool            `toml:"enable_gzip"`
@@ -40,8 +45,10 @@ type Elasticsearch struct {
 	HealthCheckTimeout  config.Duration `toml:"health_check_timeout"`
 	IndexName           string          `toml:"index_name"`
 	ManageTemplate      bool            `toml:"manage_template"`
+	MaxRetries          int             `toml:"max_retries"`
 	OverwriteTemplate   bool            `toml:"overwrite_template"`
 	Password            string          `toml:"password"`
+	TemplateFormat      string          `toml:"template_format"`
 	TemplateName        string          `toml:"template_name"`
 	Timeout             config.Duration `toml:"timeout"`
 	URLs                []string        `toml:"urls"`
@@ -49,12 +56,17 @@ type Elasticsearch struct {
 	Username            string          `toml:"username"`
 	Log                 telegraf.Logger `toml:"-"`
 	majorReleaseNumber  int
+	minorReleaseNumber  int
 	pipelineName        string
 	pipelineTagKeys     []string
 	tagKeys             []string
 	tls.ClientConfig
+	internalaws.CredentialConfig
 
 	Client *elastic.Client
+
+	// Documents rejected with a retriable error, sent again with the next write
+	pending []*bulkItem
 }
 
 const telegrafTemplate = `
@@ -127,9 +139,76 @@ const telegrafTemplate = `
 	}
 }`
 
+// Composable index template available since Elasticsearch 7.8 and required
+// for data streams. The priority exceeds the one of the built-in templates
+// of Elasticsearch.
+const telegrafComposableTemplate = `
+{
+	"index_patterns" : [ "{{.TemplatePattern}}" ],
+	{{ if .DataStream }}
+	"data_stream": {},
+	{{ end }}
+	"priority": 200,
+	"template": {
+		"settings": {
+			"index": {
+				"refresh_interval": "10s",
+				"mapping.total_fields.limit": 5000,
+				"auto_expand_replicas" : "0-1",
+				"codec" : "best_compression"
+			}
+		},
+		"mappings" : {
+			"properties" : {
+				"@timestamp" : { "type" : "date" },
+				"measurement_name" : { "type" : "keyword" }
+			},
+			"dynamic_templates": [
+				{
+					"tags": {
+						"match_mapping_type": "string",
+						"path_match": "tag.*",
+						"mapping": {
+							"ignore_above": 512,
+							"type": "keyword"
+						}
+					}
+				},
+				{
+					"metrics_long": {
+						"match_mapping_type": "long",
+						"mapping": {
+							"type": "float",
+							"index": false
+						}
+					}
+				},
+				{
+					"metrics_double": {
+						"match_mapping_type": "double",
+						"mapping": {
+							"type": "float",
+							"index": false
+						}
+					}
+				},
+				{
+					"text_fields": {
+						"match": "*",
+						"mapping": {
+							"norms": false
+						}
+					}
+				}
+			]
+		}
+	}
+}`
+
 type templatePart struct {
 	TemplatePattern string
 	Version         int
+	DataStream      bool
 }
 
 func (*Elasticsearch) SampleConfig() string {
@@ -150,6 +229,30 @@ func (a *Elasticsearch) Connect() error {
 		return fmt.Errorf("invalid float_handling type %q", a.FloatHandling)
 	}
 
+	// Data streams require composable index templates
+	switch a.TemplateFormat {
+	case "":
+		a.TemplateFormat = "legacy"
+		if a.DataStream {
+			a.TemplateFormat = "composable"
+		}
+	case "legacy", "composable":
+	default:
+		return fmt.Errorf("invalid template_format %q", a.TemplateFormat)
+	}
+	if a.DataStream {
+		if a.TemplateFormat != "composable" {
+			return fmt.Errorf("data streams require composable index templates")
+		}
+		if strings.Contains(a.IndexName, "%") {
+			return fmt.Errorf("date specifiers are not supported in data stream names")
+		}
+	}
+
+	if a.MaxRetries < 0 {
+		return fmt.Errorf("invalid max_retries %d", a.MaxRetries)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(a.Timeout))
 	defer cancel()
 
@@ -159,10 +262,24 @@ func (a *Elasticsearch) Connect() error {
 	if err != nil {
 		return err
 	}
-	tr := &http.Transport{
+	var tr http.RoundTripper = &http.Transport{
 		TLSClientConfig: tlsCfg,
 	}
 
+	// Sign the requests for Amazon OpenSearch Service
+	if a.AwsService != "" {
+		awsCfg, err := a.CredentialConfig.Credentials()
+		if err != nil {
+			return fmt.Errorf("loading AWS credentials failed: %v", err)
+		}
+		tr = &awsSigningTransport{
+			transport: tr,
+			config:    awsCfg,
+			service:   a.AwsService,
+			region:    a.Region,
+		}
+	}
+
 	httpclient := &http.Client{
 		Transport: tr,
 		Timeout:   time.Duration(a.Timeout),
@@ -210,23 +327,35 @@ func (a *Elasticsearch) Connect() error {
 		return err
 	}
 
-	// check for ES version on first node
-	esVersion, err := client.ElasticsearchVersion(a.URLs[0])
-
+	// check for ES version
+	esVersion, distribution, err := serverVersion(ctx, client)
 	if err != nil {
 		return fmt.Errorf("elasticsearch version check failed: %s", err)
 	}
 
-	// quit if ES version is not supported
-	majorReleaseNumber, err := strconv.Atoi(strings.Split(esVersion, ".")[0])
-	if err != nil || majorReleaseNumber < 5 {
-		return fmt.Errorf("elasticsearch version not supported: %s", esVersion)
+	majorReleaseNumber, minorReleaseNumber, err := parseVersion(esVersion)
+	if distribution == "opensearch" {
+		// OpenSearch is compatible to Elasticsearch 7.10 in the used features
+		a.Log.Infof("OpenSearch version: %q", esVersion)
+		majorReleaseNumber, minorReleaseNumber = 7, 10
+	} else {
+		// quit if ES version is not supported
+		if err != nil || majorReleaseNumber < 5 {
+			return fmt.Errorf("elasticsearch version not supported: %s", esVersion)
+		}
+		a.Log.Infof("Elasticsearch version: %q", esVersion)
 	}
 
-	a.Log.Infof("Elasticsearch version: %q", esVersion)
+	if a.DataStream && (majorReleaseNumber < 7 || (majorReleaseNumber == 7 && minorReleaseNumber < 9)) {
+		return fmt.Errorf("data streams require Elasticsearch 7.9 or later, found %s", esVersion)
+	}
+	if a.ManageTemplate && a.TemplateFormat == "composable" && (majorReleaseNumber < 7 || (majorReleaseNumber == 7 && minorReleaseNumber < 8)) {
+		return fmt.Errorf("composable index templates require Elasticsearch 7.8 or later, found %s", esVersion)
+	}
 
 	a.Client = client
 	a.majorReleaseNumber = majorReleaseNumber
+	a.minorReleaseNumber = minorReleaseNumber
 
 	if a.ManageTemplate {
 		err := a.manageTemplate(ctx)
@@ -241,6 +370,43 @@ func (a *Elasticsearch) Connect() error {
 	return nil
 }
 
+// serverVersion returns the version number and the distribution of the
+// server, the latter being empty for Elasticsearch
+func serverVersion(ctx context.Context, client *elastic.Client) (string, string, error) {
+	res, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{Method: "GET", Path: "/"})
+	if err != nil {
+		return "", "", err
+	}
+
+	var info struct {
+		Version struct {
+			Number       string `json:"number"`
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal(res.Body, &info); err != nil {
+		return "", "", err
+	}
+	return info.Version.Number, info.Version.Distribution, nil
+}
+
+// parseVersion returns the major and minor release number of the version
+func parseVersion(version string) (int, int, error) {
+	parts := strings.Split(version, ".")
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) < 2 {
+		return major, 0, nil
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return major, minor, nil
+}
+
 // GetPointID generates a unique ID for a Metric Point
 func GetPointID(m telegraf.Metric) string {
 	var buffer bytes.Buffer
@@ -253,84 +419,214 @@ func GetPointID(m telegraf.Metric) string {
 	return fmt.Sprintf("%x", sha256.Sum256(buffer.Bytes()))
 }
 
+// bulkItem is a document of a bulk request
+type bulkItem struct {
+	index   string
+	doc     map[string]interface{}
+	request *elastic.BulkIndexRequest
+	retries int
+}
+
 func (a *Elasticsearch) Write(metrics []telegraf.Metric) error {
 	if len(metrics) == 0 {
 		return nil
 	}
 
-	bulkRequest := a.Client.Bulk()
-
+	// Documents rejected with a retriable error by previous writes are sent
+	// together with the new ones
+	pending := a.pending
+	a.pending = nil
+	items := make([]*bulkItem, 0, len(pending)+len(metrics))
+	items = append(items, pending...)
 	for _, metric := range metrics {
-		var name = metric.Name()
-
-		// index name has to be re-evaluated each time for telegraf
-		// to send the metric to the correct time-based index
-		indexName := a.GetIndexName(a.IndexName, metric.Time(), a.tagKeys, metric.Tags())
-
-		// Handle NaN and inf field-values
-		fields := make(map[string]interface{})
-		for k, value := range metric.Fields() {
-			v, ok := value.(float64)
-			if !ok || a.FloatHandling == "none" || !(math.IsNaN(v) || math.IsInf(v, 0)) {
-				fields[k] = value
-				continue
-			}
-			if a.FloatHandling == "drop" {
-				continue
-			}
+		items = append(items, a.bulkItem(metric))
+	}
 
-			if math.IsNaN(v) || math.IsInf(v, 1) {
-				fields[k] = a.FloatReplacement
-			} else {
-				fields[k] = -a.FloatReplacement
-			}
+	failed, err := a.send(items)
+	if err != nil {
+		a.pending = pending
+		return err
+	}
+
+	// Sending the whole batch again would duplicate the indexed documents,
+	// so only the failed ones are kept for the next write. If nothing was
+	// indexed, Telegraf retries the new metrics instead.
+	var result error
+	if len(failed) == len(items) {
+		failed = failed[:len(pending)]
+		result = fmt.Errorf("elasticsearch failed to index %d metrics", len(metrics))
+	}
+
+	var dropped int
+	for _, item := range failed {
+		if item.retries >= a.MaxRetries {
+			dropped++
+			continue
 		}
+		item.retries++
+		a.pending = append(a.pending, item)
+	}
+	if dropped > 0 {
+		a.Log.Errorf("Dropped %d documents still rejected with a retriable error after %d retries", dropped, a.MaxRetries)
+	}
+	if len(a.pending) > 0 {
+		a.Log.Debugf("Retrying %d documents rejected with a retriable error with the next write", len(a.pending))
+	}
 
-		m := make(map[string]interface{})
+	return result
+}
 
-		m["@timestamp"] = metric.Time()
-		m["measurement_name"] = name
-		m["tag"] = metric.Tags()
-		m[name] = fields
+func (a *Elasticsearch) bulkItem(metric telegraf.Metric) *bulkItem {
+	var name = metric.Name()
 
-		br := elastic.NewBulkIndexRequest().Index(indexName).Doc(m)
+	// index name has to be re-evaluated each time for telegraf
+	// to send the metric to the correct time-based index
+	indexName := a.GetIndexName(a.IndexName, metric.Time(), a.tagKeys, metric.Tags())
 
-		if a.ForceDocumentID {
-			id := GetPointID(metric)
-			br.Id(id)
+	// Handle NaN and inf field-values
+	fields := make(map[string]interface{})
+	for k, value := range metric.Fields() {
+		v, ok := value.(float64)
+		if !ok || a.FloatHandling == "none" || !(math.IsNaN(v) || math.IsInf(v, 0)) {
+			fields[k] = value
+			continue
+		}
+		if a.FloatHandling == "drop" {
+			continue
 		}
 
-		if a.majorReleaseNumber <= 6 {
-			br.Type("metrics")
+		if math.IsNaN(v) || math.IsInf(v, 1) {
+			fields[k] = a.FloatReplacement
+		} else {
+			fields[k] = -a.FloatReplacement
 		}
+	}
 
-		if a.UsePipeline != "" {
-			if pipelineName := a.getPipelineName(a.pipelineName, a.pipelineTagKeys, metric.Tags()); pipelineName != "" {
-				br.Pipeline(pipelineName)
-			}
+	m := make(map[string]interface{})
+
+	m["@timestamp"] = metric.Time()
+	m["measurement_name"] = name
+	m["tag"] = metric.Tags()
+	m[name] = fields
+
+	br := elastic.NewBulkIndexRequest().Index(indexName).Doc(m)
+
+	// Data streams only accept new documents
+	if a.DataStream {
+		br.OpType("create")
+	}
+
+	if a.ForceDocumentID {
+		id := GetPointID(metric)
+		br.Id(id)
+	}
+
+	if a.majorReleaseNumber <= 6 {
+		br.Type("metrics")
+	}
+
+	if a.UsePipeline != "" {
+		if pipelineName := a.getPipelineName(a.pipelineName, a.pipelineTagKeys, metric.Tags()); pipelineName != "" {
+			br.Pipeline(pipelineName)
 		}
+	}
 
-		bulkRequest.Add(br)
+	return &bulkItem{index: indexName, doc: m, request: br}
+}
+
+// send sends the documents in a bulk request and returns the documents
+// failing with a retriable error. Documents failing with other errors are
+// dropped and written to the dead letter index if configured.
+func (a *Elasticsearch) send(items []*bulkItem) ([]*bulkItem, error) {
+	bulkRequest := a.Client.Bulk()
+	for _, item := range items {
+		bulkRequest.Add(item.request)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(a.Timeout))
 	defer cancel()
 
 	res, err := bulkRequest.Do(ctx)
-
 	if err != nil {
-		return fmt.Errorf("error sending bulk request to Elasticsearch: %s", err)
+		return nil, fmt.Errorf("error sending bulk request to Elasticsearch: %s", err)
+	}
+	if !res.Errors {
+		return nil, nil
 	}
 
-	if res.Errors {
-		for id, err := range res.Failed() {
-			a.Log.Errorf("Elasticsearch indexing failure, id: %d, error: %s, caused by: %s, %s", id, err.Error.Reason, err.Error.CausedBy["reason"], err.Error.CausedBy["type"])
-			break
+	// The items of the response are in the order of the request
+	if len(res.Items) != len(items) {
+		return nil, fmt.Errorf("elasticsearch returned %d results for %d documents", len(res.Items), len(items))
+	}
+
+	var retry, rejected []*bulkItem
+	var rejections []*elastic.BulkResponseItem
+	for i, result := range res.Items {
+		for _, r := range result {
+			switch {
+			case r.Error == nil:
+			case r.Status == http.StatusConflict && a.ForceDocumentID && a.DataStream:
+				// The document was already created by a previous write
+			case r.Status == http.StatusTooManyRequests || r.Status >= 500:
+				retry = append(retry, items[i])
+			default:
+				rejected = append(rejected, items[i])
+				rejections = append(rejections, r)
+			}
 		}
-		return fmt.Errorf("elasticsearch failed to index %d metrics", len(res.Failed()))
 	}
 
-	return nil
+	if len(rejected) > 0 {
+		r := rejections[0]
+		a.Log.Errorf("Elasticsearch rejected %d documents, first error: status: %d, error: %s, caused by: %s, %s",
+			len(rejected), r.Status, r.Error.Reason, r.Error.CausedBy["reason"], r.Error.CausedBy["type"])
+		if a.DeadLetterIndex != "" {
+			a.writeDeadLetters(ctx, rejected, rejections)
+		}
+	}
+
+	return retry, nil
+}
+
+// writeDeadLetters writes the rejected documents with their error to the dead
+// letter index. Failures are only logged as the documents cannot be written
+// anyway.
+func (a *Elasticsearch) writeDeadLetters(ctx context.Context, items []*bulkItem, rejections []*elastic.BulkResponseItem) {
+	bulkRequest := a.Client.Bulk()
+	for i, item := range items {
+		document, err := json.Marshal(item.doc)
+		if err != nil {
+			document = []byte(fmt.Sprintf("%v", item.doc))
+		}
+
+		r := rejections[i]
+		deadLetter := map[string]interface{}{
+			"@timestamp": time.Now(),
+			"index":      item.index,
+			"status":     r.Status,
+			"error": map[string]interface{}{
+				"type":   r.Error.Type,
+				"reason": r.Error.Reason,
+			},
+			"document": string(document),
+		}
+
+		// Creating documents works for indices and data streams alike
+		br := elastic.NewBulkIndexRequest().Index(a.DeadLetterIndex).OpType("create").Doc(deadLetter)
+		if a.majorReleaseNumber <= 6 {
+			br.Type("metrics")
+		}
+		bulkRequest.Add(br)
+	}
+
+	res, err := bulkRequest.Do(ctx)
+	if err != nil {
+		a.Log.Errorf("Writing %d documents to dead letter index %q failed: %s", len(items), a.DeadLetterIndex, err)
+		return
+	}
+	if res.Errors {
+		a.Log.Errorf("Writing %d of %d documents to dead letter index %q failed", len(res.Failed()), len(items), a.DeadLetterIndex)
+	}
 }
 
 func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
@@ -338,7 +634,15 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 		return fmt.Errorf("elasticsearch template_name configuration not defined")
 	}
 
-	templateExists, errExists := a.Client.IndexTemplateExists(a.TemplateName).Do(ctx)
+	composable := a.TemplateFormat == "composable"
+
+	var templateExists bool
+	var errExists error
+	if composable {
+		templateExists, errExists = a.composableTemplateExists(ctx)
+	} else {
+		templateExists, errExists = a.Client.IndexTemplateExists(a.TemplateName).Do(ctx)
+	}
 
 	if errExists != nil {
 		return fmt.Errorf("elasticsearch template check failed, template name: %s, error: %s", a.TemplateName, errExists)
@@ -362,15 +666,30 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 		tp := templatePart{
 			TemplatePattern: templatePattern + "*",
 			Version:         a.majorReleaseNumber,
+			DataStream:      a.DataStream,
 		}
 
-		t := template.Must(template.New("template").Parse(telegrafTemplate))
+		templateText := telegrafTemplate
+		if composable {
+			templateText = telegrafComposableTemplate
+		}
+		t := template.Must(template.New("template").Parse(templateText))
 		var tmpl bytes.Buffer
 
 		if err := t.Execute(&tmpl, tp); err != nil {
 			return err
 		}
-		_, errCreateTemplate := a.Client.IndexPutTemplate(a.TemplateName).BodyString(tmpl.String()).Do(ctx)
+
+		var errCreateTemplate error
+		if composable {
+			_, errCreateTemplate = a.Client.PerformRequest(ctx, elastic.PerformRequestOptions{
+				Method: "PUT",
+				Path:   "/_index_template/" + url.PathEscape(a.TemplateName),
+				Body:   tmpl.String(),
+			})
+		} else {
+			_, errCreateTemplate = a.Client.IndexPutTemplate(a.TemplateName).BodyString(tmpl.String()).Do(ctx)
+		}
 
 		if errCreateTemplate != nil {
 			return fmt.Errorf("elasticsearch failed to create index template %s : %s", a.TemplateName, errCreateTemplate)
@@ -383,6 +702,18 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 	return nil
 }
 
+func (a *Elasticsearch) composableTemplateExists(ctx context.Context) (bool, error) {
+	res, err := a.Client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method:       "HEAD",
+		Path:         "/_index_template/" + url.PathEscape(a.TemplateName),
+		IgnoreErrors: []int{http.StatusNotFound},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.StatusCode == http.StatusOK, nil
+}
+
 func (a *Elasticsearch) GetTagKeys(indexName string) (string, []string) {
 	tagKeys := []string{}
 	startTag := strings.Index(indexName, "{{")
@@ -461,6 +792,10 @@ func getISOWeek(eventTime time.Time) string {
 }
 
 func (a *Elasticsearch) Close() error {
+	if len(a.pending) > 0 {
+		a.Log.Errorf("Dropped %d documents rejected with a retriable error on close", len(a.pending))
+		a.pending = nil
+	}
 	a.Client = nil
 	return nil
 }
@@ -471,6 +806,7 @@ func init() {
 			Timeout:             config.Duration(time.Second * 5),
 			HealthCheckInterval: config.Duration(time.Second * 10),
 			HealthCheckTimeout:  config.Duration(time.Second * 1),
+			MaxRetries:          3,
 		}
 	})
 }
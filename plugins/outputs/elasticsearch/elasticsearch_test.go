@@ -1,12 +1,17 @@
 package elasticsearch
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -770,3 +775,300 @@ func TestAuthorizationHeaderWhenBearerTokenIsPresent(t *testing.T) {
 	err = e.Write(testutil.MockMetrics())
 	require.NoError(t, err)
 }
+
+// bulkServer is a fake server recording the documents of the bulk requests
+// and replying with the statuses returned by the given function
+type bulkServer struct {
+	*httptest.Server
+	version string
+	status  func(request int, action map[string]map[string]interface{}) int
+
+	sync.Mutex
+	requests  [][]map[string]map[string]interface{}
+	documents [][]map[string]interface{}
+	templates map[string]string
+}
+
+func newBulkServer(t *testing.T, version string, status func(int, map[string]map[string]interface{}) int) *bulkServer {
+	s := &bulkServer{version: version, status: status, templates: make(map[string]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+
+		switch {
+		case r.URL.Path == "/_bulk":
+			var actions []map[string]map[string]interface{}
+			var documents []map[string]interface{}
+			scanner := bufio.NewScanner(r.Body)
+			for scanner.Scan() {
+				var action map[string]map[string]interface{}
+				if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				var document map[string]interface{}
+				if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &document) != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				actions = append(actions, action)
+				documents = append(documents, document)
+			}
+
+			request := len(s.requests)
+			s.requests = append(s.requests, actions)
+			s.documents = append(s.documents, documents)
+
+			var errors bool
+			items := make([]map[string]interface{}, 0, len(actions))
+			for _, action := range actions {
+				for op := range action {
+					result := map[string]interface{}{"status": s.status(request, action)}
+					if result["status"].(int) >= 300 {
+						errors = true
+						result["error"] = map[string]interface{}{"type": "some_exception", "reason": "failed"}
+					}
+					items = append(items, map[string]interface{}{op: result})
+				}
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"errors": errors, "items": items}))
+		case strings.HasPrefix(r.URL.Path, "/_index_template/"):
+			name := strings.TrimPrefix(r.URL.Path, "/_index_template/")
+			if r.Method == http.MethodPut {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				s.templates[name] = string(body)
+				_, err = w.Write([]byte(`{"acknowledged": true}`))
+				require.NoError(t, err)
+				return
+			}
+			if _, found := s.templates[name]; !found {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		default:
+			_, err := w.Write([]byte(s.version))
+			require.NoError(t, err)
+		}
+	}))
+	return s
+}
+
+func (s *bulkServer) plugin() *Elasticsearch {
+	return &Elasticsearch{
+		URLs:       []string{s.URL},
+		IndexName:  "test-%Y.%m.%d",
+		Timeout:    config.Duration(time.Second * 5),
+		MaxRetries: 3,
+		Log:        testutil.Logger{},
+	}
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.TestMetric(1.0, "first"),
+		testutil.TestMetric(2.0, "second"),
+		testutil.TestMetric(3.0, "third"),
+	}
+}
+
+func TestBulkPartialFailure(t *testing.T) {
+	ts := newBulkServer(t, `{"version": {"number": "7.17.0"}}`, func(request int, action map[string]map[string]interface{}) int {
+		if action["create"] != nil && action["create"]["_index"] == "dead-letters" {
+			return http.StatusCreated
+		}
+		switch action["index"]["_id"] {
+		case "second":
+			// Rejected once due to a full write queue
+			if request == 0 {
+				return http.StatusTooManyRequests
+			}
+		case "third":
+			return http.StatusBadRequest
+		}
+		return http.StatusCreated
+	})
+	defer ts.Close()
+
+	e := ts.plugin()
+	e.DeadLetterIndex = "dead-letters"
+	require.NoError(t, e.Connect())
+
+	// Use the measurement as document ID to identify the documents
+	items := make([]*bulkItem, 0, 3)
+	for _, m := range testMetrics() {
+		item := e.bulkItem(m)
+		item.request.Id(m.Name())
+		items = append(items, item)
+	}
+
+	failed, err := e.send(items)
+	require.NoError(t, err)
+	require.Equal(t, []*bulkItem{items[1]}, failed)
+
+	failed, err = e.send(failed)
+	require.NoError(t, err)
+	require.Empty(t, failed)
+
+	require.Len(t, ts.requests, 3)
+
+	// The mapping error is written to the dead letter index
+	require.Len(t, ts.requests[1], 1)
+	require.Equal(t, "dead-letters", ts.requests[1][0]["create"]["_index"])
+	deadLetter := ts.documents[1][0]
+	require.Equal(t, items[2].index, deadLetter["index"])
+	require.EqualValues(t, 400, deadLetter["status"])
+	require.Equal(t, map[string]interface{}{"type": "some_exception", "reason": "failed"}, deadLetter["error"])
+	require.Contains(t, deadLetter["document"], `"measurement_name":"third"`)
+
+	// Only the retriable document is sent again
+	require.Len(t, ts.requests[2], 1)
+	require.Equal(t, "second", ts.requests[2][0]["index"]["_id"])
+}
+
+func TestBulkRetries(t *testing.T) {
+	first, second, third := testMetrics()[0], testMetrics()[1], testMetrics()[2]
+	secondID := GetPointID(second)
+
+	ts := newBulkServer(t, `{"version": {"number": "7.17.0"}}`, func(request int, action map[string]map[string]interface{}) int {
+		// The second document is rejected by the first two requests
+		if request < 2 && action["index"]["_id"] == secondID {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusCreated
+	})
+	defer ts.Close()
+
+	e := ts.plugin()
+	e.ForceDocumentID = true
+	e.MaxRetries = 1
+	require.NoError(t, e.Connect())
+
+	// The partially indexed write succeeds and the rejected document is sent
+	// again with the next write, until it is dropped after the retries
+	require.NoError(t, e.Write([]telegraf.Metric{first, second}))
+	require.NoError(t, e.Write([]telegraf.Metric{third}))
+	require.NoError(t, e.Write([]telegraf.Metric{first}))
+	require.Len(t, ts.requests, 3)
+	require.Len(t, ts.requests[0], 2)
+	require.Len(t, ts.requests[1], 2)
+	require.Equal(t, secondID, ts.requests[1][0]["index"]["_id"])
+	require.Len(t, ts.requests[2], 1)
+
+	// If no document is indexed the write fails, so the metrics are written
+	// again by Telegraf
+	ts.status = func(int, map[string]map[string]interface{}) int { return http.StatusServiceUnavailable }
+	require.ErrorContains(t, e.Write(testMetrics()), "elasticsearch failed to index 3 metrics")
+	require.Empty(t, e.pending)
+}
+
+func TestBulkRejectionsDropped(t *testing.T) {
+	ts := newBulkServer(t, `{"version": {"number": "7.17.0"}}`, func(int, map[string]map[string]interface{}) int {
+		return http.StatusBadRequest
+	})
+	defer ts.Close()
+
+	e := ts.plugin()
+	require.NoError(t, e.Connect())
+	require.NoError(t, e.Write(testMetrics()))
+	require.Len(t, ts.requests, 1)
+}
+
+func TestDataStream(t *testing.T) {
+	ts := newBulkServer(t, `{"version": {"number": "8.4.0"}}`, func(int, map[string]map[string]interface{}) int {
+		// Documents already created by an earlier write
+		return http.StatusConflict
+	})
+	defer ts.Close()
+
+	e := ts.plugin()
+	e.IndexName = "metrics-{{host}}"
+	e.DataStream = true
+	e.ForceDocumentID = true
+	e.ManageTemplate = true
+	e.TemplateName = "telegraf"
+	require.NoError(t, e.Connect())
+	require.Equal(t, "composable", e.TemplateFormat)
+
+	require.Contains(t, ts.templates, "telegraf")
+	var tmpl map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(ts.templates["telegraf"]), &tmpl))
+	require.Equal(t, []interface{}{"metrics-*"}, tmpl["index_patterns"])
+	require.Equal(t, map[string]interface{}{}, tmpl["data_stream"])
+	require.Contains(t, tmpl["template"], "mappings")
+
+	m := testutil.TestMetric(1.0)
+	m.AddTag("host", "server01")
+	require.NoError(t, e.Write([]telegraf.Metric{m}))
+	require.Len(t, ts.requests, 1)
+	action := ts.requests[0][0]["create"]
+	require.Equal(t, "metrics-server01", action["_index"])
+	require.Equal(t, GetPointID(m), action["_id"])
+}
+
+func TestDataStreamInvalidConfig(t *testing.T) {
+	ts := newBulkServer(t, `{"version": {"number": "7.8.0"}}`, nil)
+	defer ts.Close()
+
+	e := ts.plugin()
+	e.DataStream = true
+	require.ErrorContains(t, e.Connect(), "date specifiers are not supported in data stream names")
+
+	e = ts.plugin()
+	e.IndexName = "metrics-telegraf"
+	e.DataStream = true
+	e.TemplateFormat = "legacy"
+	require.ErrorContains(t, e.Connect(), "data streams require composable index templates")
+
+	e = ts.plugin()
+	e.IndexName = "metrics-telegraf"
+	e.DataStream = true
+	require.ErrorContains(t, e.Connect(), "data streams require Elasticsearch 7.9 or later, found 7.8.0")
+}
+
+func TestOpenSearch(t *testing.T) {
+	ts := newBulkServer(t, `{"version": {"number": "2.3.0", "distribution": "opensearch"}}`, func(int, map[string]map[string]interface{}) int {
+		return http.StatusCreated
+	})
+	defer ts.Close()
+
+	e := ts.plugin()
+	require.NoError(t, e.Connect())
+	require.Equal(t, 7, e.majorReleaseNumber)
+
+	require.NoError(t, e.Write(testMetrics()))
+	require.Len(t, ts.requests, 1)
+	require.NotContains(t, ts.requests[0][0]["index"], "_type")
+}
+
+func TestAWSSigning(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/es/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/_bulk":
+			_, err := w.Write([]byte("{}"))
+			require.NoError(t, err)
+		default:
+			_, err := w.Write([]byte(`{"version": {"number": "7.10.2"}}`))
+			require.NoError(t, err)
+		}
+	}))
+	defer ts.Close()
+
+	e := &Elasticsearch{
+		URLs:       []string{ts.URL},
+		IndexName:  "test-%Y.%m.%d",
+		Timeout:    config.Duration(time.Second * 5),
+		AwsService: "es",
+		Log:        testutil.Logger{},
+	}
+	e.Region = "eu-west-1"
+	e.AccessKey = "AKID"
+	e.SecretKey = "SECRET"
+
+	require.NoError(t, e.Connect())
+	require.NoError(t, e.Write(testutil.MockMetrics()))
+}
@@ -4,7 +4,9 @@ package kafka
 import (
 	_ "embed"
 	"fmt"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -13,8 +15,10 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/common/kafka"
 	"github.com/influxdata/telegraf/plugins/common/proxy"
+	"github.com/influxdata/telegraf/plugins/common/templating"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 // DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
@@ -29,6 +33,11 @@ var ValidTopicSuffixMethods = []string{
 
 var zeroTime = time.Unix(0, 0)
 
+// Maximum number of topics with their own internal counters, the messages of
+// further topics are counted for the topic "_other" as topics created by
+// templates or tags are unbounded
+const maxStatsTopics = 100
+
 type Kafka struct {
 	Brokers         []string    `toml:"brokers"`
 	Topic           string      `toml:"topic"`
@@ -38,6 +47,10 @@ type Kafka struct {
 	RoutingTag      string      `toml:"routing_tag"`
 	RoutingKey      string      `toml:"routing_key"`
 
+	TopicTemplate string            `toml:"topic_template"`
+	KeyTemplate   string            `toml:"key_template"`
+	HeaderTags    map[string]string `toml:"header_tags"`
+
 	proxy.Socks5ProxyConfig
 
 	// Legacy TLS config options
@@ -57,6 +70,17 @@ type Kafka struct {
 	producer     sarama.SyncProducer
 
 	serializer serializers.Serializer
+
+	topicTmpl  *template.Template
+	keyTmpl    *template.Template
+	headerTags []string
+	stats      map[string]*topicStats
+}
+
+// topicStats holds the internal delivery counters of a topic
+type topicStats struct {
+	delivered selfstat.Stat
+	failed    selfstat.Stat
 }
 
 type TopicSuffix struct {
@@ -144,6 +168,27 @@ func (k *Kafka) Init() error {
 	if err != nil {
 		return err
 	}
+
+	if k.TopicTemplate != "" {
+		k.topicTmpl, err = template.New("topic").Parse(k.TopicTemplate)
+		if err != nil {
+			return fmt.Errorf("parsing topic_template failed: %w", err)
+		}
+	}
+	if k.KeyTemplate != "" {
+		k.keyTmpl, err = template.New("key").Parse(k.KeyTemplate)
+		if err != nil {
+			return fmt.Errorf("parsing key_template failed: %w", err)
+		}
+	}
+
+	// Sort the tags to add the headers in a stable order
+	k.headerTags = make([]string, 0, len(k.HeaderTags))
+	for tag := range k.HeaderTags {
+		k.headerTags = append(k.headerTags, tag)
+	}
+	sort.Strings(k.headerTags)
+
 	config := sarama.NewConfig()
 
 	if err := k.SetConfig(config); err != nil {
@@ -182,10 +227,17 @@ func (k *Kafka) Connect() error {
 }
 
 func (k *Kafka) Close() error {
+	if k.producer == nil {
+		return nil
+	}
 	return k.producer.Close()
 }
 
 func (k *Kafka) routingKey(metric telegraf.Metric) (string, error) {
+	if k.keyTmpl != nil {
+		return templating.Execute(k.keyTmpl, metric)
+	}
+
 	if k.RoutingTag != "" {
 		key, ok := metric.GetTag(k.RoutingTag)
 		if ok {
@@ -204,10 +256,55 @@ func (k *Kafka) routingKey(metric telegraf.Metric) (string, error) {
 	return k.RoutingKey, nil
 }
 
+// topic returns the topic for the metric. The topic template takes precedence
+// over the other topic options.
+func (k *Kafka) topic(metric telegraf.Metric) (telegraf.Metric, string, error) {
+	if k.topicTmpl == nil {
+		metric, topic := k.GetTopicName(metric)
+		return metric, topic, nil
+	}
+
+	topic, err := templating.Execute(k.topicTmpl, metric)
+	if err != nil {
+		return metric, "", err
+	}
+	if topic == "" {
+		topic = k.Topic
+	}
+	return metric, topic, nil
+}
+
+// headers returns the record headers created from the tags of the metric
+func (k *Kafka) headers(metric telegraf.Metric) []sarama.RecordHeader {
+	var headers []sarama.RecordHeader
+	for _, tag := range k.headerTags {
+		value, ok := metric.GetTag(tag)
+		if !ok {
+			continue
+		}
+		headers = append(headers, sarama.RecordHeader{
+			Key:   []byte(k.HeaderTags[tag]),
+			Value: []byte(value),
+		})
+	}
+	return headers
+}
+
 func (k *Kafka) Write(metrics []telegraf.Metric) error {
+	// Recreate the producer if it was closed after a fatal transaction error
+	if k.producer == nil {
+		if err := k.Connect(); err != nil {
+			return fmt.Errorf("recreating producer failed: %w", err)
+		}
+	}
+
 	msgs := make([]*sarama.ProducerMessage, 0, len(metrics))
 	for _, metric := range metrics {
-		metric, topic := k.GetTopicName(metric)
+		metric, topic, err := k.topic(metric)
+		if err != nil {
+			k.Log.Errorf("Could not generate topic, dropping metric: %v", err)
+			continue
+		}
 
 		buf, err := k.serializer.Serialize(metric)
 		if err != nil {
@@ -216,8 +313,9 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 		}
 
 		m := &sarama.ProducerMessage{
-			Topic: topic,
-			Value: sarama.ByteEncoder(buf),
+			Topic:   topic,
+			Value:   sarama.ByteEncoder(buf),
+			Headers: k.headers(metric),
 		}
 
 		// Negative timestamps are not allowed by the Kafka protocol.
@@ -236,7 +334,8 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 		msgs = append(msgs, m)
 	}
 
-	err := k.producer.SendMessages(msgs)
+	err := k.send(msgs)
+	k.updateStats(msgs, err)
 	if err != nil {
 		// We could have many errors, return only the first encountered.
 		if errs, ok := err.(sarama.ProducerErrors); ok {
@@ -258,6 +357,95 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// send produces the messages, within a transaction if a transactional id
+// is configured
+func (k *Kafka) send(msgs []*sarama.ProducerMessage) error {
+	if k.TransactionalID == "" {
+		return k.producer.SendMessages(msgs)
+	}
+
+	err := k.sendTxn(msgs)
+	if err != nil && k.producer.TxnStatus()&sarama.ProducerTxnFlagFatalError != 0 {
+		// The producer cannot be used anymore after a fatal error, so it is
+		// recreated with the next write
+		k.Log.Errorf("Closing producer after fatal transaction error: %v", err)
+		if closeErr := k.producer.Close(); closeErr != nil {
+			k.Log.Errorf("Closing producer failed: %v", closeErr)
+		}
+		k.producer = nil
+	}
+	return err
+}
+
+func (k *Kafka) sendTxn(msgs []*sarama.ProducerMessage) error {
+	if err := k.producer.BeginTxn(); err != nil {
+		return fmt.Errorf("beginning transaction failed: %w", err)
+	}
+	if err := k.producer.SendMessages(msgs); err != nil {
+		if abortErr := k.producer.AbortTxn(); abortErr != nil {
+			k.Log.Errorf("Aborting transaction failed: %v", abortErr)
+		}
+		return err
+	}
+	if err := k.producer.CommitTxn(); err != nil {
+		if abortErr := k.producer.AbortTxn(); abortErr != nil {
+			k.Log.Errorf("Aborting transaction failed: %v", abortErr)
+		}
+		return fmt.Errorf("committing transaction failed: %w", err)
+	}
+	return nil
+}
+
+// updateStats counts the delivered and failed messages per topic. Within a
+// transaction either all or none of the messages are delivered.
+func (k *Kafka) updateStats(msgs []*sarama.ProducerMessage, err error) {
+	var failed map[*sarama.ProducerMessage]bool
+	allFailed := err != nil
+	if errs, ok := err.(sarama.ProducerErrors); ok && k.TransactionalID == "" {
+		allFailed = false
+		failed = make(map[*sarama.ProducerMessage]bool, len(errs))
+		for _, prodErr := range errs {
+			failed[prodErr.Msg] = true
+		}
+	}
+
+	for _, m := range msgs {
+		stats := k.topicStats(m.Topic)
+		if allFailed || failed[m] {
+			stats.failed.Incr(1)
+		} else {
+			stats.delivered.Incr(1)
+		}
+	}
+}
+
+func (k *Kafka) topicStats(topic string) *topicStats {
+	if k.stats == nil {
+		k.stats = make(map[string]*topicStats)
+	}
+	stats, ok := k.stats[topic]
+	if !ok && len(k.stats) >= maxStatsTopics {
+		topic = "_other"
+		stats, ok = k.stats[topic]
+	}
+	if !ok {
+		clientID := k.ClientID
+		if clientID == "" {
+			clientID = "Telegraf"
+		}
+		tags := map[string]string{
+			"client_id": clientID,
+			"topic":     topic,
+		}
+		stats = &topicStats{
+			delivered: selfstat.Register("kafka", "messages_delivered", tags),
+			failed:    selfstat.Register("kafka", "messages_failed", tags),
+		}
+		k.stats[topic] = stats
+	}
+	return stats
+}
+
 func init() {
 	outputs.Add("kafka", func() telegraf.Output {
 		return &Kafka{
@@ -13,6 +13,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/kafka"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -211,7 +212,11 @@ func TestRoutingKey(t *testing.T) {
 }
 
 type MockProducer struct {
-	sent []*sarama.ProducerMessage
+	sent      []*sarama.ProducerMessage
+	sendErr   error
+	txns      []string
+	txnStatus sarama.ProducerTxnStatusFlag
+	closed    bool
 }
 
 func (p *MockProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
@@ -220,11 +225,49 @@ func (p *MockProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32
 }
 
 func (p *MockProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	if p.sendErr != nil {
+		return p.sendErr
+	}
 	p.sent = append(p.sent, msgs...)
 	return nil
 }
 
 func (p *MockProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *MockProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	if p.txnStatus != 0 {
+		return p.txnStatus
+	}
+	return sarama.ProducerTxnFlagReady
+}
+
+func (p *MockProducer) IsTransactional() bool {
+	return false
+}
+
+func (p *MockProducer) BeginTxn() error {
+	p.txns = append(p.txns, "begin")
+	return nil
+}
+
+func (p *MockProducer) CommitTxn() error {
+	p.txns = append(p.txns, "commit")
+	return nil
+}
+
+func (p *MockProducer) AbortTxn() error {
+	p.txns = append(p.txns, "abort")
+	return nil
+}
+
+func (p *MockProducer) AddOffsetsToTxn(_ map[string][]*sarama.PartitionOffsetMetadata, _ string) error {
+	return nil
+}
+
+func (p *MockProducer) AddMessageToTxn(_ *sarama.ConsumerMessage, _ string, _ *string) error {
 	return nil
 }
 
@@ -354,3 +397,263 @@ func TestTopicTag(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplates(t *testing.T) {
+	plugin := &Kafka{
+		Topic:         "telegraf",
+		TopicTemplate: `{{.Name}}-{{.Tag "region"}}`,
+		KeyTemplate:   `{{.Tag "region"}}/{{.Tag "host"}}`,
+		RoutingTag:    "host",
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"host":   "server01",
+			"region": "eu-west",
+		},
+		map[string]interface{}{
+			"time_idle": 42.0,
+		},
+		time.Unix(0, 0),
+	)
+
+	_, topic, err := plugin.topic(m)
+	require.NoError(t, err)
+	require.Equal(t, "cpu-eu-west", topic)
+
+	key, err := plugin.routingKey(m)
+	require.NoError(t, err)
+	require.Equal(t, "eu-west/server01", key)
+}
+
+func TestEmptyTopicTemplateFallsBackToTopic(t *testing.T) {
+	plugin := &Kafka{
+		Topic:         "telegraf",
+		TopicTemplate: `{{.Tag "topic"}}`,
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	_, topic, err := plugin.topic(m)
+	require.NoError(t, err)
+	require.Equal(t, "telegraf", topic)
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	plugin := &Kafka{
+		Topic:       "telegraf",
+		KeyTemplate: `{{.Tag "host"`,
+		Log:         testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), "parsing key_template failed")
+}
+
+func TestHeaderTags(t *testing.T) {
+	plugin := &Kafka{
+		Topic: "telegraf",
+		HeaderTags: map[string]string{
+			"region": "x-region",
+			"host":   "x-host",
+			"absent": "x-absent",
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	s, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	plugin.SetSerializer(s)
+
+	producer := &MockProducer{}
+	plugin.producer = producer
+
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"host":   "server01",
+			"region": "eu-west",
+		},
+		map[string]interface{}{
+			"time_idle": 42.0,
+		},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Len(t, producer.sent, 1)
+
+	expected := []sarama.RecordHeader{
+		{Key: []byte("x-host"), Value: []byte("server01")},
+		{Key: []byte("x-region"), Value: []byte("eu-west")},
+	}
+	require.Equal(t, expected, producer.sent[0].Headers)
+}
+
+func TestTransactionalIDRequiresIdempotentWrites(t *testing.T) {
+	plugin := &Kafka{
+		Topic: "telegraf",
+		WriteConfig: kafka.WriteConfig{
+			TransactionalID: "telegraf",
+		},
+		Log: testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), "transactional_id requires idempotent_writes")
+}
+
+func TestTransactionalWrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		sendErr   error
+		txns      []string
+		delivered int64
+		failed    int64
+	}{
+		{
+			name:      "commit",
+			txns:      []string{"begin", "commit"},
+			delivered: 2,
+		},
+		{
+			name:    "abort",
+			sendErr: sarama.ErrOutOfBrokers,
+			txns:    []string{"begin", "abort"},
+			failed:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Use a separate topic per case as the stats are global
+			topic := "transactional_" + tt.name
+			plugin := &Kafka{
+				Topic: topic,
+				WriteConfig: kafka.WriteConfig{
+					IdempotentWrites: true,
+					TransactionalID:  "telegraf",
+				},
+				Log: testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			s, err := serializers.NewInfluxSerializer()
+			require.NoError(t, err)
+			plugin.SetSerializer(s)
+
+			producer := &MockProducer{sendErr: tt.sendErr}
+			plugin.producer = producer
+
+			input := []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+				testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 23.0}, time.Unix(0, 0)),
+			}
+			err = plugin.Write(input)
+			if tt.sendErr != nil {
+				require.ErrorIs(t, err, tt.sendErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.txns, producer.txns)
+
+			stats := plugin.topicStats(topic)
+			require.Equal(t, tt.delivered, stats.delivered.Get())
+			require.Equal(t, tt.failed, stats.failed.Get())
+		})
+	}
+}
+
+func TestFatalTransactionError(t *testing.T) {
+	var created []*MockProducer
+	plugin := &Kafka{
+		Topic: "transactional_fatal",
+		WriteConfig: kafka.WriteConfig{
+			IdempotentWrites: true,
+			TransactionalID:  "telegraf",
+		},
+		Log: testutil.Logger{},
+		producerFunc: func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+			producer := &MockProducer{}
+			created = append(created, producer)
+			return producer, nil
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	s, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	plugin.SetSerializer(s)
+	require.NoError(t, plugin.Connect())
+
+	// A fenced producer cannot be used anymore and is closed
+	created[0].sendErr = sarama.ErrProducerFenced
+	created[0].txnStatus = sarama.ProducerTxnFlagFatalError
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+	}
+	require.ErrorIs(t, plugin.Write(input), sarama.ErrProducerFenced)
+	require.True(t, created[0].closed)
+	require.Nil(t, plugin.producer)
+
+	// The next write uses a new producer
+	require.NoError(t, plugin.Write(input))
+	require.Len(t, created, 2)
+	require.Len(t, created[1].sent, 1)
+	require.Equal(t, []string{"begin", "commit"}, created[1].txns)
+}
+
+func TestPerTopicStats(t *testing.T) {
+	plugin := &Kafka{
+		Topic:    "telegraf",
+		TopicTag: "topic",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	s, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	plugin.SetSerializer(s)
+	plugin.producer = &MockProducer{}
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"topic": "a"}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"topic": "b"}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"topic": "b"}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+	}
+	require.NoError(t, plugin.Write(input))
+
+	stats := plugin.topicStats("a")
+	require.Equal(t, int64(1), stats.delivered.Get())
+	require.Equal(t, int64(0), stats.failed.Get())
+	stats = plugin.topicStats("b")
+	require.Equal(t, int64(2), stats.delivered.Get())
+	require.Equal(t, int64(0), stats.failed.Get())
+
+	// Only the message in the error is counted as failed
+	msg := &sarama.ProducerMessage{Topic: "a"}
+	msgs := []*sarama.ProducerMessage{msg, {Topic: "b"}}
+	plugin.updateStats(msgs, sarama.ProducerErrors{{Msg: msg, Err: sarama.ErrOutOfBrokers}})
+	require.Equal(t, int64(1), plugin.topicStats("a").failed.Get())
+	require.Equal(t, int64(3), plugin.topicStats("b").delivered.Get())
+}
+
+func TestPerTopicStatsBounded(t *testing.T) {
+	plugin := &Kafka{
+		Topic: "telegraf",
+		Log:   testutil.Logger{},
+		WriteConfig: kafka.WriteConfig{
+			Config: kafka.Config{ClientID: "bounded"},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	for i := 0; i < maxStatsTopics; i++ {
+		plugin.topicStats(fmt.Sprintf("topic_%d", i)).delivered.Incr(1)
+	}
+	plugin.topicStats("new").delivered.Incr(1)
+	plugin.topicStats("newer").delivered.Incr(1)
+
+	require.Len(t, plugin.stats, maxStatsTopics+1)
+	require.Equal(t, int64(2), plugin.stats["_other"].delivered.Get())
+	require.Equal(t, int64(1), plugin.topicStats("topic_0").delivered.Get())
+}
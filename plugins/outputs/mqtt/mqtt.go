@@ -4,13 +4,16 @@ package mqtt
 import (
 	// Blank import to support go:embed compile directive
 	_ "embed"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
 	"sync"
+	"text/template"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/templating"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
@@ -31,6 +34,7 @@ type MQTT struct {
 	Password    string   `toml:"password"`
 	Database    string
 	Timeout     config.Duration `toml:"timeout"`
+	Topic       string          `toml:"topic"`
 	TopicPrefix string          `toml:"topic_prefix"`
 	QoS         int             `toml:"qos"`
 	ClientID    string          `toml:"client_id"`
@@ -40,8 +44,12 @@ type MQTT struct {
 	KeepAlive    int64           `toml:"keep_alive"`
 	Log          telegraf.Logger `toml:"-"`
 
+	// MQTT 5 specific settings
+	UserPropertyTags []string `toml:"user_property_tags"`
+
 	client     Client
 	serializer serializers.Serializer
+	topic      *template.Template
 
 	sync.Mutex
 }
@@ -51,14 +59,44 @@ type MQTT struct {
 // The protocol specific clients must implement this interface
 type Client interface {
 	Connect() error
-	Publish(topic string, data []byte) error
+	Publish(topic string, data []byte, properties []userProperty) error
 	Close() error
 }
 
+// userProperty is a key-value pair sent along with the message
+type userProperty struct {
+	key   string
+	value string
+}
+
 func (*MQTT) SampleConfig() string {
 	return sampleConfig
 }
 
+func (m *MQTT) Init() error {
+	switch m.Protocol {
+	case "", "3.1.1":
+		if len(m.UserPropertyTags) > 0 {
+			return errors.New("user_property_tags requires protocol version 5")
+		}
+	case "5":
+	default:
+		return fmt.Errorf("unsuported protocol %q: must be \"3.1.1\" or \"5\"", m.Protocol)
+	}
+
+	// Without a template, the topic is built from the prefix, hostname and
+	// metric name in Write
+	if m.Topic != "" {
+		tmpl, err := template.New("topic").Parse(m.Topic)
+		if err != nil {
+			return fmt.Errorf("parsing topic template failed: %w", err)
+		}
+		m.topic = tmpl
+	}
+
+	return nil
+}
+
 func (m *MQTT) Connect() error {
 	m.Lock()
 	defer m.Unlock()
@@ -71,8 +109,6 @@ func (m *MQTT) Connect() error {
 		m.client = newMQTTv311Client(m)
 	case "5":
 		m.client = newMQTTv5Client(m)
-	default:
-		return fmt.Errorf("unsuported protocol %q: must be \"3.1.1\" or \"5\"", m.Protocol)
 	}
 
 	return m.client.Connect()
@@ -97,22 +133,36 @@ func (m *MQTT) Write(metrics []telegraf.Metric) error {
 		hostname = ""
 	}
 
-	metricsmap := make(map[string][]telegraf.Metric)
+	type batch struct {
+		topic      string
+		properties []userProperty
+		metrics    []telegraf.Metric
+	}
+	var batches []*batch
+	batchIndex := make(map[string]*batch)
 
 	for _, metric := range metrics {
-		var t []string
-		if m.TopicPrefix != "" {
-			t = append(t, m.TopicPrefix)
-		}
-		if hostname != "" {
-			t = append(t, hostname)
+		topic, err := m.generateTopic(metric, hostname)
+		if err != nil {
+			m.Log.Errorf("Could not generate topic for metric %q: %v", metric.Name(), err)
+			continue
 		}
-
-		t = append(t, metric.Name())
-		topic := strings.Join(t, "/")
+		properties := m.userProperties(metric)
 
 		if m.BatchMessage {
-			metricsmap[topic] = append(metricsmap[topic], metric)
+			// Messages can only share the topic and properties, so batch
+			// metrics by both
+			key := topic
+			for _, p := range properties {
+				key += "\x00" + p.key + "=" + p.value
+			}
+			b, found := batchIndex[key]
+			if !found {
+				b = &batch{topic: topic, properties: properties}
+				batchIndex[key] = b
+				batches = append(batches, b)
+			}
+			b.metrics = append(b.metrics, metric)
 		} else {
 			buf, err := m.serializer.Serialize(metric)
 			if err != nil {
@@ -120,20 +170,20 @@ func (m *MQTT) Write(metrics []telegraf.Metric) error {
 				continue
 			}
 
-			err = m.client.Publish(topic, buf)
+			err = m.client.Publish(topic, buf, properties)
 			if err != nil {
 				return fmt.Errorf("could not write to MQTT server, %s", err)
 			}
 		}
 	}
 
-	for key := range metricsmap {
-		buf, err := m.serializer.SerializeBatch(metricsmap[key])
+	for _, b := range batches {
+		buf, err := m.serializer.SerializeBatch(b.metrics)
 
 		if err != nil {
 			return err
 		}
-		err = m.client.Publish(key, buf)
+		err = m.client.Publish(b.topic, buf, b.properties)
 		if err != nil {
 			return fmt.Errorf("could not write to MQTT server, %s", err)
 		}
@@ -142,6 +192,43 @@ func (m *MQTT) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// generateTopic renders the topic template for the given metric. Without a
+// template the topic is '<topic_prefix>/<hostname>/<name>', skipping an empty
+// prefix or hostname.
+func (m *MQTT) generateTopic(metric telegraf.Metric, hostname string) (string, error) {
+	if m.topic == nil {
+		var t []string
+		if m.TopicPrefix != "" {
+			t = append(t, m.TopicPrefix)
+		}
+		if hostname != "" {
+			t = append(t, hostname)
+		}
+		t = append(t, metric.Name())
+		return strings.Join(t, "/"), nil
+	}
+
+	topic, err := templating.Execute(m.topic, metric)
+	if err != nil {
+		return "", err
+	}
+	if topic == "" {
+		return "", errors.New("topic is empty")
+	}
+	return topic, nil
+}
+
+// userProperties collects the configured tags of the metric as user properties
+func (m *MQTT) userProperties(metric telegraf.Metric) []userProperty {
+	var properties []userProperty
+	for _, key := range m.UserPropertyTags {
+		if value, found := metric.GetTag(key); found {
+			properties = append(properties, userProperty{key: key, value: value})
+		}
+	}
+	return properties
+}
+
 func parseServers(servers []string) ([]*url.URL, error) {
 	urls := make([]*url.URL, 0, len(servers))
 	for _, svr := range servers {
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -51,6 +54,8 @@ func TestConnectAndWriteIntegration(t *testing.T) {
 		Log:        testutil.Logger{Name: "mqtt-default-integration-test"},
 	}
 
+	require.NoError(t, m.Init())
+
 	// Verify that we can connect to the MQTT broker
 	err = m.Connect()
 	require.NoError(t, err)
@@ -81,6 +86,8 @@ func TestConnectAndWriteIntegrationMQTTv3(t *testing.T) {
 		Log:        testutil.Logger{Name: "mqttv311-integration-test"},
 	}
 
+	require.NoError(t, m.Init())
+
 	// Verify that we can connect to the MQTT broker
 	err = m.Connect()
 	require.NoError(t, err)
@@ -111,6 +118,8 @@ func TestConnectAndWriteIntegrationMQTTv5(t *testing.T) {
 		Log:        testutil.Logger{Name: "mqttv5-integration-test"},
 	}
 
+	require.NoError(t, m.Init())
+
 	// Verify that we can connect to the MQTT broker
 	err = m.Connect()
 	require.NoError(t, err)
@@ -119,3 +128,157 @@ func TestConnectAndWriteIntegrationMQTTv5(t *testing.T) {
 	err = m.Write(testutil.MockMetrics())
 	require.NoError(t, err)
 }
+
+type message struct {
+	topic      string
+	payload    string
+	properties []userProperty
+}
+
+type fakeClient struct {
+	messages []message
+}
+
+func (c *fakeClient) Connect() error {
+	return nil
+}
+
+func (c *fakeClient) Publish(topic string, data []byte, properties []userProperty) error {
+	c.messages = append(c.messages, message{topic: topic, payload: string(data), properties: properties})
+	return nil
+}
+
+func (c *fakeClient) Close() error {
+	return nil
+}
+
+func TestInit(t *testing.T) {
+	m := &MQTT{Protocol: "4"}
+	require.ErrorContains(t, m.Init(), "unsuported protocol")
+
+	m = &MQTT{UserPropertyTags: []string{"host"}}
+	require.ErrorContains(t, m.Init(), "requires protocol version 5")
+
+	m = &MQTT{Topic: "telegraf/{{ .Tag }"}
+	require.ErrorContains(t, m.Init(), "parsing topic template failed")
+}
+
+func TestTopicGeneration(t *testing.T) {
+	tests := []struct {
+		name     string
+		topic    string
+		prefix   string
+		expected []string
+	}{
+		{
+			name:     "default layout",
+			prefix:   "telegraf",
+			expected: []string{"telegraf/server01/cpu", "telegraf/server01/mem"},
+		},
+		{
+			name:     "default layout without prefix",
+			expected: []string{"server01/cpu", "server01/mem"},
+		},
+		{
+			name:     "default layout with leading slash",
+			prefix:   "/telegraf",
+			expected: []string{"/telegraf/server01/cpu", "/telegraf/server01/mem"},
+		},
+		{
+			name:     "prefix is no template",
+			prefix:   "{{ .Name }}",
+			expected: []string{"{{ .Name }}/server01/cpu", "{{ .Name }}/server01/mem"},
+		},
+		{
+			name:     "template",
+			topic:    `sensors/{{ .Tag "location" }}/{{ .Name }}/{{ .Tag "host" }}`,
+			prefix:   "ignored",
+			expected: []string{"sensors/berlin/cpu/server01", "sensors/paris/mem/"},
+		},
+		{
+			name:     "template keeps empty levels",
+			topic:    `/sensors/{{ .Tag "host" }}/{{ .Name }}`,
+			expected: []string{"/sensors/server01/cpu", "/sensors//mem"},
+		},
+	}
+
+	input := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "location": "berlin"},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"location": "paris"},
+			map[string]interface{}{"value": 23},
+			time.Unix(0, 0),
+		),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := serializers.NewInfluxSerializer()
+			require.NoError(t, err)
+			client := &fakeClient{}
+			m := &MQTT{
+				Topic:       tt.topic,
+				TopicPrefix: tt.prefix,
+				serializer:  s,
+				client:      client,
+				Log:         testutil.Logger{},
+			}
+			require.NoError(t, m.Init())
+			require.NoError(t, m.Write(input))
+
+			actual := make([]string, 0, len(client.messages))
+			for _, msg := range client.messages {
+				actual = append(actual, msg.topic)
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestBatchUserProperties(t *testing.T) {
+	s, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	client := &fakeClient{}
+	m := &MQTT{
+		Protocol:         "5",
+		Topic:            "telegraf/{{ .Name }}",
+		BatchMessage:     true,
+		UserPropertyTags: []string{"location"},
+		serializer:       s,
+		client:           client,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, m.Init())
+
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"location": "berlin"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"location": "paris"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"location": "berlin"}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 4}, time.Unix(0, 0)),
+	}
+	require.NoError(t, m.Write(input))
+
+	expected := []message{
+		{
+			topic:      "telegraf/cpu",
+			payload:    "cpu,location=berlin value=1i 0\ncpu,location=berlin value=3i 0\n",
+			properties: []userProperty{{key: "location", value: "berlin"}},
+		},
+		{
+			topic:      "telegraf/cpu",
+			payload:    "cpu,location=paris value=2i 0\n",
+			properties: []userProperty{{key: "location", value: "paris"}},
+		},
+		{
+			topic:   "telegraf/cpu",
+			payload: "cpu value=4i 0\n",
+		},
+	}
+	require.Equal(t, expected, client.messages)
+}
@@ -75,7 +75,7 @@ func (m *mqttv311Client) Connect() error {
 	return nil
 }
 
-func (m *mqttv311Client) Publish(topic string, body []byte) error {
+func (m *mqttv311Client) Publish(topic string, body []byte, _ []userProperty) error {
 	token := m.client.Publish(topic, byte(m.QoS), m.Retain, body)
 	token.WaitTimeout(time.Duration(m.Timeout))
 	if token.Error() != nil {
@@ -74,15 +74,24 @@ func (m *mqttv5Client) Connect() error {
 	return client.AwaitConnection(context.Background())
 }
 
-func (m *mqttv5Client) Publish(topic string, body []byte) error {
+func (m *mqttv5Client) Publish(topic string, body []byte, properties []userProperty) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.Timeout))
 	defer cancel()
-	_, err := m.client.Publish(ctx, &mqttv5.Publish{
+
+	msg := &mqttv5.Publish{
 		Topic:   topic,
 		QoS:     byte(m.QoS),
 		Retain:  m.Retain,
 		Payload: body,
-	})
+	}
+	if len(properties) > 0 {
+		msg.Properties = &mqttv5.PublishProperties{}
+		for _, p := range properties {
+			msg.Properties.User.Add(p.key, p.value)
+		}
+	}
+
+	_, err := m.client.Publish(ctx, msg)
 	if err != nil {
 		return err
 	}
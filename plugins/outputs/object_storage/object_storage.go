@@ -0,0 +1,247 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package object_storage
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	internalaws "github.com/influxdata/telegraf/plugins/common/aws"
+	"github.com/influxdata/telegraf/plugins/common/templating"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+// Extensions appended to the object key for the content encodings
+var encodingExtensions = map[string]string{
+	"":         "",
+	"identity": "",
+	"gzip":     ".gz",
+	"zstd":     ".zst",
+}
+
+type ObjectStorage struct {
+	Bucket               string          `toml:"bucket"`
+	KeyTemplate          string          `toml:"key_template"`
+	FileExtension        string          `toml:"file_extension"`
+	ContentEncoding      string          `toml:"content_encoding"`
+	ContentType          string          `toml:"content_type"`
+	UsePathStyle         bool            `toml:"use_path_style"`
+	PartSize             config.Size     `toml:"part_size"`
+	UploadConcurrency    int             `toml:"upload_concurrency"`
+	MaxRetries           int             `toml:"max_retries"`
+	Timeout              config.Duration `toml:"timeout"`
+	ServerSideEncryption string          `toml:"server_side_encryption"`
+	SSEKMSKeyID          string          `toml:"sse_kms_key_id"`
+	StorageClass         string          `toml:"storage_class"`
+	Log                  telegraf.Logger `toml:"-"`
+	internalaws.CredentialConfig
+
+	keyTmpl    *template.Template
+	encoder    internal.ContentEncoder
+	serializer serializers.Serializer
+	uploader   *manager.Uploader
+	sequence   uint64
+}
+
+func (*ObjectStorage) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *ObjectStorage) SetSerializer(serializer serializers.Serializer) {
+	o.serializer = serializer
+}
+
+func (o *ObjectStorage) Init() error {
+	if o.Bucket == "" {
+		return errors.New("bucket required")
+	}
+
+	var err error
+	o.keyTmpl, err = template.New("key").Parse(o.KeyTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing key_template failed: %w", err)
+	}
+
+	if _, found := encodingExtensions[o.ContentEncoding]; !found {
+		return fmt.Errorf("invalid content_encoding %q", o.ContentEncoding)
+	}
+	o.encoder, err = internal.NewContentEncoder(o.ContentEncoding)
+	if err != nil {
+		return err
+	}
+
+	if int64(o.PartSize) < manager.MinUploadPartSize {
+		return fmt.Errorf("part_size must be at least %d bytes", manager.MinUploadPartSize)
+	}
+	if o.UploadConcurrency < 1 {
+		return errors.New("upload_concurrency must be at least 1")
+	}
+	if o.MaxRetries < 0 {
+		return errors.New("max_retries must not be negative")
+	}
+
+	if o.ServerSideEncryption != "" && !validEncryption(o.ServerSideEncryption) {
+		return fmt.Errorf("invalid server_side_encryption %q", o.ServerSideEncryption)
+	}
+	if o.SSEKMSKeyID != "" && !strings.HasPrefix(o.ServerSideEncryption, "aws:kms") {
+		return errors.New("sse_kms_key_id requires server_side_encryption to be aws:kms")
+	}
+	if o.StorageClass != "" && !validStorageClass(o.StorageClass) {
+		return fmt.Errorf("invalid storage_class %q", o.StorageClass)
+	}
+
+	return nil
+}
+
+func validEncryption(value string) bool {
+	for _, v := range types.ServerSideEncryption("").Values() {
+		if string(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func validStorageClass(value string) bool {
+	for _, v := range types.StorageClass("").Values() {
+		if string(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *ObjectStorage) Connect() error {
+	cfg, err := o.CredentialConfig.Credentials()
+	if err != nil {
+		return err
+	}
+
+	client := s3.NewFromConfig(cfg, func(opts *s3.Options) {
+		if o.EndpointURL != "" {
+			opts.EndpointResolver = s3.EndpointResolverFromURL(o.EndpointURL)
+		}
+		// S3 compatible services often only support path style addressing
+		opts.UsePathStyle = o.UsePathStyle
+		opts.Retryer = retry.NewStandard(func(ro *retry.StandardOptions) {
+			ro.MaxAttempts = o.MaxRetries + 1
+		})
+	})
+
+	// Objects larger than the part size are sent as multipart upload
+	o.uploader = manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = int64(o.PartSize)
+		u.Concurrency = o.UploadConcurrency
+	})
+
+	return nil
+}
+
+func (o *ObjectStorage) Close() error {
+	return nil
+}
+
+// Write uploads the metrics as one object per distinct key prefix. A failed
+// upload fails the whole write, so objects uploaded before are sent again
+// when the batch is retried.
+func (o *ObjectStorage) Write(metrics []telegraf.Metric) error {
+	var prefixes []string
+	groups := make(map[string][]telegraf.Metric)
+	for _, metric := range metrics {
+		prefix, err := templating.Execute(o.keyTmpl, metric)
+		if err != nil {
+			o.Log.Errorf("Could not generate key, dropping metric: %v", err)
+			continue
+		}
+
+		if _, found := groups[prefix]; !found {
+			prefixes = append(prefixes, prefix)
+		}
+		groups[prefix] = append(groups[prefix], metric)
+	}
+
+	for _, prefix := range prefixes {
+		if err := o.upload(prefix, groups[prefix]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *ObjectStorage) upload(prefix string, metrics []telegraf.Metric) error {
+	data, err := o.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("serializing metrics failed: %w", err)
+	}
+	data, err = o.encoder.Encode(data)
+	if err != nil {
+		return fmt.Errorf("encoding metrics failed: %w", err)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(o.Bucket),
+		Key:    aws.String(o.objectKey(prefix)),
+		Body:   bytes.NewReader(data),
+	}
+	if o.ContentType != "" {
+		input.ContentType = aws.String(o.ContentType)
+	}
+	if o.ServerSideEncryption != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(o.ServerSideEncryption)
+	}
+	if o.SSEKMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(o.SSEKMSKeyID)
+	}
+	if o.StorageClass != "" {
+		input.StorageClass = types.StorageClass(o.StorageClass)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.Timeout))
+	defer cancel()
+	if _, err := o.uploader.Upload(ctx, input); err != nil {
+		return fmt.Errorf("uploading %q failed: %w", *input.Key, err)
+	}
+
+	o.Log.Debugf("Uploaded %d metrics to %q", len(metrics), *input.Key)
+	return nil
+}
+
+// objectKey returns a unique key below the prefix, the name consists of the
+// upload time and a sequence number to avoid overwriting objects
+func (o *ObjectStorage) objectKey(prefix string) string {
+	o.sequence++
+	name := fmt.Sprintf("%d-%d%s%s", time.Now().UnixNano(), o.sequence, o.FileExtension, encodingExtensions[o.ContentEncoding])
+	return path.Join(prefix, name)
+}
+
+func init() {
+	outputs.Add("object_storage", func() telegraf.Output {
+		return &ObjectStorage{
+			KeyTemplate:       `{{.Name}}/{{.Time.Format "2006/01/02"}}`,
+			PartSize:          config.Size(manager.DefaultUploadPartSize),
+			UploadConcurrency: manager.DefaultUploadConcurrency,
+			MaxRetries:        3,
+			Timeout:           config.Duration(5 * time.Minute),
+		}
+	})
+}
@@ -0,0 +1,295 @@
+package object_storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	internalaws "github.com/influxdata/telegraf/plugins/common/aws"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// objectStore is a minimal S3 compatible server supporting simple and
+// multipart uploads with path style addressing
+type objectStore struct {
+	sync.Mutex
+	objects map[string][]byte
+	headers map[string]http.Header
+	uploads map[string]map[int][]byte
+	parts   int
+}
+
+func newObjectStore() *objectStore {
+	return &objectStore{
+		objects: make(map[string][]byte),
+		headers: make(map[string]http.Header),
+		uploads: make(map[string]map[int][]byte),
+	}
+}
+
+func (s *objectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := strconv.Itoa(len(s.uploads) + 1)
+		s.uploads[id] = make(map[int][]byte)
+		s.headers[key] = r.Header.Clone()
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadID string `xml:"UploadId"`
+		}{Key: key, UploadID: id})
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		part, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.uploads[query.Get("uploadId")][part] = body
+		s.parts++
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, part))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := s.uploads[query.Get("uploadId")]
+		numbers := make([]int, 0, len(parts))
+		for n := range parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var data []byte
+		for _, n := range numbers {
+			data = append(data, parts[n]...)
+		}
+		s.objects[key] = data
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Key     string
+		}{Key: key})
+	case r.Method == http.MethodPut:
+		s.objects[key] = body
+		s.headers[key] = r.Header.Clone()
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	//nolint:errcheck,revive // Errors are detected by the client
+	xml.NewEncoder(w).Encode(v)
+}
+
+func (s *objectStore) keys() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newPlugin(url string) *ObjectStorage {
+	plugin := &ObjectStorage{
+		Bucket:            "telegraf",
+		KeyTemplate:       `{{.Name}}/{{.Time.Format "2006/01/02"}}`,
+		UsePathStyle:      true,
+		PartSize:          config.Size(5 * 1024 * 1024),
+		UploadConcurrency: 1,
+		Timeout:           config.Duration(10 * time.Second),
+		CredentialConfig: internalaws.CredentialConfig{
+			Region:      "us-east-1",
+			AccessKey:   "access",
+			SecretKey:   "secret",
+			EndpointURL: url,
+		},
+		Log: testutil.Logger{},
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	return plugin
+}
+
+func TestWrite(t *testing.T) {
+	store := newObjectStore()
+	ts := httptest.NewServer(store)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.KeyTemplate = `{{.Name}}/{{.Tag "host"}}/{{.Time.Format "2006-01-02"}}`
+	plugin.FileExtension = ".lp"
+	plugin.ServerSideEncryption = "AES256"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 3.0}, time.Unix(1, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 4.0}, time.Unix(86400, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	keys := store.keys()
+	require.Len(t, keys, 3)
+	require.True(t, strings.HasPrefix(keys[0], "telegraf/cpu/a/1970-01-01/"))
+	require.True(t, strings.HasPrefix(keys[1], "telegraf/cpu/a/1970-01-02/"))
+	require.True(t, strings.HasPrefix(keys[2], "telegraf/cpu/b/1970-01-01/"))
+	for _, key := range keys {
+		require.True(t, strings.HasSuffix(key, ".lp"), key)
+		require.Equal(t, "AES256", store.headers[key].Get("X-Amz-Server-Side-Encryption"))
+	}
+
+	expected := "cpu,host=a value=1 0\ncpu,host=a value=3 1000000000\n"
+	require.Equal(t, expected, string(store.objects[keys[0]]))
+}
+
+func TestWriteCompressed(t *testing.T) {
+	for _, encoding := range []string{"gzip", "zstd"} {
+		t.Run(encoding, func(t *testing.T) {
+			store := newObjectStore()
+			ts := httptest.NewServer(store)
+			defer ts.Close()
+
+			plugin := newPlugin(ts.URL)
+			plugin.ContentEncoding = encoding
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+
+			metrics := []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+			}
+			require.NoError(t, plugin.Write(metrics))
+
+			keys := store.keys()
+			require.Len(t, keys, 1)
+			require.True(t, strings.HasSuffix(keys[0], encodingExtensions[encoding]), keys[0])
+
+			decoder, err := internal.NewContentDecoder(encoding)
+			require.NoError(t, err)
+			actual, err := decoder.Decode(store.objects[keys[0]])
+			require.NoError(t, err)
+			require.Equal(t, "cpu value=1 0\n", string(actual))
+		})
+	}
+}
+
+func TestWriteMultipart(t *testing.T) {
+	store := newObjectStore()
+	ts := httptest.NewServer(store)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.StorageClass = "STANDARD_IA"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	// A single metric exceeding the part size of 5MiB
+	value := strings.Repeat("x", 6*1024*1024)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("log", map[string]string{}, map[string]interface{}{"message": value}, time.Unix(0, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	keys := store.keys()
+	require.Len(t, keys, 1)
+	require.Equal(t, 2, store.parts)
+	require.Equal(t, "STANDARD_IA", store.headers[keys[0]].Get("X-Amz-Storage-Class"))
+
+	expected := fmt.Sprintf("log message=%q 0\n", value)
+	require.True(t, bytes.Equal([]byte(expected), store.objects[keys[0]]))
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(metrics), `uploading "cpu/1970/01/01/`)
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*ObjectStorage)
+		errmsg string
+	}{
+		{
+			name:   "missing bucket",
+			modify: func(o *ObjectStorage) { o.Bucket = "" },
+			errmsg: "bucket required",
+		},
+		{
+			name:   "invalid template",
+			modify: func(o *ObjectStorage) { o.KeyTemplate = "{{.Name" },
+			errmsg: "parsing key_template failed",
+		},
+		{
+			name:   "invalid encoding",
+			modify: func(o *ObjectStorage) { o.ContentEncoding = "zlib" },
+			errmsg: `invalid content_encoding "zlib"`,
+		},
+		{
+			name:   "part size too small",
+			modify: func(o *ObjectStorage) { o.PartSize = 1024 },
+			errmsg: "part_size must be at least 5242880 bytes",
+		},
+		{
+			name:   "invalid encryption",
+			modify: func(o *ObjectStorage) { o.ServerSideEncryption = "rot13" },
+			errmsg: `invalid server_side_encryption "rot13"`,
+		},
+		{
+			name:   "kms key without kms",
+			modify: func(o *ObjectStorage) { o.ServerSideEncryption = "AES256"; o.SSEKMSKeyID = "key" },
+			errmsg: "sse_kms_key_id requires server_side_encryption to be aws:kms",
+		},
+		{
+			name:   "invalid storage class",
+			modify: func(o *ObjectStorage) { o.StorageClass = "COLD" },
+			errmsg: `invalid storage_class "COLD"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin("http://localhost:9000")
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.errmsg)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package opentelemetry
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// scope holds the tags describing the origin of a span or log record, they
+// are mapped back to the resource and instrumentation scope of OTLP
+type scope struct {
+	resource map[string]string
+	name     string
+	version  string
+}
+
+// splitTags separates the resource attributes and the instrumentation scope
+// from the tags of the metric, skipping the given record level tags. This
+// reverses the conversion done by the OpenTelemetry input plugin which adds
+// the resource attributes as tags.
+func splitTags(metric telegraf.Metric, recordTags map[string]bool) (scope, string) {
+	s := scope{resource: make(map[string]string)}
+	for _, tag := range metric.TagList() {
+		switch {
+		case tag.Key == common.AttributeInstrumentationLibraryName:
+			s.name = tag.Value
+		case tag.Key == common.AttributeInstrumentationLibraryVersion:
+			s.version = tag.Value
+		case !recordTags[tag.Key]:
+			s.resource[tag.Key] = tag.Value
+		}
+	}
+
+	// The tag list is sorted so the key is identical for identical scopes
+	var key strings.Builder
+	for _, tag := range metric.TagList() {
+		if recordTags[tag.Key] {
+			continue
+		}
+		key.WriteString(tag.Key)
+		key.WriteByte(0)
+		key.WriteString(tag.Value)
+		key.WriteByte(0)
+	}
+	return s, key.String()
+}
+
+// setResource fills the resource attributes, the additional attributes
+// override the ones of the metric
+func setResource(resource pcommon.Resource, tags, attributes map[string]string) {
+	// Insert the attributes in a stable order
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resource.Attributes().InsertString(k, tags[k])
+	}
+	for k, v := range attributes {
+		resource.Attributes().UpsertString(k, v)
+	}
+}
+
+// setInstrumentationScope fills the instrumentation scope
+func setInstrumentationScope(is pcommon.InstrumentationScope, s scope) {
+	is.SetName(s.name)
+	is.SetVersion(s.version)
+}
+
+// fieldToValue converts a field value to an OTLP attribute value
+func fieldToValue(v interface{}) (pcommon.Value, bool) {
+	switch v := v.(type) {
+	case string:
+		return pcommon.NewValueString(v), true
+	case int64:
+		return pcommon.NewValueInt(v), true
+	case uint64:
+		if v <= math.MaxInt64 {
+			return pcommon.NewValueInt(int64(v)), true
+		}
+		return pcommon.NewValueDouble(float64(v)), true
+	case float64:
+		return pcommon.NewValueDouble(v), true
+	case bool:
+		return pcommon.NewValueBool(v), true
+	}
+	return pcommon.NewValueEmpty(), false
+}
+
+// fieldToCount converts a field value to a dropped items count
+func fieldToCount(v interface{}) (uint32, bool) {
+	if n, ok := internal.FieldToUint64(v); ok && n <= math.MaxUint32 {
+		return uint32(n), true
+	}
+	return 0, false
+}
+
+// setAttributes adds the fields as attributes, fields that cannot be
+// converted are counted as dropped
+func setAttributes(attributes pcommon.Map, fields map[string]interface{}) uint32 {
+	// Insert the attributes in a stable order
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var dropped uint32
+	for _, k := range keys {
+		value, ok := fieldToValue(fields[k])
+		if !ok {
+			dropped++
+			continue
+		}
+		attributes.Insert(k, value)
+	}
+	return dropped
+}
+
+func parseTraceID(s string) (pcommon.TraceID, error) {
+	var id [16]byte
+	if err := decodeID(s, id[:]); err != nil {
+		return pcommon.NewTraceID(id), fmt.Errorf("invalid trace ID %q: %w", s, err)
+	}
+	return pcommon.NewTraceID(id), nil
+}
+
+func parseSpanID(s string) (pcommon.SpanID, error) {
+	var id [8]byte
+	if err := decodeID(s, id[:]); err != nil {
+		return pcommon.NewSpanID(id), fmt.Errorf("invalid span ID %q: %w", s, err)
+	}
+	return pcommon.NewSpanID(id), nil
+}
+
+func decodeID(s string, id []byte) error {
+	if hex.DecodedLen(len(s)) != len(id) {
+		return fmt.Errorf("expected %d hex characters", 2*len(id))
+	}
+	_, err := hex.Decode(id, []byte(s))
+	return err
+}
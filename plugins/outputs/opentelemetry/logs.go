@@ -0,0 +1,109 @@
+package opentelemetry
+
+import (
+	"fmt"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Tags of log metrics that belong to the log record and not to the resource
+var logTags = map[string]bool{
+	common.AttributeTraceID: true,
+	common.AttributeSpanID:  true,
+}
+
+// logBatch converts metrics in the layout of the OpenTelemetry input plugin
+// back to OTLP log records
+type logBatch struct {
+	logs       plog.Logs
+	scopes     map[string]plog.ScopeLogs
+	attributes map[string]string
+}
+
+func newLogBatch(attributes map[string]string) *logBatch {
+	return &logBatch{
+		logs:       plog.NewLogs(),
+		scopes:     make(map[string]plog.ScopeLogs),
+		attributes: attributes,
+	}
+}
+
+func (b *logBatch) scopeLogs(metric telegraf.Metric) plog.ScopeLogs {
+	s, key := splitTags(metric, logTags)
+	if sl, ok := b.scopes[key]; ok {
+		return sl
+	}
+
+	rl := b.logs.ResourceLogs().AppendEmpty()
+	setResource(rl.Resource(), s.resource, b.attributes)
+	sl := rl.ScopeLogs().AppendEmpty()
+	setInstrumentationScope(sl.Scope(), s)
+	b.scopes[key] = sl
+	return sl
+}
+
+// add converts a log metric
+func (b *logBatch) add(metric telegraf.Metric) error {
+	tags := metric.Tags()
+
+	var traceID pcommon.TraceID
+	var spanID pcommon.SpanID
+	var err error
+	if v, ok := tags[common.AttributeTraceID]; ok {
+		if traceID, err = parseTraceID(v); err != nil {
+			return err
+		}
+	}
+	if v, ok := tags[common.AttributeSpanID]; ok {
+		if spanID, err = parseSpanID(v); err != nil {
+			return err
+		}
+	}
+
+	fields := metric.Fields()
+	severityNumber := plog.SeverityNumberUNDEFINED
+	if v, ok := fields[common.AttributeSeverityNumber]; ok {
+		n, ok := internal.FieldToInt64(v)
+		if !ok || n < int64(plog.SeverityNumberUNDEFINED) || n > int64(plog.SeverityNumberFATAL4) {
+			return fmt.Errorf("invalid severity number %v", v)
+		}
+		severityNumber = plog.SeverityNumber(n)
+	}
+	delete(fields, common.AttributeSeverityNumber)
+
+	record := b.scopeLogs(metric).LogRecords().AppendEmpty()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(metric.Time()))
+	record.SetTraceID(traceID)
+	record.SetSpanID(spanID)
+	record.SetSeverityNumber(severityNumber)
+	if v, ok := fields[common.AttributeSeverityText].(string); ok {
+		record.SetSeverityText(v)
+	}
+	delete(fields, common.AttributeSeverityText)
+
+	if v, ok := fieldToValue(fields[common.AttributeBody]); ok {
+		v.CopyTo(record.Body())
+	}
+	delete(fields, common.AttributeBody)
+
+	// The input plugin uses the span attribute key for dropped attributes
+	// of log records
+	var droppedAttributes uint32
+	if v, ok := fieldToCount(fields[common.AttributeDroppedSpanAttributesCount]); ok {
+		droppedAttributes = v
+	}
+	delete(fields, common.AttributeDroppedSpanAttributesCount)
+
+	droppedAttributes += setAttributes(record.Attributes(), fields)
+	record.SetDroppedAttributesCount(droppedAttributes)
+	return nil
+}
+
+func (b *logBatch) len() int {
+	return b.logs.LogRecordCount()
+}
@@ -4,13 +4,17 @@ package opentelemetry
 import (
 	"context"
 	_ "embed"
+	"errors"
+	"fmt"
 	"time"
 
 	ntls "crypto/tls"
 
 	"github.com/influxdata/influxdb-observability/common"
 	"github.com/influxdata/influxdb-observability/influx2otel"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
@@ -21,6 +25,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -42,12 +47,20 @@ type OpenTelemetry struct {
 	Attributes  map[string]string `toml:"attributes"`
 	Coralogix   *CoralogixConfig  `toml:"coralogix"`
 
+	SpanMeasurements []string `toml:"span_measurements"`
+	LogMeasurements  []string `toml:"log_measurements"`
+
 	Log telegraf.Logger `toml:"-"`
 
 	metricsConverter     *influx2otel.LineProtocolToOtelMetrics
 	grpcClientConn       *grpc.ClientConn
 	metricsServiceClient pmetricotlp.Client
+	tracesServiceClient  ptraceotlp.Client
+	logsServiceClient    plogotlp.Client
 	callOptions          []grpc.CallOption
+
+	spanFilter filter.Filter
+	logFilter  filter.Filter
 }
 
 type CoralogixConfig struct {
@@ -86,6 +99,15 @@ func (o *OpenTelemetry) Connect() error {
 		return err
 	}
 
+	o.spanFilter, err = filter.Compile(o.SpanMeasurements)
+	if err != nil {
+		return fmt.Errorf("creating span measurement filter failed: %w", err)
+	}
+	o.logFilter, err = filter.Compile(o.LogMeasurements)
+	if err != nil {
+		return fmt.Errorf("creating log measurement filter failed: %w", err)
+	}
+
 	var grpcTLSDialOption grpc.DialOption
 	if tlsConfig, err := o.ClientConfig.TLSConfig(); err != nil {
 		return err
@@ -103,11 +125,11 @@ func (o *OpenTelemetry) Connect() error {
 		return err
 	}
 
-	metricsServiceClient := pmetricotlp.NewClient(grpcClientConn)
-
 	o.metricsConverter = metricsConverter
 	o.grpcClientConn = grpcClientConn
-	o.metricsServiceClient = metricsServiceClient
+	o.metricsServiceClient = pmetricotlp.NewClient(grpcClientConn)
+	o.tracesServiceClient = ptraceotlp.NewClient(grpcClientConn)
+	o.logsServiceClient = plogotlp.NewClient(grpcClientConn)
 
 	if o.Compression != "" && o.Compression != "none" {
 		o.callOptions = append(o.callOptions, grpc.UseCompressor(o.Compression))
@@ -127,40 +149,39 @@ func (o *OpenTelemetry) Close() error {
 
 func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
 	batch := o.metricsConverter.NewBatch()
+	spans := newSpanBatch(o.Attributes)
+	logs := newLogBatch(o.Attributes)
+
+	// Links refer to spans, so they are added after all spans of the batch
+	var links []telegraf.Metric
 	for _, metric := range metrics {
-		var vType common.InfluxMetricValueType
-		switch metric.Type() {
-		case telegraf.Gauge:
-			vType = common.InfluxMetricValueTypeGauge
-		case telegraf.Untyped:
-			vType = common.InfluxMetricValueTypeUntyped
-		case telegraf.Counter:
-			vType = common.InfluxMetricValueTypeSum
-		case telegraf.Histogram:
-			vType = common.InfluxMetricValueTypeHistogram
-		case telegraf.Summary:
-			vType = common.InfluxMetricValueTypeSummary
-		default:
-			o.Log.Warnf("unrecognized metric type %Q", metric.Type())
-			continue
+		if o.spanFilter != nil {
+			if metric.Name() == common.MeasurementSpanLinks {
+				links = append(links, metric)
+				continue
+			}
+			if o.spanFilter.Match(metric.Name()) {
+				if err := spans.addSpan(metric); err != nil {
+					o.Log.Warnf("failed to convert span: %s", err)
+				}
+				continue
+			}
 		}
-		err := batch.AddPoint(metric.Name(), metric.Tags(), metric.Fields(), metric.Time(), vType)
-		if err != nil {
-			o.Log.Warnf("failed to add point: %s", err)
+		if o.logFilter != nil && o.logFilter.Match(metric.Name()) {
+			if err := logs.add(metric); err != nil {
+				o.Log.Warnf("failed to convert log record: %s", err)
+			}
 			continue
 		}
+		o.addPoint(batch, metric)
 	}
-
-	md := pmetricotlp.NewRequestFromMetrics(batch.GetMetrics())
-	if md.Metrics().ResourceMetrics().Len() == 0 {
-		return nil
-	}
-
-	if len(o.Attributes) > 0 {
-		for i := 0; i < md.Metrics().ResourceMetrics().Len(); i++ {
-			for k, v := range o.Attributes {
-				md.Metrics().ResourceMetrics().At(i).Resource().Attributes().UpsertString(k, v)
-			}
+	for _, link := range links {
+		err := spans.addLink(link)
+		if errors.Is(err, errSpanNotFound) {
+			// Links to spans of other batches are kept as metrics
+			o.addPoint(batch, link)
+		} else if err != nil {
+			o.Log.Warnf("failed to convert span link: %s", err)
 		}
 	}
 
@@ -170,8 +191,81 @@ func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
 		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
 	}
 	defer cancel()
-	_, err := o.metricsServiceClient.Export(ctx, md, o.callOptions...)
-	return err
+
+	// Metrics, spans and log records are exported separately. Only if none of
+	// them were exported the write fails, as retrying the whole batch would
+	// duplicate the exported data.
+	var exported bool
+	var errs []error
+	md := pmetricotlp.NewRequestFromMetrics(batch.GetMetrics())
+	if md.Metrics().ResourceMetrics().Len() > 0 {
+		if len(o.Attributes) > 0 {
+			for i := 0; i < md.Metrics().ResourceMetrics().Len(); i++ {
+				for k, v := range o.Attributes {
+					md.Metrics().ResourceMetrics().At(i).Resource().Attributes().UpsertString(k, v)
+				}
+			}
+		}
+
+		if _, err := o.metricsServiceClient.Export(ctx, md, o.callOptions...); err != nil {
+			errs = append(errs, fmt.Errorf("exporting metrics failed: %w", err))
+		} else {
+			exported = true
+		}
+	}
+
+	if spans.len() > 0 {
+		td := ptraceotlp.NewRequestFromTraces(spans.traces)
+		if _, err := o.tracesServiceClient.Export(ctx, td, o.callOptions...); err != nil {
+			errs = append(errs, fmt.Errorf("exporting spans failed: %w", err))
+		} else {
+			exported = true
+		}
+	}
+
+	if logs.len() > 0 {
+		ld := plogotlp.NewRequestFromLogs(logs.logs)
+		if _, err := o.logsServiceClient.Export(ctx, ld, o.callOptions...); err != nil {
+			errs = append(errs, fmt.Errorf("exporting logs failed: %w", err))
+		} else {
+			exported = true
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if !exported {
+		return errs[0]
+	}
+	for _, err := range errs {
+		o.Log.Errorf("Dropping data of partially written batch: %v", err)
+	}
+	return nil
+}
+
+// addPoint adds the metric to the batch of OTLP metrics
+func (o *OpenTelemetry) addPoint(batch *influx2otel.MetricsBatch, metric telegraf.Metric) {
+	var vType common.InfluxMetricValueType
+	switch metric.Type() {
+	case telegraf.Gauge:
+		vType = common.InfluxMetricValueTypeGauge
+	case telegraf.Untyped:
+		vType = common.InfluxMetricValueTypeUntyped
+	case telegraf.Counter:
+		vType = common.InfluxMetricValueTypeSum
+	case telegraf.Histogram:
+		vType = common.InfluxMetricValueTypeHistogram
+	case telegraf.Summary:
+		vType = common.InfluxMetricValueTypeSummary
+	default:
+		o.Log.Warnf("unrecognized metric type %Q", metric.Type())
+		return
+	}
+	err := batch.AddPoint(metric.Name(), metric.Tags(), metric.Fields(), metric.Time(), vType)
+	if err != nil {
+		o.Log.Warnf("failed to add point: %s", err)
+	}
 }
 
 const (
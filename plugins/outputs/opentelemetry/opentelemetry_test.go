@@ -2,19 +2,26 @@ package opentelemetry
 
 import (
 	"context"
+	"errors"
 	"net"
 	"testing"
 	"time"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/influxdata/influxdb-observability/common"
 	"github.com/influxdata/influxdb-observability/influx2otel"
+	"github.com/influxdata/influxdb-observability/otel2influx"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -79,6 +86,258 @@ func TestOpenTelemetry(t *testing.T) {
 	assert.JSONEq(t, string(expectJSON), string(gotJSON))
 }
 
+func TestOpenTelemetrySpans(t *testing.T) {
+	expect := ptrace.NewTraces()
+	{
+		rs := expect.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().InsertString("attr-key", "attr-val")
+		rs.Resource().Attributes().InsertString("service.name", "tracegen")
+		ss := rs.ScopeSpans().AppendEmpty()
+		ss.Scope().SetName("tracegen")
+		span := ss.Spans().AppendEmpty()
+		span.SetTraceID(pcommon.NewTraceID([16]byte{0x7d, 0x48, 0x54, 0x81, 0x52, 0x25, 0x33, 0x2c, 0x98, 0x34, 0xe6, 0xdb, 0xf8, 0x5b, 0x93, 0x80}))
+		span.SetSpanID(pcommon.NewSpanID([8]byte{0x4c, 0x28, 0x22, 0x7b, 0xe6, 0xa0, 0x10, 0xe1}))
+		span.SetParentSpanID(pcommon.NewSpanID([8]byte{0xd5, 0x27, 0x0e, 0x78, 0xd8, 0x5f, 0x57, 0x0f}))
+		span.SetName("okey-dokey")
+		span.SetKind(ptrace.SpanKindServer)
+		span.SetStartTimestamp(pcommon.Timestamp(1613767825689169000))
+		span.SetEndTimestamp(pcommon.Timestamp(1613767825689395200))
+		span.Attributes().InsertString("net.peer.ip", "1.2.3.4")
+		span.Attributes().InsertString("peer.service", "tracegen-client")
+		span.Status().SetCode(ptrace.StatusCodeOk)
+		link := span.Links().AppendEmpty()
+		link.SetTraceID(pcommon.NewTraceID([16]byte{0xfd, 0x6b, 0x8b, 0xb5, 0x96, 0x5e, 0x72, 0x6c, 0x94, 0x97, 0x8c, 0x64, 0x49, 0x62, 0xcd, 0xc8}))
+		link.SetSpanID(pcommon.NewSpanID([8]byte{0xa0, 0x64, 0x3a, 0x15, 0x6d, 0x7f, 0x9f, 0x7f}))
+		link.Attributes().InsertInt("retries", 2)
+	}
+
+	// Convert the spans like the OpenTelemetry input plugin does
+	input := convertTraces(t, expect)
+
+	m := newMockOtelService(t)
+	t.Cleanup(m.Cleanup)
+
+	plugin := &OpenTelemetry{
+		ServiceAddress:   m.Address(),
+		Timeout:          config.Duration(time.Second),
+		Headers:          map[string]string{"test": "header1"},
+		Attributes:       map[string]string{"attr-key": "attr-val"},
+		SpanMeasurements: []string{"spans"},
+		LogMeasurements:  []string{"logs"},
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(input))
+	require.NoError(t, plugin.Close())
+
+	expectJSON, err := ptrace.NewJSONMarshaler().MarshalTraces(expect)
+	require.NoError(t, err)
+
+	gotJSON, err := ptrace.NewJSONMarshaler().MarshalTraces(m.GotTraces())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, string(expectJSON), string(gotJSON))
+	assert.Equal(t, pmetric.Metrics{}, m.GotMetrics(), "no metrics expected")
+}
+
+func TestOpenTelemetryLogs(t *testing.T) {
+	expect := plog.NewLogs()
+	{
+		rl := expect.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().InsertString("service.name", "fluentd")
+		sl := rl.ScopeLogs().AppendEmpty()
+		record := sl.LogRecords().AppendEmpty()
+		record.SetTimestamp(pcommon.Timestamp(1613769568895331700))
+		record.SetTraceID(pcommon.NewTraceID([16]byte{0x7d, 0x48, 0x54, 0x81, 0x52, 0x25, 0x33, 0x2c, 0x98, 0x34, 0xe6, 0xdb, 0xf8, 0x5b, 0x93, 0x80}))
+		record.SetSpanID(pcommon.NewSpanID([8]byte{0x4c, 0x28, 0x22, 0x7b, 0xe6, 0xa0, 0x10, 0xe1}))
+		record.SetSeverityNumber(plog.SeverityNumberINFO)
+		record.SetSeverityText("info")
+		record.Body().SetStringVal("worker started")
+		record.Attributes().InsertString("fluent.tag", "fluent.info")
+		record.Attributes().InsertInt("pid", 18)
+	}
+
+	// Convert the log records like the OpenTelemetry input plugin does
+	w := &metricWriter{}
+	converter := otel2influx.NewOtelLogsToLineProtocol(common.NoopLogger{})
+	require.NoError(t, converter.WriteLogs(context.Background(), expect, w))
+
+	// Metrics not matching the filters are still sent as metrics
+	input := w.metrics
+	input = append(input, testutil.MustMetric(
+		"cpu_temp",
+		map[string]string{"foo": "bar"},
+		map[string]interface{}{"gauge": 87.332},
+		time.Unix(0, 1622848686000000000),
+	))
+
+	m := newMockOtelService(t)
+	t.Cleanup(m.Cleanup)
+
+	plugin := &OpenTelemetry{
+		ServiceAddress:   m.Address(),
+		Timeout:          config.Duration(time.Second),
+		Headers:          map[string]string{"test": "header1"},
+		SpanMeasurements: []string{"spans"},
+		LogMeasurements:  []string{"logs"},
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(input))
+	require.NoError(t, plugin.Close())
+
+	expectJSON, err := plog.NewJSONMarshaler().MarshalLogs(expect)
+	require.NoError(t, err)
+
+	gotJSON, err := plog.NewJSONMarshaler().MarshalLogs(m.GotLogs())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, string(expectJSON), string(gotJSON))
+	assert.Equal(t, 1, m.GotMetrics().MetricCount())
+}
+
+func TestOpenTelemetryUnmatchedLinks(t *testing.T) {
+	m := newMockOtelService(t)
+	t.Cleanup(m.Cleanup)
+
+	plugin := &OpenTelemetry{
+		ServiceAddress:   m.Address(),
+		Timeout:          config.Duration(time.Second),
+		Headers:          map[string]string{"test": "header1"},
+		SpanMeasurements: []string{"spans"},
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Connect())
+
+	// The linked span is not part of the batch, so the link is kept as metric
+	input := []telegraf.Metric{
+		testutil.MustMetric(
+			common.MeasurementSpanLinks,
+			map[string]string{
+				common.AttributeTraceID:       "7d4854815225332c9834e6dbf85b9380",
+				common.AttributeSpanID:        "4c28227be6a010e1",
+				common.AttributeLinkedTraceID: "fd6b8bb5965e726c94978c644962cdc8",
+				common.AttributeLinkedSpanID:  "a0643a156d7f9f7f",
+			},
+			map[string]interface{}{"retries": int64(2)},
+			time.Unix(0, 1622848686000000000),
+		),
+	}
+	require.NoError(t, plugin.Write(input))
+	require.NoError(t, plugin.Close())
+
+	require.Equal(t, 1, m.GotMetrics().MetricCount())
+	require.Equal(t, ptrace.Traces{}, m.GotTraces())
+}
+
+func TestOpenTelemetryPartialExportFailure(t *testing.T) {
+	m := newMockOtelService(t)
+	t.Cleanup(m.Cleanup)
+	m.tracesErr = errors.New("unavailable")
+
+	plugin := &OpenTelemetry{
+		ServiceAddress:   m.Address(),
+		Timeout:          config.Duration(time.Second),
+		Headers:          map[string]string{"test": "header1"},
+		SpanMeasurements: []string{"spans"},
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Connect())
+
+	span := testutil.MustMetric(
+		"spans",
+		map[string]string{
+			common.AttributeTraceID: "7d4854815225332c9834e6dbf85b9380",
+			common.AttributeSpanID:  "4c28227be6a010e1",
+		},
+		map[string]interface{}{common.AttributeDurationNano: int64(1000)},
+		time.Unix(0, 1622848686000000000),
+	)
+	point := testutil.MustMetric(
+		"cpu_temp",
+		map[string]string{"foo": "bar"},
+		map[string]interface{}{"gauge": 87.332},
+		time.Unix(0, 1622848686000000000),
+	)
+
+	// The metrics were exported, so the write succeeds to not send them again
+	require.NoError(t, plugin.Write([]telegraf.Metric{span, point}))
+	require.Equal(t, 1, m.GotMetrics().MetricCount())
+
+	// Nothing was exported, so the batch is retried
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{span}), "exporting spans failed")
+	require.NoError(t, plugin.Close())
+}
+
+func TestInvalidSpans(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		fields   map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "missing trace id",
+			tags:     map[string]string{"span_id": "4c28227be6a010e1"},
+			fields:   map[string]interface{}{"duration_nano": int64(1000)},
+			expected: "invalid trace ID",
+		},
+		{
+			name: "invalid span id",
+			tags: map[string]string{
+				"trace_id": "7d4854815225332c9834e6dbf85b9380",
+				"span_id":  "xyz",
+			},
+			fields:   map[string]interface{}{"duration_nano": int64(1000)},
+			expected: "invalid span ID",
+		},
+		{
+			name: "unknown kind",
+			tags: map[string]string{
+				"trace_id": "7d4854815225332c9834e6dbf85b9380",
+				"span_id":  "4c28227be6a010e1",
+				"kind":     "SPAN_KIND_SOMETHING",
+			},
+			fields:   map[string]interface{}{"duration_nano": int64(1000)},
+			expected: "unknown span kind",
+		},
+		{
+			name: "unknown status",
+			tags: map[string]string{
+				"trace_id": "7d4854815225332c9834e6dbf85b9380",
+				"span_id":  "4c28227be6a010e1",
+			},
+			fields:   map[string]interface{}{"otel.status_code": "MAYBE"},
+			expected: "unknown status code",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newSpanBatch(nil)
+			err := b.addSpan(testutil.MustMetric("spans", tt.tags, tt.fields, time.Unix(0, 0)))
+			require.ErrorContains(t, err, tt.expected)
+			require.Equal(t, 0, b.len())
+		})
+	}
+}
+
+// metricWriter collects the metrics converted by otel2influx
+type metricWriter struct {
+	metrics []telegraf.Metric
+}
+
+func (w *metricWriter) WritePoint(_ context.Context, name string, tags map[string]string, fields map[string]interface{}, ts time.Time, _ common.InfluxMetricValueType) error {
+	w.metrics = append(w.metrics, metric.New(name, tags, fields, ts))
+	return nil
+}
+
+func convertTraces(t *testing.T, td ptrace.Traces) []telegraf.Metric {
+	w := &metricWriter{}
+	converter := otel2influx.NewOtelTracesToLineProtocol(common.NoopLogger{})
+	require.NoError(t, converter.WriteTraces(context.Background(), td, w))
+	return w.metrics
+}
+
 var _ pmetricotlp.Server = (*mockOtelService)(nil)
 
 type mockOtelService struct {
@@ -87,7 +346,10 @@ type mockOtelService struct {
 	grpcServer *grpc.Server
 	grpcClient *grpc.ClientConn
 
-	metrics pmetric.Metrics
+	metrics   pmetric.Metrics
+	traces    ptrace.Traces
+	logs      plog.Logs
+	tracesErr error
 }
 
 func newMockOtelService(t *testing.T) *mockOtelService {
@@ -102,6 +364,8 @@ func newMockOtelService(t *testing.T) *mockOtelService {
 	}
 
 	pmetricotlp.RegisterServer(grpcServer, mockOtelService)
+	ptraceotlp.RegisterServer(grpcServer, &mockOtelTraceService{mockOtelService})
+	plogotlp.RegisterServer(grpcServer, &mockOtelLogService{mockOtelService})
 	go func() { assert.NoError(t, grpcServer.Serve(listener)) }()
 
 	grpcClient, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
@@ -124,6 +388,14 @@ func (m *mockOtelService) GotMetrics() pmetric.Metrics {
 	return m.metrics
 }
 
+func (m *mockOtelService) GotTraces() ptrace.Traces {
+	return m.traces
+}
+
+func (m *mockOtelService) GotLogs() plog.Logs {
+	return m.logs
+}
+
 func (m *mockOtelService) Address() string {
 	return m.listener.Addr().String()
 }
@@ -135,3 +407,30 @@ func (m *mockOtelService) Export(ctx context.Context, request pmetricotlp.Reques
 	assert.True(m.t, ok)
 	return pmetricotlp.NewResponse(), nil
 }
+
+type mockOtelTraceService struct {
+	*mockOtelService
+}
+
+func (m *mockOtelTraceService) Export(ctx context.Context, request ptraceotlp.Request) (ptraceotlp.Response, error) {
+	if m.tracesErr != nil {
+		return ptraceotlp.NewResponse(), m.tracesErr
+	}
+	m.traces = request.Traces().Clone()
+	ctxMetadata, ok := metadata.FromIncomingContext(ctx)
+	assert.Equal(m.t, []string{"header1"}, ctxMetadata.Get("test"))
+	assert.True(m.t, ok)
+	return ptraceotlp.NewResponse(), nil
+}
+
+type mockOtelLogService struct {
+	*mockOtelService
+}
+
+func (m *mockOtelLogService) Export(ctx context.Context, request plogotlp.Request) (plogotlp.Response, error) {
+	m.logs = request.Logs().Clone()
+	ctxMetadata, ok := metadata.FromIncomingContext(ctx)
+	assert.Equal(m.t, []string{"header1"}, ctxMetadata.Get("test"))
+	assert.True(m.t, ok)
+	return plogotlp.NewResponse(), nil
+}
@@ -0,0 +1,209 @@
+package opentelemetry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Tags of span metrics that belong to the span and not to the resource
+var spanTags = map[string]bool{
+	common.AttributeTraceID:      true,
+	common.AttributeSpanID:       true,
+	common.AttributeTraceState:   true,
+	common.AttributeParentSpanID: true,
+	common.AttributeName:         true,
+	common.AttributeSpanKind:     true,
+}
+
+// errSpanNotFound is returned for links to spans not part of the batch
+var errSpanNotFound = errors.New("linked span not found in batch")
+
+var spanKinds = map[string]ptrace.SpanKind{
+	ptrace.SpanKindUnspecified.String(): ptrace.SpanKindUnspecified,
+	ptrace.SpanKindInternal.String():    ptrace.SpanKindInternal,
+	ptrace.SpanKindServer.String():      ptrace.SpanKindServer,
+	ptrace.SpanKindClient.String():      ptrace.SpanKindClient,
+	ptrace.SpanKindProducer.String():    ptrace.SpanKindProducer,
+	ptrace.SpanKindConsumer.String():    ptrace.SpanKindConsumer,
+}
+
+// spanBatch converts metrics in the layout of the OpenTelemetry input plugin
+// back to OTLP spans
+type spanBatch struct {
+	traces     ptrace.Traces
+	scopes     map[string]ptrace.ScopeSpans
+	spans      map[string]ptrace.Span
+	attributes map[string]string
+}
+
+func newSpanBatch(attributes map[string]string) *spanBatch {
+	return &spanBatch{
+		traces:     ptrace.NewTraces(),
+		scopes:     make(map[string]ptrace.ScopeSpans),
+		spans:      make(map[string]ptrace.Span),
+		attributes: attributes,
+	}
+}
+
+func (b *spanBatch) scopeSpans(metric telegraf.Metric) ptrace.ScopeSpans {
+	s, key := splitTags(metric, spanTags)
+	if ss, ok := b.scopes[key]; ok {
+		return ss
+	}
+
+	rs := b.traces.ResourceSpans().AppendEmpty()
+	setResource(rs.Resource(), s.resource, b.attributes)
+	ss := rs.ScopeSpans().AppendEmpty()
+	setInstrumentationScope(ss.Scope(), s)
+	b.scopes[key] = ss
+	return ss
+}
+
+// addSpan converts a span metric
+func (b *spanBatch) addSpan(metric telegraf.Metric) error {
+	tags := metric.Tags()
+
+	traceID, err := parseTraceID(tags[common.AttributeTraceID])
+	if err != nil {
+		return err
+	}
+	spanID, err := parseSpanID(tags[common.AttributeSpanID])
+	if err != nil {
+		return err
+	}
+
+	var parentSpanID pcommon.SpanID
+	if v, ok := tags[common.AttributeParentSpanID]; ok {
+		if parentSpanID, err = parseSpanID(v); err != nil {
+			return err
+		}
+	}
+	kind := ptrace.SpanKindUnspecified
+	if v, ok := tags[common.AttributeSpanKind]; ok {
+		var found bool
+		if kind, found = spanKinds[v]; !found {
+			return fmt.Errorf("unknown span kind %q", v)
+		}
+	}
+
+	fields := metric.Fields()
+	statusCode := ptrace.StatusCodeUnset
+	switch v := fields[common.AttributeStatusCode]; v {
+	case nil:
+	case common.AttributeStatusCodeOK:
+		statusCode = ptrace.StatusCodeOk
+	case common.AttributeStatusCodeError:
+		statusCode = ptrace.StatusCodeError
+	default:
+		return fmt.Errorf("unknown status code %v", v)
+	}
+	delete(fields, common.AttributeStatusCode)
+
+	span := b.scopeSpans(metric).Spans().AppendEmpty()
+	span.SetTraceID(traceID)
+	span.SetSpanID(spanID)
+	span.SetParentSpanID(parentSpanID)
+	span.SetName(tags[common.AttributeName])
+	span.SetKind(kind)
+	span.SetTraceState(ptrace.TraceState(tags[common.AttributeTraceState]))
+
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(metric.Time()))
+	if v, ok := internal.FieldToInt64(fields[common.AttributeEndTimeUnixNano]); ok {
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, v)))
+	} else if v, ok := internal.FieldToInt64(fields[common.AttributeDurationNano]); ok {
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(metric.Time().Add(time.Duration(v))))
+	}
+	delete(fields, common.AttributeEndTimeUnixNano)
+	delete(fields, common.AttributeDurationNano)
+
+	span.Status().SetCode(statusCode)
+	if v, ok := fields[common.AttributeStatusMessage].(string); ok {
+		span.Status().SetMessage(v)
+	}
+	delete(fields, common.AttributeStatusMessage)
+
+	var droppedAttributes uint32
+	if v, ok := fieldToCount(fields[common.AttributeDroppedSpanAttributesCount]); ok {
+		droppedAttributes = v
+	}
+	if v, ok := fieldToCount(fields[common.AttributeDroppedEventsCount]); ok {
+		span.SetDroppedEventsCount(v)
+	}
+	if v, ok := fieldToCount(fields[common.AttributeDroppedLinksCount]); ok {
+		span.SetDroppedLinksCount(v)
+	}
+	delete(fields, common.AttributeDroppedSpanAttributesCount)
+	delete(fields, common.AttributeDroppedEventsCount)
+	delete(fields, common.AttributeDroppedLinksCount)
+
+	droppedAttributes += setAttributes(span.Attributes(), fields)
+	span.SetDroppedAttributesCount(droppedAttributes)
+
+	b.spans[spanKey(traceID, spanID)] = span
+	return nil
+}
+
+// addLink converts a span link metric and adds it to its span, the span
+// must be part of the batch
+func (b *spanBatch) addLink(metric telegraf.Metric) error {
+	tags := metric.Tags()
+
+	traceID, err := parseTraceID(tags[common.AttributeTraceID])
+	if err != nil {
+		return err
+	}
+	spanID, err := parseSpanID(tags[common.AttributeSpanID])
+	if err != nil {
+		return err
+	}
+	span, ok := b.spans[spanKey(traceID, spanID)]
+	if !ok {
+		return errSpanNotFound
+	}
+
+	linkedTraceID, err := parseTraceID(tags[common.AttributeLinkedTraceID])
+	if err != nil {
+		return err
+	}
+	linkedSpanID, err := parseSpanID(tags[common.AttributeLinkedSpanID])
+	if err != nil {
+		return err
+	}
+
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(linkedTraceID)
+	link.SetSpanID(linkedSpanID)
+	link.SetTraceState(ptrace.TraceState(tags[common.AttributeTraceState]))
+
+	fields := metric.Fields()
+	var droppedAttributes uint32
+	if v, ok := fieldToCount(fields[common.AttributeDroppedLinkAttributesCount]); ok {
+		droppedAttributes = v
+	}
+	delete(fields, common.AttributeDroppedLinkAttributesCount)
+
+	// The input plugin adds a count if the link has no attributes
+	if len(fields) == 1 {
+		delete(fields, "count")
+	}
+
+	droppedAttributes += setAttributes(link.Attributes(), fields)
+	link.SetDroppedAttributesCount(droppedAttributes)
+	return nil
+}
+
+func (b *spanBatch) len() int {
+	return b.traces.SpanCount()
+}
+
+func spanKey(traceID pcommon.TraceID, spanID pcommon.SpanID) string {
+	return traceID.HexString() + spanID.HexString()
+}
@@ -0,0 +1,306 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package parquet
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pq "github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+// Value of partition tags missing in a metric as used by Hive
+const defaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+var compressionCodecs = map[string]pq.CompressionCodec{
+	"uncompressed": pq.CompressionCodec_UNCOMPRESSED,
+	"snappy":       pq.CompressionCodec_SNAPPY,
+	"gzip":         pq.CompressionCodec_GZIP,
+	"lz4":          pq.CompressionCodec_LZ4,
+	"zstd":         pq.CompressionCodec_ZSTD,
+}
+
+var timePartitions = map[string][]string{
+	"none":  {},
+	"year":  {"year=2006"},
+	"month": {"year=2006", "month=01"},
+	"day":   {"year=2006", "month=01", "day=02"},
+	"hour":  {"year=2006", "month=01", "day=02", "hour=15"},
+}
+
+type Parquet struct {
+	Directory        string          `toml:"directory"`
+	TimePartition    string          `toml:"time_partition"`
+	PartitionTags    []string        `toml:"partition_tags"`
+	Compression      string          `toml:"compression"`
+	RotationInterval config.Duration `toml:"rotation_interval"`
+	RotationMaxSize  config.Size     `toml:"rotation_max_size"`
+	Log              telegraf.Logger `toml:"-"`
+
+	codec         pq.CompressionCodec
+	timeLayout    string
+	partitionTags map[string]bool
+	schemas       map[string]*schema
+	files         map[string]*partitionFile
+}
+
+func (*Parquet) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Parquet) Init() error {
+	if p.Directory == "" {
+		return errors.New("directory required")
+	}
+
+	var found bool
+	p.codec, found = compressionCodecs[strings.ToLower(p.Compression)]
+	if !found {
+		return fmt.Errorf("invalid compression %q", p.Compression)
+	}
+
+	layout, found := timePartitions[p.TimePartition]
+	if !found {
+		return fmt.Errorf("invalid time partition %q", p.TimePartition)
+	}
+	p.timeLayout = filepath.Join(layout...)
+
+	p.partitionTags = make(map[string]bool, len(p.PartitionTags))
+	for _, tag := range p.PartitionTags {
+		p.partitionTags[tag] = true
+	}
+	p.schemas = make(map[string]*schema)
+	p.files = make(map[string]*partitionFile)
+
+	return nil
+}
+
+func (p *Parquet) Connect() error {
+	return os.MkdirAll(p.Directory, 0750)
+}
+
+func (p *Parquet) Close() error {
+	var errs []string
+	for dir, f := range p.files {
+		if err := p.closeFile(dir, f); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Write appends the metrics to the file of their partition. The schemas are
+// updated first, so files are rotated at most once per write when new tags
+// or fields appear. Every write creates a row group in the touched files.
+func (p *Parquet) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		s, found := p.schemas[m.Name()]
+		if !found {
+			s = newSchema()
+			p.schemas[m.Name()] = s
+		}
+		s.update(m, p.partitionTags)
+	}
+
+	// Convert the metrics and open all files before writing any row, so a
+	// failing write does not leave rows behind that are written again when
+	// the batch is retried
+	var dirs []string
+	rows := make(map[string][][]interface{})
+	for _, m := range metrics {
+		s := p.schemas[m.Name()]
+		dir := p.partitionDir(m)
+
+		f, found := p.files[dir]
+		if found && f.version != s.version {
+			if err := p.closeFile(dir, f); err != nil {
+				return err
+			}
+			found = false
+		}
+		if !found {
+			var err error
+			if f, err = p.openFile(dir, s); err != nil {
+				return err
+			}
+			p.files[dir] = f
+		}
+
+		if _, found := rows[dir]; !found {
+			dirs = append(dirs, dir)
+		}
+		rows[dir] = append(rows[dir], s.row(m, p.Log))
+	}
+
+	for _, dir := range dirs {
+		f := p.files[dir]
+		if err := p.writeRows(f, rows[dir]); err != nil {
+			// The file is in an unknown state, so it is discarded instead of
+			// keeping a part of the batch
+			p.discardFile(dir, f)
+			return err
+		}
+	}
+
+	return p.rotate()
+}
+
+func (p *Parquet) writeRows(f *partitionFile, rows [][]interface{}) error {
+	for _, row := range rows {
+		if err := f.writer.Write(row); err != nil {
+			return fmt.Errorf("writing to %q failed: %v", f.tmpPath, err)
+		}
+	}
+	if err := f.writer.Flush(true); err != nil {
+		return fmt.Errorf("flushing %q failed: %v", f.tmpPath, err)
+	}
+	return nil
+}
+
+// rotate closes the files exceeding the maximum size or age
+func (p *Parquet) rotate() error {
+	now := time.Now()
+	for dir, f := range p.files {
+		expired := p.RotationInterval > 0 && now.Sub(f.opened) >= time.Duration(p.RotationInterval)
+		oversized := p.RotationMaxSize > 0 && f.size() >= int64(p.RotationMaxSize)
+		if expired || oversized {
+			if err := p.closeFile(dir, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// partitionDir returns the directory of the metric consisting of the
+// measurement, the time partition and the partition tags in Hive style
+func (p *Parquet) partitionDir(m telegraf.Metric) string {
+	parts := []string{p.Directory, url.PathEscape(m.Name())}
+	if p.timeLayout != "" {
+		parts = append(parts, m.Time().UTC().Format(p.timeLayout))
+	}
+	for _, tag := range p.PartitionTags {
+		value, ok := m.GetTag(tag)
+		if !ok || value == "" {
+			value = defaultPartition
+		}
+		parts = append(parts, url.PathEscape(tag)+"="+url.PathEscape(value))
+	}
+	return filepath.Join(parts...)
+}
+
+func (p *Parquet) openFile(dir string, s *schema) (*partitionFile, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	f := &partitionFile{
+		path:    filepath.Join(dir, fmt.Sprintf("part-%d.parquet", now.UnixNano())),
+		opened:  now,
+		version: s.version,
+	}
+	// Files are written with a hidden name ignored by most query engines
+	// until they are complete
+	f.tmpPath = filepath.Join(dir, "."+filepath.Base(f.path)+".tmp")
+
+	file, err := os.OpenFile(f.tmpPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+	f.file = &countingWriter{file: file}
+
+	f.writer, err = writer.NewCSVWriterFromWriter(s.metadata(), f.file, 1)
+	if err != nil {
+		file.Close()
+		os.Remove(f.tmpPath)
+		return nil, fmt.Errorf("creating parquet writer failed: %v", err)
+	}
+	f.writer.CompressionType = p.codec
+
+	p.Log.Debugf("Opened %q", f.tmpPath)
+	return f, nil
+}
+
+func (p *Parquet) closeFile(dir string, f *partitionFile) error {
+	delete(p.files, dir)
+
+	if err := f.writer.WriteStop(); err != nil {
+		f.file.file.Close()
+		return fmt.Errorf("finishing %q failed: %v", f.tmpPath, err)
+	}
+	if err := f.file.file.Close(); err != nil {
+		return fmt.Errorf("closing %q failed: %v", f.tmpPath, err)
+	}
+	if err := os.Rename(f.tmpPath, f.path); err != nil {
+		return err
+	}
+
+	p.Log.Debugf("Closed %q", f.path)
+	return nil
+}
+
+// discardFile closes and removes the unfinished file
+func (p *Parquet) discardFile(dir string, f *partitionFile) {
+	delete(p.files, dir)
+
+	if err := f.file.file.Close(); err != nil {
+		p.Log.Errorf("Closing %q failed: %v", f.tmpPath, err)
+	}
+	if err := os.Remove(f.tmpPath); err != nil {
+		p.Log.Errorf("Removing %q failed: %v", f.tmpPath, err)
+	}
+	p.Log.Warnf("Discarded unfinished file %q", f.tmpPath)
+}
+
+type partitionFile struct {
+	path    string
+	tmpPath string
+	opened  time.Time
+	version int
+	file    *countingWriter
+	writer  *writer.CSVWriter
+}
+
+func (f *partitionFile) size() int64 {
+	return f.file.written
+}
+
+// countingWriter keeps track of the bytes written to the file
+type countingWriter struct {
+	file    *os.File
+	written int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.file.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func init() {
+	outputs.Add("parquet", func() telegraf.Output {
+		return &Parquet{
+			TimePartition:    "day",
+			Compression:      "snappy",
+			RotationInterval: config.Duration(time.Hour),
+		}
+	})
+}
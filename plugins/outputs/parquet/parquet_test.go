@@ -0,0 +1,251 @@
+package parquet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// readFile returns the column names and the rows of the file with missing
+// values set to nil
+func readFile(t *testing.T, path string) ([]string, [][]interface{}) {
+	fr, err := local.NewLocalFileReader(path)
+	require.NoError(t, err)
+	defer fr.Close()
+
+	pr, err := reader.NewParquetColumnReader(fr, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	n := pr.GetNumRows()
+	columns := pr.SchemaHandler.SchemaElements[1:]
+	names := make([]string, 0, len(columns))
+	rows := make([][]interface{}, n)
+	for i, c := range columns {
+		names = append(names, pr.SchemaHandler.Infos[i+1].ExName)
+		values, _, dls, err := pr.ReadColumnByIndex(int64(i), n)
+		require.NoError(t, err)
+		require.Len(t, values, int(n))
+		for j, v := range values {
+			if c.GetRepetitionType() == 1 && dls[j] == 0 {
+				v = nil
+			}
+			rows[j] = append(rows[j], v)
+		}
+	}
+	return names, rows
+}
+
+func findFiles(t *testing.T, dir string) []string {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func newPlugin(t *testing.T) *Parquet {
+	plugin := &Parquet{
+		Directory:        t.TempDir(),
+		TimePartition:    "day",
+		Compression:      "snappy",
+		RotationInterval: config.Duration(time.Hour),
+		Log:              testutil.Logger{},
+	}
+	return plugin
+}
+
+func TestWrite(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.PartitionTags = []string{"host"}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	ts := time.Date(2022, 10, 17, 13, 0, 0, 0, time.UTC)
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 98.5, "count": int64(3), "ok": true},
+			ts,
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"usage_idle": 97.0, "state": "running", "total": uint64(5)},
+			ts.Add(time.Second),
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"cpu": "cpu1"},
+			map[string]interface{}{"usage_idle": 42.0},
+			ts.Add(24*time.Hour),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	// Files are hidden until they are closed
+	for _, f := range findFiles(t, plugin.Directory) {
+		require.Contains(t, filepath.Base(f), ".tmp")
+	}
+	require.NoError(t, plugin.Close())
+
+	files := findFiles(t, plugin.Directory)
+	require.Len(t, files, 2)
+	for _, f := range files {
+		require.Regexp(t, `^part-\d+\.parquet$`, filepath.Base(f))
+	}
+
+	dir := filepath.Join(plugin.Directory, "cpu", "year=2022", "month=10", "day=17", "host=server01")
+	files = findFiles(t, dir)
+	require.Len(t, files, 1)
+	names, rows := readFile(t, files[0])
+	require.Equal(t, []string{"time", "cpu", "count", "ok", "state", "total", "usage_idle"}, names)
+	require.Equal(t, [][]interface{}{
+		{ts.UnixNano(), "cpu0", int64(3), true, nil, nil, 98.5},
+		{ts.Add(time.Second).UnixNano(), nil, nil, nil, "running", int64(5), 97.0},
+	}, rows)
+
+	dir = filepath.Join(plugin.Directory, "cpu", "year=2022", "month=10", "day=18", "host=__HIVE_DEFAULT_PARTITION__")
+	files = findFiles(t, dir)
+	require.Len(t, files, 1)
+	_, rows = readFile(t, files[0])
+	require.Equal(t, [][]interface{}{
+		{ts.Add(24 * time.Hour).UnixNano(), "cpu1", nil, nil, nil, nil, 42.0},
+	}, rows)
+}
+
+// warnLogger counts the logged warnings
+type warnLogger struct {
+	testutil.Logger
+	warnings int
+}
+
+func (l *warnLogger) Warnf(format string, args ...interface{}) {
+	l.warnings++
+	l.Logger.Warnf(format, args...)
+}
+
+func TestSchemaEvolution(t *testing.T) {
+	logger := &warnLogger{}
+	plugin := newPlugin(t)
+	plugin.TimePartition = "none"
+	plugin.Log = logger
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	ts := time.Unix(1666000000, 0)
+	m1 := metric.New("mem", map[string]string{}, map[string]interface{}{"used": int64(1)}, ts)
+	m2 := metric.New("mem", map[string]string{}, map[string]interface{}{"used": 2.5}, ts.Add(time.Second))
+	m3 := metric.New("mem", map[string]string{"host": "a"}, map[string]interface{}{"free": int64(3)}, ts.Add(2*time.Second))
+
+	// The type of existing columns does not change, dropped values are
+	// logged once per column
+	require.NoError(t, plugin.Write([]telegraf.Metric{m1, m2, m2}))
+	require.Len(t, findFiles(t, plugin.Directory), 1)
+	require.Equal(t, 1, logger.warnings)
+
+	// New columns start a new file
+	require.NoError(t, plugin.Write([]telegraf.Metric{m3}))
+	files := findFiles(t, plugin.Directory)
+	require.Len(t, files, 2)
+	require.NoError(t, plugin.Close())
+
+	files = findFiles(t, plugin.Directory)
+	require.Len(t, files, 2)
+	var schemas [][]string
+	var contents [][][]interface{}
+	for _, f := range files {
+		names, rows := readFile(t, f)
+		schemas = append(schemas, names)
+		contents = append(contents, rows)
+	}
+	require.ElementsMatch(t, [][]string{
+		{"time", "used"},
+		{"time", "host", "free", "used"},
+	}, schemas)
+	require.ElementsMatch(t, [][][]interface{}{
+		{{ts.UnixNano(), int64(1)}, {ts.Add(time.Second).UnixNano(), nil}, {ts.Add(time.Second).UnixNano(), nil}},
+		{{ts.Add(2 * time.Second).UnixNano(), "a", int64(3), nil}},
+	}, contents)
+}
+
+func TestRotation(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.RotationMaxSize = config.Size(1)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	m := metric.New("mem", map[string]string{}, map[string]interface{}{"used": int64(1)}, time.Now())
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Empty(t, plugin.files)
+
+	files := findFiles(t, plugin.Directory)
+	require.Len(t, files, 2)
+	for _, f := range files {
+		_, rows := readFile(t, f)
+		require.Len(t, rows, 1)
+	}
+	require.NoError(t, plugin.Close())
+}
+
+func TestFailedWriteRetried(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.TimePartition = "none"
+	plugin.PartitionTags = []string{"host"}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	// Block the directory of the second partition with a file
+	blocked := filepath.Join(plugin.Directory, "cpu", "host=b")
+	require.NoError(t, os.MkdirAll(filepath.Dir(blocked), 0750))
+	require.NoError(t, os.WriteFile(blocked, nil, 0600))
+
+	ts := time.Unix(1666000000, 0)
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, ts),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, ts),
+	}
+	require.Error(t, plugin.Write(metrics))
+
+	// Telegraf retries the whole batch
+	require.NoError(t, os.Remove(blocked))
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	for _, host := range []string{"a", "b"} {
+		files := findFiles(t, filepath.Join(plugin.Directory, "cpu", "host="+host))
+		require.Len(t, files, 1)
+		_, rows := readFile(t, files[0])
+		require.Len(t, rows, 1)
+	}
+}
+
+func TestInit(t *testing.T) {
+	plugin := &Parquet{TimePartition: "day", Compression: "snappy"}
+	require.ErrorContains(t, plugin.Init(), "directory required")
+
+	plugin = &Parquet{Directory: "/tmp", TimePartition: "day", Compression: "brotli"}
+	require.ErrorContains(t, plugin.Init(), `invalid compression "brotli"`)
+
+	plugin = &Parquet{Directory: "/tmp", TimePartition: "week", Compression: "zstd"}
+	require.ErrorContains(t, plugin.Init(), `invalid time partition "week"`)
+}
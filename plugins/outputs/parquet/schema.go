@@ -0,0 +1,206 @@
+package parquet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+type columnType int
+
+const (
+	columnTag columnType = iota
+	columnFloat
+	columnInt
+	columnUint
+	columnBool
+	columnString
+)
+
+// Characters separating the column definitions of the parquet library
+var columnNameReplacer = strings.NewReplacer(",", "_", "=", "_")
+
+type column struct {
+	name   string
+	key    string
+	ctype  columnType
+	warned bool
+}
+
+// metadata returns the column definition in the format of the parquet library
+func (c *column) metadata() string {
+	var definition string
+	switch c.ctype {
+	case columnTag, columnString:
+		definition = "type=BYTE_ARRAY, convertedtype=UTF8"
+	case columnFloat:
+		definition = "type=DOUBLE"
+	case columnInt:
+		definition = "type=INT64"
+	case columnUint:
+		definition = "type=INT64, convertedtype=UINT_64"
+	case columnBool:
+		definition = "type=BOOLEAN"
+	}
+	return fmt.Sprintf("name=%s, %s, repetitiontype=OPTIONAL", c.name, definition)
+}
+
+// value converts the field value to the type of the column. Values that
+// cannot be converted without loss are returned as nil.
+func (c *column) value(v interface{}) interface{} {
+	switch c.ctype {
+	case columnFloat:
+		if v, ok := internal.FieldToFloat64(v); ok {
+			return v
+		}
+	case columnInt:
+		if v, ok := internal.FieldToInt64(v); ok {
+			return v
+		}
+	case columnUint:
+		// Unsigned values are stored as INT64 annotated as UINT_64
+		if v, ok := internal.FieldToUint64(v); ok {
+			return int64(v)
+		}
+	case columnBool:
+		if v, ok := v.(bool); ok {
+			return v
+		}
+	case columnString, columnTag:
+		if v, ok := v.(string); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func fieldColumnType(v interface{}) (columnType, bool) {
+	switch v.(type) {
+	case float64:
+		return columnFloat, true
+	case int64:
+		return columnInt, true
+	case uint64:
+		return columnUint, true
+	case bool:
+		return columnBool, true
+	case string:
+		return columnString, true
+	}
+	return 0, false
+}
+
+// schema holds the columns of a measurement. New tags and fields are added
+// as columns when they appear and increase the version, while the type of an
+// existing column never changes.
+type schema struct {
+	version int
+	tags    []*column
+	fields  []*column
+	byTag   map[string]*column
+	byField map[string]*column
+}
+
+func newSchema() *schema {
+	return &schema{
+		byTag:   make(map[string]*column),
+		byField: make(map[string]*column),
+	}
+}
+
+// update adds the tags and fields of the metric missing in the schema,
+// excluding the given tags
+func (s *schema) update(m telegraf.Metric, exclude map[string]bool) {
+	changed := false
+	for _, tag := range m.TagList() {
+		if exclude[tag.Key] || s.byTag[tag.Key] != nil {
+			continue
+		}
+		c := &column{key: tag.Key, ctype: columnTag}
+		s.tags = append(s.tags, c)
+		s.byTag[tag.Key] = c
+		changed = true
+	}
+	for _, field := range m.FieldList() {
+		if s.byField[field.Key] != nil {
+			continue
+		}
+		ctype, ok := fieldColumnType(field.Value)
+		if !ok {
+			continue
+		}
+		c := &column{key: field.Key, ctype: ctype}
+		s.fields = append(s.fields, c)
+		s.byField[field.Key] = c
+		changed = true
+	}
+
+	if changed {
+		s.version++
+		s.rename()
+	}
+}
+
+// rename sorts the columns and assigns unique names to tags and fields
+func (s *schema) rename() {
+	sort.Slice(s.tags, func(i, j int) bool { return s.tags[i].key < s.tags[j].key })
+	sort.Slice(s.fields, func(i, j int) bool { return s.fields[i].key < s.fields[j].key })
+
+	for _, c := range s.tags {
+		c.name = columnNameReplacer.Replace(c.key)
+		if c.name == "time" {
+			c.name = "time_tag"
+		}
+	}
+	for _, c := range s.fields {
+		c.name = columnNameReplacer.Replace(c.key)
+		if c.name == "time" || s.byTag[c.key] != nil {
+			c.name += "_field"
+		}
+	}
+}
+
+// metadata returns the definitions of all columns starting with the time
+func (s *schema) metadata() []string {
+	md := make([]string, 0, 1+len(s.tags)+len(s.fields))
+	md = append(md, "name=time, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=NANOS, repetitiontype=REQUIRED")
+	for _, c := range s.tags {
+		md = append(md, c.metadata())
+	}
+	for _, c := range s.fields {
+		md = append(md, c.metadata())
+	}
+	return md
+}
+
+// row returns the values of the metric in the order of the columns. Field
+// values not matching the type of their column are written as null, which is
+// logged once per column.
+func (s *schema) row(m telegraf.Metric, log telegraf.Logger) []interface{} {
+	row := make([]interface{}, 0, 1+len(s.tags)+len(s.fields))
+	row = append(row, m.Time().UnixNano())
+	for _, c := range s.tags {
+		if v, ok := m.GetTag(c.key); ok {
+			row = append(row, v)
+		} else {
+			row = append(row, nil)
+		}
+	}
+	for _, c := range s.fields {
+		v, ok := m.GetField(c.key)
+		if !ok {
+			row = append(row, nil)
+			continue
+		}
+		value := c.value(v)
+		if value == nil && !c.warned {
+			log.Warnf("Value %v (%T) of field %q of %q does not fit the type of column %q, writing null", v, v, c.key, m.Name(), c.name)
+			c.warned = true
+		}
+		row = append(row, value)
+	}
+	return row
+}
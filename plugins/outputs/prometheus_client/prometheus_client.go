@@ -2,19 +2,24 @@
 package prometheus_client
 
 import (
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	_ "embed"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
@@ -23,6 +28,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	v1 "github.com/influxdata/telegraf/plugins/outputs/prometheus_client/v1"
 	v2 "github.com/influxdata/telegraf/plugins/outputs/prometheus_client/v2"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/prometheus"
 )
 
 // DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
@@ -33,6 +39,7 @@ var (
 	defaultListen             = ":9273"
 	defaultPath               = "/metrics"
 	defaultExpirationInterval = config.Duration(60 * time.Second)
+	defaultHistogramSchema    = int32(3)
 )
 
 type Collector interface {
@@ -52,6 +59,12 @@ type PrometheusClient struct {
 	CollectorsExclude  []string        `toml:"collectors_exclude"`
 	StringAsLabel      bool            `toml:"string_as_label"`
 	ExportTimestamp    bool            `toml:"export_timestamp"`
+
+	EnableOpenMetrics     bool     `toml:"enable_openmetrics"`
+	ExemplarTags          []string `toml:"exemplar_tags"`
+	NativeHistograms      bool     `toml:"native_histograms"`
+	NativeHistogramSchema int32    `toml:"native_histogram_schema"`
+	StalenessMarkers      bool     `toml:"staleness_markers"`
 	tlsint.ServerConfig
 
 	Log telegraf.Logger `toml:"-"`
@@ -67,6 +80,15 @@ func (*PrometheusClient) SampleConfig() string {
 }
 
 func (p *PrometheusClient) Init() error {
+	if p.MetricVersion != 2 {
+		if p.EnableOpenMetrics || len(p.ExemplarTags) > 0 || p.NativeHistograms || p.StalenessMarkers {
+			return errors.New("enable_openmetrics, exemplar_tags, native_histograms and staleness_markers require metric_version = 2")
+		}
+	}
+	if p.NativeHistogramSchema < serializer.MinNativeHistogramSchema || p.NativeHistogramSchema > serializer.MaxNativeHistogramSchema {
+		return fmt.Errorf("native_histogram_schema must be between %d and %d", serializer.MinNativeHistogramSchema, serializer.MaxNativeHistogramSchema)
+	}
+
 	defaultCollectors := map[string]bool{
 		"gocollector": true,
 		"process":     true,
@@ -93,21 +115,42 @@ func (p *PrometheusClient) Init() error {
 		}
 	}
 
+	// The metrics of the plugin are kept in a separate registry, the
+	// OpenMetrics exposition needs them along with the data not contained in
+	// the protobuf messages
+	collectorRegistry := prometheus.NewRegistry()
+	var openMetrics *v2.Collector
 	switch p.MetricVersion {
 	default:
 		fallthrough
 	case 1:
 		p.collector = v1.NewCollector(time.Duration(p.ExpirationInterval), p.StringAsLabel, p.Log)
-		err := registry.Register(p.collector)
+		err := collectorRegistry.Register(p.collector)
 		if err != nil {
 			return err
 		}
 	case 2:
-		p.collector = v2.NewCollector(time.Duration(p.ExpirationInterval), p.StringAsLabel, p.ExportTimestamp)
-		err := registry.Register(p.collector)
+		cfg := serializer.FormatConfig{
+			ExemplarTags:          p.ExemplarTags,
+			NativeHistograms:      p.NativeHistograms,
+			NativeHistogramSchema: p.NativeHistogramSchema,
+			StalenessMarkers:      p.StalenessMarkers,
+		}
+		if p.StringAsLabel {
+			cfg.StringHandling = serializer.StringAsLabel
+		}
+		if p.ExportTimestamp {
+			cfg.TimestampExport = serializer.ExportTimestamp
+		}
+		collector := v2.NewCollector(time.Duration(p.ExpirationInterval), cfg)
+		err := collectorRegistry.Register(collector)
 		if err != nil {
 			return err
 		}
+		p.collector = collector
+		if p.EnableOpenMetrics {
+			openMetrics = collector
+		}
 	}
 
 	ipRange := make([]*net.IPNet, 0, len(p.IPRange))
@@ -122,7 +165,11 @@ func (p *PrometheusClient) Init() error {
 
 	authHandler := internal.AuthHandler(p.BasicUsername, p.BasicPassword, "prometheus", onAuthError)
 	rangeHandler := internal.IPRangeHandler(ipRange, onError)
-	promHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+	gatherers := prometheus.Gatherers{registry, collectorRegistry}
+	var promHandler http.Handler = promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+	if openMetrics != nil {
+		promHandler = p.openMetricsHandler(registry, openMetrics, promHandler)
+	}
 	landingPageHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := w.Write([]byte("Telegraf Output Plugin: Prometheus Client "))
 		if err != nil {
@@ -189,6 +236,41 @@ func (p *PrometheusClient) Connect() error {
 	return nil
 }
 
+// openMetricsHandler serves the OpenMetrics text format to clients accepting
+// it and passes all other requests to the Prometheus handler
+func (p *PrometheusClient) openMetricsHandler(defaults prometheus.Gatherer, collector *v2.Collector, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+		if format != expfmt.FmtOpenMetrics {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Serve what is available like the Prometheus handler does
+		mfs, err := defaults.Gather()
+		if err != nil {
+			p.Log.Errorf("Error gathering default metrics: %v", err)
+		}
+		families := make([]serializer.OpenMetricsFamily, 0, len(mfs))
+		for _, mf := range mfs {
+			families = append(families, serializer.OpenMetricsFamily{Family: mf})
+		}
+		families = append(families, collector.GetOpenMetrics()...)
+
+		w.Header().Set("Content-Type", string(format))
+		var out io.Writer = w
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			out = gz
+		}
+		if err := serializer.WriteOpenMetrics(out, families); err != nil {
+			p.Log.Errorf("Error occurred when writing HTTP reply: %v", err)
+		}
+	})
+}
+
 func onAuthError(_ http.ResponseWriter) {
 }
 
@@ -227,6 +309,8 @@ func init() {
 			Path:               defaultPath,
 			ExpirationInterval: defaultExpirationInterval,
 			StringAsLabel:      true,
+
+			NativeHistogramSchema: defaultHistogramSchema,
 		}
 	})
 }
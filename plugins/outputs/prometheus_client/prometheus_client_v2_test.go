@@ -464,3 +464,104 @@ rpc_duration_seconds_count 2693
 		})
 	}
 }
+
+func TestOpenMetricsExposition(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:            "127.0.0.1:0",
+		Path:              defaultPath,
+		MetricVersion:     2,
+		EnableOpenMetrics: true,
+		ExemplarTags:      []string{"trace_id"},
+		CollectorsExclude: []string{"gocollector", "process"},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, output.Init())
+	require.NoError(t, output.Connect())
+	defer func() {
+		require.NoError(t, output.Close())
+	}()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"http",
+			map[string]string{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"},
+			map[string]interface{}{"requests": 42.0},
+			time.Unix(10, 0),
+			telegraf.Counter,
+		),
+	}
+	require.NoError(t, output.Write(metrics))
+
+	// Clients accepting OpenMetrics get the counter with exemplar and
+	// creation time
+	req, err := http.NewRequest("GET", output.URL(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/openmetrics-text; version=0.0.1; charset=utf-8", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.Len(t, lines, 5)
+	require.Equal(t, "# TYPE http_requests counter", lines[0])
+	require.Equal(t, `http_requests_total 42.0 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 42.0 10`, lines[2])
+	require.True(t, strings.HasPrefix(lines[3], "http_requests_created "))
+	require.Equal(t, "# EOF", lines[4])
+
+	// All other clients get the Prometheus text format
+	resp, err = http.Get(output.URL())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	expected := `
+# HELP http_requests Telegraf collected metric
+# TYPE http_requests counter
+http_requests 42
+`
+	require.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(string(body)))
+}
+
+func TestInitOpenMetricsOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		output *PrometheusClient
+		errmsg string
+	}{
+		{
+			name: "openmetrics with metric version 1",
+			output: &PrometheusClient{
+				MetricVersion:     1,
+				EnableOpenMetrics: true,
+			},
+			errmsg: "require metric_version = 2",
+		},
+		{
+			name: "staleness markers with metric version 1",
+			output: &PrometheusClient{
+				MetricVersion:    1,
+				StalenessMarkers: true,
+			},
+			errmsg: "require metric_version = 2",
+		},
+		{
+			name: "invalid schema",
+			output: &PrometheusClient{
+				MetricVersion:         2,
+				NativeHistograms:      true,
+				NativeHistogramSchema: 9,
+			},
+			errmsg: "native_histogram_schema must be between -4 and 8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.output.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.output.Init(), tt.errmsg)
+		})
+	}
+}
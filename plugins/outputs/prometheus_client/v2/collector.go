@@ -43,16 +43,7 @@ type Collector struct {
 	coll           *serializer.Collection
 }
 
-func NewCollector(expire time.Duration, stringsAsLabel bool, exportTimestamp bool) *Collector {
-	config := serializer.FormatConfig{}
-	if stringsAsLabel {
-		config.StringHandling = serializer.StringAsLabel
-	}
-
-	if exportTimestamp {
-		config.TimestampExport = serializer.ExportTimestamp
-	}
-
+func NewCollector(expire time.Duration, config serializer.FormatConfig) *Collector {
 	return &Collector{
 		expireDuration: expire,
 		coll:           serializer.NewCollection(config),
@@ -82,6 +73,18 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	}
 }
 
+// GetOpenMetrics returns the metric families for the OpenMetrics exposition
+func (c *Collector) GetOpenMetrics() []serializer.OpenMetricsFamily {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.expireDuration != 0 {
+		c.coll.Expire(time.Now(), c.expireDuration)
+	}
+
+	return c.coll.GetOpenMetrics()
+}
+
 func (c *Collector) Add(metrics []telegraf.Metric) error {
 	c.Lock()
 	defer c.Unlock()
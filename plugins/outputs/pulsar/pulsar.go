@@ -0,0 +1,308 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar
+
+import (
+	"container/list"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	pulsarcommon "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/common/templating"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+var compressionTypes = map[string]pulsar.CompressionType{
+	"none": pulsar.NoCompression,
+	"lz4":  pulsar.LZ4,
+	"zlib": pulsar.ZLib,
+	"zstd": pulsar.ZSTD,
+}
+
+var compressionLevels = map[string]pulsar.CompressionLevel{
+	"default": pulsar.Default,
+	"faster":  pulsar.Faster,
+	"better":  pulsar.Better,
+}
+
+// topicProducer is the producer of a topic in the list of producers ordered
+// by their last use
+type topicProducer struct {
+	topic    string
+	producer pulsar.Producer
+}
+
+type Pulsar struct {
+	Topic                   string            `toml:"topic"`
+	TopicTemplate           string            `toml:"topic_template"`
+	KeyTemplate             string            `toml:"key_template"`
+	PropertyTags            map[string]string `toml:"property_tags"`
+	Compression             string            `toml:"compression"`
+	CompressionLevel        string            `toml:"compression_level"`
+	DisableBatching         bool              `toml:"disable_batching"`
+	BatchingMaxMessages     uint              `toml:"batching_max_messages"`
+	BatchingMaxSize         config.Size       `toml:"batching_max_size"`
+	BatchingMaxPublishDelay config.Duration   `toml:"batching_max_publish_delay"`
+	SendTimeout             config.Duration   `toml:"send_timeout"`
+	MaxProducers            int               `toml:"max_producers"`
+
+	pulsarcommon.Config
+
+	Log telegraf.Logger `toml:"-"`
+
+	clientOptions   pulsar.ClientOptions
+	producerOptions pulsar.ProducerOptions
+	newClient       func(pulsar.ClientOptions) (pulsar.Client, error)
+	client          pulsar.Client
+	producers       map[string]*list.Element
+	lru             *list.List
+
+	topicTmpl    *template.Template
+	keyTmpl      *template.Template
+	propertyTags []string
+	serializer   serializers.Serializer
+}
+
+func (*Pulsar) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Pulsar) SetSerializer(serializer serializers.Serializer) {
+	p.serializer = serializer
+}
+
+func (p *Pulsar) Init() error {
+	if p.Topic == "" {
+		return errors.New("topic required")
+	}
+
+	var err error
+	if p.TopicTemplate != "" {
+		if p.topicTmpl, err = template.New("topic").Parse(p.TopicTemplate); err != nil {
+			return fmt.Errorf("parsing topic_template failed: %w", err)
+		}
+	}
+	if p.KeyTemplate != "" {
+		if p.keyTmpl, err = template.New("key").Parse(p.KeyTemplate); err != nil {
+			return fmt.Errorf("parsing key_template failed: %w", err)
+		}
+	}
+
+	for tag := range p.PropertyTags {
+		p.propertyTags = append(p.propertyTags, tag)
+	}
+	sort.Strings(p.propertyTags)
+
+	if p.MaxProducers < 1 {
+		return errors.New("max_producers must be at least 1")
+	}
+
+	compressionType, found := compressionTypes[strings.ToLower(p.Compression)]
+	if !found {
+		return fmt.Errorf("invalid compression %q", p.Compression)
+	}
+	compressionLevel, found := compressionLevels[strings.ToLower(p.CompressionLevel)]
+	if !found {
+		return fmt.Errorf("invalid compression level %q", p.CompressionLevel)
+	}
+
+	p.clientOptions, err = p.Config.ClientOptions(p.Log)
+	if err != nil {
+		return err
+	}
+
+	p.producerOptions = pulsar.ProducerOptions{
+		CompressionType:         compressionType,
+		CompressionLevel:        compressionLevel,
+		DisableBatching:         p.DisableBatching,
+		BatchingMaxMessages:     p.BatchingMaxMessages,
+		BatchingMaxSize:         uint(p.BatchingMaxSize),
+		BatchingMaxPublishDelay: time.Duration(p.BatchingMaxPublishDelay),
+		SendTimeout:             time.Duration(p.SendTimeout),
+	}
+
+	if p.newClient == nil {
+		p.newClient = pulsar.NewClient
+	}
+
+	return nil
+}
+
+func (p *Pulsar) Connect() error {
+	client, err := p.newClient(p.clientOptions)
+	if err != nil {
+		return fmt.Errorf("creating client failed: %w", err)
+	}
+	p.client = client
+	p.producers = make(map[string]*list.Element)
+	p.lru = list.New()
+
+	// Fail early if the default topic cannot be produced to
+	_, err = p.producer(p.Topic)
+	if err != nil {
+		p.client.Close()
+	}
+	return err
+}
+
+func (p *Pulsar) Close() error {
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*topicProducer).producer.Close()
+	}
+	p.client.Close()
+	return nil
+}
+
+// producer returns the producer of the topic, producers are created on the
+// first message sent to the topic
+func (p *Pulsar) producer(topic string) (pulsar.Producer, error) {
+	if e, found := p.producers[topic]; found {
+		p.lru.MoveToFront(e)
+		return e.Value.(*topicProducer).producer, nil
+	}
+
+	opts := p.producerOptions
+	opts.Topic = topic
+	producer, err := p.client.CreateProducer(opts)
+	if err != nil {
+		return nil, fmt.Errorf("creating producer for topic %q failed: %w", topic, err)
+	}
+	p.producers[topic] = p.lru.PushFront(&topicProducer{topic: topic, producer: producer})
+	return producer, nil
+}
+
+// evict closes the least recently used producers exceeding the maximum
+// number of producers, as topics created by templates are unbounded
+func (p *Pulsar) evict() {
+	for p.lru.Len() > p.MaxProducers {
+		tp := p.lru.Remove(p.lru.Back()).(*topicProducer)
+		delete(p.producers, tp.topic)
+		tp.producer.Close()
+		p.Log.Debugf("Closed producer of topic %q", tp.topic)
+	}
+}
+
+// topic returns the topic of the metric, the template takes precedence over
+// the topic option
+func (p *Pulsar) topic(metric telegraf.Metric) (string, error) {
+	if p.topicTmpl == nil {
+		return p.Topic, nil
+	}
+	topic, err := templating.Execute(p.topicTmpl, metric)
+	if err != nil {
+		return "", err
+	}
+	if topic == "" {
+		return p.Topic, nil
+	}
+	return topic, nil
+}
+
+// message creates the message of the metric
+func (p *Pulsar) message(metric telegraf.Metric) (*pulsar.ProducerMessage, error) {
+	payload, err := p.serializer.Serialize(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &pulsar.ProducerMessage{
+		Payload:   payload,
+		EventTime: metric.Time(),
+	}
+	if p.keyTmpl != nil {
+		if msg.Key, err = templating.Execute(p.keyTmpl, metric); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range p.propertyTags {
+		if value, ok := metric.GetTag(tag); ok {
+			if msg.Properties == nil {
+				msg.Properties = make(map[string]string)
+			}
+			msg.Properties[p.PropertyTags[tag]] = value
+		}
+	}
+	return msg, nil
+}
+
+// Write sends the metrics asynchronously, so the producers can batch them,
+// and waits for all messages to be acknowledged by the brokers
+func (p *Pulsar) Write(metrics []telegraf.Metric) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var sendErr error
+	used := make(map[string]pulsar.Producer)
+
+	ctx := context.Background()
+	for _, metric := range metrics {
+		topic, err := p.topic(metric)
+		if err != nil {
+			p.Log.Errorf("Could not generate topic, dropping metric: %v", err)
+			continue
+		}
+		msg, err := p.message(metric)
+		if err != nil {
+			p.Log.Debugf("Could not serialize metric: %v", err)
+			continue
+		}
+
+		producer, err := p.producer(topic)
+		if err != nil {
+			wg.Wait()
+			p.evict()
+			return err
+		}
+		used[topic] = producer
+
+		wg.Add(1)
+		producer.SendAsync(ctx, msg, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+			defer wg.Done()
+			if err != nil {
+				mu.Lock()
+				if sendErr == nil {
+					sendErr = fmt.Errorf("sending message to %q failed: %w", topic, err)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+
+	// Send the pending batches right away instead of waiting for the
+	// publish delay
+	for topic, producer := range used {
+		if err := producer.Flush(); err != nil {
+			p.Log.Debugf("Flushing producer of topic %q failed: %v", topic, err)
+		}
+	}
+	wg.Wait()
+
+	// Producers are only closed after their messages were acknowledged
+	p.evict()
+
+	return sendErr
+}
+
+func init() {
+	outputs.Add("pulsar", func() telegraf.Output {
+		return &Pulsar{
+			Compression:      "none",
+			CompressionLevel: "default",
+			MaxProducers:     100,
+		}
+	})
+}
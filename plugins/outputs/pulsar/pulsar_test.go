@@ -0,0 +1,233 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	pulsarcommon "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type fakeProducer struct {
+	pulsar.Producer
+	options  pulsar.ProducerOptions
+	messages []*pulsar.ProducerMessage
+	pending  []func()
+	err      error
+	closed   bool
+}
+
+// SendAsync completes the messages on flush, like a batching producer
+func (p *fakeProducer) SendAsync(_ context.Context, msg *pulsar.ProducerMessage, callback func(pulsar.MessageID, *pulsar.ProducerMessage, error)) {
+	p.pending = append(p.pending, func() {
+		if p.err == nil {
+			p.messages = append(p.messages, msg)
+		}
+		callback(nil, msg, p.err)
+	})
+}
+
+func (p *fakeProducer) Flush() error {
+	for _, complete := range p.pending {
+		complete()
+	}
+	p.pending = nil
+	return nil
+}
+
+func (p *fakeProducer) Close() {
+	p.closed = true
+}
+
+type fakeClient struct {
+	pulsar.Client
+	producers map[string]*fakeProducer
+	err       error
+	closed    bool
+}
+
+func (c *fakeClient) CreateProducer(options pulsar.ProducerOptions) (pulsar.Producer, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	producer := &fakeProducer{options: options}
+	c.producers[options.Topic] = producer
+	return producer, nil
+}
+
+func (c *fakeClient) Close() {
+	c.closed = true
+}
+
+func newTestPlugin(t *testing.T, client *fakeClient) *Pulsar {
+	plugin := &Pulsar{
+		Topic:            "persistent://public/default/telegraf",
+		Compression:      "zstd",
+		CompressionLevel: "better",
+		MaxProducers:     100,
+		Config: pulsarcommon.Config{
+			ServiceURL: "pulsar://localhost:6650",
+		},
+		Log: testutil.Logger{},
+		newClient: func(pulsar.ClientOptions) (pulsar.Client, error) {
+			return client, nil
+		},
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	require.NoError(t, plugin.Init())
+	return plugin
+}
+
+func TestWrite(t *testing.T) {
+	client := &fakeClient{producers: make(map[string]*fakeProducer)}
+	plugin := newTestPlugin(t, client)
+	plugin.BatchingMaxMessages = 10
+	plugin.TopicTemplate = `{{if .Tag "topic"}}persistent://public/default/{{.Tag "topic"}}{{end}}`
+	plugin.KeyTemplate = `{{.Tag "host"}}`
+	plugin.PropertyTags = map[string]string{"region": "x-region"}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a", "region": "eu"}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{"host": "b", "topic": "memory"}, map[string]interface{}{"value": 23.0}, time.Unix(1, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	require.Len(t, client.producers, 2)
+	defaultProducer := client.producers["persistent://public/default/telegraf"]
+	require.Equal(t, pulsar.ZSTD, defaultProducer.options.CompressionType)
+	require.Equal(t, pulsar.Better, defaultProducer.options.CompressionLevel)
+	require.Equal(t, uint(10), defaultProducer.options.BatchingMaxMessages)
+	require.True(t, defaultProducer.closed)
+	require.True(t, client.closed)
+
+	require.Len(t, defaultProducer.messages, 1)
+	msg := defaultProducer.messages[0]
+	require.Equal(t, "cpu,host=a,region=eu value=42 0\n", string(msg.Payload))
+	require.Equal(t, "a", msg.Key)
+	require.Equal(t, map[string]string{"x-region": "eu"}, msg.Properties)
+	require.Equal(t, time.Unix(0, 0), msg.EventTime)
+
+	memoryProducer := client.producers["persistent://public/default/memory"]
+	require.Len(t, memoryProducer.messages, 1)
+	msg = memoryProducer.messages[0]
+	require.Equal(t, "mem,host=b,topic=memory value=23 1000000000\n", string(msg.Payload))
+	require.Equal(t, "b", msg.Key)
+	require.Nil(t, msg.Properties)
+}
+
+func TestWriteError(t *testing.T) {
+	client := &fakeClient{producers: make(map[string]*fakeProducer)}
+	plugin := newTestPlugin(t, client)
+	require.NoError(t, plugin.Connect())
+
+	client.producers[plugin.Topic].err = errors.New("timeout")
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(metrics), `sending message to "persistent://public/default/telegraf" failed: timeout`)
+}
+
+func TestProducerEviction(t *testing.T) {
+	client := &fakeClient{producers: make(map[string]*fakeProducer)}
+	plugin := newTestPlugin(t, client)
+	plugin.TopicTemplate = `persistent://public/default/{{.Tag "topic"}}`
+	plugin.MaxProducers = 2
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	write := func(topic string) {
+		m := testutil.MustMetric("cpu", map[string]string{"topic": topic}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+		require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	}
+
+	// The producer of the default topic created on connect is used least
+	// recently and closed first
+	defaultProducer := client.producers[plugin.Topic]
+	write("a")
+	require.False(t, defaultProducer.closed)
+	write("b")
+	require.True(t, defaultProducer.closed)
+
+	// Using a topic again keeps its producer open
+	producerA := client.producers["persistent://public/default/a"]
+	write("a")
+	write("c")
+	require.False(t, producerA.closed)
+	require.True(t, client.producers["persistent://public/default/b"].closed)
+	require.Len(t, plugin.producers, 2)
+
+	// Messages to a topic with a closed producer use a new one
+	write("b")
+	require.True(t, producerA.closed)
+	require.False(t, client.producers["persistent://public/default/b"].closed)
+	require.Len(t, client.producers["persistent://public/default/b"].messages, 1)
+	require.NoError(t, plugin.Close())
+}
+
+func TestConnectError(t *testing.T) {
+	client := &fakeClient{err: errors.New("topic not found")}
+	plugin := newTestPlugin(t, client)
+	require.ErrorContains(t, plugin.Connect(), "topic not found")
+	require.True(t, client.closed)
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Pulsar)
+		errmsg string
+	}{
+		{
+			name:   "missing topic",
+			modify: func(p *Pulsar) { p.Topic = "" },
+			errmsg: "topic required",
+		},
+		{
+			name:   "invalid topic template",
+			modify: func(p *Pulsar) { p.TopicTemplate = "{{.Name" },
+			errmsg: "parsing topic_template failed",
+		},
+		{
+			name:   "invalid key template",
+			modify: func(p *Pulsar) { p.KeyTemplate = "{{.Tag" },
+			errmsg: "parsing key_template failed",
+		},
+		{
+			name:   "invalid compression",
+			modify: func(p *Pulsar) { p.Compression = "snappy" },
+			errmsg: `invalid compression "snappy"`,
+		},
+		{
+			name:   "invalid compression level",
+			modify: func(p *Pulsar) { p.CompressionLevel = "best" },
+			errmsg: `invalid compression level "best"`,
+		},
+		{
+			name:   "invalid max producers",
+			modify: func(p *Pulsar) { p.MaxProducers = 0 },
+			errmsg: "max_producers must be at least 1",
+		},
+		{
+			name:   "missing service url",
+			modify: func(p *Pulsar) { p.ServiceURL = "" },
+			errmsg: "service_url required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, &fakeClient{})
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.errmsg)
+		})
+	}
+}
@@ -1,51 +1,140 @@
+//go:generate ../../../tools/readme_config_includer/generator
 package redistimeseries
 
 import (
+	_ "embed"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/go-redis/redis/v7"
+
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/templating"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
-const sampleConfig = `
-  ## The address of the RedisTimeSeries server.
-  address = "127.0.0.1:6379"
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+var duplicatePolicies = []string{"block", "first", "last", "min", "max", "sum"}
+
+var encodings = []string{"compressed", "uncompressed"}
 
-  ## Redis ACL credentials
-  # username = ""
-  # password = ""
-  # database = 0
+var aggregations = []string{
+	"avg", "sum", "min", "max", "range", "count", "first", "last",
+	"std.p", "std.s", "var.p", "var.s", "twa",
+}
 
-  ## Optional TLS Config
-  # tls_ca = "/etc/telegraf/ca.pem"
-  # tls_cert = "/etc/telegraf/cert.pem"
-  # tls_key = "/etc/telegraf/key.pem"
-  # insecure_skip_verify = false
-`
+// Compaction is a downsampling rule created for every new series
+type Compaction struct {
+	Aggregation string          `toml:"aggregation"`
+	Bucket      config.Duration `toml:"bucket"`
+	Retention   config.Duration `toml:"retention"`
+}
 
 type RedisTimeSeries struct {
-	Address  string          `toml:"address"`
-	Username string          `toml:"username"`
-	Password string          `toml:"password"`
-	Database int             `toml:"database"`
-	Log      telegraf.Logger `toml:"-"`
+	Address         string          `toml:"address"`
+	Username        string          `toml:"username"`
+	Password        string          `toml:"password"`
+	Database        int             `toml:"database"`
+	KeyTemplate     string          `toml:"key_template"`
+	NameLabel       string          `toml:"name_label"`
+	FieldLabel      string          `toml:"field_label"`
+	Retention       config.Duration `toml:"retention"`
+	DuplicatePolicy string          `toml:"duplicate_policy"`
+	Encoding        string          `toml:"encoding"`
+	Compactions     []Compaction    `toml:"compaction"`
+	Log             telegraf.Logger `toml:"-"`
 	tls.ClientConfig
-	client *redis.Client
+
+	client  *redis.Client
+	keyTmpl *template.Template
+
+	// Keys known to exist, so they are not created again
+	known map[string]bool
 }
 
-func (r *RedisTimeSeries) Connect() error {
+// keyMetric is the data the key template is executed on, extending the
+// metric with the field of the series
+type keyMetric struct {
+	*templating.Metric
+	field string
+}
+
+func (m *keyMetric) Field() string {
+	return m.field
+}
+
+// series is a time series written in a batch
+type series struct {
+	key    string
+	labels []interface{}
+}
+
+func (*RedisTimeSeries) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RedisTimeSeries) Init() error {
 	if r.Address == "" {
 		return errors.New("redis address must be specified")
 	}
+
+	var err error
+	r.keyTmpl, err = template.New("key").Parse(r.KeyTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing key_template failed: %w", err)
+	}
+
+	if r.DuplicatePolicy != "" && !choice(r.DuplicatePolicy, duplicatePolicies) {
+		return fmt.Errorf("invalid duplicate_policy %q", r.DuplicatePolicy)
+	}
+	if r.Encoding != "" && !choice(r.Encoding, encodings) {
+		return fmt.Errorf("invalid encoding %q", r.Encoding)
+	}
+	for _, c := range r.Compactions {
+		if !choice(c.Aggregation, aggregations) {
+			return fmt.Errorf("invalid compaction aggregation %q", c.Aggregation)
+		}
+		if time.Duration(c.Bucket) < time.Millisecond {
+			return fmt.Errorf("compaction bucket of %q must be at least 1ms", c.Aggregation)
+		}
+	}
+
+	return nil
+}
+
+func choice(value string, choices []string) bool {
+	for _, c := range choices {
+		if strings.EqualFold(value, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RedisTimeSeries) Connect() error {
+	tlsConfig, err := r.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
 	r.client = redis.NewClient(&redis.Options{
-		Addr:     r.Address,
-		Password: r.Password,
-		Username: r.Username,
-		DB:       r.Database,
+		Addr:      r.Address,
+		Password:  r.Password,
+		Username:  r.Username,
+		DB:        r.Database,
+		TLSConfig: tlsConfig,
 	})
+	r.known = make(map[string]bool)
 	return r.client.Ping().Err()
 }
 
@@ -53,39 +142,188 @@ func (r *RedisTimeSeries) Close() error {
 	return r.client.Close()
 }
 
-func (r *RedisTimeSeries) Description() string {
-	return "Plugin for sending metrics to RedisTimeSeries"
-}
-
-func (r *RedisTimeSeries) SampleConfig() string {
-	return sampleConfig
-}
+// Write sends the samples of all fields in one pipeline, after creating the
+// keys not written before. Errors returned by the server for single samples,
+// e.g. samples older than the retention, are logged as sending them again
+// would fail as well.
 func (r *RedisTimeSeries) Write(metrics []telegraf.Metric) error {
+	var created []series
+	pipe := r.client.Pipeline()
 	for _, m := range metrics {
-		now := m.Time().UnixNano() / 1000000 // in milliseconds
-		name := m.Name()
+		now := m.Time().UnixNano() / int64(time.Millisecond)
+		for _, field := range m.FieldList() {
+			value, err := toFloat(field.Value)
+			if err != nil {
+				r.Log.Debugf("Skipping field %q of %q: %v", field.Key, m.Name(), err)
+				continue
+			}
 
-		var tags []interface{}
-		for k, v := range m.Tags() {
-			tags = append(tags, k, v)
+			var b strings.Builder
+			if err := r.keyTmpl.Execute(&b, &keyMetric{templating.NewMetric(m), field.Key}); err != nil {
+				r.Log.Errorf("Could not generate key, dropping field %q of %q: %v", field.Key, m.Name(), err)
+				continue
+			}
+			key := b.String()
+
+			if !r.known[key] {
+				created = append(created, series{key: key, labels: r.labels(m, field.Key)})
+				r.known[key] = true
+			}
+			pipe.Do("TS.ADD", key, now, value)
 		}
+	}
 
-		for fieldName, value := range m.Fields() {
-			key := name + "_" + fieldName
+	if len(created) > 0 {
+		if err := r.create(created); err != nil {
+			//nolint:errcheck,revive // Discarding the queued samples cannot fail
+			pipe.Close()
+			return err
+		}
+	}
 
-			addSlice := []interface{}{"TS.ADD", key, now, value}
-			addSlice = append(addSlice, tags...)
+	cmds, err := pipe.Exec()
+	if err != nil && !isRedisError(err) {
+		return fmt.Errorf("adding samples failed: %w", err)
+	}
+	r.logErrors(cmds)
+	return nil
+}
 
-			if err := r.client.Do(addSlice...).Err(); err != nil {
-				return fmt.Errorf("adding sample failed: %v", err)
-			}
+// create creates the keys with the labels and settings of the series and
+// their compaction rules. Keys created before, e.g. by an earlier run, are
+// left unchanged.
+func (r *RedisTimeSeries) create(created []series) error {
+	pipe := r.client.Pipeline()
+	for _, s := range created {
+		pipe.Do(r.createArgs(s.key, r.Retention, s.labels)...)
+	}
+	cmds, err := pipe.Exec()
+	if err != nil && !isRedisError(err) {
+		// Retry creating the keys with the next write
+		for _, s := range created {
+			delete(r.known, s.key)
+		}
+		return fmt.Errorf("creating keys failed: %w", err)
+	}
+
+	var fresh []series
+	for i, cmd := range cmds {
+		err := cmd.Err()
+		switch {
+		case err == nil:
+			fresh = append(fresh, created[i])
+		case !alreadyExists(err):
+			r.Log.Errorf("Creating key %q failed: %v", created[i].key, err)
+		}
+	}
+	if len(fresh) == 0 || len(r.Compactions) == 0 {
+		return nil
+	}
+
+	pipe = r.client.Pipeline()
+	for _, s := range fresh {
+		for _, c := range r.Compactions {
+			aggregation := strings.ToLower(c.Aggregation)
+			bucket := time.Duration(c.Bucket).Milliseconds()
+			dest := s.key + "_" + aggregation + "_" + strconv.FormatInt(bucket, 10)
+
+			labels := append([]interface{}{"aggregation", aggregation, "bucket_duration", bucket}, s.labels...)
+			pipe.Do(r.createArgs(dest, c.Retention, labels)...)
+			pipe.Do("TS.CREATERULE", s.key, dest, "AGGREGATION", aggregation, bucket)
+		}
+	}
+	cmds, err = pipe.Exec()
+	if err != nil && !isRedisError(err) {
+		return fmt.Errorf("creating compaction rules failed: %w", err)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && !alreadyExists(err) {
+			r.Log.Errorf("Creating compaction %v failed: %v", cmd.Args(), err)
 		}
 	}
 	return nil
 }
 
+func (r *RedisTimeSeries) createArgs(key string, retention config.Duration, labels []interface{}) []interface{} {
+	args := []interface{}{"TS.CREATE", key}
+	if retention > 0 {
+		args = append(args, "RETENTION", time.Duration(retention).Milliseconds())
+	}
+	if r.Encoding != "" {
+		args = append(args, "ENCODING", strings.ToUpper(r.Encoding))
+	}
+	if r.DuplicatePolicy != "" {
+		args = append(args, "DUPLICATE_POLICY", strings.ToUpper(r.DuplicatePolicy))
+	}
+	if len(labels) > 0 {
+		args = append(args, "LABELS")
+		args = append(args, labels...)
+	}
+	return args
+}
+
+// labels returns the tags of the metric and optionally its name and the
+// field as label pairs
+func (r *RedisTimeSeries) labels(m telegraf.Metric, field string) []interface{} {
+	var labels []interface{}
+	if r.NameLabel != "" {
+		labels = append(labels, r.NameLabel, m.Name())
+	}
+	if r.FieldLabel != "" {
+		labels = append(labels, r.FieldLabel, field)
+	}
+	tags := m.TagList()
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	for _, tag := range tags {
+		labels = append(labels, tag.Key, tag.Value)
+	}
+	return labels
+}
+
+// logErrors logs the samples rejected by the server
+func (r *RedisTimeSeries) logErrors(cmds []redis.Cmder) {
+	var failed int
+	var first error
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		r.Log.Errorf("Adding %d of %d samples failed, first error: %v", failed, len(cmds), first)
+	}
+}
+
+func isRedisError(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr)
+}
+
+func alreadyExists(err error) bool {
+	return strings.Contains(err.Error(), "already exists")
+}
+
+// toFloat converts the field value to the float samples of RedisTimeSeries
+func toFloat(value interface{}) (float64, error) {
+	if v, ok := value.(bool); ok {
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	if _, ok := value.(string); ok {
+		return 0, errors.New("string values are not supported")
+	}
+	return internal.ToFloat64(value)
+}
+
 func init() {
 	outputs.Add("redistimeseries", func() telegraf.Output {
-		return &RedisTimeSeries{}
+		return &RedisTimeSeries{
+			KeyTemplate: "{{.Name}}_{{.Field}}",
+		}
 	})
 }
@@ -1,24 +1,313 @@
 package redistimeseries
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/docker/go-connections/nat"
-	"github.com/influxdata/telegraf/testutil"
+	"github.com/go-redis/redis/v7"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
 )
 
+// server is a minimal Redis server recording the received commands
+type server struct {
+	sync.Mutex
+	listener net.Listener
+	commands [][]string
+	keys     map[string]bool
+	failAdd  string
+}
+
+func newServer(t *testing.T) *server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &server{listener: listener, keys: make(map[string]bool)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		//nolint:errcheck,revive // The listener might be closed by the test already
+		listener.Close()
+	})
+	return s
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		cmd, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.reply(cmd)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *server) reply(cmd []string) string {
+	s.Lock()
+	defer s.Unlock()
+
+	s.commands = append(s.commands, cmd)
+	switch strings.ToUpper(cmd[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "TS.CREATE":
+		if s.keys[cmd[1]] {
+			return "-ERR TSDB: key already exists\r\n"
+		}
+		s.keys[cmd[1]] = true
+		return "+OK\r\n"
+	case "TS.ADD":
+		if cmd[1] == s.failAdd {
+			return "-ERR TSDB: Timestamp cannot be older than the retention\r\n"
+		}
+		s.keys[cmd[1]] = true
+		return ":" + cmd[2] + "\r\n"
+	case "TS.CREATERULE":
+		return "+OK\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func (s *server) received(name string) [][]string {
+	s.Lock()
+	defer s.Unlock()
+
+	var commands [][]string
+	for _, cmd := range s.commands {
+		if cmd[0] == name {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	cmd := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, string(buf[:size]))
+	}
+	return cmd, nil
+}
+
+func newPlugin(address string) *RedisTimeSeries {
+	return &RedisTimeSeries{
+		Address:     address,
+		KeyTemplate: "{{.Name}}_{{.Field}}",
+		Log:         testutil.Logger{},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	s := newServer(t)
+	plugin := newPlugin(s.listener.Addr().String())
+	plugin.KeyTemplate = `{{.Name}}:{{.Field}}:{{.Tag "host"}}`
+	plugin.NameLabel = "__name__"
+	plugin.Retention = config.Duration(time.Hour)
+	plugin.DuplicatePolicy = "last"
+	plugin.Encoding = "compressed"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a", "cpu": "total"}, map[string]interface{}{"usage": 42.5, "status": "ok"}, time.Unix(1, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a", "cpu": "total"}, map[string]interface{}{"usage": 23.0}, time.Unix(2, 0)),
+		testutil.MustMetric("up", map[string]string{"host": "b"}, map[string]interface{}{"value": true}, time.Unix(2, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	expected := [][]string{
+		{"TS.CREATE", "cpu:usage:a", "RETENTION", "3600000", "ENCODING", "COMPRESSED", "DUPLICATE_POLICY", "LAST",
+			"LABELS", "__name__", "cpu", "cpu", "total", "host", "a"},
+		{"TS.CREATE", "up:value:b", "RETENTION", "3600000", "ENCODING", "COMPRESSED", "DUPLICATE_POLICY", "LAST",
+			"LABELS", "__name__", "up", "host", "b"},
+	}
+	require.Equal(t, expected, s.received("TS.CREATE"))
+
+	expected = [][]string{
+		{"TS.ADD", "cpu:usage:a", "1000", "42.5"},
+		{"TS.ADD", "cpu:usage:a", "2000", "23"},
+		{"TS.ADD", "up:value:b", "2000", "1"},
+	}
+	require.Equal(t, expected, s.received("TS.ADD"))
+
+	// Known keys are not created again
+	require.NoError(t, plugin.Write(metrics[:1]))
+	require.Len(t, s.received("TS.CREATE"), 2)
+	require.Len(t, s.received("TS.ADD"), 4)
+}
+
+func TestWriteCompaction(t *testing.T) {
+	s := newServer(t)
+	s.keys["cpu_system"] = true
+
+	plugin := newPlugin(s.listener.Addr().String())
+	plugin.FieldLabel = "field"
+	plugin.Compactions = []Compaction{
+		{Aggregation: "avg", Bucket: config.Duration(time.Minute), Retention: config.Duration(24 * time.Hour)},
+		{Aggregation: "MAX", Bucket: config.Duration(time.Hour)},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"user": 1.0, "system": 2.0}, time.Unix(0, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	// Rules are only created for new keys
+	expected := [][]string{
+		{"TS.CREATE", "cpu_system", "LABELS", "field", "system"},
+		{"TS.CREATE", "cpu_user", "LABELS", "field", "user"},
+		{"TS.CREATE", "cpu_user_avg_60000", "RETENTION", "86400000", "LABELS", "aggregation", "avg", "bucket_duration", "60000", "field", "user"},
+		{"TS.CREATE", "cpu_user_max_3600000", "LABELS", "aggregation", "max", "bucket_duration", "3600000", "field", "user"},
+	}
+	require.ElementsMatch(t, expected, s.received("TS.CREATE"))
+
+	expected = [][]string{
+		{"TS.CREATERULE", "cpu_user", "cpu_user_avg_60000", "AGGREGATION", "avg", "60000"},
+		{"TS.CREATERULE", "cpu_user", "cpu_user_max_3600000", "AGGREGATION", "max", "3600000"},
+	}
+	require.Equal(t, expected, s.received("TS.CREATERULE"))
+}
+
+func TestWriteRejectedSamples(t *testing.T) {
+	s := newServer(t)
+	s.failAdd = "cpu_old"
+
+	plugin := newPlugin(s.listener.Addr().String())
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Samples rejected by the server are dropped instead of retrying the batch
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"old": 1.0, "new": 2.0}, time.Unix(0, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.Len(t, s.received("TS.ADD"), 2)
+}
+
+func TestWriteConnectionError(t *testing.T) {
+	s := newServer(t)
+	plugin := newPlugin(s.listener.Addr().String())
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Replace the client to not reuse the pooled connection
+	require.NoError(t, s.listener.Close())
+	require.NoError(t, plugin.client.Close())
+	plugin.client = redis.NewClient(&redis.Options{Addr: s.listener.Addr().String(), MaxRetries: -1})
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+	require.ErrorContains(t, plugin.Write(metrics), "creating keys failed")
+	require.Empty(t, plugin.known)
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*RedisTimeSeries)
+		errmsg string
+	}{
+		{
+			name:   "missing address",
+			modify: func(r *RedisTimeSeries) { r.Address = "" },
+			errmsg: "redis address must be specified",
+		},
+		{
+			name:   "invalid key template",
+			modify: func(r *RedisTimeSeries) { r.KeyTemplate = "{{.Name" },
+			errmsg: "parsing key_template failed",
+		},
+		{
+			name:   "invalid duplicate policy",
+			modify: func(r *RedisTimeSeries) { r.DuplicatePolicy = "newest" },
+			errmsg: `invalid duplicate_policy "newest"`,
+		},
+		{
+			name:   "invalid encoding",
+			modify: func(r *RedisTimeSeries) { r.Encoding = "gorilla" },
+			errmsg: `invalid encoding "gorilla"`,
+		},
+		{
+			name: "invalid aggregation",
+			modify: func(r *RedisTimeSeries) {
+				r.Compactions = []Compaction{{Aggregation: "median", Bucket: config.Duration(time.Minute)}}
+			},
+			errmsg: `invalid compaction aggregation "median"`,
+		},
+		{
+			name: "missing bucket",
+			modify: func(r *RedisTimeSeries) {
+				r.Compactions = []Compaction{{Aggregation: "avg"}}
+			},
+			errmsg: `compaction bucket of "avg" must be at least 1ms`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newPlugin("127.0.0.1:6379")
+			tt.modify(plugin)
+			require.ErrorContains(t, plugin.Init(), tt.errmsg)
+		})
+	}
+}
+
 func TestConnectAndWrite(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
 	}
 
 	address := testutil.GetLocalHost() + ":6379"
-	redis := &RedisTimeSeries{
-		Address: address,
-	}
+	redis := newPlugin(address)
+	require.NoError(t, redis.Init())
 
 	// Verify that we can connect to the RedisTimeSeries server
 	err := redis.Connect()
@@ -43,11 +332,18 @@ func TestConnectAndWriteIntegration(t *testing.T) {
 	defer func() {
 		require.NoError(t, container.Terminate(), "terminating container failed")
 	}()
-	redis := &RedisTimeSeries{
-		Address: fmt.Sprintf("%s:%s", container.Address, container.Ports[servicePort]),
-	}
+	redis := newPlugin(fmt.Sprintf("%s:%s", container.Address, container.Ports[servicePort]))
+	redis.KeyTemplate = `{{.Name}}_{{.Field}}_{{.Tag "tag1"}}`
+	redis.Compactions = []Compaction{{Aggregation: "avg", Bucket: config.Duration(time.Minute)}}
+	require.NoError(t, redis.Init())
 	// Verify that we can connect to the RedisTimeSeries server
 	require.NoError(t, redis.Connect())
+	defer redis.Close()
 	// Verify that we can successfully write data to the RedisTimeSeries server
 	require.NoError(t, redis.Write(testutil.MockMetrics()))
+
+	// Verify that the series can be queried by its labels
+	result, err := redis.client.Do("TS.MRANGE", "-", "+", "FILTER", "tag1=value1").Result()
+	require.NoError(t, err)
+	require.Len(t, result, 2)
 }
@@ -0,0 +1,381 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package statsd
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// DO NOT REMOVE THE NEXT TWO LINES! This is required to embed the sampleConfig data.
+//go:embed sample.conf
+var sampleConfig string
+
+// Default payload sizes recommended for DogStatsD over UDP respectively unix
+// domain sockets, the latter is also used as write size for stream sockets
+const (
+	defaultUDPPacketSize  = 1432
+	defaultUnixPacketSize = 8192
+)
+
+var (
+	nameReplacer     = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+	tagValueReplacer = strings.NewReplacer("|", "_", ",", "_", "\n", "_")
+)
+
+type StatsD struct {
+	Address           string            `toml:"address"`
+	Protocol          string            `toml:"protocol"`
+	Prefix            string            `toml:"prefix"`
+	Separator         string            `toml:"separator"`
+	MaxPacketSize     int               `toml:"max_packet_size"`
+	CounterDelta      bool              `toml:"counter_delta"`
+	CounterExpiration config.Duration   `toml:"counter_expiration"`
+	TypeMapping       map[string]string `toml:"type_mapping"`
+	Timeout           config.Duration   `toml:"timeout"`
+	Log               telegraf.Logger   `toml:"-"`
+
+	network  string
+	address  string
+	stream   bool
+	types    map[telegraf.ValueType]string
+	counters map[counterKey]counterValue
+	conn     net.Conn
+
+	// Packets sent by the last write if it failed
+	sent []sentPacket
+}
+
+// sentPacket is a packet together with the metrics it was serialized from
+type sentPacket struct {
+	metrics []telegraf.Metric
+	data    []byte
+}
+
+// equal reports whether the packet was built from the same metrics with the
+// same content. The metrics are compared by identity as StatsD lines have no
+// timestamp, so equal lines of different metrics must not be skipped.
+func (p *sentPacket) equal(metrics []telegraf.Metric, data []byte) bool {
+	if len(p.metrics) != len(metrics) || !bytes.Equal(p.data, data) {
+		return false
+	}
+	for i, m := range metrics {
+		if p.metrics[i] != m {
+			return false
+		}
+	}
+	return true
+}
+
+type counterKey struct {
+	id    uint64
+	field string
+}
+
+type counterValue struct {
+	value float64
+	seen  time.Time
+}
+
+func (*StatsD) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *StatsD) Init() error {
+	parts := strings.SplitN(s.Address, "://", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid address %q", s.Address)
+	}
+	s.network, s.address = parts[0], parts[1]
+
+	switch s.network {
+	case "udp", "udp4", "udp6":
+		if s.MaxPacketSize == 0 {
+			s.MaxPacketSize = defaultUDPPacketSize
+		}
+	case "unixgram":
+		if s.MaxPacketSize == 0 {
+			s.MaxPacketSize = defaultUnixPacketSize
+		}
+	case "tcp", "tcp4", "tcp6", "unix":
+		s.stream = true
+		if s.MaxPacketSize == 0 {
+			s.MaxPacketSize = defaultUnixPacketSize
+		}
+	default:
+		return fmt.Errorf("unsupported network %q", s.network)
+	}
+
+	// The types "h" and "d" are DogStatsD extensions and signed gauges are
+	// interpreted as relative changes in plain StatsD
+	var allowed map[string]bool
+	switch s.Protocol {
+	case "", "dogstatsd":
+		s.Protocol = "dogstatsd"
+		allowed = map[string]bool{"c": true, "g": true, "ms": true, "h": true, "d": true}
+		s.types = map[telegraf.ValueType]string{
+			telegraf.Counter:   "c",
+			telegraf.Gauge:     "g",
+			telegraf.Untyped:   "g",
+			telegraf.Histogram: "h",
+			telegraf.Summary:   "h",
+		}
+	case "statsd":
+		allowed = map[string]bool{"c": true, "g": true, "ms": true}
+		s.types = map[telegraf.ValueType]string{
+			telegraf.Counter:   "c",
+			telegraf.Gauge:     "g",
+			telegraf.Untyped:   "g",
+			telegraf.Histogram: "ms",
+			telegraf.Summary:   "ms",
+		}
+	default:
+		return fmt.Errorf("invalid protocol %q", s.Protocol)
+	}
+
+	valueTypes := map[string]telegraf.ValueType{
+		"counter":   telegraf.Counter,
+		"gauge":     telegraf.Gauge,
+		"untyped":   telegraf.Untyped,
+		"histogram": telegraf.Histogram,
+		"summary":   telegraf.Summary,
+	}
+	for name, statsdType := range s.TypeMapping {
+		vt, found := valueTypes[name]
+		if !found {
+			return fmt.Errorf("invalid value type %q in type mapping", name)
+		}
+		if !allowed[statsdType] {
+			return fmt.Errorf("invalid type %q for %s protocol in type mapping", statsdType, s.Protocol)
+		}
+		s.types[vt] = statsdType
+	}
+
+	if s.Separator == "" {
+		s.Separator = "."
+	}
+	if s.CounterExpiration <= 0 {
+		s.CounterExpiration = config.Duration(time.Hour)
+	}
+	s.counters = make(map[counterKey]counterValue)
+
+	return nil
+}
+
+func (s *StatsD) Connect() error {
+	conn, err := net.DialTimeout(s.network, s.address, time.Duration(s.Timeout))
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *StatsD) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// Write sends the metrics in packets of at most the maximum packet size.
+// Lines exceeding the packet size are sent in a packet of their own.
+func (s *StatsD) Write(metrics []telegraf.Metric) error {
+	if s.conn == nil {
+		if err := s.Connect(); err != nil {
+			return err
+		}
+	}
+
+	// The counter values are only remembered if all metrics were sent, so
+	// the deltas are computed again if the metrics are retried
+	counters := make(map[counterKey]float64)
+
+	// StatsD counters are increments, so the packets sent before a write
+	// failed are skipped if the same metrics are retried
+	retried := s.sent
+	s.sent = nil
+
+	var packet bytes.Buffer
+	var packetMetrics []telegraf.Metric
+	flush := func() error {
+		buf := packet.Bytes()
+		if len(retried) > 0 && retried[0].equal(packetMetrics, buf) {
+			retried = retried[1:]
+		} else {
+			retried = nil
+			if err := s.send(buf); err != nil {
+				return err
+			}
+		}
+		s.sent = append(s.sent, sentPacket{metrics: packetMetrics, data: append([]byte(nil), buf...)})
+		packet.Reset()
+		packetMetrics = nil
+		return nil
+	}
+
+	for _, m := range metrics {
+		for _, line := range s.serialize(m, counters) {
+			if packet.Len() > 0 && packet.Len()+len(line)+1 > s.MaxPacketSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+			if len(packetMetrics) == 0 || packetMetrics[len(packetMetrics)-1] != m {
+				packetMetrics = append(packetMetrics, m)
+			}
+		}
+	}
+	if packet.Len() > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	s.sent = nil
+
+	// Forget the counters not seen for a while to not grow without bounds,
+	// their next value is treated as the first one of the series
+	now := time.Now()
+	for k, v := range counters {
+		s.counters[k] = counterValue{value: v, seen: now}
+	}
+	for k, v := range s.counters {
+		if now.Sub(v.seen) > time.Duration(s.CounterExpiration) {
+			delete(s.counters, k)
+		}
+	}
+	return nil
+}
+
+func (s *StatsD) send(packet []byte) error {
+	// Stream sockets require every line to be terminated
+	if s.stream {
+		packet = append(packet, '\n')
+	}
+
+	if s.Timeout > 0 {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(time.Duration(s.Timeout))); err != nil {
+			return err
+		}
+	}
+	if _, err := s.conn.Write(packet); err != nil {
+		// Reconnect on the next write
+		s.Close() //nolint:revive // There is another error which will be returned here
+		return fmt.Errorf("writing to %s failed: %v", s.Address, err)
+	}
+	return nil
+}
+
+// serialize converts the metric into StatsD lines, one per numeric field.
+// The field name is appended to the metric name unless it is "value".
+func (s *StatsD) serialize(m telegraf.Metric, counters map[counterKey]float64) []string {
+	statsdType := s.types[m.Type()]
+	tags := s.tags(m)
+
+	lines := make([]string, 0, len(m.FieldList()))
+	for _, field := range m.FieldList() {
+		value, ok := toFloat(field.Value)
+		if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+
+		if statsdType == "c" && m.Type() == telegraf.Counter && s.CounterDelta {
+			value, ok = s.delta(counters, m, field.Key, value)
+			if !ok {
+				continue
+			}
+		}
+
+		name := s.Prefix + m.Name()
+		if field.Key != "value" {
+			name += s.Separator + field.Key
+		}
+		name = nameReplacer.Replace(name)
+
+		v := strconv.FormatFloat(value, 'f', -1, 64)
+
+		// A signed gauge is a relative change in plain StatsD so negative
+		// values need to be reset to zero first
+		if statsdType == "g" && value < 0 && s.Protocol == "statsd" {
+			lines = append(lines, name+":0|g")
+		}
+		lines = append(lines, name+":"+v+"|"+statsdType+tags)
+	}
+	return lines
+}
+
+// tags returns the DogStatsD tag suffix of the line. Plain StatsD does not
+// support tags.
+func (s *StatsD) tags(m telegraf.Metric) string {
+	if s.Protocol != "dogstatsd" || len(m.TagList()) == 0 {
+		return ""
+	}
+
+	tags := make([]string, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		tags = append(tags, nameReplacer.Replace(tag.Key)+":"+tagValueReplacer.Replace(tag.Value))
+	}
+	return "|#" + strings.Join(tags, ",")
+}
+
+// delta returns the increase of a cumulative counter since its last value
+// in the current or the previous writes and records the value in counters.
+// Nothing is sent for the first value of a series, while a decreasing value
+// is treated as a reset of the counter.
+func (s *StatsD) delta(counters map[counterKey]float64, m telegraf.Metric, field string, value float64) (float64, bool) {
+	key := counterKey{id: m.HashID(), field: field}
+	last, found := counters[key]
+	if !found {
+		var previous counterValue
+		previous, found = s.counters[key]
+		last = previous.value
+	}
+	counters[key] = value
+	if !found {
+		return 0, false
+	}
+	if value < last {
+		return value, true
+	}
+	return value - last, true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func init() {
+	outputs.Add("statsd", func() telegraf.Output {
+		return &StatsD{
+			Timeout: config.Duration(5 * time.Second),
+		}
+	})
+}
@@ -0,0 +1,388 @@
+package statsd
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSerialize(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		plugin   *StatsD
+		metric   telegraf.Metric
+		expected []string
+	}{
+		{
+			name:   "gauge with tags",
+			plugin: &StatsD{},
+			metric: metric.New(
+				"cpu",
+				map[string]string{"host": "server01", "cpu": "cpu0"},
+				map[string]interface{}{"usage_idle": 98.5, "usage_user": int64(1)},
+				now,
+				telegraf.Gauge,
+			),
+			expected: []string{
+				"cpu.usage_idle:98.5|g|#cpu:cpu0,host:server01",
+				"cpu.usage_user:1|g|#cpu:cpu0,host:server01",
+			},
+		},
+		{
+			name:   "value field and prefix",
+			plugin: &StatsD{Prefix: "telegraf.", Separator: "_"},
+			metric: metric.New(
+				"requests",
+				map[string]string{},
+				map[string]interface{}{"value": uint64(42), "failed": true, "state": "ok"},
+				now,
+				telegraf.Counter,
+			),
+			expected: []string{
+				"telegraf.requests_failed:1|c",
+				"telegraf.requests:42|c",
+			},
+		},
+		{
+			name:   "histogram and untyped",
+			plugin: &StatsD{},
+			metric: metric.New(
+				"latency",
+				map[string]string{"path": "/a|b,c"},
+				map[string]interface{}{"sum": 1.5},
+				now,
+				telegraf.Histogram,
+			),
+			expected: []string{"latency.sum:1.5|h|#path:/a_b_c"},
+		},
+		{
+			name:   "statsd without tags",
+			plugin: &StatsD{Protocol: "statsd"},
+			metric: metric.New(
+				"latency",
+				map[string]string{"host": "server01"},
+				map[string]interface{}{"p99": 0.25},
+				now,
+				telegraf.Summary,
+			),
+			expected: []string{"latency.p99:0.25|ms"},
+		},
+		{
+			name:   "statsd negative gauge",
+			plugin: &StatsD{Protocol: "statsd"},
+			metric: metric.New(
+				"temperature",
+				map[string]string{},
+				map[string]interface{}{"value": -3.5},
+				now,
+			),
+			expected: []string{"temperature:0|g", "temperature:-3.5|g"},
+		},
+		{
+			name:   "type mapping and sanitized names",
+			plugin: &StatsD{TypeMapping: map[string]string{"untyped": "d"}},
+			metric: metric.New(
+				"my metric",
+				map[string]string{"a:b": "c"},
+				map[string]interface{}{"value": 1.0},
+				now,
+			),
+			expected: []string{"my_metric:1|d|#a_b:c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Address = "udp://127.0.0.1:8125"
+			require.NoError(t, tt.plugin.Init())
+			require.ElementsMatch(t, tt.expected, tt.plugin.serialize(tt.metric, make(map[counterKey]float64)))
+		})
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	plugin := &StatsD{
+		Address:      "udp://" + listener.LocalAddr().String(),
+		CounterDelta: true,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	counter := func(value int64) telegraf.Metric {
+		return metric.New(
+			"requests",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"total": value},
+			time.Now(),
+			telegraf.Counter,
+		)
+	}
+
+	// The first value is only recorded
+	require.NoError(t, plugin.Write([]telegraf.Metric{counter(10)}))
+	require.NoError(t, plugin.Write([]telegraf.Metric{counter(15), counter(18)}))
+	// Decreasing values are a reset of the counter
+	require.NoError(t, plugin.Write([]telegraf.Metric{counter(2)}))
+
+	require.Equal(t, "requests.total:5|c|#host:server01\nrequests.total:3|c|#host:server01", readPacket(t, listener))
+	require.Equal(t, "requests.total:2|c|#host:server01", readPacket(t, listener))
+}
+
+func TestCounterDeltaRetry(t *testing.T) {
+	plugin := &StatsD{
+		Address:      "udp://127.0.0.1:8125",
+		CounterDelta: true,
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New("requests", map[string]string{}, map[string]interface{}{"value": 10}, time.Now(), telegraf.Counter)
+	plugin.counters[counterKey{id: m.HashID(), field: "value"}] = counterValue{value: 4, seen: time.Now()}
+
+	// Without committing, a retry of the same metrics yields the same delta
+	require.Equal(t, []string{"requests:6|c"}, plugin.serialize(m, make(map[counterKey]float64)))
+	require.Equal(t, []string{"requests:6|c"}, plugin.serialize(m, make(map[counterKey]float64)))
+}
+
+type fakeConn struct {
+	net.Conn
+	packets []string
+	fail    int
+}
+
+// Write fails once after the given number of packets
+func (c *fakeConn) Write(b []byte) (int, error) {
+	if c.fail == len(c.packets) {
+		c.fail = -1
+		return 0, errors.New("connection refused")
+	}
+	c.packets = append(c.packets, string(b))
+	return len(b), nil
+}
+
+func (*fakeConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+func (*fakeConn) Close() error {
+	return nil
+}
+
+func TestRetrySkipsSentPackets(t *testing.T) {
+	plugin := &StatsD{
+		Address:       "udp://127.0.0.1:8125",
+		MaxPacketSize: 20,
+	}
+	require.NoError(t, plugin.Init())
+
+	metrics := make([]telegraf.Metric, 0, 3)
+	for i := 0; i < 3; i++ {
+		metrics = append(metrics, metric.New("hits", map[string]string{}, map[string]interface{}{"value": i}, time.Now(), telegraf.Counter))
+	}
+
+	// The second packet fails so the first one must not be sent again
+	conn := &fakeConn{fail: 1}
+	plugin.conn = conn
+	require.ErrorContains(t, plugin.Write(metrics), "connection refused")
+	plugin.conn = conn
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, []string{"hits:0|c\nhits:1|c", "hits:2|c"}, conn.packets)
+
+	// Successfully written metrics are sent again
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, []string{"hits:0|c\nhits:1|c", "hits:2|c", "hits:0|c\nhits:1|c", "hits:2|c"}, conn.packets)
+}
+
+func TestRetryOfDifferentBatch(t *testing.T) {
+	plugin := &StatsD{
+		Address:       "udp://127.0.0.1:8125",
+		MaxPacketSize: 20,
+	}
+	require.NoError(t, plugin.Init())
+
+	gauges := func() []telegraf.Metric {
+		metrics := make([]telegraf.Metric, 0, 3)
+		for i := 0; i < 3; i++ {
+			metrics = append(metrics, metric.New("load", map[string]string{}, map[string]interface{}{"value": i}, time.Now(), telegraf.Gauge))
+		}
+		return metrics
+	}
+
+	// The next batch contains new metrics serialized to the same lines, so
+	// none of its packets must be skipped
+	conn := &fakeConn{fail: 1}
+	plugin.conn = conn
+	require.ErrorContains(t, plugin.Write(gauges()), "connection refused")
+	plugin.conn = conn
+	require.NoError(t, plugin.Write(gauges()))
+	require.Equal(t, []string{"load:0|g\nload:1|g", "load:0|g\nload:1|g", "load:2|g"}, conn.packets)
+}
+
+func TestCounterExpiration(t *testing.T) {
+	plugin := &StatsD{
+		Address:           "udp://127.0.0.1:8125",
+		CounterDelta:      true,
+		CounterExpiration: config.Duration(time.Minute),
+	}
+	require.NoError(t, plugin.Init())
+	conn := &fakeConn{fail: -1}
+	plugin.conn = conn
+
+	stale := metric.New("stale", map[string]string{}, map[string]interface{}{"value": 1}, time.Now(), telegraf.Counter)
+	plugin.counters[counterKey{id: stale.HashID(), field: "value"}] = counterValue{value: 1, seen: time.Now().Add(-time.Hour)}
+
+	m := metric.New("requests", map[string]string{}, map[string]interface{}{"value": 10}, time.Now(), telegraf.Counter)
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Len(t, plugin.counters, 1)
+	require.Contains(t, plugin.counters, counterKey{id: m.HashID(), field: "value"})
+}
+
+func TestBatching(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	plugin := &StatsD{
+		Address:       "udp://" + listener.LocalAddr().String(),
+		MaxPacketSize: 40,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := make([]telegraf.Metric, 0, 5)
+	for i := 0; i < 5; i++ {
+		metrics = append(metrics, metric.New(
+			"metric",
+			map[string]string{},
+			map[string]interface{}{"value": i},
+			time.Now(),
+			telegraf.Gauge,
+		))
+	}
+	metrics = append(metrics, metric.New(
+		"a_very_long_metric_name_exceeding_the_packet_size",
+		map[string]string{},
+		map[string]interface{}{"value": 1},
+		time.Now(),
+		telegraf.Gauge,
+	))
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Equal(t, "metric:0|g\nmetric:1|g\nmetric:2|g", readPacket(t, listener))
+	require.Equal(t, "metric:3|g\nmetric:4|g", readPacket(t, listener))
+	require.Equal(t, "a_very_long_metric_name_exceeding_the_packet_size:1|g", readPacket(t, listener))
+}
+
+func TestTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	plugin := &StatsD{Address: "tcp://" + listener.Addr().String()}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("a", map[string]string{}, map[string]interface{}{"value": 1}, time.Now(), telegraf.Gauge),
+		metric.New("b", map[string]string{}, map[string]interface{}{"value": 2}, time.Now(), telegraf.Gauge),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	reader := bufio.NewReader(conn)
+	for _, expected := range []string{"a:1|g\n", "b:2|g\n"} {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, expected, line)
+	}
+}
+
+func TestUnixgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping on Windows, as unixgram sockets are not supported")
+	}
+
+	sock := testutil.TempSocket(t)
+	listener, err := net.ListenPacket("unixgram", sock)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	plugin := &StatsD{Address: "unixgram://" + sock}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, defaultUnixPacketSize, plugin.MaxPacketSize)
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	m := metric.New("a", map[string]string{"host": "server01"}, map[string]interface{}{"value": 1}, time.Now())
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Equal(t, "a:1|g|#host:server01", readPacket(t, listener))
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *StatsD
+		expected string
+	}{
+		{
+			name:     "missing network",
+			plugin:   &StatsD{Address: "127.0.0.1:8125"},
+			expected: `invalid address "127.0.0.1:8125"`,
+		},
+		{
+			name:     "unsupported network",
+			plugin:   &StatsD{Address: "ip://127.0.0.1"},
+			expected: `unsupported network "ip"`,
+		},
+		{
+			name:     "invalid protocol",
+			plugin:   &StatsD{Address: "udp://127.0.0.1:8125", Protocol: "graphite"},
+			expected: `invalid protocol "graphite"`,
+		},
+		{
+			name:     "invalid value type",
+			plugin:   &StatsD{Address: "udp://127.0.0.1:8125", TypeMapping: map[string]string{"timer": "ms"}},
+			expected: `invalid value type "timer"`,
+		},
+		{
+			name:     "dogstatsd type with statsd",
+			plugin:   &StatsD{Address: "udp://127.0.0.1:8125", Protocol: "statsd", TypeMapping: map[string]string{"histogram": "h"}},
+			expected: `invalid type "h" for statsd protocol`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func readPacket(t *testing.T, listener net.PacketConn) string {
+	buf := make([]byte, 65536)
+	require.NoError(t, listener.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := listener.ReadFrom(buf)
+	require.NoError(t, err)
+	return strings.TrimSpace(string(buf[:n]))
+}
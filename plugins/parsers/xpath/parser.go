@@ -10,6 +10,7 @@ import (
 	"github.com/antchfx/jsonquery"
 	path "github.com/antchfx/xpath"
 	"github.com/doclambda/protobufquery"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
@@ -42,6 +43,9 @@ type Parser struct {
 	DefaultTags         map[string]string `toml:"-"`
 	Log                 telegraf.Logger   `toml:"-"`
 
+	// Message descriptor to use instead of the protocol-buffer definition file
+	ProtobufMessageDescriptor protoreflect.MessageDescriptor `toml:"-"`
+
 	// Required for backward compatibility
 	ConfigsXML     []xpath.Config `toml:"xml" deprecated:"1.23.1;use 'xpath' instead"`
 	ConfigsJSON    []xpath.Config `toml:"xpath_json"`
@@ -94,6 +98,7 @@ func (p *Parser) Init() error {
 			MessageDefinition: p.ProtobufMessageDef,
 			MessageType:       p.ProtobufMessageType,
 			ImportPaths:       p.ProtobufImportPaths,
+			Descriptor:        p.ProtobufMessageDescriptor,
 			Log:               p.Log,
 		}
 		if err := pbdoc.Init(); err != nil {
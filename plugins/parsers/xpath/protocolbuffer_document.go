@@ -23,11 +23,19 @@ type protobufDocument struct {
 	MessageDefinition string
 	MessageType       string
 	ImportPaths       []string
+	Descriptor        protoreflect.MessageDescriptor
 	Log               telegraf.Logger
 	msg               *dynamicpb.Message
 }
 
 func (d *protobufDocument) Init() error {
+	// Use the descriptor if provided by the caller, e.g. after resolving it
+	// via gRPC reflection
+	if d.Descriptor != nil {
+		d.msg = dynamicpb.NewMessage(d.Descriptor)
+		return nil
+	}
+
 	// Check the message definition and type
 	if d.MessageDefinition == "" {
 		return fmt.Errorf("protocol-buffer message-definition not set")
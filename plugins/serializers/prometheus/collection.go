@@ -9,6 +9,7 @@ import (
 
 	dto "github.com/prometheus/client_model/go"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/influxdata/telegraf"
 )
@@ -29,6 +30,18 @@ type Metric struct {
 	Scaler    *Scaler
 	Histogram *Histogram
 	Summary   *Summary
+	// Created is the time the series was first added
+	Created  time.Time
+	Exemplar *Exemplar
+	// Stale metrics are expired and exported with the staleness marker
+	Stale     bool
+	StaleTime time.Time
+}
+
+type Exemplar struct {
+	Labels []LabelPair
+	Value  float64
+	Time   time.Time
 }
 
 type LabelPair struct {
@@ -101,14 +114,19 @@ type Entry struct {
 }
 
 type Collection struct {
-	Entries map[MetricFamily]Entry
-	config  FormatConfig
+	Entries      map[MetricFamily]Entry
+	config       FormatConfig
+	exemplarTags map[string]bool
 }
 
 func NewCollection(config FormatConfig) *Collection {
 	cache := &Collection{
-		Entries: make(map[MetricFamily]Entry),
-		config:  config,
+		Entries:      make(map[MetricFamily]Entry),
+		config:       config,
+		exemplarTags: make(map[string]bool, len(config.ExemplarTags)),
+	}
+	for _, tag := range config.ExemplarTags {
+		cache.exemplarTags[tag] = true
 	}
 	return cache
 }
@@ -137,6 +155,11 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 			}
 		}
 
+		// Exemplar tags would create a new series for each value
+		if c.exemplarTags[tag.Key] {
+			continue
+		}
+
 		name, ok := SanitizeLabelName(tag.Key)
 		if !ok {
 			continue
@@ -180,6 +203,26 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 	return labels
 }
 
+// createExemplar returns the exemplar with the exemplar tags of the metric
+// or nil if the metric has none of the tags
+func (c *Collection) createExemplar(metric telegraf.Metric, value float64) *Exemplar {
+	var labels []LabelPair
+	for _, tag := range metric.TagList() {
+		if !c.exemplarTags[tag.Key] {
+			continue
+		}
+		name, ok := SanitizeLabelName(tag.Key)
+		if !ok {
+			continue
+		}
+		labels = append(labels, LabelPair{Name: name, Value: tag.Value})
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return &Exemplar{Labels: labels, Value: value, Time: metric.Time()}
+}
+
 func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 	labels := c.createLabels(metric)
 	for _, field := range metric.FieldList() {
@@ -206,7 +249,11 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 		metricKey := MakeMetricKey(labels)
 
 		m, ok := entry.Metrics[metricKey]
-		if ok {
+		if ok && m.Stale {
+			// The series starts again after it was expired
+			m = nil
+		}
+		if m != nil {
 			// A batch of metrics can contain multiple values for a single
 			// Prometheus sample.  If this metric is older than the existing
 			// sample then we can skip over it.
@@ -226,11 +273,25 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 				continue
 			}
 
+			created := now
+			var exemplar *Exemplar
+			if m != nil {
+				created = m.Created
+				exemplar = m.Exemplar
+			}
+			if metric.Type() == telegraf.Counter && len(c.exemplarTags) > 0 {
+				if e := c.createExemplar(metric, value); e != nil {
+					exemplar = e
+				}
+			}
+
 			m = &Metric{
-				Labels:  labels,
-				Time:    metric.Time(),
-				AddTime: now,
-				Scaler:  &Scaler{Value: value},
+				Labels:   labels,
+				Time:     metric.Time(),
+				AddTime:  now,
+				Scaler:   &Scaler{Value: value},
+				Created:  created,
+				Exemplar: exemplar,
 			}
 
 			entry.Metrics[metricKey] = m
@@ -241,6 +302,7 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 					Time:      metric.Time(),
 					AddTime:   now,
 					Histogram: &Histogram{},
+					Created:   now,
 				}
 			} else {
 				m.Time = metric.Time()
@@ -292,6 +354,7 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 					Time:    metric.Time(),
 					AddTime: now,
 					Summary: &Summary{},
+					Created: now,
 				}
 			} else {
 				m.Time = metric.Time()
@@ -342,11 +405,23 @@ func (c *Collection) Expire(now time.Time, age time.Duration) {
 	expireTime := now.Add(-age)
 	for _, entry := range c.Entries {
 		for key, metric := range entry.Metrics {
-			if metric.AddTime.Before(expireTime) {
-				delete(entry.Metrics, key)
-				if len(entry.Metrics) == 0 {
-					delete(c.Entries, entry.Family)
+			switch {
+			case metric.Stale:
+				// Keep the marker for another period so it is scraped
+				if !metric.StaleTime.Before(expireTime) {
+					continue
 				}
+			case !metric.AddTime.Before(expireTime):
+				continue
+			case c.config.StalenessMarkers && metric.Scaler != nil:
+				metric.Stale = true
+				metric.StaleTime = now
+				continue
+			}
+
+			delete(entry.Metrics, key)
+			if len(entry.Metrics) == 0 {
+				delete(c.Entries, entry.Family)
 			}
 		}
 	}
@@ -407,7 +482,18 @@ func (c *Collection) GetMetrics(entry Entry, order MetricSortOrder) []*Metric {
 }
 
 func (c *Collection) GetProto() []*dto.MetricFamily {
-	result := make([]*dto.MetricFamily, 0, len(c.Entries))
+	families := c.families()
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		result = append(result, family.Family)
+	}
+	return result
+}
+
+// families returns the metric families along with the data only used by the
+// OpenMetrics format
+func (c *Collection) families() []OpenMetricsFamily {
+	result := make([]OpenMetricsFamily, 0, len(c.Entries))
 
 	for _, entry := range c.GetEntries(c.config.MetricSortOrder) {
 		mf := &dto.MetricFamily{
@@ -419,69 +505,112 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 			mf.Help = proto.String(helpString)
 		}
 
-		for _, metric := range c.GetMetrics(entry, c.config.MetricSortOrder) {
-			l := make([]*dto.LabelPair, 0, len(metric.Labels))
-			for _, label := range metric.Labels {
-				l = append(l, &dto.LabelPair{
-					Name:  proto.String(label.Name),
-					Value: proto.String(label.Value),
-				})
-			}
+		metrics := c.GetMetrics(entry, c.config.MetricSortOrder)
+		created := make([]time.Time, 0, len(metrics))
+		for _, metric := range metrics {
+			mf.Metric = append(mf.Metric, c.metricProto(entry.Family.Type, metric))
+			created = append(created, metric.Created)
+		}
 
-			m := &dto.Metric{
-				Label: l,
-			}
+		if len(mf.Metric) != 0 {
+			result = append(result, OpenMetricsFamily{
+				Family:  mf,
+				Unit:    metricUnit(entry.Family.Name),
+				Created: created,
+			})
+		}
+	}
 
-			if c.config.TimestampExport == ExportTimestamp {
-				m.TimestampMs = proto.Int64(metric.Time.UnixNano() / int64(time.Millisecond))
-			}
+	return result
+}
 
-			switch entry.Family.Type {
-			case telegraf.Gauge:
-				m.Gauge = &dto.Gauge{Value: proto.Float64(metric.Scaler.Value)}
-			case telegraf.Counter:
-				m.Counter = &dto.Counter{Value: proto.Float64(metric.Scaler.Value)}
-			case telegraf.Untyped:
-				m.Untyped = &dto.Untyped{Value: proto.Float64(metric.Scaler.Value)}
-			case telegraf.Histogram:
-				buckets := make([]*dto.Bucket, 0, len(metric.Histogram.Buckets))
-				for _, bucket := range metric.Histogram.Buckets {
-					buckets = append(buckets, &dto.Bucket{
-						UpperBound:      proto.Float64(bucket.Bound),
-						CumulativeCount: proto.Uint64(bucket.Count),
-					})
-				}
+func (c *Collection) metricProto(valueType telegraf.ValueType, metric *Metric) *dto.Metric {
+	l := make([]*dto.LabelPair, 0, len(metric.Labels))
+	for _, label := range metric.Labels {
+		l = append(l, &dto.LabelPair{
+			Name:  proto.String(label.Name),
+			Value: proto.String(label.Value),
+		})
+	}
 
-				m.Histogram = &dto.Histogram{
-					Bucket:      buckets,
-					SampleCount: proto.Uint64(metric.Histogram.Count),
-					SampleSum:   proto.Float64(metric.Histogram.Sum),
-				}
-			case telegraf.Summary:
-				quantiles := make([]*dto.Quantile, 0, len(metric.Summary.Quantiles))
-				for _, quantile := range metric.Summary.Quantiles {
-					quantiles = append(quantiles, &dto.Quantile{
-						Quantile: proto.Float64(quantile.Quantile),
-						Value:    proto.Float64(quantile.Value),
-					})
-				}
+	m := &dto.Metric{
+		Label: l,
+	}
 
-				m.Summary = &dto.Summary{
-					Quantile:    quantiles,
-					SampleCount: proto.Uint64(metric.Summary.Count),
-					SampleSum:   proto.Float64(metric.Summary.Sum),
-				}
-			default:
-				panic("unknown telegraf.ValueType")
+	if c.config.TimestampExport == ExportTimestamp {
+		t := metric.Time
+		if metric.Stale {
+			t = metric.StaleTime
+		}
+		m.TimestampMs = proto.Int64(t.UnixNano() / int64(time.Millisecond))
+	}
+
+	switch valueType {
+	case telegraf.Gauge, telegraf.Counter, telegraf.Untyped:
+		value := metric.Scaler.Value
+		if metric.Stale {
+			value = staleNaN
+		}
+		switch valueType {
+		case telegraf.Gauge:
+			m.Gauge = &dto.Gauge{Value: proto.Float64(value)}
+		case telegraf.Counter:
+			m.Counter = &dto.Counter{Value: proto.Float64(value)}
+			if metric.Exemplar != nil && !metric.Stale {
+				m.Counter.Exemplar = exemplarProto(metric.Exemplar)
 			}
+		default:
+			m.Untyped = &dto.Untyped{Value: proto.Float64(value)}
+		}
+	case telegraf.Histogram:
+		buckets := make([]*dto.Bucket, 0, len(metric.Histogram.Buckets))
+		for _, bucket := range metric.Histogram.Buckets {
+			buckets = append(buckets, &dto.Bucket{
+				UpperBound:      proto.Float64(bucket.Bound),
+				CumulativeCount: proto.Uint64(bucket.Count),
+			})
+		}
 
-			mf.Metric = append(mf.Metric, m)
+		m.Histogram = &dto.Histogram{
+			Bucket:      buckets,
+			SampleCount: proto.Uint64(metric.Histogram.Count),
+			SampleSum:   proto.Float64(metric.Histogram.Sum),
+		}
+		if c.config.NativeHistograms {
+			setNativeHistogram(m.Histogram, metric.Histogram, c.config.NativeHistogramSchema)
+		}
+	case telegraf.Summary:
+		quantiles := make([]*dto.Quantile, 0, len(metric.Summary.Quantiles))
+		for _, quantile := range metric.Summary.Quantiles {
+			quantiles = append(quantiles, &dto.Quantile{
+				Quantile: proto.Float64(quantile.Quantile),
+				Value:    proto.Float64(quantile.Value),
+			})
 		}
 
-		if len(mf.Metric) != 0 {
-			result = append(result, mf)
+		m.Summary = &dto.Summary{
+			Quantile:    quantiles,
+			SampleCount: proto.Uint64(metric.Summary.Count),
+			SampleSum:   proto.Float64(metric.Summary.Sum),
 		}
+	default:
+		panic("unknown telegraf.ValueType")
 	}
 
-	return result
+	return m
+}
+
+func exemplarProto(e *Exemplar) *dto.Exemplar {
+	labels := make([]*dto.LabelPair, 0, len(e.Labels))
+	for _, label := range e.Labels {
+		labels = append(labels, &dto.LabelPair{
+			Name:  proto.String(label.Name),
+			Value: proto.String(label.Value),
+		})
+	}
+	return &dto.Exemplar{
+		Label:     labels,
+		Value:     proto.Float64(e.Value),
+		Timestamp: timestamppb.New(e.Time),
+	}
 }
@@ -10,6 +10,7 @@ import (
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Input struct {
@@ -842,3 +843,160 @@ func TestExportTimestamps(t *testing.T) {
 		})
 	}
 }
+
+func TestExemplars(t *testing.T) {
+	c := NewCollection(FormatConfig{ExemplarTags: []string{"trace_id"}})
+	c.Add(testutil.MustMetric(
+		"http",
+		map[string]string{"host": "example.org", "trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"},
+		map[string]interface{}{"requests": 42.0},
+		time.Unix(10, 0),
+		telegraf.Counter,
+	), time.Unix(10, 0))
+	// A metric without trace keeps the last exemplar
+	c.Add(testutil.MustMetric(
+		"http",
+		map[string]string{"host": "example.org"},
+		map[string]interface{}{"requests": 43.0},
+		time.Unix(20, 0),
+		telegraf.Counter,
+	), time.Unix(20, 0))
+
+	expected := []*dto.MetricFamily{
+		{
+			Name: proto.String("http_requests"),
+			Help: proto.String(helpString),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{
+						{Name: proto.String("host"), Value: proto.String("example.org")},
+					},
+					Counter: &dto.Counter{
+						Value: proto.Float64(43.0),
+						Exemplar: &dto.Exemplar{
+							Label: []*dto.LabelPair{
+								{Name: proto.String("trace_id"), Value: proto.String("4bf92f3577b34da6a3ce929d0e0e4736")},
+							},
+							Value:     proto.Float64(42.0),
+							Timestamp: timestamppb.New(time.Unix(10, 0)),
+						},
+					},
+				},
+			},
+		},
+	}
+	require.Equal(t, expected, c.GetProto())
+}
+
+func TestStalenessMarkers(t *testing.T) {
+	c := NewCollection(FormatConfig{StalenessMarkers: true})
+	c.Add(testutil.MustMetric(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"time_idle": 42.0},
+		time.Unix(0, 0),
+		telegraf.Gauge,
+	), time.Unix(0, 0))
+	c.Add(testutil.MustMetric(
+		"prometheus",
+		map[string]string{},
+		map[string]interface{}{
+			"http_request_duration_seconds_sum":   10.0,
+			"http_request_duration_seconds_count": 2,
+		},
+		time.Unix(0, 0),
+		telegraf.Histogram,
+	), time.Unix(0, 0))
+
+	// Expired gauges are kept with the marker, histograms are removed
+	c.Expire(time.Unix(20, 0), 10*time.Second)
+	families := c.GetProto()
+	require.Len(t, families, 1)
+	require.Equal(t, "cpu_time_idle", families[0].GetName())
+	require.Len(t, families[0].Metric, 1)
+	value := families[0].Metric[0].Gauge.GetValue()
+	require.Equal(t, math.Float64bits(staleNaN), math.Float64bits(value))
+
+	// The marker is kept for another period
+	c.Expire(time.Unix(25, 0), 10*time.Second)
+	require.Len(t, c.GetProto(), 1)
+	c.Expire(time.Unix(31, 0), 10*time.Second)
+	require.Empty(t, c.GetProto())
+}
+
+func TestStalenessMarkersRestart(t *testing.T) {
+	c := NewCollection(FormatConfig{StalenessMarkers: true})
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"time_idle": 42.0},
+		time.Unix(30, 0),
+		telegraf.Counter,
+	)
+	c.Add(m, time.Unix(0, 0))
+	c.Expire(time.Unix(20, 0), 10*time.Second)
+
+	// The series starts again with a new creation time
+	c.Add(m, time.Unix(22, 0))
+	families := c.GetOpenMetrics()
+	require.Len(t, families, 1)
+	require.Equal(t, []time.Time{time.Unix(22, 0)}, families[0].Created)
+	require.Equal(t, 42.0, families[0].Family.Metric[0].Counter.GetValue())
+}
+
+func TestNativeHistograms(t *testing.T) {
+	c := NewCollection(FormatConfig{NativeHistograms: true, NativeHistogramSchema: 0})
+	c.Add(testutil.MustMetric(
+		"prometheus",
+		map[string]string{},
+		map[string]interface{}{
+			"http_request_duration_seconds_sum":   10.0,
+			"http_request_duration_seconds_count": 5,
+		},
+		time.Unix(0, 0),
+		telegraf.Histogram,
+	), time.Unix(0, 0))
+	buckets := map[string]float64{"0": 1, "1": 2, "2": 4, "+Inf": 4}
+	for le, count := range buckets {
+		c.Add(testutil.MustMetric(
+			"prometheus",
+			map[string]string{"le": le},
+			map[string]interface{}{"http_request_duration_seconds_bucket": count},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		), time.Unix(0, 0))
+	}
+
+	families := c.GetProto()
+	require.Len(t, families, 1)
+	h := families[0].Metric[0].Histogram
+	require.Equal(t, int32(0), h.GetSchema())
+	require.Equal(t, nativeZeroThreshold, h.GetZeroThreshold())
+	// The bucket with bound 0 and the observation above the +Inf bucket
+	// count go to the zero bucket and the bucket after the largest bound
+	require.Equal(t, uint64(1), h.GetZeroCount())
+	require.Equal(t, []*dto.BucketSpan{
+		{Offset: proto.Int32(0), Length: proto.Uint32(3)},
+	}, h.PositiveSpan)
+	require.Equal(t, []int64{1, 1, -1}, h.PositiveDelta)
+	require.Empty(t, h.NegativeSpan)
+}
+
+func TestNativeBucketIndex(t *testing.T) {
+	tests := []struct {
+		value    float64
+		schema   int32
+		expected int32
+	}{
+		{value: 1, schema: 0, expected: 0},
+		{value: 2, schema: 0, expected: 1},
+		{value: 3, schema: 0, expected: 2},
+		{value: 0.5, schema: 0, expected: -1},
+		{value: 2, schema: 3, expected: 8},
+		{value: 16, schema: -2, expected: 1},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, nativeBucketIndex(tt.value, tt.schema), "%v with schema %d", tt.value, tt.schema)
+	}
+}
@@ -0,0 +1,124 @@
+package prometheus
+
+import (
+	"math"
+	"sort"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// staleNaN is the value Prometheus uses to mark a series as stale, it is only
+// preserved by the protobuf format
+var staleNaN = math.Float64frombits(0x7ff0000000000002)
+
+// nativeZeroThreshold is the width of the zero bucket, the same value is used
+// by the Prometheus client library
+const nativeZeroThreshold = 2.938735877055719e-39
+
+// Valid schemas of native histograms
+const (
+	MinNativeHistogramSchema = -4
+	MaxNativeHistogramSchema = 8
+)
+
+// nativeBucketIndex returns the index of the exponential bucket containing
+// the positive value, bucket i covers (base^(i-1), base^i] with
+// base = 2^(2^-schema)
+func nativeBucketIndex(v float64, schema int32) int32 {
+	return int32(math.Ceil(math.Log2(v) * math.Exp2(float64(schema))))
+}
+
+// setNativeHistogram adds the native representation of the histogram. The
+// observations of a classic bucket are placed in the exponential bucket
+// containing its upper bound, observations above the largest finite bound
+// in the next bucket. The result is an approximation with the resolution of
+// the classic buckets.
+func setNativeHistogram(h *dto.Histogram, hist *Histogram, schema int32) {
+	buckets := make([]Bucket, len(hist.Buckets))
+	copy(buckets, hist.Buckets)
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Bound < buckets[j].Bound
+	})
+
+	positive := make(map[int32]uint64)
+	negative := make(map[int32]uint64)
+	var zero, cumulative uint64
+	lastBound := math.Inf(-1)
+	overflow := func(count uint64) {
+		if lastBound > 0 {
+			positive[nativeBucketIndex(lastBound, schema)+1] += count
+		} else {
+			zero += count
+		}
+	}
+	for _, b := range buckets {
+		if b.Count < cumulative {
+			continue
+		}
+		count := b.Count - cumulative
+		cumulative = b.Count
+
+		switch {
+		case math.IsInf(b.Bound, 1):
+			if count > 0 {
+				overflow(count)
+			}
+			continue
+		case count == 0:
+			// Only populated buckets are added
+		case b.Bound > 0:
+			positive[nativeBucketIndex(b.Bound, schema)] += count
+		case b.Bound < 0:
+			negative[nativeBucketIndex(-b.Bound, schema)] += count
+		default:
+			zero += count
+		}
+		lastBound = b.Bound
+	}
+	// Observations not covered by a +Inf bucket
+	if hist.Count > cumulative {
+		overflow(hist.Count - cumulative)
+	}
+
+	h.Schema = proto.Int32(schema)
+	h.ZeroThreshold = proto.Float64(nativeZeroThreshold)
+	h.ZeroCount = proto.Uint64(zero)
+	h.PositiveSpan, h.PositiveDelta = nativeSpans(positive)
+	h.NegativeSpan, h.NegativeDelta = nativeSpans(negative)
+}
+
+// nativeSpans returns the spans of the populated buckets and the delta
+// encoded counts of the buckets
+func nativeSpans(counts map[int32]uint64) ([]*dto.BucketSpan, []int64) {
+	if len(counts) == 0 {
+		return nil, nil
+	}
+
+	indices := make([]int32, 0, len(counts))
+	for index := range counts {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	var spans []*dto.BucketSpan
+	deltas := make([]int64, 0, len(indices))
+	var previousIndex int32
+	var previousCount int64
+	for i, index := range indices {
+		switch {
+		case i == 0:
+			spans = append(spans, &dto.BucketSpan{Offset: proto.Int32(index), Length: proto.Uint32(1)})
+		case index == previousIndex+1:
+			span := spans[len(spans)-1]
+			span.Length = proto.Uint32(span.GetLength() + 1)
+		default:
+			spans = append(spans, &dto.BucketSpan{Offset: proto.Int32(index - previousIndex - 1), Length: proto.Uint32(1)})
+		}
+		count := int64(counts[index])
+		deltas = append(deltas, count-previousCount)
+		previousIndex = index
+		previousCount = count
+	}
+	return spans, deltas
+}
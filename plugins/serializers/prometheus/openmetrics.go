@@ -0,0 +1,228 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// OpenMetricsFamily is a metric family along with the data of the
+// OpenMetrics format that is not part of the protobuf messages
+type OpenMetricsFamily struct {
+	Family *dto.MetricFamily
+	// Unit of the family, empty if unknown
+	Unit string
+	// Created holds the creation time of each metric of the family, zero
+	// if unknown
+	Created []time.Time
+}
+
+// Units detected from the suffix of metric names, the base units recommended
+// by Prometheus and common derived units
+var units = []string{
+	"seconds",
+	"bytes",
+	"bits",
+	"ratio",
+	"percent",
+	"celsius",
+	"meters",
+	"grams",
+	"joules",
+	"volts",
+	"amperes",
+	"watts",
+	"hertz",
+}
+
+// metricUnit returns the unit from the suffix of the metric name
+func metricUnit(name string) string {
+	name = strings.TrimSuffix(name, "_total")
+	for _, unit := range units {
+		if strings.HasSuffix(name, "_"+unit) {
+			return unit
+		}
+	}
+	return ""
+}
+
+// GetOpenMetrics returns the metric families for the OpenMetrics format
+func (c *Collection) GetOpenMetrics() []OpenMetricsFamily {
+	return c.families()
+}
+
+// WriteOpenMetrics writes the families in the OpenMetrics text format
+// including the terminating EOF marker
+func WriteOpenMetrics(w io.Writer, families []OpenMetricsFamily) error {
+	var buf bytes.Buffer
+	for _, family := range families {
+		writeOpenMetricsFamily(&buf, family)
+	}
+	buf.WriteString("# EOF\n")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func writeOpenMetricsFamily(w *bytes.Buffer, family OpenMetricsFamily) {
+	mf := family.Family
+	name := mf.GetName()
+
+	var typ string
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		// The family name of counters has no suffix, the samples use _total
+		name = strings.TrimSuffix(name, "_total")
+		typ = "counter"
+	case dto.MetricType_GAUGE:
+		typ = "gauge"
+	case dto.MetricType_HISTOGRAM:
+		typ = "histogram"
+	case dto.MetricType_SUMMARY:
+		typ = "summary"
+	default:
+		typ = "unknown"
+	}
+
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	if family.Unit != "" {
+		fmt.Fprintf(w, "# UNIT %s %s\n", name, family.Unit)
+	}
+	if mf.Help != nil {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeOpenMetrics(mf.GetHelp(), false))
+	}
+
+	for i, m := range mf.Metric {
+		var created time.Time
+		if i < len(family.Created) {
+			created = family.Created[i]
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			writeOpenMetricsSample(w, name+"_total", m, nil, m.Counter.GetValue(), m.Counter.Exemplar)
+			writeOpenMetricsCreated(w, name, m, created)
+		case dto.MetricType_GAUGE:
+			writeOpenMetricsSample(w, name, m, nil, m.Gauge.GetValue(), nil)
+		case dto.MetricType_HISTOGRAM:
+			h := m.Histogram
+			hasInf := false
+			for _, b := range h.Bucket {
+				le := &dto.LabelPair{Name: proto.String("le"), Value: proto.String(formatOpenMetricsFloat(b.GetUpperBound()))}
+				writeOpenMetricsSample(w, name+"_bucket", m, le, float64(b.GetCumulativeCount()), b.Exemplar)
+				hasInf = hasInf || math.IsInf(b.GetUpperBound(), 1)
+			}
+			if !hasInf {
+				le := &dto.LabelPair{Name: proto.String("le"), Value: proto.String("+Inf")}
+				writeOpenMetricsSample(w, name+"_bucket", m, le, float64(h.GetSampleCount()), nil)
+			}
+			writeOpenMetricsSample(w, name+"_count", m, nil, float64(h.GetSampleCount()), nil)
+			writeOpenMetricsSample(w, name+"_sum", m, nil, h.GetSampleSum(), nil)
+			writeOpenMetricsCreated(w, name, m, created)
+		case dto.MetricType_SUMMARY:
+			s := m.Summary
+			for _, q := range s.Quantile {
+				quantile := &dto.LabelPair{Name: proto.String("quantile"), Value: proto.String(formatOpenMetricsFloat(q.GetQuantile()))}
+				writeOpenMetricsSample(w, name, m, quantile, q.GetValue(), nil)
+			}
+			writeOpenMetricsSample(w, name+"_count", m, nil, float64(s.GetSampleCount()), nil)
+			writeOpenMetricsSample(w, name+"_sum", m, nil, s.GetSampleSum(), nil)
+			writeOpenMetricsCreated(w, name, m, created)
+		default:
+			writeOpenMetricsSample(w, name, m, nil, m.Untyped.GetValue(), nil)
+		}
+	}
+}
+
+func writeOpenMetricsCreated(w *bytes.Buffer, name string, m *dto.Metric, created time.Time) {
+	if created.IsZero() {
+		return
+	}
+	value := float64(created.UnixNano()) / float64(time.Second)
+	writeOpenMetricsSample(w, name+"_created", m, nil, value, nil)
+}
+
+func writeOpenMetricsSample(w *bytes.Buffer, name string, m *dto.Metric, extra *dto.LabelPair, value float64, exemplar *dto.Exemplar) {
+	w.WriteString(name)
+	labels := m.Label
+	if extra != nil {
+		labels = append(labels[:len(labels):len(labels)], extra)
+	}
+	writeOpenMetricsLabels(w, labels)
+	w.WriteByte(' ')
+	w.WriteString(formatOpenMetricsFloat(value))
+	if m.TimestampMs != nil {
+		w.WriteByte(' ')
+		w.WriteString(formatOpenMetricsTimestamp(m.GetTimestampMs()))
+	}
+	if exemplar != nil {
+		w.WriteString(" # ")
+		writeOpenMetricsLabels(w, exemplar.Label)
+		if len(exemplar.Label) == 0 {
+			w.WriteString("{}")
+		}
+		w.WriteByte(' ')
+		w.WriteString(formatOpenMetricsFloat(exemplar.GetValue()))
+		if exemplar.Timestamp != nil {
+			w.WriteByte(' ')
+			w.WriteString(strconv.FormatFloat(float64(exemplar.Timestamp.AsTime().UnixNano())/float64(time.Second), 'f', -1, 64))
+		}
+	}
+	w.WriteByte('\n')
+}
+
+func writeOpenMetricsLabels(w *bytes.Buffer, labels []*dto.LabelPair) {
+	if len(labels) == 0 {
+		return
+	}
+	w.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(label.GetName())
+		w.WriteString(`="`)
+		w.WriteString(escapeOpenMetrics(label.GetValue(), true))
+		w.WriteByte('"')
+	}
+	w.WriteByte('}')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	valueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeOpenMetrics(s string, quote bool) string {
+	if quote {
+		return valueEscaper.Replace(s)
+	}
+	return helpEscaper.Replace(s)
+}
+
+func formatOpenMetricsFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	// Use the canonical representation, e.g. for the le and quantile labels
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if !strings.ContainsAny(s, "e.") {
+		s += ".0"
+	}
+	return s
+}
+
+func formatOpenMetricsTimestamp(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+}
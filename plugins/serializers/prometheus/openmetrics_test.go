@@ -0,0 +1,98 @@
+package prometheus
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	c := NewCollection(FormatConfig{
+		MetricSortOrder: SortMetrics,
+		ExemplarTags:    []string{"trace_id"},
+	})
+	c.Add(testutil.MustMetric(
+		"http",
+		map[string]string{"path": "/", "trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"},
+		map[string]interface{}{"requests_total": 42.0},
+		time.Unix(10, 0),
+		telegraf.Counter,
+	), time.Unix(5, 0))
+	c.Add(testutil.MustMetric(
+		"disk",
+		map[string]string{},
+		map[string]interface{}{"free_bytes": 1024},
+		time.Unix(10, 0),
+		telegraf.Gauge,
+	), time.Unix(5, 0))
+	c.Add(testutil.MustMetric(
+		"prometheus",
+		map[string]string{},
+		map[string]interface{}{
+			"request_duration_seconds_sum":   3.5,
+			"request_duration_seconds_count": 2,
+		},
+		time.Unix(10, 0),
+		telegraf.Histogram,
+	), time.Unix(5, 0))
+	c.Add(testutil.MustMetric(
+		"prometheus",
+		map[string]string{"le": "0.5"},
+		map[string]interface{}{"request_duration_seconds_bucket": 1.0},
+		time.Unix(10, 0),
+		telegraf.Histogram,
+	), time.Unix(5, 0))
+	c.Add(testutil.MustMetric(
+		"cpu",
+		map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 98.5},
+		time.Unix(10, 0),
+		telegraf.Untyped,
+	), time.Unix(5, 0))
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOpenMetrics(&buf, c.GetOpenMetrics()))
+
+	expected := `# TYPE cpu_usage_idle unknown
+# HELP cpu_usage_idle Telegraf collected metric
+cpu_usage_idle{cpu="cpu0"} 98.5
+# TYPE disk_free_bytes gauge
+# UNIT disk_free_bytes bytes
+# HELP disk_free_bytes Telegraf collected metric
+disk_free_bytes 1024.0
+# TYPE http_requests counter
+# HELP http_requests Telegraf collected metric
+http_requests_total{path="/"} 42.0 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 42.0 10
+http_requests_created{path="/"} 5.0
+# TYPE request_duration_seconds histogram
+# UNIT request_duration_seconds seconds
+# HELP request_duration_seconds Telegraf collected metric
+request_duration_seconds_bucket{le="0.5"} 1.0
+request_duration_seconds_bucket{le="+Inf"} 2.0
+request_duration_seconds_count 2.0
+request_duration_seconds_sum 3.5
+request_duration_seconds_created 5.0
+# EOF
+`
+	require.Equal(t, expected, buf.String())
+}
+
+func TestMetricUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "request_duration_seconds", expected: "seconds"},
+		{name: "network_received_bytes_total", expected: "bytes"},
+		{name: "cpu_usage_idle", expected: ""},
+		{name: "seconds", expected: ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, metricUnit(tt.name), tt.name)
+	}
+}
@@ -40,6 +40,16 @@ type FormatConfig struct {
 	// HELP metadata in Prometheus payload. Setting to true
 	// helps to reduce payload size.
 	CompactEncoding bool
+	// ExemplarTags are the tags moved from the labels of counters to their
+	// exemplar, e.g. the trace id.
+	ExemplarTags []string
+	// NativeHistograms enables the generation of native histograms from the
+	// buckets of histograms using the given schema.
+	NativeHistograms      bool
+	NativeHistogramSchema int32
+	// StalenessMarkers keeps expired counters, gauges and untyped metrics for
+	// another expiration period with the value set to the staleness marker.
+	StalenessMarkers bool
 }
 
 type Serializer struct {